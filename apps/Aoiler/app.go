@@ -1,46 +1,69 @@
 package main
 
 import (
-	"context"
-	"os/exec"
-	"strings"
 	"Aoiler/services"
+	"context"
+	"sync"
+
+	"kaguyadots/filepicker"
 )
 
 type App struct {
 	ctx            context.Context
 	serviceManager *services.ServiceManager
-	fileSearch     *services.FileSearchService
+
+	queryMu     sync.Mutex
+	nextQueryID int
+	inFlight    map[int]context.CancelFunc
 }
 
 type QueryRequest struct {
-	Query string `json:"query"`
+	Query        string `json:"query"`
+	ConfirmToken string `json:"confirmToken,omitempty"`
+	NoCache      bool   `json:"noCache,omitempty"`
 }
 
 type QueryResponse struct {
-	Success bool        `json:"success"`
-	Service string      `json:"service"`
-	Result  interface{} `json:"result"`
-	Error   string      `json:"error,omitempty"`
+	Success           bool                          `json:"success"`
+	Service           string                        `json:"service"`
+	Result            interface{}                   `json:"result"`
+	Error             string                        `json:"error,omitempty"`
+	NeedsConfirmation bool                          `json:"needsConfirmation,omitempty"`
+	Confirmation      *services.ConfirmationRequest `json:"confirmation,omitempty"`
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
 	return &App{
 		serviceManager: services.NewServiceManager(),
-		fileSearch:     services.NewFileSearchService(),
+		inFlight:       make(map[int]context.CancelFunc),
 	}
 }
 // startup is called when the app starts
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
-	// a.services = services.NewServiceManager()
+	if err := a.serviceManager.Start(ctx); err != nil {
+		println("Clipboard history disabled:", err.Error())
+	}
+}
+
+// shutdown is called when the app exits
+func (a *App) shutdown(ctx context.Context) {
+	a.CancelQuery()
+	a.serviceManager.Stop()
 }
 
 // ProcessQuery handles the main query processing
 func (a *App) ProcessQuery(req QueryRequest) QueryResponse {
-	intent := a.serviceManager.ClassifyIntent(req.Query)
-	result, err := a.serviceManager.RouteToService(intent, req.Query)
+	ctx, done := a.trackQuery()
+	defer done()
+
+	query := a.serviceManager.ResolveCaptureReference(req.Query)
+	intent := a.serviceManager.IntentForQuery(query, req.ConfirmToken)
+	result, err := a.serviceManager.Execute(ctx, intent, query, services.QueryOptions{
+		ConfirmToken: req.ConfirmToken,
+		BypassCache:  req.NoCache,
+	})
 
 	if err != nil {
 		return QueryResponse{
@@ -50,6 +73,17 @@ func (a *App) ProcessQuery(req QueryRequest) QueryResponse {
 		}
 	}
 
+	if confirmation, ok := result.(*services.ConfirmationRequest); ok {
+		return QueryResponse{
+			Success:           false,
+			Service:           intent.ServiceName,
+			NeedsConfirmation: true,
+			Confirmation:      confirmation,
+		}
+	}
+
+	a.serviceManager.RecordQueryPaths(query)
+
 	return QueryResponse{
 		Success: true,
 		Service: intent.ServiceName,
@@ -57,6 +91,44 @@ func (a *App) ProcessQuery(req QueryRequest) QueryResponse {
 	}
 }
 
+// CancelQuery stops every query that is still running
+func (a *App) CancelQuery() bool {
+	a.queryMu.Lock()
+	defer a.queryMu.Unlock()
+
+	for _, cancel := range a.inFlight {
+		cancel()
+	}
+	return len(a.inFlight) > 0
+}
+
+// trackQuery derives a cancellable context for one query from the app context
+func (a *App) trackQuery() (context.Context, func()) {
+	parent := a.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+
+	a.queryMu.Lock()
+	id := a.nextQueryID
+	a.nextQueryID++
+	a.inFlight[id] = cancel
+	a.queryMu.Unlock()
+
+	return ctx, func() {
+		a.queryMu.Lock()
+		delete(a.inFlight, id)
+		a.queryMu.Unlock()
+		cancel()
+	}
+}
+
+// UndoLastAction reverts the most recent file-changing action
+func (a *App) UndoLastAction() (services.UndoResult, error) {
+	return a.serviceManager.UndoLastAction()
+}
+
 // GetAvailableServices returns list of available services
 func (a *App) GetAvailableServices() []ServiceInfo {
 	return []ServiceInfo{
@@ -65,44 +137,119 @@ func (a *App) GetAvailableServices() []ServiceInfo {
 		{Name: "linter", Description: "Lint and format code files"},
 		{Name: "ocr", Description: "Extract text from screen area"},
 		{Name: "converter", Description: "Convert media files with ffmpeg"},
+		{Name: "clipboard", Description: "Search and re-copy clipboard history"},
+		{Name: "launcher", Description: "Launch applications and quickapps"},
+		{Name: "vision", Description: "Ask the LLM about a screen region or image"},
+		{Name: "text", Description: "Transform clipboard, OCR or file text locally or with the LLM"},
+		{Name: "capture", Description: "Take screenshots and screen recordings"},
+		{Name: "usage", Description: "Show LLM token usage and spend against the budget"},
 		{Name: "llm", Description: "Query LLM for assistance"},
 	}
 }
 
+// ClearLLMCache drops every cached LLM answer
+func (a *App) ClearLLMCache() error {
+	return a.serviceManager.LLM().ClearCache()
+}
+
+// GetLLMUsage returns this month's token usage, cost and budget state
+func (a *App) GetLLMUsage() services.UsageSummary {
+	return a.serviceManager.LLM().UsageSummary()
+}
+
+// GetPathSuggestions completes the path being typed, filtered for the
+// service the query is aimed at
 func (a *App) GetPathSuggestions(input string) services.AutoCompleteResult {
-	result, err := a.fileSearch.GetPathSuggestions(input, false)
-	if err != nil {
-		return services.AutoCompleteResult{
-			Suggestions: []string{},
-			IsPath:      false,
-		}
-	}
-	return result
+	return a.serviceManager.GetPathSuggestions(input)
+}
+
+// GetBaseDirectory returns the directory relative paths resolve against
+func (a *App) GetBaseDirectory() string {
+	return a.serviceManager.FileSearch().BaseDir()
+}
+
+// SetBaseDirectory changes the directory relative paths resolve against
+func (a *App) SetBaseDirectory(dir string) error {
+	return a.serviceManager.FileSearch().SetBaseDir(dir)
+}
+
+// GetClipboardHistory returns the most recent clipboard entries
+func (a *App) GetClipboardHistory(limit int) []services.ClipboardEntry {
+	return a.serviceManager.Clipboard().List(limit)
+}
+
+// CopyClipboardEntry puts a history entry back on the clipboard
+func (a *App) CopyClipboardEntry(id int) error {
+	_, err := a.serviceManager.Clipboard().Copy(a.ctx, id)
+	return err
+}
+
+// PinClipboardEntry pins or unpins a history entry
+func (a *App) PinClipboardEntry(id int, pinned bool) error {
+	_, err := a.serviceManager.Clipboard().SetPinned(id, pinned)
+	return err
+}
+
+// DeleteClipboardEntry removes a history entry
+func (a *App) DeleteClipboardEntry(id int) error {
+	return a.serviceManager.Clipboard().Delete(id)
 }
+
 type ServiceInfo struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 }
 
-// PickFile opens a file picker dialog using yad
+// PickFile opens a file picker dialog and returns "" when cancelled
 func (a *App) PickFile(fileType string) string {
-    var cmd *exec.Cmd
-
-    switch fileType {
-    case "directory":
-        cmd = exec.Command("yad", "--file", "--directory", "--title=Select Directory")
-    case "image":
-        cmd = exec.Command("yad", "--file", "--title=Select Image",
-            "--file-filter=Images | *.png *.jpg *.jpeg *.bmp *.gif *.tiff")
-    default:
-        cmd = exec.Command("yad", "--file", "--title=Select File")
-    }
-
-    output, err := cmd.Output()
-    if err != nil {
-        return "" // User cancelled or error occurred
-    }
-
-    // yad returns the path with a newline, so trim it
-    return strings.TrimSpace(string(output))
+	path, err := filepicker.PickOne(a.ctx, a.pickerOptions(fileType))
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// PickFiles opens a multi-select file picker dialog
+func (a *App) PickFiles(fileType string) []string {
+	opts := a.pickerOptions(fileType)
+	opts.Multiple = true
+
+	paths, err := filepicker.Pick(a.ctx, opts)
+	if err != nil {
+		return []string{}
+	}
+	return paths
+}
+
+func (a *App) pickerOptions(fileType string) filepicker.Options {
+	opts := filepicker.Options{
+		Title:    "Select File",
+		Fallback: filepicker.WailsFallback(a.ctx),
+	}
+
+	switch fileType {
+	case "directory":
+		opts.Title = "Select Directory"
+		opts.SelectDirectory = true
+	case "image":
+		opts.Title = "Select Image"
+		opts.Filters = []filepicker.Filter{
+			{Name: "Images", Patterns: []string{"*.png", "*.jpg", "*.jpeg", "*.bmp", "*.gif", "*.tiff", "*.webp"}},
+		}
+	case "media":
+		opts.Title = "Select Media"
+		opts.Filters = []filepicker.Filter{
+			{Name: "Media", Patterns: []string{"*.mp4", "*.webm", "*.avi", "*.mkv", "*.mov", "*.mp3", "*.wav", "*.flac", "*.ogg", "*.m4a", "*.png", "*.jpg", "*.jpeg", "*.gif", "*.webp"}},
+		}
+	case "code":
+		opts.Title = "Select Source File"
+		opts.Filters = []filepicker.Filter{
+			{Name: "Source files", Patterns: []string{"*.py", "*.go", "*.sh", "*.js", "*.ts", "*.jsx", "*.tsx"}},
+		}
+	}
+
+	if len(opts.Filters) > 0 {
+		opts.Filters = append(opts.Filters, filepicker.Filter{Name: "All files", Patterns: []string{"*"}})
+	}
+	return opts
 }
@@ -1,11 +1,12 @@
 import { useState, useRef, useEffect } from 'react';
-import { Send, Loader2, Search, FolderTree, Code, ScanText, Film, Sparkles, HelpCircle, FileText } from 'lucide-react';
-import { ProcessQuery, GetPathSuggestions, PickFile } from '../wailsjs/go/main/App';
+import { Send, X, Loader2, Search, FolderTree, Code, ScanText, Film, Sparkles, HelpCircle, FileText } from 'lucide-react';
+import { ProcessQuery, CancelQuery, GetPathSuggestions, PickFile } from '../wailsjs/go/main/App';
 
 interface Message {
   id: string;
   type: 'user' | 'assistant';
   content: string;
+  query?: string;
   service?: string;
   result?: any;
   error?: string;
@@ -17,10 +18,28 @@ interface QueryResponse {
   service: string;
   result: any;
   error?: string;
+  needsConfirmation?: boolean;
+  confirmation?: Confirmation;
+}
+
+interface Confirmation {
+  token: string;
+  query: string;
+  plan: { description: string; irreversible?: boolean };
+}
+
+interface ToolCall {
+  name: string;
+  arguments: Record<string, any>;
+  result?: any;
+  error?: string;
 }
 
 interface AutoCompleteResult {
   suggestions: string[];
+  replacements: string[];
+  prefix: string;
+  service?: string;
   isPath: boolean;
 }
 
@@ -40,6 +59,7 @@ function App() {
   const [input, setInput] = useState('');
   const [loading, setLoading] = useState(false);
   const [suggestions, setSuggestions] = useState<string[]>([]);
+  const [completion, setCompletion] = useState<{ prefix: string; replacements: string[] } | null>(null);
   const [showSuggestions, setShowSuggestions] = useState(false);
   const [selectedIndex, setSelectedIndex] = useState(0);
   const [showQuickActions, setShowQuickActions] = useState(true);
@@ -125,6 +145,25 @@ function App() {
       needsFile: true,
       fileType: 'image',
     },
+    {
+      id: 'explain-screen',
+      label: 'Explain Screen',
+      icon: Sparkles,
+      description: 'Ask about a screen region',
+      category: 'OCR & Text',
+      query: 'explain this error on screen',
+      needsFile: false,
+    },
+    {
+      id: 'ask-image',
+      label: 'Ask About Image',
+      icon: Sparkles,
+      description: 'Ask the LLM about an image',
+      category: 'OCR & Text',
+      query: 'describe {path} ',
+      needsFile: true,
+      fileType: 'image',
+    },
     {
       id: 'convert-media',
       label: 'Convert Media',
@@ -170,6 +209,7 @@ function App() {
 
         if (result.isPath && result.suggestions && result.suggestions.length > 0) {
           setSuggestions(result.suggestions);
+          setCompletion({ prefix: result.prefix, replacements: result.replacements });
           setShowSuggestions(true);
         } else {
           setSuggestions([]);
@@ -225,7 +265,7 @@ function App() {
     setTimeout(() => handleSubmit(finalQuery), 100);
   };
 
-  const handleSubmit = async (queryOverride?: string) => {
+  const handleSubmit = async (queryOverride?: string, confirmToken?: string, noCache?: boolean) => {
     const queryToSubmit = queryOverride || input;
     if (!queryToSubmit.trim() || loading) return;
 
@@ -243,7 +283,18 @@ function App() {
     setSuggestions([]);
 
     try {
-      const response: QueryResponse = await ProcessQuery({ query: queryToSubmit });
+      let response: QueryResponse = await ProcessQuery({ query: queryToSubmit, confirmToken, noCache });
+
+      // File-changing actions come back unexecuted until the user confirms them
+      if (response.needsConfirmation && response.confirmation) {
+        const { plan } = response.confirmation;
+        const undo = plan.irreversible ? 'This cannot be undone.' : 'A backup is kept so this can be undone.';
+        if (window.confirm(`${plan.description}\n\n${undo} Continue?`)) {
+          response = await ProcessQuery({ query: queryToSubmit, confirmToken: response.confirmation.token });
+        } else {
+          response = { ...response, error: 'Cancelled.' };
+        }
+      }
 
       let assistantContent = '';
 
@@ -268,7 +319,21 @@ function App() {
           case 'converter':
             assistantContent = `Conversion completed.`;
             break;
+          case 'capture':
+            assistantContent = response.result?.kind === 'recording'
+              ? `Recording saved.`
+              : `Screenshot saved.`;
+            break;
+          case 'text':
+            assistantContent = response.result?.copied
+              ? `Done, copied to clipboard.`
+              : `Done.`;
+            break;
+          case 'usage':
+            assistantContent = response.result?.message || 'No usage recorded.';
+            break;
           case 'llm':
+          case 'vision':
             assistantContent = response.result?.response || 'Response received.';
             break;
           default:
@@ -282,6 +347,7 @@ function App() {
         id: (Date.now() + 1).toString(),
         type: 'assistant',
         content: assistantContent,
+        query: queryToSubmit,
         service: response.service,
         result: response.success ? response.result : null,
         error: response.error,
@@ -338,6 +404,16 @@ function App() {
   };
 
   const handleSuggestionClick = (suggestion: string) => {
+    const index = suggestions.indexOf(suggestion);
+    if (completion && index >= 0 && completion.replacements[index] !== undefined) {
+      // The backend returns the text before the path plus a quoted/escaped replacement
+      setInput(completion.prefix + completion.replacements[index]);
+      setShowSuggestions(false);
+      setSuggestions([]);
+      inputRef.current?.focus();
+      return;
+    }
+
     const words = input.split(' ');
     let replaced = false;
 
@@ -378,7 +454,10 @@ function App() {
       linter: { border: 'border-purple-900/30', bg: '#0F1416', accent: 'text-purple-400' },
       ocr: { border: 'border-amber-900/30', bg: '#0F1416', accent: 'text-amber-400' },
       converter: { border: 'border-cyan-900/30', bg: '#0F1416', accent: 'text-cyan-400' },
+      capture: { border: 'border-sky-900/30', bg: '#0F1416', accent: 'text-sky-400' },
+      text: { border: 'border-lime-900/30', bg: '#0F1416', accent: 'text-lime-400' },
       llm: { border: 'border-pink-900/30', bg: '#0F1416', accent: 'text-pink-400' },
+      vision: { border: 'border-pink-900/30', bg: '#0F1416', accent: 'text-pink-400' },
     };
 
     const style = resultStyles[msg.service as keyof typeof resultStyles] || resultStyles.llm;
@@ -440,19 +519,118 @@ function App() {
           </>
         )}
 
-        {msg.service === 'llm' && (
+        {msg.service === 'capture' && (
+          <>
+            <div className="flex items-center justify-between mb-2">
+              <p className={`font-medium ${style.accent} text-xs`}>
+                {msg.result.kind === 'recording' ? 'Recorded' : 'Captured'} {msg.result.target || msg.result.mode}
+              </p>
+              <span className="text-xs px-2 py-0.5 rounded bg-gray-800 text-gray-400">
+                {msg.result.duration ? `${msg.result.duration}s` : `${(msg.result.size / 1024).toFixed(0)} KB`}
+                {msg.result.annotated && ' · annotated'}
+                {msg.result.copied && ' · copied'}
+              </span>
+            </div>
+            <p className="text-xs text-gray-300 break-all font-mono">
+              {msg.result.path}
+            </p>
+            <div className="flex gap-2 mt-2">
+              {(msg.result.kind === 'recording'
+                ? [['Convert to GIF', `convert "${msg.result.path}" to gif`]]
+                : [['Extract text', `ocr "${msg.result.path}"`], ['Describe', `describe "${msg.result.path}"`]]
+              ).map(([label, query]) => (
+                <button
+                  key={label}
+                  onClick={() => handleSubmit(query)}
+                  className="text-xs px-2 py-0.5 rounded bg-gray-800 text-sky-300 hover:bg-gray-700"
+                >
+                  {label}
+                </button>
+              ))}
+            </div>
+          </>
+        )}
+
+        {msg.service === 'text' && (
+          <>
+            <div className="flex items-center justify-between mb-2">
+              <p className={`font-medium ${style.accent} text-xs`}>{msg.result.task}</p>
+              <span className="text-xs px-2 py-0.5 rounded bg-gray-800 text-gray-400">
+                {msg.result.local
+                  ? 'local'
+                  : msg.result.llm?.model ? `${msg.result.llm.provider} · ${msg.result.llm.model}` : msg.result.llm?.provider}
+                {msg.result.copied && ' · copied'}
+              </span>
+            </div>
+            <div className="p-2 rounded" style={{ backgroundColor: '#0A0E10' }}>
+              <pre className="text-xs text-gray-300 whitespace-pre-wrap break-words">
+                {msg.result.output}
+              </pre>
+            </div>
+            <p className="mt-2 text-xs text-gray-500 font-mono break-all">From {msg.result.source}</p>
+          </>
+        )}
+
+        {(msg.service === 'llm' || msg.service === 'vision') && (
           <>
             <div className="flex items-center justify-between mb-2">
               <p className={`font-medium ${style.accent} text-xs`}>Response</p>
               {msg.result.provider && (
                 <span className="text-xs px-2 py-0.5 rounded bg-gray-800 text-gray-400">
-                  {msg.result.provider}
+                  {msg.result.model ? `${msg.result.provider} · ${msg.result.model}` : msg.result.provider}
+                  {msg.result.usage && ` · ${msg.result.usage.promptTokens + msg.result.usage.completionTokens} tokens · $${msg.result.usage.cost.toFixed(4)}`}
+                  {msg.result.cached && ' · cached'}
                 </span>
               )}
+              {msg.result.cached && msg.query && (
+                <button
+                  onClick={() => handleSubmit(msg.query, undefined, true)}
+                  className="text-xs px-2 py-0.5 rounded bg-gray-800 text-pink-300 hover:bg-gray-700"
+                >
+                  Ask again
+                </button>
+              )}
             </div>
             <p className="text-xs text-gray-300 whitespace-pre-wrap break-words">
               {msg.result.response}
             </p>
+            {msg.result.images && msg.result.images.length > 0 && (
+              <p className="mt-2 text-xs text-gray-500 font-mono break-all">
+                Images: {msg.result.images.join(', ')}
+              </p>
+            )}
+            {msg.result.citations && msg.result.citations.length > 0 && (
+              <div className="mt-3 pt-2 border-t border-gray-800">
+                <p className="text-xs text-gray-500 mb-1">Based on</p>
+                {msg.result.citations.map((c: { path: string; startLine: number; endLine: number }, i: number) => (
+                  <p key={i} className="text-xs font-mono text-gray-400 break-all">
+                    {c.path}:{c.startLine}-{c.endLine}
+                  </p>
+                ))}
+              </div>
+            )}
+            {msg.result.toolCalls && msg.result.toolCalls.length > 0 && (
+              <div className="mt-3 pt-2 border-t border-gray-800 space-y-1">
+                {msg.result.toolCalls.map((call: ToolCall, i: number) => {
+                  const confirmation: Confirmation | undefined = call.result?.confirmation;
+                  return (
+                    <div key={i} className="text-xs">
+                      <span className="font-mono text-gray-400">{call.name}</span>
+                      <span className="text-gray-600 ml-2 font-mono break-all">{JSON.stringify(call.arguments)}</span>
+                      {call.error && <span className="text-red-400 ml-2">{call.error}</span>}
+                      {confirmation && (
+                        <button
+                          onClick={() => handleSubmit(confirmation.query, confirmation.token)}
+                          className="ml-2 px-2 py-0.5 rounded bg-gray-800 text-pink-300 hover:bg-gray-700"
+                        >
+                          Run: {confirmation.plan.description}
+                        </button>
+                      )}
+                    </div>
+                  );
+                })}
+              </div>
+            )}
           </>
         )}
       </div>
@@ -631,13 +809,14 @@ function App() {
                 }}
               />
               <button
-                onClick={() => handleSubmit()}
-                disabled={loading || !input.trim()}
+                onClick={() => (loading ? CancelQuery() : handleSubmit())}
+                disabled={!loading && !input.trim()}
+                title={loading ? 'Cancel' : 'Send'}
                 className="p-2.5 rounded-lg transition-all disabled:opacity-40 disabled:cursor-not-allowed flex-shrink-0 hover:opacity-80"
                 style={{ backgroundColor: '#1E3A5F' }}
               >
                 {loading ? (
-                  <Loader2 className="animate-spin text-gray-100" size={18} />
+                  <X className="text-gray-100" size={18} />
                 ) : (
                   <Send size={18} className="text-gray-100" />
                 )}
@@ -2,7 +2,13 @@ module Aoiler
 
 go 1.24.0
 
-require github.com/wailsapp/wails/v2 v2.11.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/wailsapp/wails/v2 v2.11.0
+	gopkg.in/yaml.v3 v3.0.1
+	kaguyadots/filepicker v0.0.0
+	kaguyadots/runner v0.0.0
+)
 
 require (
 	github.com/bep/debounce v1.2.1 // indirect
@@ -34,4 +40,8 @@ require (
 	golang.org/x/text v0.33.0 // indirect
 )
 
+replace kaguyadots/filepicker => ../shared/filepicker
+
+replace kaguyadots/runner => ../shared/runner
+
 // replace github.com/wailsapp/wails/v2 v2.10.2 => /home/dawu/go/pkg/mod
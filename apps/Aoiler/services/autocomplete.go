@@ -0,0 +1,429 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// queryToken is one shell-like word of a query with quotes and escapes removed
+type queryToken struct {
+	Value  string
+	Start  int  // byte offset of the raw token in the query
+	Quote  byte // opening quote character, 0 if unquoted
+	Closed bool // false when the query ends inside an open quote
+}
+
+// tokenizeQuery splits a query like a shell would: whitespace separates
+// words, single and double quotes group them and backslash escapes the
+// next character outside single quotes.
+func tokenizeQuery(query string) []queryToken {
+	var tokens []queryToken
+	var current strings.Builder
+	var tok queryToken
+	inToken := false
+
+	flush := func() {
+		if inToken {
+			tok.Value = current.String()
+			tokens = append(tokens, tok)
+		}
+		current.Reset()
+		tok = queryToken{}
+		inToken = false
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		if tok.Quote != 0 && !tok.Closed {
+			switch {
+			case c == tok.Quote:
+				tok.Closed = true
+			case c == '\\' && tok.Quote == '"' && i+1 < len(query):
+				i++
+				current.WriteByte(query[i])
+			default:
+				current.WriteByte(c)
+			}
+			continue
+		}
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			flush()
+		case c == '\\' && i+1 < len(query):
+			if !inToken {
+				tok.Start = i
+				inToken = true
+			}
+			i++
+			current.WriteByte(query[i])
+		case (c == '"' || c == '\'') && !inToken:
+			tok.Start = i
+			tok.Quote = c
+			inToken = true
+		default:
+			if !inToken {
+				tok.Start = i
+				inToken = true
+			}
+			current.WriteByte(c)
+		}
+	}
+
+	if tok.Quote != 0 && !tok.Closed {
+		inToken = true
+	}
+	flush()
+	return tokens
+}
+
+// looksLikePath reports whether a token is written as a path
+func looksLikePath(value string) bool {
+	return strings.Contains(value, "/") || strings.HasPrefix(value, "~") ||
+		strings.HasPrefix(value, ".") || strings.HasPrefix(value, "$HOME")
+}
+
+// escapePath renders a path so the tokenizer reads it back unchanged
+func escapePath(path string, quote byte, closeQuote bool) string {
+	if quote != 0 {
+		escaped := path
+		if quote == '"' {
+			escaped = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(path)
+		}
+		if closeQuote {
+			return string(quote) + escaped + string(quote)
+		}
+		return string(quote) + escaped
+	}
+	return strings.NewReplacer(`\`, `\\`, " ", `\ `, `"`, `\"`, "'", `\'`).Replace(path)
+}
+
+// Extension sets used to narrow suggestions to what a service accepts
+var (
+	codeExtensions = map[string]bool{
+		".py": true, ".go": true, ".sh": true,
+		".js": true, ".ts": true, ".jsx": true, ".tsx": true,
+	}
+	imageExtensions = map[string]bool{
+		".png": true, ".jpg": true, ".jpeg": true,
+		".bmp": true, ".tiff": true, ".tif": true,
+		".gif": true, ".webp": true,
+	}
+	mediaExtensions = map[string]bool{
+		".mp4": true, ".webm": true, ".avi": true, ".mkv": true, ".mov": true,
+		".mp3": true, ".wav": true, ".flac": true, ".ogg": true, ".m4a": true,
+		".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true,
+	}
+)
+
+// pathFilter decides whether a directory entry is offered for a service
+type pathFilter func(path string, isDir bool) bool
+
+func extensionFilter(exts map[string]bool) pathFilter {
+	return func(path string, isDir bool) bool {
+		return isDir || exts[strings.ToLower(filepath.Ext(path))]
+	}
+}
+
+func directoryFilter(path string, isDir bool) bool {
+	return isDir
+}
+
+// filterForService returns the suggestion filter for a classified service
+func filterForService(service string) pathFilter {
+	switch service {
+	case "linter":
+		return extensionFilter(codeExtensions)
+	case "ocr":
+		return extensionFilter(imageExtensions)
+	case "converter":
+		return extensionFilter(mediaExtensions)
+	case "organizer":
+		return directoryFilter
+	default:
+		return nil
+	}
+}
+
+const (
+	// maxPathHistory bounds frecency.json; the lowest scoring paths go first
+	maxPathHistory = 500
+	// pathHistoryRetention forgets paths that haven't been used for this long
+	pathHistoryRetention = 90 * 24 * time.Hour
+)
+
+// PathHistory remembers which paths were used in queries for frecency ranking
+type PathHistory struct {
+	mu      sync.Mutex
+	path    string
+	Entries map[string]pathVisit `json:"entries"`
+}
+
+type pathVisit struct {
+	Count int       `json:"count"`
+	Last  time.Time `json:"last"`
+}
+
+// score weights the visit count by how recently the path was used
+func (v pathVisit) score(now time.Time) float64 {
+	age := now.Sub(v.Last)
+	switch {
+	case age < time.Hour:
+		return float64(v.Count) * 4
+	case age < 24*time.Hour:
+		return float64(v.Count) * 2
+	case age < 7*24*time.Hour:
+		return float64(v.Count) * 0.5
+	default:
+		return float64(v.Count) * 0.25
+	}
+}
+
+func NewPathHistory() *PathHistory {
+	h := &PathHistory{
+		path:    filepath.Join(dataDir("paths"), "frecency.json"),
+		Entries: make(map[string]pathVisit),
+	}
+	if data, err := os.ReadFile(h.path); err == nil {
+		json.Unmarshal(data, h)
+	}
+	return h
+}
+
+// Record marks a path and the directory holding it as used
+func (h *PathHistory) Record(path string) {
+	if path == "" {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	path = filepath.Clean(path)
+	for _, p := range []string{path, filepath.Dir(path)} {
+		if p == "/" || p == "." {
+			continue
+		}
+		visit := h.Entries[p]
+		visit.Count++
+		visit.Last = now
+		h.Entries[p] = visit
+	}
+	h.prune(now)
+
+	// Paths show what the user works on, so the file is private like the
+	// other Aoiler stores
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return
+	}
+	if data, err := json.Marshal(h); err == nil {
+		os.WriteFile(h.path, data, 0600)
+		// Earlier versions wrote it world-readable
+		os.Chmod(h.path, 0600)
+	}
+}
+
+// prune forgets paths unused for pathHistoryRetention, then the lowest
+// scoring ones past maxPathHistory
+func (h *PathHistory) prune(now time.Time) {
+	for p, visit := range h.Entries {
+		if now.Sub(visit.Last) > pathHistoryRetention {
+			delete(h.Entries, p)
+		}
+	}
+	if len(h.Entries) <= maxPathHistory {
+		return
+	}
+
+	paths := make([]string, 0, len(h.Entries))
+	for p := range h.Entries {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		a, b := h.Entries[paths[i]], h.Entries[paths[j]]
+		if sa, sb := a.score(now), b.score(now); sa != sb {
+			return sa < sb
+		}
+		return a.Last.Before(b.Last)
+	})
+	for _, p := range paths[:len(paths)-maxPathHistory] {
+		delete(h.Entries, p)
+	}
+}
+
+// Score weights the visit count by how recently the path was used
+func (h *PathHistory) Score(path string) float64 {
+	h.mu.Lock()
+	visit, ok := h.Entries[filepath.Clean(path)]
+	h.mu.Unlock()
+
+	if !ok {
+		return 0
+	}
+	return visit.score(time.Now())
+}
+
+// suggestion is a candidate path with its ranking score
+type suggestion struct {
+	path  string
+	isDir bool
+	score float64
+}
+
+// Suggest completes the path being typed at the end of input. When
+// expectsPath is set the last word is completed even without a path prefix.
+func (fs *FileSearchService) Suggest(input string, expectsPath bool, filter pathFilter) AutoCompleteResult {
+	empty := AutoCompleteResult{Suggestions: []string{}, Replacements: []string{}}
+
+	tokens := tokenizeQuery(input)
+	endsWithSpace := strings.HasSuffix(input, " ") && (len(tokens) == 0 || tokens[len(tokens)-1].Quote == 0 || tokens[len(tokens)-1].Closed)
+
+	var tok queryToken
+	switch {
+	case endsWithSpace || len(tokens) == 0:
+		if !expectsPath || len(tokens) == 0 {
+			return empty
+		}
+		tok = queryToken{Start: len(input)}
+	default:
+		tok = tokens[len(tokens)-1]
+		isPath := looksLikePath(tok.Value) || tok.Quote != 0 ||
+			(expectsPath && len(tokens) > 1)
+		if !isPath {
+			return empty
+		}
+	}
+
+	value := tok.Value
+	if value == "~" || value == "$HOME" {
+		value += "/"
+	}
+	dirPart, prefix := "", value
+	if idx := strings.LastIndex(value, "/"); idx >= 0 {
+		dirPart, prefix = value[:idx+1], value[idx+1:]
+	}
+	dir := fs.ResolvePath(dirPart)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		empty.IsPath = true
+		return empty
+	}
+
+	lowerPrefix := strings.ToLower(prefix)
+	var candidates []suggestion
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(strings.ToLower(name), lowerPrefix) {
+			continue
+		}
+
+		fullPath := filepath.Join(dir, name)
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			if info, err := os.Stat(fullPath); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		if filter != nil && !filter(fullPath, isDir) {
+			continue
+		}
+
+		score := fs.history.Score(fullPath) * 10
+		if info, err := entry.Info(); err == nil {
+			// Recently modified entries float up a little
+			age := time.Since(info.ModTime())
+			switch {
+			case age < 24*time.Hour:
+				score += 5
+			case age < 7*24*time.Hour:
+				score += 2
+			}
+		}
+		if strings.HasPrefix(name, prefix) {
+			score += 1
+		}
+		// Dotfiles are offered but sink below regular entries unless asked for
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".") {
+			score -= 20
+		}
+
+		candidates = append(candidates, suggestion{path: fullPath, isDir: isDir, score: score})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return strings.ToLower(candidates[i].path) < strings.ToLower(candidates[j].path)
+	})
+
+	total := len(candidates)
+	if fs.maxSuggestions > 0 && len(candidates) > fs.maxSuggestions {
+		candidates = candidates[:fs.maxSuggestions]
+	}
+
+	result := AutoCompleteResult{
+		Suggestions:  []string{},
+		Replacements: []string{},
+		Prefix:       input[:tok.Start],
+		IsPath:       true,
+		Total:        total,
+	}
+	for _, c := range candidates {
+		fullPath, display := c.path, fs.displayPath(tok.Value, c.path)
+		if c.isDir {
+			fullPath += "/"
+			display += "/"
+		}
+		result.Suggestions = append(result.Suggestions, fullPath)
+		result.Replacements = append(result.Replacements, escapePath(display, tok.Quote, !c.isDir))
+	}
+	return result
+}
+
+// displayPath keeps the user's notation (~, relative) in completions
+func (fs *FileSearchService) displayPath(typed, resolved string) string {
+	homeDir, _ := os.UserHomeDir()
+
+	switch {
+	case strings.HasPrefix(typed, "~") && homeDir != "":
+		if rel, err := filepath.Rel(homeDir, resolved); err == nil {
+			return "~/" + rel
+		}
+	case strings.HasPrefix(typed, "$HOME") && homeDir != "":
+		if rel, err := filepath.Rel(homeDir, resolved); err == nil {
+			return "$HOME/" + rel
+		}
+	case !filepath.IsAbs(typed):
+		if rel, err := filepath.Rel(fs.BaseDir(), resolved); err == nil && !strings.HasPrefix(rel, "..") {
+			if strings.HasPrefix(typed, "./") {
+				return "./" + rel
+			}
+			return rel
+		}
+	}
+	return resolved
+}
+
+// ResolvePath expands ~ and $HOME and anchors relative paths at the base directory
+func (fs *FileSearchService) ResolvePath(path string) string {
+	return fs.paths.Resolve(path)
+}
+
+// BaseDir is the directory relative paths are resolved against
+func (fs *FileSearchService) BaseDir() string {
+	return fs.paths.BaseDir()
+}
+
+// SetBaseDir changes the directory relative paths are resolved against
+func (fs *FileSearchService) SetBaseDir(dir string) error {
+	return fs.paths.SetBaseDir(dir)
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTokenizeQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []queryToken
+	}{
+		{`lint main.go`, []queryToken{{Value: "lint"}, {Value: "main.go", Start: 5}}},
+		{`convert "~/My Clip.webm" to mp4`, []queryToken{
+			{Value: "convert"}, {Value: "~/My Clip.webm", Start: 8, Quote: '"', Closed: true},
+			{Value: "to", Start: 25}, {Value: "mp4", Start: 28},
+		}},
+		{`ocr My\ Scan.png`, []queryToken{{Value: "ocr"}, {Value: "My Scan.png", Start: 4}}},
+		{`"say \"hi\""`, []queryToken{{Value: `say "hi"`, Quote: '"', Closed: true}}},
+		// Single quotes keep backslashes
+		{`'C:\dir'`, []queryToken{{Value: `C:\dir`, Quote: '\'', Closed: true}}},
+		{`lint "~/My Proj`, []queryToken{{Value: "lint"}, {Value: "~/My Proj", Start: 5, Quote: '"'}}},
+		{"  a\tb\n", []queryToken{{Value: "a", Start: 2}, {Value: "b", Start: 4}}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := tokenizeQuery(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenizeQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	paths := []string{"/plain/path", "/My Clip.webm", `/it's "here"`, `/back\slash`, "~/a b/c"}
+
+	for _, path := range paths {
+		for _, quote := range []byte{0, '"'} {
+			escaped := escapePath(path, quote, true)
+			tokens := tokenizeQuery("open " + escaped)
+			if len(tokens) != 2 || tokens[1].Value != path {
+				t.Errorf("escapePath(%q, %q) = %s, read back as %+v", path, quote, escaped, tokens)
+			}
+		}
+	}
+	if got := escapePath("/a b", '"', false); got != `"/a b` {
+		t.Errorf("open quote = %s", got)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	paths, _, work := testPaths(t, "main.go", "main.txt", "My Notes/todo.md", ".hidden.go", "Downloads/clip.mkv")
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	fs := NewFileSearchService(paths)
+
+	tests := []struct {
+		input        string
+		expectsPath  bool
+		service      string
+		prefix       string
+		replacements []string
+	}{
+		{"lint ma", true, "linter", "lint ", []string{"main.go"}},
+		{"lint ./ma", true, "linter", "lint ", []string{"./main.go"}},
+		{"show ~/work/My", false, "", "show ", []string{`~/work/My\ Notes/`}},
+		{`show "My`, true, "", "show ", []string{`"My Notes/`}},
+		{"organize ", true, "organizer", "organize ", []string{"Downloads/", `My\ Notes/`}},
+		// Dotfiles are offered last unless asked for
+		{"lint ", true, "linter", "lint ", []string{"Downloads/", "main.go", `My\ Notes/`, ".hidden.go"}},
+		{"lint .h", true, "linter", "lint ", []string{".hidden.go"}},
+		{"hello wor", false, "", "", nil},
+	}
+
+	for _, tt := range tests {
+		result := fs.Suggest(tt.input, tt.expectsPath, filterForService(tt.service))
+		if tt.replacements == nil {
+			if len(result.Suggestions) != 0 || result.IsPath {
+				t.Errorf("Suggest(%q) = %+v, want nothing", tt.input, result)
+			}
+			continue
+		}
+		if result.Prefix != tt.prefix || !reflect.DeepEqual(result.Replacements, tt.replacements) {
+			t.Errorf("Suggest(%q) = %q + %q, want %q + %q", tt.input, result.Prefix, result.Replacements, tt.prefix, tt.replacements)
+		}
+	}
+
+	// A path used before ranks first
+	fs.RecordPath(filepath.Join(work, "main.txt"))
+	if result := fs.Suggest("open ma", true, nil); !reflect.DeepEqual(result.Replacements, []string{"main.txt", "main.go"}) {
+		t.Errorf("after use = %q, want main.txt first", result.Replacements)
+	}
+}
+
+func TestPathHistory(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	h := NewPathHistory()
+
+	h.Record("/home/k/projects/app/main.go")
+	h.Record("/home/k/projects/app/main.go")
+	want := map[string]int{"/home/k/projects/app/main.go": 2, "/home/k/projects/app": 2}
+	got := map[string]int{}
+	for path, visit := range h.Entries {
+		got[path] = visit.Count
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %v, want the path and its directory only", got)
+	}
+	if h.Score("/home/k/projects/app/main.go") != 8 || h.Score("/home/k") != 0 {
+		t.Errorf("scores = %v, %v", h.Score("/home/k/projects/app/main.go"), h.Score("/home/k"))
+	}
+
+	info, err := os.Stat(h.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	if reloaded := NewPathHistory(); len(reloaded.Entries) != 2 {
+		t.Errorf("reloaded %d entries", len(reloaded.Entries))
+	}
+}
+
+func TestPathHistoryPrune(t *testing.T) {
+	now := time.Now()
+	h := &PathHistory{Entries: map[string]pathVisit{
+		"/stale": {Count: 100, Last: now.Add(-pathHistoryRetention - time.Hour)},
+		"/kept":  {Count: 100, Last: now.Add(-48 * time.Hour)},
+	}}
+	for i := 0; i < maxPathHistory; i++ {
+		h.Entries[fmt.Sprintf("/once/%d", i)] = pathVisit{Count: 1, Last: now.Add(-time.Duration(i) * time.Minute)}
+	}
+
+	h.prune(now)
+	if len(h.Entries) != maxPathHistory {
+		t.Fatalf("%d entries, want %d", len(h.Entries), maxPathHistory)
+	}
+	if _, ok := h.Entries["/stale"]; ok {
+		t.Error("path past the retention kept")
+	}
+	if _, ok := h.Entries["/kept"]; !ok {
+		t.Error("frequently used path dropped")
+	}
+	// The oldest of the equally scored paths goes
+	if _, ok := h.Entries[fmt.Sprintf("/once/%d", maxPathHistory-1)]; ok {
+		t.Error("oldest single-use path kept")
+	}
+}
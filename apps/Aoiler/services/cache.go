@@ -0,0 +1,192 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QueryOptions are per-query switches set by the UI
+type QueryOptions struct {
+	ConfirmToken string // authorises a pending mutating action
+	BypassCache  bool   // always ask the provider, skipping cached answers
+}
+
+// cachedResponse is one cache file
+type cachedResponse struct {
+	StoredAt time.Time `json:"storedAt"`
+	Result   LLMResult `json:"result"`
+}
+
+// ResponseCache stores LLM answers by the hash of everything sent to the provider
+type ResponseCache struct {
+	mu       sync.Mutex
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+}
+
+const (
+	defaultCacheTTL      = 24 * time.Hour
+	defaultCacheMaxBytes = 50 * 1024 * 1024
+)
+
+// NewResponseCache reads cache_ttl and cache_max_mb from the [aoiler]
+// table of kaguyadots.toml; cache_ttl = "0" disables caching
+func NewResponseCache() *ResponseCache {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+
+	cache := &ResponseCache{
+		dir:      filepath.Join(cacheDir, "aoiler", "llm"),
+		ttl:      defaultCacheTTL,
+		maxBytes: defaultCacheMaxBytes,
+	}
+
+	table := readKaguyaTable("aoiler")
+	// "0" parses as a zero duration, which Enabled treats as off
+	if ttl, err := time.ParseDuration(table["cache_ttl"]); err == nil {
+		cache.ttl = ttl
+	}
+	if mb, err := strconv.Atoi(table["cache_max_mb"]); err == nil && mb > 0 {
+		cache.maxBytes = int64(mb) * 1024 * 1024
+	}
+	return cache
+}
+
+// Enabled reports whether answers are cached at all
+func (c *ResponseCache) Enabled() bool {
+	return c.ttl > 0
+}
+
+// cacheKey hashes the provider, model, system prompt and messages of a call
+func cacheKey(provider LLMProvider, call *llmCall) string {
+	type message struct {
+		Role   string   `json:"role"`
+		Text   string   `json:"text"`
+		Images []string `json:"images,omitempty"` // sha256 of the image bytes
+	}
+
+	user := message{Role: "user", Text: call.Query}
+	for _, image := range call.Images {
+		user.Images = append(user.Images, hashBytes(image.Data))
+	}
+
+	data, _ := json.Marshal(struct {
+		Provider string    `json:"provider"`
+		Model    string    `json:"model"`
+		System   string    `json:"system"`
+		Messages []message `json:"messages"`
+	}{string(provider), call.Model, call.System, []message{user}})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns a stored answer that is younger than the TTL
+func (c *ResponseCache) Get(key string) (LLMResult, bool) {
+	if !c.Enabled() {
+		return LLMResult{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := os.ReadFile(filepath.Join(c.dir, key+".json"))
+	if err != nil {
+		return LLMResult{}, false
+	}
+
+	var entry cachedResponse
+	if err := json.Unmarshal(data, &entry); err != nil || time.Since(entry.StoredAt) > c.ttl {
+		os.Remove(filepath.Join(c.dir, key+".json"))
+		return LLMResult{}, false
+	}
+	return entry.Result, true
+}
+
+// Put stores an answer and trims the cache to its size limit
+func (c *ResponseCache) Put(key string, result LLMResult) error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Cached answers hold the user's prompts, so keep them private
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+	os.Chmod(c.dir, 0700)
+	data, err := json.Marshal(cachedResponse{StoredAt: time.Now(), Result: result})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, key+".json"), data, 0600); err != nil {
+		return err
+	}
+
+	c.prune()
+	return nil
+}
+
+// prune drops expired entries, then the oldest ones until under maxBytes
+func (c *ResponseCache) prune() {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+
+	type cacheFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []cacheFile
+	var total int64
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(c.dir, entry.Name())
+		if time.Since(info.ModTime()) > c.ttl {
+			os.Remove(path)
+			continue
+		}
+		files = append(files, cacheFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, file := range files {
+		if total <= c.maxBytes {
+			break
+		}
+		if os.Remove(file.path) == nil {
+			total -= file.size
+		}
+	}
+}
+
+// Clear removes every cached answer
+func (c *ResponseCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return os.RemoveAll(c.dir)
+}
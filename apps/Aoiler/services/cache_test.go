@@ -0,0 +1,131 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testCache(t *testing.T, config string) *ResponseCache {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+	if config != "" {
+		path := kaguyaConfigPath()
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte("[aoiler]\n"+config+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewResponseCache()
+}
+
+func TestResponseCacheConfig(t *testing.T) {
+	tests := []struct {
+		config   string
+		ttl      time.Duration
+		maxBytes int64
+	}{
+		{"", defaultCacheTTL, defaultCacheMaxBytes},
+		{`cache_ttl = "0"`, 0, defaultCacheMaxBytes},
+		{`cache_ttl = "1h"`, time.Hour, defaultCacheMaxBytes},
+		{"cache_ttl = \"soon\"\ncache_max_mb = \"2\"", defaultCacheTTL, 2 << 20},
+	}
+	for _, tt := range tests {
+		cache := testCache(t, tt.config)
+		if cache.ttl != tt.ttl || cache.maxBytes != tt.maxBytes {
+			t.Errorf("%q: ttl %v, max %d; want %v, %d", tt.config, cache.ttl, cache.maxBytes, tt.ttl, tt.maxBytes)
+		}
+	}
+
+	disabled := testCache(t, `cache_ttl = "0"`)
+	disabled.Put("key", LLMResult{Response: "hi"})
+	if _, ok := disabled.Get("key"); ok || disabled.Enabled() {
+		t.Error("cache_ttl = 0 still caches")
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	base := llmCall{Model: "gpt-4o-mini", System: "be brief", Query: "what is my gaps_in?"}
+	key := cacheKey(ProviderOpenAI, &base)
+	if again := base; cacheKey(ProviderOpenAI, &again) != key {
+		t.Error("the same call hashed differently")
+	}
+
+	// Usage and tools don't change the answer's key
+	withUsage := base
+	withUsage.Usage = TokenUsage{PromptTokens: 10}
+	if cacheKey(ProviderOpenAI, &withUsage) != key {
+		t.Error("usage changed the key")
+	}
+
+	variants := map[string]llmCall{
+		"model":  {Model: "gpt-4o", System: base.System, Query: base.Query},
+		"system": {Model: base.Model, System: "be verbose", Query: base.Query},
+		"query":  {Model: base.Model, System: base.System, Query: "what is my gaps_out?"},
+		"image":  {Model: base.Model, System: base.System, Query: base.Query, Images: []ImageInput{{Data: []byte("png")}}},
+	}
+	for name, call := range variants {
+		if cacheKey(ProviderOpenAI, &call) == key {
+			t.Errorf("changing the %s kept the key", name)
+		}
+	}
+	if cacheKey(ProviderClaude, &base) == key {
+		t.Error("changing the provider kept the key")
+	}
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	cache := testCache(t, `cache_ttl = "1h"`)
+	if err := cache.Put("fresh", LLMResult{Response: "42", Success: true}); err != nil {
+		t.Fatal(err)
+	}
+	if result, ok := cache.Get("fresh"); !ok || result.Response != "42" {
+		t.Errorf("Get(fresh) = %+v, %v", result, ok)
+	}
+	info, err := os.Stat(filepath.Join(cache.dir, "fresh.json"))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("cache file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	// An entry stored before the TTL is dropped on read
+	data, _ := json.Marshal(cachedResponse{StoredAt: time.Now().Add(-2 * time.Hour), Result: LLMResult{Response: "old"}})
+	stale := filepath.Join(cache.dir, "stale.json")
+	os.WriteFile(stale, data, 0600)
+	if _, ok := cache.Get("stale"); ok {
+		t.Error("Get(stale) hit")
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("stale entry left on disk")
+	}
+	if _, ok := cache.Get("missing"); ok {
+		t.Error("Get(missing) hit")
+	}
+}
+
+func TestResponseCachePrune(t *testing.T) {
+	cache := testCache(t, `cache_ttl = "1h"`)
+	now := time.Now()
+	for i, key := range []string{"a", "b", "c"} {
+		cache.Put(key, LLMResult{Response: "answer"})
+		// Oldest first: a, then b, then c
+		at := now.Add(time.Duration(i-3) * time.Minute)
+		os.Chtimes(filepath.Join(cache.dir, key+".json"), at, at)
+	}
+	expired := now.Add(-2 * time.Hour)
+	os.Chtimes(filepath.Join(cache.dir, "c.json"), expired, expired)
+
+	info, _ := os.Stat(filepath.Join(cache.dir, "a.json"))
+	cache.maxBytes = 2 * info.Size()
+	cache.Put("d", LLMResult{Response: "answer"})
+
+	// c expired, and a is the oldest once the cache is over its size
+	for key, want := range map[string]bool{"a": false, "b": true, "c": false, "d": true} {
+		if _, err := os.Stat(filepath.Join(cache.dir, key+".json")); (err == nil) != want {
+			t.Errorf("%s kept = %v, want %v", key, err == nil, want)
+		}
+	}
+}
@@ -0,0 +1,644 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kaguyadots/runner"
+)
+
+// CaptureResult describes a saved screenshot or recording. Path is what
+// follow-up queries like "ocr the screenshot" operate on.
+type CaptureResult struct {
+	Kind      string  `json:"kind"` // screenshot or recording
+	Mode      string  `json:"mode"` // region, window, screen or monitor
+	Target    string  `json:"target,omitempty"`
+	Path      string  `json:"path"`
+	Size      int64   `json:"size"`
+	Duration  float64 `json:"duration,omitempty"` // seconds recorded
+	Annotated bool    `json:"annotated"`
+	Copied    bool    `json:"copied"`
+	Success   bool    `json:"success"`
+}
+
+// CaptureRequest is what a capture query asks for
+type CaptureRequest struct {
+	Kind     string
+	Mode     string
+	Target   string // window class or title for window captures
+	Duration time.Duration
+	Delay    time.Duration
+	Annotate bool
+	Copy     *bool // nil leaves it to the config
+	Audio    bool
+}
+
+// CaptureConfig is the [aoiler.capture] table of kaguyadots.toml
+type CaptureConfig struct {
+	ScreenshotDir  string
+	RecordingDir   string
+	ScreenshotName string
+	RecordingName  string
+	Annotate       bool
+	AnnotateTool   string
+	Copy           bool
+	Duration       time.Duration
+}
+
+const (
+	defaultRecordDuration = 30 * time.Second
+	minRecordDuration     = time.Second // timeout(1) treats 0 as no limit
+	maxRecordDuration     = 10 * time.Minute
+	maxCaptureDelay       = time.Minute
+)
+
+// ReadCaptureConfig reads [aoiler.capture], defaulting to the directories
+// and names used by the ScreenShot.sh keybind script
+func ReadCaptureConfig() CaptureConfig {
+	homeDir, _ := os.UserHomeDir()
+	pictures := os.Getenv("XDG_PICTURES_DIR")
+	if pictures == "" {
+		pictures = filepath.Join(homeDir, "Pictures")
+	}
+	videos := os.Getenv("XDG_VIDEOS_DIR")
+	if videos == "" {
+		videos = filepath.Join(homeDir, "Videos")
+	}
+
+	config := CaptureConfig{
+		ScreenshotDir:  filepath.Join(pictures, "Screenshots"),
+		RecordingDir:   filepath.Join(videos, "Recordings"),
+		ScreenshotName: "Screenshot_{date}_{time}",
+		RecordingName:  "Recording_{date}_{time}",
+		Copy:           true,
+		Duration:       defaultRecordDuration,
+	}
+
+	table := readKaguyaTable("aoiler.capture")
+	if dir := table["screenshot_dir"]; dir != "" {
+		config.ScreenshotDir = dir
+	}
+	if dir := table["recording_dir"]; dir != "" {
+		config.RecordingDir = dir
+	}
+	if name := table["screenshot_name"]; name != "" {
+		config.ScreenshotName = name
+	}
+	if name := table["recording_name"]; name != "" {
+		config.RecordingName = name
+	}
+	if annotate, err := strconv.ParseBool(table["annotate"]); err == nil {
+		config.Annotate = annotate
+	}
+	config.AnnotateTool = table["annotate_tool"]
+	if copyCapture, err := strconv.ParseBool(table["copy"]); err == nil {
+		config.Copy = copyCapture
+	}
+	if duration, err := time.ParseDuration(table["duration"]); err == nil && duration > 0 {
+		config.Duration = clampDuration(duration, maxRecordDuration)
+	}
+	return config
+}
+
+// CaptureService takes screenshots with grim and records the screen with
+// wf-recorder, selecting regions with slurp and windows through hyprctl
+type CaptureService struct {
+	runner    runner.Runner
+	clipboard *ClipboardService
+	paths     *PathResolver
+	mu        sync.Mutex
+	last      string
+	now       func() time.Time
+}
+
+func NewCaptureService(r runner.Runner, clipboard *ClipboardService, paths *PathResolver) *CaptureService {
+	return &CaptureService{runner: r, clipboard: clipboard, paths: paths, now: time.Now}
+}
+
+// Last returns the path of the most recent capture, or "" before the first
+func (cs *CaptureService) Last() string {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.last
+}
+
+// Capture runs a screenshot or recording query
+func (cs *CaptureService) Capture(ctx context.Context, query string) (CaptureResult, error) {
+	request, ok := parseCaptureRequest(query)
+	if !ok {
+		return CaptureResult{Success: false}, fmt.Errorf("not a screenshot or recording request")
+	}
+	if duration := captureUnsupportedDuration.FindString(query); duration != "" {
+		return CaptureResult{Success: false}, fmt.Errorf("unsupported duration %q, use seconds or minutes such as 30s or 5m", duration)
+	}
+	return cs.Run(ctx, request, ReadCaptureConfig())
+}
+
+// Run performs a parsed capture request
+func (cs *CaptureService) Run(ctx context.Context, request CaptureRequest, config CaptureConfig) (CaptureResult, error) {
+	result := CaptureResult{Kind: request.Kind, Mode: request.Mode, Target: request.Target}
+
+	if request.Delay > 0 {
+		select {
+		case <-time.After(request.Delay):
+		case <-ctx.Done():
+			return result, ctx.Err()
+		}
+	}
+
+	geometry, output, err := cs.area(ctx, request)
+	if err != nil {
+		return result, err
+	}
+
+	dir, name, ext := config.ScreenshotDir, config.ScreenshotName, ".png"
+	if request.Kind == "recording" {
+		dir, name, ext = config.RecordingDir, config.RecordingName, ".mp4"
+	}
+	path, err := cs.outputPath(dir, name, ext, request)
+	if err != nil {
+		return result, err
+	}
+	result.Path = path
+
+	if request.Kind == "recording" {
+		duration := request.Duration
+		if duration == 0 {
+			duration = config.Duration
+		}
+		if duration < minRecordDuration {
+			duration = minRecordDuration
+		}
+		if err := cs.record(ctx, geometry, output, path, duration, request.Audio); err != nil {
+			return result, err
+		}
+		result.Duration = duration.Seconds()
+	} else {
+		args := []string{}
+		if geometry != "" {
+			args = append(args, "-g", geometry)
+		} else if output != "" {
+			args = append(args, "-o", output)
+		}
+		args = append(args, path)
+		if out, err := cs.runner.CombinedOutput(ctx, runner.Cmd("grim", args...)); err != nil {
+			return result, fmt.Errorf("screenshot failed: %s", strings.TrimSpace(string(out)))
+		}
+
+		if request.Annotate || config.Annotate {
+			if err := cs.annotate(ctx, path, config.AnnotateTool); err != nil {
+				return result, err
+			}
+			result.Annotated = true
+		}
+	}
+
+	cs.mu.Lock()
+	cs.last = path
+	cs.mu.Unlock()
+
+	copyCapture := config.Copy
+	if request.Copy != nil {
+		copyCapture = *request.Copy
+	}
+	// Recordings are only copied on request; as a file URI, not their bytes
+	if copyCapture && (request.Kind == "screenshot" || request.Copy != nil) {
+		if err := cs.copy(ctx, request.Kind, path); err != nil {
+			return result, err
+		}
+		result.Copied = true
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		result.Size = info.Size()
+	}
+	result.Success = true
+	return result, nil
+}
+
+// area returns the grim/wf-recorder geometry or output name for a request;
+// both are empty for the whole screen
+func (cs *CaptureService) area(ctx context.Context, request CaptureRequest) (geometry, output string, err error) {
+	switch request.Mode {
+	case "region":
+		selected, err := cs.runner.Output(ctx, runner.Cmd("slurp"))
+		if err != nil {
+			return "", "", fmt.Errorf("region selection cancelled or slurp unavailable")
+		}
+		return strings.TrimSpace(string(selected)), "", nil
+	case "window":
+		window, err := cs.findWindow(ctx, request.Target)
+		if err != nil {
+			return "", "", err
+		}
+		return window.geometry(), "", nil
+	case "monitor":
+		monitors, err := cs.monitors(ctx)
+		if err != nil {
+			return "", "", err
+		}
+		for _, monitor := range monitors {
+			if monitor.Focused {
+				return "", monitor.Name, nil
+			}
+		}
+		return "", "", fmt.Errorf("no focused monitor reported by hyprctl")
+	default:
+		return "", "", nil
+	}
+}
+
+// hyprClient is the part of `hyprctl clients -j` a window capture needs
+type hyprClient struct {
+	Mapped    bool   `json:"mapped"`
+	Hidden    bool   `json:"hidden"`
+	At        [2]int `json:"at"`
+	Size      [2]int `json:"size"`
+	Class     string `json:"class"`
+	Title     string `json:"title"`
+	Workspace struct {
+		ID int `json:"id"`
+	} `json:"workspace"`
+	FocusHistoryID int `json:"focusHistoryID"`
+}
+
+func (c hyprClient) geometry() string {
+	return fmt.Sprintf("%d,%d %dx%d", c.At[0], c.At[1], c.Size[0], c.Size[1])
+}
+
+type hyprMonitor struct {
+	Name            string `json:"name"`
+	Focused         bool   `json:"focused"`
+	ActiveWorkspace struct {
+		ID int `json:"id"`
+	} `json:"activeWorkspace"`
+	SpecialWorkspace struct {
+		ID int `json:"id"`
+	} `json:"specialWorkspace"`
+}
+
+func (cs *CaptureService) monitors(ctx context.Context) ([]hyprMonitor, error) {
+	data, err := cs.runner.Output(ctx, runner.Cmd("hyprctl", "monitors", "-j"))
+	if err != nil {
+		return nil, fmt.Errorf("hyprctl monitors failed: %w", err)
+	}
+	var monitors []hyprMonitor
+	if err := json.Unmarshal(data, &monitors); err != nil {
+		return nil, fmt.Errorf("failed to parse hyprctl monitors: %w", err)
+	}
+	return monitors, nil
+}
+
+// findWindow returns the active window when target is empty, otherwise the
+// most recently focused visible window whose class or title contains it.
+// grim captures screen pixels, so windows on hidden workspaces can't be shot.
+func (cs *CaptureService) findWindow(ctx context.Context, target string) (hyprClient, error) {
+	if target == "" {
+		data, err := cs.runner.Output(ctx, runner.Cmd("hyprctl", "activewindow", "-j"))
+		if err != nil {
+			return hyprClient{}, fmt.Errorf("hyprctl activewindow failed: %w", err)
+		}
+		var window hyprClient
+		if err := json.Unmarshal(data, &window); err != nil || window.Size[0] == 0 {
+			return hyprClient{}, fmt.Errorf("no active window found")
+		}
+		return window, nil
+	}
+
+	data, err := cs.runner.Output(ctx, runner.Cmd("hyprctl", "clients", "-j"))
+	if err != nil {
+		return hyprClient{}, fmt.Errorf("hyprctl clients failed: %w", err)
+	}
+	var clients []hyprClient
+	if err := json.Unmarshal(data, &clients); err != nil {
+		return hyprClient{}, fmt.Errorf("failed to parse hyprctl clients: %w", err)
+	}
+	monitors, err := cs.monitors(ctx)
+	if err != nil {
+		return hyprClient{}, err
+	}
+	visible := make(map[int]bool)
+	for _, monitor := range monitors {
+		visible[monitor.ActiveWorkspace.ID] = true
+		if monitor.SpecialWorkspace.ID != 0 {
+			visible[monitor.SpecialWorkspace.ID] = true
+		}
+	}
+
+	lowerTarget := strings.ToLower(target)
+	var best *hyprClient
+	matched := false
+	for i := range clients {
+		client := &clients[i]
+		if !client.Mapped || client.Hidden {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(client.Class), lowerTarget) &&
+			!strings.Contains(strings.ToLower(client.Title), lowerTarget) {
+			continue
+		}
+		matched = true
+		if !visible[client.Workspace.ID] {
+			continue
+		}
+		if best == nil || client.FocusHistoryID < best.FocusHistoryID {
+			best = client
+		}
+	}
+
+	switch {
+	case best != nil:
+		return *best, nil
+	case matched:
+		return hyprClient{}, fmt.Errorf("%s is not on a visible workspace", target)
+	default:
+		return hyprClient{}, fmt.Errorf("no window matching %q", target)
+	}
+}
+
+// record runs wf-recorder under timeout(1), which stops it with SIGINT so
+// the file is finalised; timeout exits 124 when the duration ran out.
+// Cancelling also interrupts rather than kills it, so what was recorded
+// so far is kept as a playable file.
+func (cs *CaptureService) record(ctx context.Context, geometry, output, path string, duration time.Duration, audio bool) error {
+	args := []string{"--signal=INT", strconv.Itoa(int(duration.Seconds())), "wf-recorder"}
+	if geometry != "" {
+		args = append(args, "-g", geometry)
+	} else if output != "" {
+		args = append(args, "-o", output)
+	}
+	if audio {
+		args = append(args, "--audio")
+	}
+	args = append(args, "-f", path)
+
+	out, err := cs.runner.CombinedOutput(ctx, runner.Cmd("timeout", args...).WithInterrupt())
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 124) {
+		if ctx.Err() != nil {
+			if info, statErr := os.Stat(path); statErr == nil && info.Size() > 0 {
+				return fmt.Errorf("recording stopped early, saved to %s: %w", path, ctx.Err())
+			}
+			os.Remove(path)
+			return ctx.Err()
+		}
+		return fmt.Errorf("recording failed: %s", lastLine(string(out)))
+	}
+	return nil
+}
+
+// annotate opens the screenshot in satty or swappy, which save over it
+func (cs *CaptureService) annotate(ctx context.Context, path, tool string) error {
+	if tool == "" {
+		for _, candidate := range []string{"satty", "swappy"} {
+			if _, err := cs.runner.LookPath(candidate); err == nil {
+				tool = candidate
+				break
+			}
+		}
+	}
+
+	var cmd runner.Command
+	switch tool {
+	case "satty":
+		cmd = runner.Cmd("satty", "--filename", path, "--output-filename", path, "--early-exit")
+	case "swappy":
+		cmd = runner.Cmd("swappy", "-f", path, "-o", path)
+	case "":
+		return fmt.Errorf("annotation needs satty or swappy installed")
+	default:
+		return fmt.Errorf("unsupported annotate_tool %q, use satty or swappy", tool)
+	}
+
+	if out, err := cs.runner.CombinedOutput(ctx, cmd); err != nil {
+		return fmt.Errorf("%s failed: %s", tool, lastLine(string(out)))
+	}
+	return nil
+}
+
+func (cs *CaptureService) copy(ctx context.Context, kind, path string) error {
+	if kind == "recording" {
+		return cs.clipboard.WriteData(ctx, []byte("file://"+path+"\n"), "text/uri-list")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read screenshot: %w", err)
+	}
+	return cs.clipboard.WriteData(ctx, data, "image/png")
+}
+
+// outputPath expands a filename template in dir, adding a counter when the
+// name is taken. Templates may use {date}, {time}, {kind}, {mode} and
+// {target}, and may contain subdirectories.
+func (cs *CaptureService) outputPath(dir, template, ext string, request CaptureRequest) (string, error) {
+	now := cs.now()
+	target := request.Target
+	if target == "" {
+		target = request.Mode
+	}
+	name := strings.NewReplacer(
+		"{date}", now.Format("2006-01-02"),
+		"{time}", now.Format("15-04-05"),
+		"{kind}", request.Kind,
+		"{mode}", request.Mode,
+		"{target}", fileNamePart(target),
+	).Replace(template)
+
+	base := filepath.Join(cs.paths.Resolve(dir), name)
+	if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+		return "", fmt.Errorf("failed to create capture directory: %w", err)
+	}
+
+	path := base + ext
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path, nil
+		}
+		path = fmt.Sprintf("%s_%d%s", base, i, ext)
+	}
+}
+
+// fileNamePart reduces a window title to something safe in a file name
+func fileNamePart(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteRune('-')
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+func clampDuration(d, limit time.Duration) time.Duration {
+	if d > limit {
+		return limit
+	}
+	return d
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+var captureDurationPattern = regexp.MustCompile(`(?i)\b(?:(in|after|delay|wait)\s+)?(\d+)\s*(s|secs?|seconds?|m|mins?|minutes?)\b`)
+
+// captureUnsupportedDuration catches the units captureDurationPattern
+// doesn't know, which would otherwise fall back to the default length
+var captureUnsupportedDuration = regexp.MustCompile(`(?i)\b\d+\s*(?:ms|h|hrs?|hours?|days?)\b`)
+
+// captureFillers are words in a window capture that don't name the window
+var captureFillers = map[string]bool{
+	"a": true, "an": true, "the": true, "of": true, "my": true, "and": true,
+	"take": true, "screenshot": true, "record": true, "capture": true,
+	"window": true, "for": true, "with": true, "then": true, "it": true,
+	"copy": true, "to": true, "clipboard": true, "annotate": true, "edit": true,
+	"audio": true, "sound": true, "no": true, "without": true, "don't": true,
+}
+
+// parseCaptureRequest recognises queries that start with a capture verb:
+// "screenshot region", "take a screenshot of the firefox window",
+// "record screen 30s". Screenshots default to a region selection and
+// recordings to the whole screen.
+func parseCaptureRequest(query string) (CaptureRequest, bool) {
+	lower := strings.ToLower(strings.TrimSpace(query))
+	words := strings.Fields(lower)
+	if len(words) == 0 {
+		return CaptureRequest{}, false
+	}
+
+	var request CaptureRequest
+	switch {
+	case strings.HasPrefix(lower, "screenshot"), strings.HasPrefix(lower, "screen shot"),
+		strings.HasPrefix(lower, "take a screenshot"), strings.HasPrefix(lower, "take screenshot"):
+		request = CaptureRequest{Kind: "screenshot", Mode: "region"}
+	case strings.HasPrefix(lower, "capture ") && len(words) > 1 && captureModes[words[1]] != "":
+		request = CaptureRequest{Kind: "screenshot", Mode: "region"}
+	case words[0] == "record" || words[0] == "screencast" || strings.HasPrefix(lower, "screen record") ||
+		strings.HasPrefix(lower, "start recording"):
+		request = CaptureRequest{Kind: "recording", Mode: "screen"}
+	default:
+		return CaptureRequest{}, false
+	}
+	// "screenshot text" is OCR
+	if queryHasWord(lower, "text") {
+		return CaptureRequest{}, false
+	}
+
+	for _, match := range captureDurationPattern.FindAllStringSubmatch(lower, -1) {
+		n, _ := strconv.Atoi(match[2])
+		unit := time.Second
+		if strings.HasPrefix(match[3], "m") {
+			unit = time.Minute
+		}
+		if match[1] != "" || request.Kind == "screenshot" {
+			request.Delay = clampDuration(time.Duration(n)*unit, maxCaptureDelay)
+		} else {
+			request.Duration = clampDuration(time.Duration(n)*unit, maxRecordDuration)
+		}
+	}
+	rest := captureDurationPattern.ReplaceAllString(lower, " ")
+
+	windowAt := -1
+	restWords := strings.Fields(rest)
+	for i, word := range restWords {
+		if mode := captureModes[word]; mode != "" {
+			request.Mode = mode
+			if mode == "window" {
+				windowAt = i
+			}
+			break
+		}
+	}
+	if windowAt >= 0 {
+		request.Target = windowTarget(restWords, windowAt)
+	}
+
+	request.Annotate = queryHasWord(lower, "annotate") || queryHasWord(lower, "markup") ||
+		strings.Contains(lower, "and edit")
+	request.Audio = queryHasWord(lower, "audio") || queryHasWord(lower, "sound")
+	switch {
+	case strings.Contains(lower, "no copy"), strings.Contains(lower, "don't copy"),
+		strings.Contains(lower, "without copying"):
+		no := false
+		request.Copy = &no
+	case queryHasWord(lower, "copy") || queryHasWord(lower, "clipboard"):
+		yes := true
+		request.Copy = &yes
+	}
+	return request, true
+}
+
+var captureModes = map[string]string{
+	"region": "region", "area": "region", "selection": "region", "select": "region",
+	"window": "window",
+	"screen": "screen", "fullscreen": "screen", "full": "screen", "everything": "screen", "desktop": "screen",
+	"monitor": "monitor", "output": "monitor", "display": "monitor",
+}
+
+// windowTarget reads the window name after "window" ("window firefox") or,
+// failing that, right before it ("the firefox window"). The active window
+// is meant when neither is given.
+func windowTarget(words []string, windowAt int) string {
+	var after []string
+	for _, word := range words[windowAt+1:] {
+		if captureFillers[word] {
+			break
+		}
+		after = append(after, word)
+	}
+	if len(after) > 0 {
+		return strings.Join(after, " ")
+	}
+
+	var before []string
+	for i := windowAt - 1; i >= 0 && !captureFillers[words[i]]; i-- {
+		before = append([]string{words[i]}, before...)
+	}
+	target := strings.Join(before, " ")
+	switch target {
+	case "active", "current", "focused", "this":
+		return ""
+	}
+	return target
+}
+
+var captureReferencePattern = regexp.MustCompile(`(?i)\b(?:the|that|my)\s+(?:last\s+|latest\s+)?(?:screenshot|recording|capture)\b|\b(?:last|latest)\s+(?:screenshot|recording|capture)\b`)
+
+// captureFollowUps are the commands where a bare "it" means the last capture
+var captureFollowUps = map[string]bool{"convert": true, "transcode": true, "ocr": true, "describe": true}
+
+var itPattern = regexp.MustCompile(`(?i)\b(?:it|that)\b`)
+
+// replaceCaptureReference swaps "the screenshot", "last recording" and,
+// after convert/ocr/describe, a bare "it" for the quoted capture path so
+// the query routes like one naming the file
+func replaceCaptureReference(query, path string) string {
+	if path == "" {
+		return query
+	}
+	quoted := `"` + path + `"`
+	if captureReferencePattern.MatchString(query) {
+		return captureReferencePattern.ReplaceAllLiteralString(query, quoted)
+	}
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) > 1 && captureFollowUps[words[0]] {
+		if loc := itPattern.FindStringIndex(query); loc != nil {
+			return query[:loc[0]] + quoted + query[loc[1]:]
+		}
+	}
+	return query
+}
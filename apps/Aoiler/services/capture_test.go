@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"kaguyadots/runner"
+)
+
+func TestParseCaptureRequest(t *testing.T) {
+	yes, no := true, false
+
+	tests := []struct {
+		query string
+		want  CaptureRequest
+		ok    bool
+	}{
+		{"screenshot region", CaptureRequest{Kind: "screenshot", Mode: "region"}, true},
+		{"screenshot", CaptureRequest{Kind: "screenshot", Mode: "region"}, true},
+		{"screenshot window firefox", CaptureRequest{Kind: "screenshot", Mode: "window", Target: "firefox"}, true},
+		{"take a screenshot of the firefox window", CaptureRequest{Kind: "screenshot", Mode: "window", Target: "firefox"}, true},
+		{"screenshot the active window and copy", CaptureRequest{Kind: "screenshot", Mode: "window", Copy: &yes}, true},
+		{"screenshot full screen in 3s", CaptureRequest{Kind: "screenshot", Mode: "screen", Delay: 3 * time.Second}, true},
+		{"capture monitor no copy", CaptureRequest{Kind: "screenshot", Mode: "monitor", Copy: &no}, true},
+		{"screenshot area and annotate", CaptureRequest{Kind: "screenshot", Mode: "region", Annotate: true}, true},
+		{"record screen 30s", CaptureRequest{Kind: "recording", Mode: "screen", Duration: 30 * time.Second}, true},
+		{"record region for 2 minutes with audio", CaptureRequest{Kind: "recording", Mode: "region", Duration: 2 * time.Minute, Audio: true}, true},
+		{"record window kitty 10s", CaptureRequest{Kind: "recording", Mode: "window", Target: "kitty", Duration: 10 * time.Second}, true},
+		{"record screen 90m", CaptureRequest{Kind: "recording", Mode: "screen", Duration: maxRecordDuration}, true},
+		{"screenshot text", CaptureRequest{}, false},
+		{"ocr the screenshot", CaptureRequest{}, false},
+		{"capture text", CaptureRequest{}, false},
+		{"what is a screenshot", CaptureRequest{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseCaptureRequest(tt.query)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCaptureRequest(%q) = %+v, %v; want %+v, %v", tt.query, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestReplaceCaptureReference(t *testing.T) {
+	path := "/home/u/Pictures/Screenshots/shot.png"
+	quoted := `"` + path + `"`
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"ocr the screenshot", "ocr " + quoted},
+		{"describe my last capture", "describe " + quoted},
+		{"convert the recording to gif", "convert " + quoted + " to gif"},
+		{"convert it to jpg", "convert " + quoted + " to jpg"},
+		{"what is in the latest screenshot", "what is in " + quoted},
+		{"what is it", "what is it"},
+		{"convert clip.mkv to mp4", "convert clip.mkv to mp4"},
+	}
+
+	for _, tt := range tests {
+		if got := replaceCaptureReference(tt.query, path); got != tt.want {
+			t.Errorf("replaceCaptureReference(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+	if got := replaceCaptureReference("ocr the screenshot", ""); got != "ocr the screenshot" {
+		t.Errorf("without a capture the query changed to %q", got)
+	}
+}
+
+const testClients = `[
+	{"mapped": true, "hidden": false, "at": [0, 0], "size": [800, 600], "class": "firefox", "title": "Docs", "workspace": {"id": 2}, "focusHistoryID": 3},
+	{"mapped": true, "hidden": false, "at": [10, 40], "size": [1200, 900], "class": "firefox", "title": "Mail", "workspace": {"id": 1}, "focusHistoryID": 1},
+	{"mapped": true, "hidden": false, "at": [1930, 40], "size": [900, 700], "class": "kitty", "title": "nvim", "workspace": {"id": 5}, "focusHistoryID": 0}
+]`
+
+const testMonitors = `[
+	{"name": "DP-1", "focused": true, "activeWorkspace": {"id": 1}, "specialWorkspace": {"id": 0}},
+	{"name": "HDMI-A-1", "focused": false, "activeWorkspace": {"id": 2}, "specialWorkspace": {"id": 0}}
+]`
+
+func testCapture(t *testing.T, fake *runner.Fake) (*CaptureService, CaptureConfig) {
+	t.Helper()
+	paths, home, _ := testPaths(t)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("XDG_PICTURES_DIR", "")
+	t.Setenv("XDG_VIDEOS_DIR", "")
+
+	capture := NewCaptureService(fake, NewClipboardService(fake), paths)
+	capture.now = func() time.Time { return time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC) }
+
+	config := ReadCaptureConfig()
+	if want := filepath.Join(home, "Pictures", "Screenshots"); config.ScreenshotDir != want {
+		t.Fatalf("ScreenshotDir = %q, want %q", config.ScreenshotDir, want)
+	}
+	return capture, config
+}
+
+func TestCaptureScreenshot(t *testing.T) {
+	fake := runner.NewFake().
+		On("slurp", "10,20 300x200\n", nil).
+		On("hyprctl clients", testClients, nil).
+		On("hyprctl monitors", testMonitors, nil)
+	capture, config := testCapture(t, fake)
+	dir := config.ScreenshotDir
+
+	tests := []struct {
+		query    string
+		path     string
+		commands []string
+	}{
+		{"screenshot region no copy", "Screenshot_2026-03-14_09-26-53.png", []string{
+			"slurp",
+			"grim -g 10,20 300x200 " + filepath.Join(dir, "Screenshot_2026-03-14_09-26-53.png"),
+		}},
+		{"screenshot window firefox no copy", "Screenshot_2026-03-14_09-26-53_1.png", []string{
+			"hyprctl clients -j",
+			"hyprctl monitors -j",
+			"grim -g 10,40 1200x900 " + filepath.Join(dir, "Screenshot_2026-03-14_09-26-53_1.png"),
+		}},
+		{"screenshot monitor no copy", "Screenshot_2026-03-14_09-26-53_2.png", []string{
+			"hyprctl monitors -j",
+			"grim -o DP-1 " + filepath.Join(dir, "Screenshot_2026-03-14_09-26-53_2.png"),
+		}},
+	}
+
+	for _, tt := range tests {
+		fake.Reset()
+		result, err := capture.Capture(context.Background(), tt.query)
+		if err != nil {
+			t.Fatalf("Capture(%q): %v", tt.query, err)
+		}
+		want := filepath.Join(dir, tt.path)
+		if result.Path != want || !result.Success || result.Copied {
+			t.Errorf("Capture(%q) = %+v, want path %s", tt.query, result, want)
+		}
+		if got := fake.Commands(); !reflect.DeepEqual(got, tt.commands) {
+			t.Errorf("Capture(%q) commands = %q, want %q", tt.query, got, tt.commands)
+		}
+		if capture.Last() != want {
+			t.Errorf("Last() = %q, want %q", capture.Last(), want)
+		}
+		// grim doesn't run under the fake, so claim the name for the next case
+		if err := os.WriteFile(want, []byte("png"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCaptureWindowErrors(t *testing.T) {
+	fake := runner.NewFake().
+		On("hyprctl clients", testClients, nil).
+		On("hyprctl monitors", testMonitors, nil).
+		On("hyprctl activewindow", "{}", nil)
+	capture, _ := testCapture(t, fake)
+
+	tests := []struct {
+		query   string
+		wantErr string
+	}{
+		{"screenshot window kitty", "kitty is not on a visible workspace"},
+		{"screenshot window zed", `no window matching "zed"`},
+		{"screenshot the active window", "no active window found"},
+	}
+
+	for _, tt := range tests {
+		_, err := capture.Capture(context.Background(), tt.query)
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("Capture(%q) err = %v, want %q", tt.query, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCaptureTemplateAndAnnotate(t *testing.T) {
+	fake := runner.NewFake()
+	capture, config := testCapture(t, fake)
+	config.ScreenshotDir = "~/shots"
+	config.ScreenshotName = "{date}/{mode}-{target}"
+	config.Copy = false
+
+	request, _ := parseCaptureRequest("screenshot the firefox window and annotate")
+	request.Target = "Mozilla Firefox — Docs"
+	fake.On("hyprctl clients", `[{"mapped": true, "at": [1, 2], "size": [3, 4], "title": "Mozilla Firefox — Docs", "workspace": {"id": 1}}]`, nil).
+		On("hyprctl monitors", testMonitors, nil)
+
+	result, err := capture.Run(context.Background(), request, config)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	home, _ := os.UserHomeDir()
+	want := filepath.Join(home, "shots", "2026-03-14", "window-mozilla-firefox-docs.png")
+	if result.Path != want || !result.Annotated || result.Copied {
+		t.Errorf("result = %+v, want annotated %s", result, want)
+	}
+	wantCommands := []string{
+		"hyprctl clients -j",
+		"hyprctl monitors -j",
+		"grim -g 1,2 3x4 " + want,
+		"satty --filename " + want + " --output-filename " + want + " --early-exit",
+	}
+	if got := fake.Commands(); !reflect.DeepEqual(got, wantCommands) {
+		t.Errorf("commands = %q, want %q", got, wantCommands)
+	}
+
+	fake.Reset()
+	fake.Missing("satty")
+	if _, err := capture.Run(context.Background(), request, config); err != nil {
+		t.Fatalf("Run without satty: %v", err)
+	}
+	if got := fake.Commands()[3]; got[:7] != "swappy " {
+		t.Errorf("annotated with %q, want swappy", got)
+	}
+}
+
+func TestCaptureCopiesScreenshotBytes(t *testing.T) {
+	fake := runner.NewFake()
+	capture, _ := testCapture(t, fake)
+	path := filepath.Join(t.TempDir(), "shot.png")
+	if err := os.WriteFile(path, []byte("png-bytes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := capture.copy(context.Background(), "screenshot", path); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	calls := fake.Calls()
+	if len(calls) != 1 || calls[0].String() != "wl-copy --type image/png" || calls[0].Input != "png-bytes" {
+		t.Errorf("calls = %+v, want the PNG piped to wl-copy", calls)
+	}
+}
+
+func TestCaptureRecording(t *testing.T) {
+	fake := runner.NewFake()
+	capture, config := testCapture(t, fake)
+
+	result, err := capture.Capture(context.Background(), "record screen 30s and copy")
+	if err != nil {
+		t.Fatalf("Capture: %v", err)
+	}
+	want := filepath.Join(config.RecordingDir, "Recording_2026-03-14_09-26-53.mp4")
+	if result.Path != want || result.Duration != 30 || !result.Copied {
+		t.Errorf("result = %+v, want 30s copied recording at %s", result, want)
+	}
+	calls := fake.Calls()
+	wantCommands := []string{
+		"timeout --signal=INT 30 wf-recorder -f " + want,
+		"wl-copy --type text/uri-list",
+	}
+	if got := fake.Commands(); !reflect.DeepEqual(got, wantCommands) {
+		t.Fatalf("commands = %q, want %q", got, wantCommands)
+	}
+	if calls[1].Input != "file://"+want+"\n" {
+		t.Errorf("copied %q, want the file URI", calls[1].Input)
+	}
+	if !calls[0].Interrupt {
+		t.Error("wf-recorder would be killed on cancel, leaving an unfinished file")
+	}
+
+	// Units other than seconds and minutes are refused, not guessed
+	for _, query := range []string{"record screen 2h", "record screen 500ms"} {
+		if _, err := capture.Capture(context.Background(), query); err == nil {
+			t.Errorf("Capture(%q): want an unsupported duration error", query)
+		}
+	}
+
+	// A configured length under a second still stops: timeout 0 never would
+	fake.Reset()
+	config.Duration = 500 * time.Millisecond
+	request, _ := parseCaptureRequest("record screen")
+	if result, err := capture.Run(context.Background(), request, config); err != nil || result.Duration != 1 {
+		t.Errorf("Run = %+v, %v; want a 1s recording", result, err)
+	}
+	if got := fake.Commands(); len(got) != 1 || !strings.HasPrefix(got[0], "timeout --signal=INT 1 wf-recorder") {
+		t.Errorf("commands = %q, want a 1s timeout", got)
+	}
+
+	// timeout(1) exits 124 when the duration runs out, which is success
+	expired := exec.Command("sh", "-c", "exit 124").Run()
+	fake = runner.NewFake().On("timeout", "", expired)
+	capture.runner = fake
+	if _, err := capture.Capture(context.Background(), "record screen 5s"); err != nil {
+		t.Errorf("exit 124: %v", err)
+	}
+
+	fake = runner.NewFake().On("timeout", "wf-recorder: no outputs\nfailed", errors.New("exit status 1"))
+	capture.runner = fake
+	if _, err := capture.Capture(context.Background(), "record screen 5s"); err == nil || err.Error() != "recording failed: failed" {
+		t.Errorf("err = %v, want recording failure", err)
+	}
+
+	// Cancelled before anything was written: no empty file is left behind
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fake = runner.NewFake()
+	capture.runner = fake
+	result, err = capture.Capture(ctx, "record screen 5s")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want cancelled", err)
+	}
+	if _, statErr := os.Stat(result.Path); !os.IsNotExist(statErr) {
+		t.Errorf("%s left behind", result.Path)
+	}
+}
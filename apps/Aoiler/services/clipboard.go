@@ -0,0 +1,507 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kaguyadots/runner"
+)
+
+// ClipboardEntry is a single item in the clipboard history
+type ClipboardEntry struct {
+	ID        int       `json:"id"`
+	Kind      string    `json:"kind"`
+	Text      string    `json:"text,omitempty"`
+	ImagePath string    `json:"imagePath,omitempty"`
+	MimeType  string    `json:"mimeType"`
+	Hash      string    `json:"hash"`
+	Source    string    `json:"source"`
+	Pinned    bool      `json:"pinned"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ClipboardResult struct {
+	Action  string           `json:"action"`
+	Entries []ClipboardEntry `json:"entries"`
+	Total   int              `json:"total"`
+	Message string           `json:"message,omitempty"`
+}
+
+// clipboardStore is the on-disk layout of the history file
+type clipboardStore struct {
+	NextID  int              `json:"nextId"`
+	Entries []ClipboardEntry `json:"entries"`
+}
+
+// ClipboardService records clipboard changes into a bounded local history
+type ClipboardService struct {
+	runner      runner.Runner
+	mu          sync.Mutex
+	storeDir    string
+	maxEntries  int
+	maxTextSize int
+	store       clipboardStore
+	watcher     *exec.Cmd
+}
+
+func NewClipboardService(r runner.Runner) *ClipboardService {
+	cs := &ClipboardService{
+		runner:      r,
+		storeDir:    dataDir("clipboard"),
+		maxEntries:  200,
+		maxTextSize: 64 * 1024,
+	}
+	cs.load()
+	return cs
+}
+
+// StartWatcher spawns `wl-paste --watch` and records every clipboard change.
+// It is a no-op when wl-paste is not installed.
+func (cs *ClipboardService) StartWatcher(ctx context.Context) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.watcher != nil {
+		return nil
+	}
+
+	wlPaste, err := cs.runner.LookPath("wl-paste")
+	if err != nil {
+		return fmt.Errorf("wl-paste not found: install wl-clipboard")
+	}
+
+	// wl-paste runs `echo` on every change; we only use it as a tick and
+	// read the new contents ourselves so text and images share one path.
+	// The watcher streams for the app's lifetime, so it bypasses the runner.
+	cmd := exec.CommandContext(ctx, wlPaste, "--watch", "echo", "changed")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start clipboard watcher: %w", err)
+	}
+	cs.watcher = cmd
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			cs.captureCurrent(ctx)
+		}
+		cmd.Wait()
+	}()
+
+	return nil
+}
+
+// StopWatcher terminates the wl-paste listener
+func (cs *ClipboardService) StopWatcher() {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.watcher != nil && cs.watcher.Process != nil {
+		cs.watcher.Process.Kill()
+	}
+	cs.watcher = nil
+}
+
+// captureCurrent reads the current selection and stores it
+func (cs *ClipboardService) captureCurrent(ctx context.Context) {
+	types, err := cs.runner.Output(ctx, runner.Cmd("wl-paste", "--list-types"))
+	if err != nil {
+		return
+	}
+
+	mimeTypes := strings.Fields(string(types))
+	for _, mime := range mimeTypes {
+		// Password managers mark secrets so clipboard managers skip them
+		if mime == "x-kde-passwordManagerHint" {
+			return
+		}
+	}
+
+	for _, mime := range mimeTypes {
+		if mime == "image/png" {
+			data, err := cs.runner.Output(ctx, runner.Cmd("wl-paste", "--no-newline", "--type", mime))
+			if err == nil && len(data) > 0 {
+				cs.AddImage(data, mime, "clipboard")
+			}
+			return
+		}
+	}
+
+	data, err := cs.runner.Output(ctx, runner.Cmd("wl-paste", "--no-newline", "--type", "text"))
+	if err == nil {
+		cs.AddText(string(data), "clipboard")
+	}
+}
+
+// AddText stores a text entry, moving duplicates to the top
+func (cs *ClipboardService) AddText(text, source string) (ClipboardEntry, error) {
+	if strings.TrimSpace(text) == "" {
+		return ClipboardEntry{}, fmt.Errorf("empty clipboard text")
+	}
+	if len(text) > cs.maxTextSize {
+		return ClipboardEntry{}, fmt.Errorf("clipboard text exceeds %d bytes", cs.maxTextSize)
+	}
+
+	return cs.add(ClipboardEntry{
+		Kind:     "text",
+		Text:     text,
+		MimeType: "text/plain",
+		Hash:     hashBytes([]byte(text)),
+		Source:   source,
+	}, nil)
+}
+
+// AddImage stores image bytes next to the history file
+func (cs *ClipboardService) AddImage(data []byte, mime, source string) (ClipboardEntry, error) {
+	hash := hashBytes(data)
+	return cs.add(ClipboardEntry{
+		Kind:      "image",
+		ImagePath: filepath.Join(cs.storeDir, "images", hash+".png"),
+		MimeType:  mime,
+		Hash:      hash,
+		Source:    source,
+	}, data)
+}
+
+func (cs *ClipboardService) add(entry ClipboardEntry, imageData []byte) (ClipboardEntry, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	// Re-copying something already in history bumps it instead of duplicating
+	for i, existing := range cs.store.Entries {
+		if existing.Hash == entry.Hash {
+			existing.CreatedAt = time.Now()
+			cs.store.Entries = append(cs.store.Entries[:i], cs.store.Entries[i+1:]...)
+			cs.store.Entries = append([]ClipboardEntry{existing}, cs.store.Entries...)
+			return existing, cs.save()
+		}
+	}
+
+	if imageData != nil {
+		if err := os.MkdirAll(filepath.Dir(entry.ImagePath), 0755); err != nil {
+			return ClipboardEntry{}, err
+		}
+		if err := os.WriteFile(entry.ImagePath, imageData, 0600); err != nil {
+			return ClipboardEntry{}, fmt.Errorf("failed to store image: %w", err)
+		}
+	}
+
+	cs.store.NextID++
+	entry.ID = cs.store.NextID
+	entry.CreatedAt = time.Now()
+	cs.store.Entries = append([]ClipboardEntry{entry}, cs.store.Entries...)
+	cs.trim()
+
+	return entry, cs.save()
+}
+
+// trim drops the oldest unpinned entries once the history is over its limit
+func (cs *ClipboardService) trim() {
+	for len(cs.store.Entries) > cs.maxEntries {
+		dropped := false
+		for i := len(cs.store.Entries) - 1; i >= 0; i-- {
+			if !cs.store.Entries[i].Pinned {
+				cs.removeAt(i)
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			return
+		}
+	}
+}
+
+func (cs *ClipboardService) removeAt(i int) {
+	if path := cs.store.Entries[i].ImagePath; path != "" {
+		os.Remove(path)
+	}
+	cs.store.Entries = append(cs.store.Entries[:i], cs.store.Entries[i+1:]...)
+}
+
+func (cs *ClipboardService) indexOf(id int) int {
+	for i, entry := range cs.store.Entries {
+		if entry.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// List returns the most recent entries, pinned ones first
+func (cs *ClipboardService) List(limit int) []ClipboardEntry {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	var pinned, rest []ClipboardEntry
+	for _, entry := range cs.store.Entries {
+		if entry.Pinned {
+			pinned = append(pinned, entry)
+		} else {
+			rest = append(rest, entry)
+		}
+	}
+
+	entries := append(pinned, rest...)
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
+}
+
+// LatestText returns the newest text entry, from any source when source is ""
+func (cs *ClipboardService) LatestText(source string) (ClipboardEntry, bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for _, entry := range cs.store.Entries {
+		if entry.Kind == "text" && (source == "" || entry.Source == source) {
+			return entry, true
+		}
+	}
+	return ClipboardEntry{}, false
+}
+
+// ReadText returns the text currently on the clipboard
+func (cs *ClipboardService) ReadText(ctx context.Context) (string, error) {
+	data, err := cs.runner.Output(ctx, runner.Cmd("wl-paste", "--no-newline", "--type", "text"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read clipboard: %w", err)
+	}
+	return string(data), nil
+}
+
+// WriteText puts text on the clipboard with wl-copy
+func (cs *ClipboardService) WriteText(ctx context.Context, text string) error {
+	output, err := cs.runner.CombinedOutput(ctx, runner.Cmd("wl-copy").WithStdin(strings.NewReader(text)))
+	if err != nil {
+		return fmt.Errorf("wl-copy failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// WriteData puts data of the given MIME type on the clipboard with wl-copy
+func (cs *ClipboardService) WriteData(ctx context.Context, data []byte, mimeType string) error {
+	output, err := cs.runner.CombinedOutput(ctx, runner.Cmd("wl-copy", "--type", mimeType).WithStdin(bytes.NewReader(data)))
+	if err != nil {
+		return fmt.Errorf("wl-copy failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Search returns text entries containing every term
+func (cs *ClipboardService) Search(terms []string) []ClipboardEntry {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	var matches []ClipboardEntry
+	for _, entry := range cs.store.Entries {
+		lowerText := strings.ToLower(entry.Text)
+		matched := entry.Kind == "text"
+		for _, term := range terms {
+			if !strings.Contains(lowerText, term) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+// SetPinned pins or unpins an entry so it is never trimmed
+func (cs *ClipboardService) SetPinned(id int, pinned bool) (ClipboardEntry, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	i := cs.indexOf(id)
+	if i < 0 {
+		return ClipboardEntry{}, fmt.Errorf("clipboard entry %d not found", id)
+	}
+	cs.store.Entries[i].Pinned = pinned
+	return cs.store.Entries[i], cs.save()
+}
+
+// Delete removes an entry and its stored image
+func (cs *ClipboardService) Delete(id int) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	i := cs.indexOf(id)
+	if i < 0 {
+		return fmt.Errorf("clipboard entry %d not found", id)
+	}
+	cs.removeAt(i)
+	return cs.save()
+}
+
+// Clear removes every unpinned entry
+func (cs *ClipboardService) Clear() (int, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	removed := 0
+	for i := len(cs.store.Entries) - 1; i >= 0; i-- {
+		if !cs.store.Entries[i].Pinned {
+			cs.removeAt(i)
+			removed++
+		}
+	}
+	return removed, cs.save()
+}
+
+// Copy puts an entry back on the clipboard with wl-copy
+func (cs *ClipboardService) Copy(ctx context.Context, id int) (ClipboardEntry, error) {
+	cs.mu.Lock()
+	i := cs.indexOf(id)
+	var entry ClipboardEntry
+	if i >= 0 {
+		entry = cs.store.Entries[i]
+	}
+	cs.mu.Unlock()
+
+	if i < 0 {
+		return ClipboardEntry{}, fmt.Errorf("clipboard entry %d not found", id)
+	}
+
+	var cmd runner.Command
+	if entry.Kind == "image" {
+		data, err := os.ReadFile(entry.ImagePath)
+		if err != nil {
+			return entry, fmt.Errorf("stored image missing: %w", err)
+		}
+		cmd = runner.Cmd("wl-copy", "--type", entry.MimeType).WithStdin(bytes.NewReader(data))
+	} else {
+		cmd = runner.Cmd("wl-copy").WithStdin(strings.NewReader(entry.Text))
+	}
+
+	if output, err := cs.runner.CombinedOutput(ctx, cmd); err != nil {
+		return entry, fmt.Errorf("wl-copy failed: %s", strings.TrimSpace(string(output)))
+	}
+	return entry, nil
+}
+
+// clipboardAction works out which command a query asks for: pin, unpin,
+// delete, clear, copy, or "" for listing and searching
+func clipboardAction(lowerQuery string) (action string, id int) {
+	id, hasID := extractEntryID(lowerQuery)
+
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(lowerQuery) {
+		words[strings.Trim(word, ".,!?;:#")] = true
+	}
+
+	switch {
+	case words["unpin"] && hasID:
+		return "unpin", id
+	case words["pin"] && hasID:
+		return "pin", id
+	case (words["delete"] || words["remove"]) && hasID:
+		return "delete", id
+	case words["clear"]:
+		return "clear", 0
+	case (words["copy"] || words["recopy"]) && hasID:
+		return "copy", id
+	}
+	return "", 0
+}
+
+// HandleQuery interprets natural language clipboard requests
+func (cs *ClipboardService) HandleQuery(ctx context.Context, query string) (ClipboardResult, error) {
+	lowerQuery := strings.ToLower(query)
+
+	switch action, id := clipboardAction(lowerQuery); action {
+	case "unpin":
+		entry, err := cs.SetPinned(id, false)
+		return ClipboardResult{Action: "unpin", Entries: []ClipboardEntry{entry}, Total: 1}, err
+	case "pin":
+		entry, err := cs.SetPinned(id, true)
+		return ClipboardResult{Action: "pin", Entries: []ClipboardEntry{entry}, Total: 1}, err
+	case "delete":
+		if err := cs.Delete(id); err != nil {
+			return ClipboardResult{Action: "delete"}, err
+		}
+		return ClipboardResult{Action: "delete", Message: fmt.Sprintf("Deleted entry %d", id)}, nil
+	case "clear":
+		removed, err := cs.Clear()
+		return ClipboardResult{Action: "clear", Message: fmt.Sprintf("Removed %d entries", removed)}, err
+	case "copy":
+		entry, err := cs.Copy(ctx, id)
+		return ClipboardResult{Action: "copy", Entries: []ClipboardEntry{entry}, Total: 1}, err
+	}
+
+	terms := extractClipboardTerms(lowerQuery)
+	if len(terms) == 0 {
+		entries := cs.List(20)
+		return ClipboardResult{Action: "list", Entries: entries, Total: len(entries)}, nil
+	}
+
+	entries := cs.Search(terms)
+	return ClipboardResult{Action: "search", Entries: entries, Total: len(entries)}, nil
+}
+
+func (cs *ClipboardService) load() {
+	data, err := os.ReadFile(filepath.Join(cs.storeDir, "history.json"))
+	if err != nil {
+		return
+	}
+	json.Unmarshal(data, &cs.store)
+}
+
+func (cs *ClipboardService) save() error {
+	if err := os.MkdirAll(cs.storeDir, 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cs.store, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(cs.storeDir, "history.json"), data, 0600)
+}
+
+// entryIDPattern takes an id written as "#3" or right after the verb
+// ("copy 3", "pin entry #3"); any other number is part of a search
+var entryIDPattern = regexp.MustCompile(`#(\d+)\b|\b(?:copy|recopy|pin|unpin|delete|remove)\s+(?:entry\s+|item\s+)?(\d+)\b`)
+
+func extractEntryID(query string) (int, bool) {
+	matches := entryIDPattern.FindStringSubmatch(query)
+	if matches == nil {
+		return 0, false
+	}
+	id, err := strconv.Atoi(matches[1] + matches[2])
+	return id, err == nil
+}
+
+// extractClipboardTerms keeps the words after "about", "for" or "containing"
+func extractClipboardTerms(query string) []string {
+	for _, marker := range []string{" about ", " containing ", " with ", " for "} {
+		if idx := strings.Index(query, marker); idx >= 0 {
+			return extractSearchTerms(query[idx+len(marker):])
+		}
+	}
+	return nil
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
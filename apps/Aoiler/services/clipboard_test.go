@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"kaguyadots/runner"
+)
+
+func testClipboard(t *testing.T) (*ClipboardService, *runner.Fake) {
+	t.Helper()
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	fake := runner.NewFake()
+	return NewClipboardService(fake), fake
+}
+
+func TestExtractEntryID(t *testing.T) {
+	tests := []struct {
+		query string
+		id    int
+		ok    bool
+	}{
+		{"copy 3", 3, true},
+		{"recopy #12", 12, true},
+		{"pin entry 4", 4, true},
+		{"unpin #4", 4, true},
+		{"delete item 7", 7, true},
+		{"put #5 back on the clipboard", 5, true},
+		{"what did i copy about port 8080", 0, false},
+		{"find what i copied for 2024 taxes", 0, false},
+		{"copy the last 3 urls", 0, false},
+	}
+	for _, tt := range tests {
+		id, ok := extractEntryID(tt.query)
+		if id != tt.id || ok != tt.ok {
+			t.Errorf("extractEntryID(%q) = %d, %v, want %d, %v", tt.query, id, ok, tt.id, tt.ok)
+		}
+	}
+}
+
+func TestClipboardHistory(t *testing.T) {
+	cs, _ := testClipboard(t)
+	cs.maxEntries = 3
+
+	first, _ := cs.AddText("ssh -p 8080 kaguya@host", "wl-paste")
+	cs.AddText("hello", "wl-paste")
+	if _, err := cs.SetPinned(first.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	// A duplicate is bumped to the top rather than stored twice
+	if again, _ := cs.AddText("hello", "wl-paste"); again.ID != 2 {
+		t.Errorf("duplicate got id %d, want 2", again.ID)
+	}
+	cs.AddText("third", "wl-paste")
+	cs.AddText("fourth", "wl-paste")
+
+	// Over the limit the oldest unpinned entry goes, never the pinned one
+	var ids []int
+	for _, entry := range cs.List(10) {
+		ids = append(ids, entry.ID)
+	}
+	if want := []int{1, 4, 3}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+
+	// The history survives a restart
+	reloaded := NewClipboardService(runner.NewFake())
+	if got := reloaded.List(10); len(got) != 3 || !got[0].Pinned {
+		t.Errorf("reloaded = %+v", got)
+	}
+
+	if removed, _ := cs.Clear(); removed != 2 || len(cs.List(10)) != 1 {
+		t.Errorf("Clear removed %d, left %v", removed, cs.List(10))
+	}
+}
+
+func TestClipboardHandleQuery(t *testing.T) {
+	cs, fake := testClipboard(t)
+	ctx := context.Background()
+	port, _ := cs.AddText("ssh -p 8080 kaguya@host", "wl-paste")
+	cs.AddText("hello world", "wl-paste")
+
+	// A number that isn't an id is searched for, not copied
+	result, err := cs.HandleQuery(ctx, "what did I copy about port 8080")
+	if err != nil || result.Action != "search" || len(result.Entries) != 0 {
+		t.Errorf("port 8080 = %+v, %v, want an empty search", result, err)
+	}
+	result, err = cs.HandleQuery(ctx, "what did I copy about 8080")
+	if err != nil || result.Action != "search" || len(result.Entries) != 1 || result.Entries[0].ID != port.ID {
+		t.Errorf("8080 = %+v, %v, want the ssh entry", result, err)
+	}
+	if len(fake.Calls()) != 0 {
+		t.Errorf("searching ran %v", fake.Commands())
+	}
+
+	result, err = cs.HandleQuery(ctx, "copy #1")
+	if err != nil || result.Action != "copy" {
+		t.Fatalf("copy #1 = %+v, %v", result, err)
+	}
+	if calls := fake.Calls(); len(calls) != 1 || calls[0].Input != port.Text {
+		t.Errorf("wl-copy calls = %+v", calls)
+	}
+
+	if result, _ := cs.HandleQuery(ctx, "pin 2"); result.Action != "pin" || !result.Entries[0].Pinned {
+		t.Errorf("pin 2 = %+v", result)
+	}
+	if _, err := cs.HandleQuery(ctx, "delete #9"); err == nil {
+		t.Error("delete #9: want entry not found")
+	}
+	if result, _ := cs.HandleQuery(ctx, "show my clipboard history"); result.Action != "list" || result.Total != 2 {
+		t.Errorf("list = %+v", result)
+	}
+}
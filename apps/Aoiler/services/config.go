@@ -0,0 +1,62 @@
+package services
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// kaguyaConfigPath is the KaguyaDots settings file shared with the Help app
+func kaguyaConfigPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "kaguyadots", "kaguyadots.toml")
+}
+
+// readKaguyaTable returns the key/value pairs of one table in
+// kaguyadots.toml. Values are unquoted strings; inline comments are dropped.
+func readKaguyaTable(table string) map[string]string {
+	values := make(map[string]string)
+
+	file, err := os.Open(kaguyaConfigPath())
+	if err != nil {
+		return values
+	}
+	defer file.Close()
+
+	header := "[" + table + "]"
+	inTable := false
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "[") {
+			inTable = line == header
+			continue
+		}
+		if !inTable || strings.HasPrefix(line, "#") || !strings.Contains(line, "=") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		key := strings.Trim(strings.TrimSpace(parts[0]), `"`)
+		values[key] = tomlValue(parts[1])
+	}
+	return values
+}
+
+// tomlValue strips quotes and trailing comments from a raw TOML value
+func tomlValue(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, `"`) {
+		if end := strings.Index(raw[1:], `"`); end >= 0 {
+			return raw[1 : end+1]
+		}
+		return strings.Trim(raw, `"`)
+	}
+	if idx := strings.Index(raw, "#"); idx >= 0 {
+		raw = raw[:idx]
+	}
+	return strings.TrimSpace(raw)
+}
@@ -0,0 +1,372 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Citation points at the part of a config file an answer was based on
+type Citation struct {
+	Path      string  `json:"path"`
+	StartLine int     `json:"startLine"`
+	EndLine   int     `json:"endLine"`
+	Score     float64 `json:"score"`
+}
+
+// DotfileSnippet is a retrieved chunk of a config file
+type DotfileSnippet struct {
+	Citation
+	Text string `json:"text"`
+}
+
+// dotfileChunk is an indexed block of consecutive lines
+type dotfileChunk struct {
+	path      string
+	startLine int
+	endLine   int
+	text      string
+	terms     map[string]int
+	length    int
+}
+
+// DotfilesIndex is a BM25 keyword index over the KaguyaDots config files
+type DotfilesIndex struct {
+	mu        sync.Mutex
+	configDir string
+	chunks    []dotfileChunk
+	docFreq   map[string]int
+	avgLength float64
+	mtimes    map[string]time.Time
+	checked   time.Time
+}
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	maxChunkLines     = 30
+	dotfilesRecheck   = 30 * time.Second
+	maxContextChars   = 6000
+	minSnippetScore   = 1.0
+	defaultSnippetMax = 4
+)
+
+// NewDotfilesIndex indexes the configs under ~/.config
+func NewDotfilesIndex() *DotfilesIndex {
+	homeDir, _ := os.UserHomeDir()
+	return NewDotfilesIndexAt(filepath.Join(homeDir, ".config"))
+}
+
+// NewDotfilesIndexAt indexes the configs under configDir
+func NewDotfilesIndexAt(configDir string) *DotfilesIndex {
+	return &DotfilesIndex{
+		configDir: configDir,
+		docFreq:   make(map[string]int),
+		mtimes:    make(map[string]time.Time),
+	}
+}
+
+// dotfileDirs are the config directories the index reads from
+var dotfileDirs = []string{"hypr", "kaguyadots", "waybar"}
+
+// Contains reports whether path is inside one of the indexed config
+// directories. Symlinks are resolved first so a link can't point out of
+// them.
+func (idx *DotfilesIndex) Contains(path string) bool {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	for _, dir := range dotfileDirs {
+		root, err := filepath.EvalSymlinks(filepath.Join(idx.configDir, dir))
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(root, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceFiles lists the files KaguyaDots keeps its settings in
+func (idx *DotfilesIndex) sourceFiles() []string {
+	files, _ := filepath.Glob(filepath.Join(idx.configDir, "hypr", "configs", "*.conf"))
+	files = append(files,
+		filepath.Join(idx.configDir, "hypr", "hyprland.conf"),
+		filepath.Join(idx.configDir, "kaguyadots", "kaguyadots.toml"),
+		filepath.Join(idx.configDir, "waybar", "config"),
+		filepath.Join(idx.configDir, "waybar", "modules"),
+		filepath.Join(idx.configDir, "waybar", "module"),
+		filepath.Join(idx.configDir, "waybar", "style.css"),
+	)
+
+	var existing []string
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+			existing = append(existing, file)
+		}
+	}
+	return existing
+}
+
+// refresh rebuilds the index when a source file was added, removed or edited
+func (idx *DotfilesIndex) refresh() {
+	if time.Since(idx.checked) < dotfilesRecheck && idx.chunks != nil {
+		return
+	}
+	idx.checked = time.Now()
+
+	files := idx.sourceFiles()
+	mtimes := make(map[string]time.Time, len(files))
+	changed := len(files) != len(idx.mtimes)
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		mtimes[file] = info.ModTime()
+		if !idx.mtimes[file].Equal(info.ModTime()) {
+			changed = true
+		}
+	}
+	if !changed && idx.chunks != nil {
+		return
+	}
+
+	idx.chunks = nil
+	idx.docFreq = make(map[string]int)
+	idx.mtimes = mtimes
+
+	totalLength := 0
+	for _, file := range files {
+		chunks, err := chunkFile(file)
+		if err != nil {
+			continue
+		}
+		for _, chunk := range chunks {
+			for term := range chunk.terms {
+				idx.docFreq[term]++
+			}
+			totalLength += chunk.length
+			idx.chunks = append(idx.chunks, chunk)
+		}
+	}
+	if len(idx.chunks) > 0 {
+		idx.avgLength = float64(totalLength) / float64(len(idx.chunks))
+	}
+	if idx.chunks == nil {
+		idx.chunks = []dotfileChunk{}
+	}
+}
+
+// chunkFile splits a file into blocks at blank lines, keeping each block
+// under maxChunkLines so a snippet stays readable in the prompt
+func chunkFile(path string) ([]dotfileChunk, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var chunks []dotfileChunk
+	var lines []string
+	start := 0
+
+	flush := func(end int) {
+		text := strings.TrimRight(strings.Join(lines, "\n"), " \t\n")
+		lines = nil
+		if text == "" {
+			return
+		}
+		terms := make(map[string]int)
+		length := 0
+		for _, term := range tokenizeTerms(text) {
+			terms[term]++
+			length++
+		}
+		if length == 0 {
+			return
+		}
+		chunks = append(chunks, dotfileChunk{
+			path:      path,
+			startLine: start,
+			endLine:   end,
+			text:      text,
+			terms:     terms,
+			length:    length,
+		})
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+
+		if strings.TrimSpace(line) == "" {
+			// Blank lines end a block once it has grown past a few lines
+			if len(lines) >= 4 {
+				flush(lineNo - 1)
+			} else if len(lines) > 0 {
+				lines = append(lines, line)
+			}
+			continue
+		}
+
+		if len(lines) == 0 {
+			start = lineNo
+		}
+		lines = append(lines, line)
+		if len(lines) >= maxChunkLines {
+			flush(lineNo)
+		}
+	}
+	flush(lineNo)
+
+	return chunks, scanner.Err()
+}
+
+// termSynonyms folds spellings together so the query matches config keys
+var termSynonyms = map[string]string{
+	"colour":       "color",
+	"colours":      "color",
+	"col":          "color",
+	"keybind":      "bind",
+	"keybinds":     "bind",
+	"keybinding":   "bind",
+	"keybindings":  "bind",
+	"shortcut":     "bind",
+	"shortcuts":    "bind",
+	"hotkey":       "bind",
+	"bar":          "waybar",
+	"startup":      "exec",
+	"autostart":    "exec",
+	"transparency": "opacity",
+	"transparent":  "opacity",
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "to": true,
+	"of": true, "in": true, "on": true, "is": true, "it": true, "my": true,
+	"i": true, "do": true, "how": true, "what": true, "which": true, "can": true,
+	"change": true, "set": true, "for": true, "with": true, "me": true,
+	"where": true, "does": true, "are": true, "this": true, "that": true,
+}
+
+// tokenizeTerms lowercases and splits on anything that isn't a letter or
+// digit, so col.active_border yields color, active and border
+func tokenizeTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if len(word) < 2 || stopWords[word] {
+			continue
+		}
+		if synonym, ok := termSynonyms[word]; ok {
+			word = synonym
+		} else if len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+			word = word[:len(word)-1]
+		}
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// Search returns the best matching chunks for a question
+func (idx *DotfilesIndex) Search(query string, limit int) []DotfileSnippet {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.refresh()
+
+	queryTerms := tokenizeTerms(query)
+	if len(queryTerms) == 0 || len(idx.chunks) == 0 {
+		return []DotfileSnippet{}
+	}
+
+	n := float64(len(idx.chunks))
+	var snippets []DotfileSnippet
+	for _, chunk := range idx.chunks {
+		score := 0.0
+		for _, term := range queryTerms {
+			tf := float64(chunk.terms[term])
+			if tf == 0 {
+				continue
+			}
+			df := float64(idx.docFreq[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := tf + bm25K1*(1-bm25B+bm25B*float64(chunk.length)/idx.avgLength)
+			score += idf * tf * (bm25K1 + 1) / norm
+		}
+		if score < minSnippetScore {
+			continue
+		}
+		snippets = append(snippets, DotfileSnippet{
+			Citation: Citation{
+				Path:      chunk.path,
+				StartLine: chunk.startLine,
+				EndLine:   chunk.endLine,
+				Score:     math.Round(score*100) / 100,
+			},
+			Text: chunk.text,
+		})
+	}
+
+	sort.SliceStable(snippets, func(i, j int) bool {
+		return snippets[i].Score > snippets[j].Score
+	})
+	if limit > 0 && len(snippets) > limit {
+		snippets = snippets[:limit]
+	}
+	if snippets == nil {
+		return []DotfileSnippet{}
+	}
+	return snippets
+}
+
+// BuildContext renders the best snippets as a prompt section and returns
+// the citations for the ones that fit
+func (idx *DotfilesIndex) BuildContext(query string) (string, []Citation) {
+	snippets := idx.Search(query, defaultSnippetMax)
+	if len(snippets) == 0 {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	var citations []Citation
+	sb.WriteString("Excerpts from the user's own KaguyaDots configuration. Base answers on these files, name the file and line you refer to, and quote the exact setting to edit:\n")
+	for _, snippet := range snippets {
+		block := fmt.Sprintf("\n--- %s (lines %d-%d)\n%s\n", displayConfigPath(snippet.Path), snippet.StartLine, snippet.EndLine, snippet.Text)
+		if sb.Len()+len(block) > maxContextChars && len(citations) > 0 {
+			break
+		}
+		sb.WriteString(block)
+		citations = append(citations, snippet.Citation)
+	}
+	return sb.String(), citations
+}
+
+// displayConfigPath shortens paths under the home directory to ~/...
+func displayConfigPath(path string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(homeDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return "~/" + rel
+	}
+	return path
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDotfiles writes a small KaguyaDots config tree under a fake home
+func testDotfiles(t *testing.T) (*DotfilesIndex, string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	config := filepath.Join(home, ".config")
+
+	files := map[string]string{
+		"hypr/hyprland.conf": "source = ~/.config/hypr/configs/decoration.conf\n" +
+			"source = ~/.config/hypr/configs/keybinds.conf\n",
+		"hypr/configs/decoration.conf": "general {\n" +
+			"    gaps_in = 4\n" +
+			"    gaps_out = 8\n" +
+			"    border_size = 2\n" +
+			"    col.active_border = rgba(cba6f7ff) rgba(89b4faff) 45deg\n" +
+			"    col.inactive_border = rgba(585b70aa)\n" +
+			"}\n" +
+			"\n" +
+			"decoration {\n" +
+			"    rounding = 10\n" +
+			"    active_opacity = 1.0\n" +
+			"    inactive_opacity = 0.9\n" +
+			"    blur {\n" +
+			"        enabled = true\n" +
+			"    }\n" +
+			"}\n",
+		"hypr/configs/keybinds.conf": "$mainMod = SUPER\n" +
+			"bind = $mainMod, Return, exec, kitty\n" +
+			"bind = $mainMod, Q, killactive\n" +
+			"bind = $mainMod, E, exec, thunar\n" +
+			"\n" +
+			"# Screenshots\n" +
+			"bind = , Print, exec, ~/.config/hypr/scripts/ScreenShot.sh --now\n" +
+			"bind = SHIFT, Print, exec, ~/.config/hypr/scripts/ScreenShot.sh --area\n",
+		"waybar/config": "{\n" +
+			"    \"layer\": \"top\",\n" +
+			"    \"modules-left\": [\"hyprland/workspaces\", \"hyprland/window\"],\n" +
+			"    \"modules-center\": [\"clock\"],\n" +
+			"    \"modules-right\": [\"pulseaudio\", \"network\", \"battery\"]\n" +
+			"}\n",
+		"kaguyadots/kaguyadots.toml": "[pulse]\ninterval = \"1s\"\n",
+		// Not a KaguyaDots config, so never indexed
+		"kitty/kitty.conf": "active_border_color #cba6f7\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(config, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewDotfilesIndexAt(config), config
+}
+
+func TestTokenizeTerms(t *testing.T) {
+	tests := map[string][]string{
+		"col.active_border":                   {"color", "active", "border"},
+		"How do I change my keybinds?":        {"bind"},
+		"bar colours and window transparency": {"waybar", "color", "window", "opacity"},
+		"gaps_in = 4":                         {"gap"},
+		"class windows glass":                 {"class", "window", "glass"},
+		"what is the":                         {},
+	}
+	for text, want := range tests {
+		if got := tokenizeTerms(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("tokenizeTerms(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestChunkFile(t *testing.T) {
+	_, config := testDotfiles(t)
+	chunks, err := chunkFile(filepath.Join(config, "hypr", "configs", "decoration.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The general and decoration blocks are split at the blank line
+	var lines [][2]int
+	for _, chunk := range chunks {
+		lines = append(lines, [2]int{chunk.startLine, chunk.endLine})
+	}
+	if want := [][2]int{{1, 7}, {9, 16}}; !reflect.DeepEqual(lines, want) {
+		t.Fatalf("chunks = %v, want %v", lines, want)
+	}
+	if chunks[0].terms["border"] != 3 || chunks[0].terms["color"] != 2 {
+		t.Errorf("general terms = %v", chunks[0].terms)
+	}
+
+	// Short blocks are kept together with the one that follows
+	hyprland, _ := chunkFile(filepath.Join(config, "hypr", "hyprland.conf"))
+	keybinds, _ := chunkFile(filepath.Join(config, "hypr", "configs", "keybinds.conf"))
+	if len(hyprland) != 1 || hyprland[0].startLine != 1 || hyprland[0].endLine != 2 {
+		t.Errorf("hyprland chunks = %+v", hyprland)
+	}
+	if len(keybinds) != 2 || keybinds[1].startLine != 6 || keybinds[1].endLine != 8 {
+		t.Errorf("keybinds chunks = %+v", keybinds)
+	}
+}
+
+func TestDotfilesSearch(t *testing.T) {
+	idx, config := testDotfiles(t)
+	decoration := filepath.Join(config, "hypr", "configs", "decoration.conf")
+
+	tests := []struct {
+		query string
+		path  string
+		lines [2]int
+	}{
+		{"how do I change the active border colour", decoration, [2]int{1, 7}},
+		{"window transparency", decoration, [2]int{9, 16}},
+		{"which keybind takes a screenshot", filepath.Join(config, "hypr", "configs", "keybinds.conf"), [2]int{6, 8}},
+		{"what modules are on the left of my bar", filepath.Join(config, "waybar", "config"), [2]int{1, 6}},
+	}
+	for _, tt := range tests {
+		snippets := idx.Search(tt.query, 3)
+		if len(snippets) == 0 {
+			t.Errorf("Search(%q) found nothing", tt.query)
+			continue
+		}
+		top := snippets[0]
+		if top.Path != tt.path || top.StartLine != tt.lines[0] || top.EndLine != tt.lines[1] {
+			t.Errorf("Search(%q) top = %s:%d-%d, want %s:%d-%d", tt.query, top.Path, top.StartLine, top.EndLine, tt.path, tt.lines[0], tt.lines[1])
+		}
+		for i := 1; i < len(snippets); i++ {
+			if snippets[i].Score > snippets[i-1].Score {
+				t.Errorf("Search(%q) not ranked: %+v", tt.query, snippets)
+			}
+		}
+		for _, snippet := range snippets {
+			if strings.Contains(snippet.Path, "kitty") {
+				t.Errorf("Search(%q) returned %s, which isn't indexed", tt.query, snippet.Path)
+			}
+		}
+	}
+
+	if got := idx.Search("what is the", 3); len(got) != 0 {
+		t.Errorf("stop words only = %+v", got)
+	}
+	if got := idx.Search("border", 1); len(got) != 1 {
+		t.Errorf("limit 1 = %d snippets", len(got))
+	}
+
+	// An edited file is picked up once the recheck interval has passed
+	os.WriteFile(decoration, []byte("animations {\n    enabled = yes\n    bezier = wind, 0.05, 0.9, 0.1, 1.05\n}\n"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(decoration, later, later)
+	idx.checked = time.Now().Add(-dotfilesRecheck)
+	if got := idx.Search("bezier animations", 1); len(got) != 1 || got[0].Path != decoration {
+		t.Errorf("after edit = %+v", got)
+	}
+}
+
+func TestDotfilesBuildContext(t *testing.T) {
+	idx, _ := testDotfiles(t)
+
+	context, citations := idx.BuildContext("active border colour")
+	if len(citations) == 0 {
+		t.Fatal("no citations")
+	}
+	first := citations[0]
+	header := "--- ~/.config/hypr/configs/decoration.conf (lines 1-7)\n"
+	if !strings.Contains(context, header) || !strings.Contains(context, "col.active_border = rgba(cba6f7ff)") {
+		t.Errorf("context = %q, want the decoration excerpt", context)
+	}
+	if first.StartLine != 1 || first.EndLine != 7 || first.Score <= 0 {
+		t.Errorf("citation = %+v", first)
+	}
+	if len(citations) > defaultSnippetMax {
+		t.Errorf("%d citations, want at most %d", len(citations), defaultSnippetMax)
+	}
+
+	if context, citations := idx.BuildContext("zzz"); context != "" || citations != nil {
+		t.Errorf("no match = %q, %v", context, citations)
+	}
+}
+
+func TestDotfilesContains(t *testing.T) {
+	idx, config := testDotfiles(t)
+	home := filepath.Dir(config)
+
+	secret := filepath.Join(home, ".ssh", "id_ed25519")
+	os.MkdirAll(filepath.Dir(secret), 0700)
+	os.WriteFile(secret, []byte("key"), 0600)
+	os.Symlink(secret, filepath.Join(config, "waybar", "key"))
+
+	tests := map[string]bool{
+		filepath.Join(config, "hypr", "configs", "keybinds.conf"): true,
+		filepath.Join(config, "waybar"):                           true,
+		filepath.Join(config, "kitty", "kitty.conf"):              false,
+		secret:                                 false,
+		filepath.Join(config, "waybar", "key"): false, // links out
+		filepath.Join(config, "waybar", "..", "..", ".ssh", "id_ed25519"): false,
+		filepath.Join(config, "hypr", "missing.conf"):                     false,
+	}
+	for path, want := range tests {
+		if got := idx.Contains(path); got != want {
+			t.Errorf("Contains(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
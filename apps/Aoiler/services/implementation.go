@@ -1,12 +1,14 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"kaguyadots/runner"
 )
 
 // Result types with more metadata
@@ -53,26 +55,46 @@ type ConverterResult struct {
 }
 
 type AutoCompleteResult struct {
-	Suggestions []string `json:"suggestions"`
-	IsPath      bool     `json:"isPath"`
-	Total       int      `json:"total"`
+	Suggestions  []string `json:"suggestions"`
+	Replacements []string `json:"replacements"`
+	Prefix       string   `json:"prefix"`
+	Service      string   `json:"service,omitempty"`
+	IsPath       bool     `json:"isPath"`
+	Total        int      `json:"total"`
 }
 
 // FileSearchService with enhanced search capabilities
 type FileSearchService struct {
-	maxDepth   int
-	maxResults int
+	maxDepth       int
+	maxResults     int
+	maxSuggestions int
+	paths          *PathResolver
+	history        *PathHistory
 }
 
-func NewFileSearchService() *FileSearchService {
+func NewFileSearchService(paths *PathResolver) *FileSearchService {
 	return &FileSearchService{
-		maxDepth:   10,
-		maxResults: 5,
+		maxDepth:       10,
+		maxResults:     5,
+		maxSuggestions: 30,
+		paths:          paths,
+		history:        NewPathHistory(),
 	}
 }
 
 // Search with scoring and multiple results
-func (fs *FileSearchService) Search(query string) (FileSearchResult, error) {
+func (fs *FileSearchService) Search(ctx context.Context, query string) (FileSearchResult, error) {
+	// A query naming an existing path ("find notes.md") needs no search
+	for _, path := range fs.paths.Paths(query) {
+		if info, err := os.Stat(path); err == nil {
+			fileType := "file"
+			if info.IsDir() {
+				fileType = "directory"
+			}
+			return FileSearchResult{Path: path, Type: fileType, Found: true, Size: info.Size(), ModTime: info.ModTime()}, nil
+		}
+	}
+
 	searchTerms := extractSearchTerms(query)
 
 	homeDir, _ := os.UserHomeDir()
@@ -81,7 +103,10 @@ func (fs *FileSearchService) Search(query string) (FileSearchResult, error) {
 	var foundPath string
 	var bestScore int
 
-	filepath.Walk(configDir, func(path string, info os.FileInfo, err error) error {
+	walkErr := filepath.Walk(configDir, func(path string, info os.FileInfo, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if err != nil {
 			return nil
 		}
@@ -118,6 +143,9 @@ func (fs *FileSearchService) Search(query string) (FileSearchResult, error) {
 
 		return nil
 	})
+	if walkErr != nil {
+		return FileSearchResult{Found: false}, walkErr
+	}
 
 	if foundPath != "" {
 		fileType := "file"
@@ -135,143 +163,80 @@ func (fs *FileSearchService) Search(query string) (FileSearchResult, error) {
 	return FileSearchResult{Found: false}, fmt.Errorf("file not found")
 }
 
-// Enhanced autocomplete with better context awareness
+// AutoComplete lists completions for a partial path
 func (fs *FileSearchService) AutoComplete(partial string) ([]string, error) {
-	if partial == "" {
-		cwd, _ := os.Getwd()
-		return []string{cwd + "/"}, nil
-	}
-
-	// Expand ~ to home directory
-	if strings.HasPrefix(partial, "~") {
-		homeDir, _ := os.UserHomeDir()
-		partial = filepath.Join(homeDir, partial[1:])
-	}
-
-	// Handle relative paths
-	if !filepath.IsAbs(partial) && !strings.HasPrefix(partial, "~") {
-		cwd, _ := os.Getwd()
-		partial = filepath.Join(cwd, partial)
-	}
-
-	dir := filepath.Dir(partial)
-	prefix := filepath.Base(partial)
-
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		return []string{}, nil
-	}
-
-	var matches []string
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return matches, err
-	}
-
-	for _, entry := range entries {
-		name := entry.Name()
-
-		// Skip hidden files unless explicitly searching for them
-		if strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".") {
-			continue
-		}
-
-		if strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix)) {
-			fullPath := filepath.Join(dir, name)
-			if entry.IsDir() {
-				fullPath += "/"
-			}
-			matches = append(matches, fullPath)
-		}
-	}
-
-	// Limit results
-	if len(matches) > 20 {
-		matches = matches[:20]
-	}
-
-	return matches, nil
+	return fs.Suggest(escapePath(partial, 0, false), true, nil).Suggestions, nil
 }
 
 func (fs *FileSearchService) GetPathSuggestions(input string, forceFromStart bool) (AutoCompleteResult, error) {
-	isPath := forceFromStart || strings.Contains(input, "/") ||
-		strings.Contains(input, "~") || strings.HasPrefix(input, ".")
-
-	if !isPath {
-		return AutoCompleteResult{Suggestions: []string{}, IsPath: false, Total: 0}, nil
-	}
-
-	pathPart := extractPathFromInput(input)
-	if pathPart == "" && forceFromStart {
-		pathPart = "./"
-	}
-
-	suggestions, err := fs.AutoComplete(pathPart)
-	if err != nil {
-		return AutoCompleteResult{Suggestions: []string{}, IsPath: true, Total: 0}, err
-	}
+	return fs.Suggest(input, forceFromStart, nil), nil
+}
 
-	return AutoCompleteResult{
-		Suggestions: suggestions,
-		IsPath:      true,
-		Total:       len(suggestions),
-	}, nil
+// RecordPath feeds a used path into the frecency ranking
+func (fs *FileSearchService) RecordPath(path string) {
+	fs.history.Record(fs.ResolvePath(path))
 }
 
 // OrganizerService with better feedback
-type OrganizerService struct{}
+type OrganizerService struct {
+	runner runner.Runner
+	paths  *PathResolver
+}
 
-func NewOrganizerService() *OrganizerService {
-	return &OrganizerService{}
+func NewOrganizerService(r runner.Runner, paths *PathResolver) *OrganizerService {
+	return &OrganizerService{runner: r, paths: paths}
 }
 
-func (o *OrganizerService) Organize(query, mode string) (OrganizerResult, error) {
-	path := extractPath(query)
-	homeDir, _ := os.UserHomeDir()
-	if path == "" {
-		path = "."
-	}
+// Target is the directory a query organizes, "" when it names none. There
+// is no default: a bare "organize by type" would sort the whole base directory.
+func (o *OrganizerService) Target(query string) string {
+	return o.paths.Path(query)
+}
 
-	if strings.HasPrefix(path, "~") {
-		path = filepath.Join(homeDir, path[1:])
+func (o *OrganizerService) Organize(ctx context.Context, query, mode string) (OrganizerResult, error) {
+	path := o.Target(query)
+	if path == "" {
+		return OrganizerResult{Success: false, Mode: mode}, fmt.Errorf("name the directory to organize, e.g. organize ~/Downloads")
 	}
+	homeDir, _ := os.UserHomeDir()
 
-	var cmd *exec.Cmd
+	flag := "-c"
 	if mode == "filename" {
-		cmd = exec.Command("tyr", "-f", "-nui", path)
-		cmd.Env = append(os.Environ(), "PATH="+os.Getenv("PATH")+":"+filepath.Join(homeDir, ".local/bin"))
-	} else {
-		cmd = exec.Command("tyr", "-c", "-nui", path)
-		cmd.Env = append(os.Environ(), "PATH="+os.Getenv("PATH")+":"+filepath.Join(homeDir, ".local/bin"))
+		flag = "-f"
 	}
+	cmd := runner.Cmd("tyr", flag, "-nui", path).
+		WithEnv("PATH=" + os.Getenv("PATH") + ":" + filepath.Join(homeDir, ".local/bin"))
 
-	output, err := cmd.CombinedOutput()
+	output, err := o.runner.CombinedOutput(ctx, cmd)
 	if err != nil {
 		return OrganizerResult{
 			Output:  string(output),
 			Success: false,
+			Path:    path,
+			Mode:    mode,
 		}, err
 	}
 
 	return OrganizerResult{
 		Output:  string(output),
 		Success: true,
+		Path:    path,
+		Mode:    mode,
 	}, nil
 }
 
-func (o *OrganizerService) GetPathSuggestions(input string) (AutoCompleteResult, error) {
-	fs := NewFileSearchService()
-	return fs.GetPathSuggestions(input, true)
-}
-
 // LinterService with better error reporting
-type LinterService struct{}
+type LinterService struct {
+	runner runner.Runner
+	paths  *PathResolver
+}
 
-func NewLinterService() *LinterService {
-	return &LinterService{}
+func NewLinterService(r runner.Runner, paths *PathResolver) *LinterService {
+	return &LinterService{runner: r, paths: paths}
 }
 
-func (ls *LinterService) LintFormat(query string) (LinterResult, error) {
-	filePath := extractPath(query)
+func (ls *LinterService) LintFormat(ctx context.Context, query string) (LinterResult, error) {
+	filePath := ls.paths.Path(query)
 	if filePath == "" {
 		return LinterResult{}, fmt.Errorf("no file path found in query")
 	}
@@ -283,27 +248,27 @@ func (ls *LinterService) LintFormat(query string) (LinterResult, error) {
 
 	ext := strings.ToLower(filepath.Ext(filePath))
 
-	var cmd *exec.Cmd
+	var cmd runner.Command
 	var linterName string
 
 	switch ext {
 	case ".py":
-		cmd = exec.Command("black", filePath)
+		cmd = runner.Cmd("black", filePath)
 		linterName = "black"
 	case ".go":
-		cmd = exec.Command("gofmt", "-w", filePath)
+		cmd = runner.Cmd("gofmt", "-w", filePath)
 		linterName = "gofmt"
 	case ".sh":
-		cmd = exec.Command("shfmt", "-w", filePath)
+		cmd = runner.Cmd("shfmt", "-w", filePath)
 		linterName = "shfmt"
 	case ".js", ".ts", ".jsx", ".tsx":
-		cmd = exec.Command("prettier", "--write", filePath)
+		cmd = runner.Cmd("prettier", "--write", filePath)
 		linterName = "prettier"
 	default:
 		return LinterResult{}, fmt.Errorf("unsupported file type: %s", ext)
 	}
 
-	output, err := cmd.CombinedOutput()
+	output, err := ls.runner.CombinedOutput(ctx, cmd)
 
 	// Count errors (rough heuristic)
 	errorCount := strings.Count(strings.ToLower(string(output)), "error")
@@ -317,48 +282,23 @@ func (ls *LinterService) LintFormat(query string) (LinterResult, error) {
 	}, err
 }
 
-func (ls *LinterService) GetPathSuggestions(input string) (AutoCompleteResult, error) {
-	fs := NewFileSearchService()
-	result, err := fs.GetPathSuggestions(input, true)
-
-	if err != nil {
-		return result, err
-	}
-
-	supportedExts := map[string]bool{
-		".py": true, ".go": true, ".sh": true,
-		".js": true, ".ts": true, ".jsx": true, ".tsx": true,
-	}
-
-	var filtered []string
-	for _, path := range result.Suggestions {
-		ext := strings.ToLower(filepath.Ext(path))
-		if strings.HasSuffix(path, "/") || supportedExts[ext] {
-			filtered = append(filtered, path)
-		}
-	}
-
-	result.Suggestions = filtered
-	result.Total = len(filtered)
-	return result, nil
-}
-
 // OCRService with confidence estimation
 type OCRService struct {
+	runner     runner.Runner
 	scriptPath string
 }
 
-func NewOCRService() *OCRService {
+func NewOCRService(r runner.Runner) *OCRService {
 	homeDir, _ := os.UserHomeDir()
 	scriptPath := filepath.Join(homeDir, ".config/kaguyadots/scripts/ocr-capture.sh")
 	if _, err := os.Stat(scriptPath); err == nil {
-		return &OCRService{scriptPath: scriptPath}
+		return &OCRService{runner: r, scriptPath: scriptPath}
 	}
-	return &OCRService{}
+	return &OCRService{runner: r}
 }
 
-func (ocr *OCRService) ExtractText() (OCRResult, error) {
-	text, err := ocr.runOCR("", true)
+func (ocr *OCRService) ExtractText(ctx context.Context) (OCRResult, error) {
+	text, err := ocr.runOCR(ctx, "", true)
 
 	if err != nil {
 		return OCRResult{
@@ -377,12 +317,12 @@ func (ocr *OCRService) ExtractText() (OCRResult, error) {
 	}, nil
 }
 
-func (ocr *OCRService) ExtractTextFromFile(imagePath string) (OCRResult, error) {
+func (ocr *OCRService) ExtractTextFromFile(ctx context.Context, imagePath string) (OCRResult, error) {
 	if _, err := os.Stat(imagePath); os.IsNotExist(err) {
 		return OCRResult{Success: false}, fmt.Errorf("image file not found: %s", imagePath)
 	}
 
-	text, err := ocr.runOCR(imagePath, false)
+	text, err := ocr.runOCR(ctx, imagePath, false)
 
 	if err != nil {
 		return OCRResult{
@@ -401,25 +341,25 @@ func (ocr *OCRService) ExtractTextFromFile(imagePath string) (OCRResult, error)
 	}, nil
 }
 
-func (ocr *OCRService) runOCR(imagePath string, screenCapture bool) (string, error) {
-	var cmd *exec.Cmd
+func (ocr *OCRService) runOCR(ctx context.Context, imagePath string, screenCapture bool) (string, error) {
+	var cmd runner.Command
 
 	if screenCapture {
 		if ocr.scriptPath != "" {
-			cmd = exec.Command(ocr.scriptPath, "-au")
+			cmd = runner.Cmd(ocr.scriptPath, "-au")
 		} else {
 			// Use inline script
-			return ocr.runInlineOCRScript()
+			return ocr.runInlineOCRScript(ctx)
 		}
 	} else {
-		cmd = exec.Command("tesseract", imagePath, "stdout")
+		cmd = runner.Cmd("tesseract", imagePath, "stdout")
 	}
 
-	output, err := cmd.CombinedOutput()
+	output, err := ocr.runner.CombinedOutput(ctx, cmd)
 	return string(output), err
 }
 
-func (ocr *OCRService) runInlineOCRScript() (string, error) {
+func (ocr *OCRService) runInlineOCRScript(ctx context.Context) (string, error) {
 	scriptPath := "/tmp/ocr_capture.sh"
 	script := `#!/bin/bash
 set -e
@@ -448,61 +388,84 @@ exit 0
 	}
 	defer os.Remove(scriptPath)
 
-	cmd := exec.Command("bash", scriptPath, "-au")
-	output, err := cmd.CombinedOutput()
+	output, err := ocr.runner.CombinedOutput(ctx, runner.Cmd("bash", scriptPath, "-au"))
 
 	return string(output), err
 }
 
-func (ocr *OCRService) GetPathSuggestions(input string) (AutoCompleteResult, error) {
-	fs := NewFileSearchService()
-	result, err := fs.GetPathSuggestions(input, true)
+// ConverterService with format detection
+type ConverterService struct {
+	runner runner.Runner
+	paths  *PathResolver
+}
 
-	if err != nil {
-		return result, err
-	}
+func NewConverterService(r runner.Runner, paths *PathResolver) *ConverterService {
+	return &ConverterService{runner: r, paths: paths}
+}
+
+// Conversion is what a convert query asks for
+type Conversion struct {
+	Input  string
+	Output string
+	Format string
+}
 
-	imageExts := map[string]bool{
-		".png": true, ".jpg": true, ".jpeg": true,
-		".bmp": true, ".tiff": true, ".tif": true,
-		".gif": true, ".webp": true,
+// Plan reads the input, target format and output path from a query. A
+// second path names the destination ("convert a.mkv to ~/b.mp4") and
+// supplies the format when none is given on its own.
+func (cs *ConverterService) Plan(query string) (Conversion, error) {
+	paths := cs.paths.Paths(query)
+	if len(paths) == 0 {
+		return Conversion{}, fmt.Errorf("no input file found")
 	}
 
-	var filtered []string
-	for _, path := range result.Suggestions {
-		ext := strings.ToLower(filepath.Ext(path))
-		if strings.HasSuffix(path, "/") || imageExts[ext] {
-			filtered = append(filtered, path)
+	conversion := Conversion{Input: paths[0], Format: extractFormat(query)}
+	if len(paths) > 1 {
+		conversion.Output = paths[1]
+		if ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(paths[1]), ".")); ext != "" {
+			conversion.Format = ext
 		}
 	}
-
-	result.Suggestions = filtered
-	result.Total = len(filtered)
-	return result, nil
+	if conversion.Format == "" {
+		return Conversion{}, fmt.Errorf("no target format specified")
+	}
+	if conversion.Output == "" {
+		conversion.Output = cs.OutputPath(conversion.Input, conversion.Format)
+	}
+	return conversion, nil
 }
 
-// ConverterService with format detection
-type ConverterService struct{}
-
-func NewConverterService() *ConverterService {
-	return &ConverterService{}
+func (cs *ConverterService) Convert(ctx context.Context, query string) (ConverterResult, error) {
+	conversion, err := cs.Plan(query)
+	if err != nil {
+		return ConverterResult{}, err
+	}
+	return cs.convert(ctx, conversion)
 }
 
-func (cs *ConverterService) Convert(query string) (ConverterResult, error) {
-	inputPath := extractPath(query)
-	if inputPath == "" {
-		return ConverterResult{}, fmt.Errorf("no input file found")
-	}
+// OutputPath picks where a conversion will be written, avoiding the input's siblings
+func (cs *ConverterService) OutputPath(inputPath, targetFormat string) string {
+	outputPath := strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + "." + targetFormat
 
-	targetFormat := extractFormat(query)
-	if targetFormat == "" {
-		return ConverterResult{}, fmt.Errorf("no target format specified")
+	// Check if output already exists
+	if _, err := os.Stat(outputPath); err == nil {
+		outputPath = strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) +
+			"_converted." + targetFormat
 	}
+	return outputPath
+}
 
-	return cs.ConvertWithFormat(inputPath, targetFormat)
+func (cs *ConverterService) ConvertWithFormat(ctx context.Context, inputPath, targetFormat string) (ConverterResult, error) {
+	return cs.convert(ctx, Conversion{
+		Input:  inputPath,
+		Output: cs.OutputPath(inputPath, targetFormat),
+		Format: targetFormat,
+	})
 }
 
-func (cs *ConverterService) ConvertWithFormat(inputPath, targetFormat string) (ConverterResult, error) {
+func (cs *ConverterService) convert(ctx context.Context, conversion Conversion) (ConverterResult, error) {
+	inputPath, outputPath, targetFormat := conversion.Input, conversion.Output, conversion.Format
+
 	// Verify input file exists
 	_, err := os.Stat(inputPath)
 	if os.IsNotExist(err) {
@@ -510,18 +473,15 @@ func (cs *ConverterService) ConvertWithFormat(inputPath, targetFormat string) (C
 	}
 
 	inputFormat := strings.TrimPrefix(filepath.Ext(inputPath), ".")
-	outputPath := strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + "." + targetFormat
 
-	// Check if output already exists
-	if _, err := os.Stat(outputPath); err == nil {
-		outputPath = strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) +
-			"_converted." + targetFormat
-	}
-
-	cmd := exec.Command("ffmpeg", "-i", inputPath, "-y", outputPath)
-	output, err := cmd.CombinedOutput()
+	output, err := cs.runner.CombinedOutput(ctx, runner.Cmd("ffmpeg", "-i", inputPath, "-y", outputPath))
 
 	if err != nil {
+		if ctx.Err() != nil {
+			// Don't leave a half-written file behind
+			os.Remove(outputPath)
+			return ConverterResult{Success: false, InputFormat: inputFormat, OutputFormat: targetFormat}, ctx.Err()
+		}
 		return ConverterResult{
 			Success:      false,
 			InputFormat:  inputFormat,
@@ -529,93 +489,55 @@ func (cs *ConverterService) ConvertWithFormat(inputPath, targetFormat string) (C
 		}, fmt.Errorf("conversion failed: %s", string(output))
 	}
 
-	outputInfo, _ := os.Stat(outputPath)
+	var fileSize int64
+	if outputInfo, err := os.Stat(outputPath); err == nil {
+		fileSize = outputInfo.Size()
+	}
 
 	return ConverterResult{
 		OutputPath:   outputPath,
 		Success:      true,
 		InputFormat:  inputFormat,
 		OutputFormat: targetFormat,
-		FileSize:     outputInfo.Size(),
+		FileSize:     fileSize,
 	}, nil
 }
 
-func (cs *ConverterService) GetPathSuggestions(input string) (AutoCompleteResult, error) {
-	fs := NewFileSearchService()
-	result, err := fs.GetPathSuggestions(input, true)
-
-	if err != nil {
-		return result, err
-	}
-
-	mediaExts := map[string]bool{
-		".mp4": true, ".webm": true, ".avi": true, ".mkv": true, ".mov": true,
-		".mp3": true, ".wav": true, ".flac": true, ".ogg": true, ".m4a": true,
-		".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true,
-	}
-
-	var filtered []string
-	for _, path := range result.Suggestions {
-		ext := strings.ToLower(filepath.Ext(path))
-		if strings.HasSuffix(path, "/") || mediaExts[ext] {
-			filtered = append(filtered, path)
-		}
-	}
-
-	result.Suggestions = filtered
-	result.Total = len(filtered)
-	return result, nil
-}
-
 // Helper functions
-func extractPath(query string) string {
-	words := strings.Fields(query)
-	for _, word := range words {
-		// Clean quotes
-		word = strings.Trim(word, "\"'")
-
-		if strings.Contains(word, "/") || strings.HasPrefix(word, "~") ||
-			strings.HasPrefix(word, ".") {
-			return word
-		}
-	}
-	return ""
-}
-
-func extractPathFromInput(input string) string {
-	if strings.HasPrefix(input, "/") || strings.HasPrefix(input, "~") ||
-		strings.HasPrefix(input, "./") || strings.HasPrefix(input, "../") {
-		return input
-	}
-
-	words := strings.Fields(input)
-	for i := len(words) - 1; i >= 0; i-- {
-		word := words[i]
-		if strings.Contains(word, "/") || strings.Contains(word, "~") ||
-			strings.HasPrefix(word, ".") {
-			return word
-		}
-	}
-
-	return ""
-}
-
 func extractFormat(query string) string {
 	formats := []string{
 		"mp4", "webm", "avi", "mkv", "mov",
 		"mp3", "wav", "flac", "ogg", "m4a",
 		"png", "jpg", "jpeg", "gif", "webp",
 	}
-	lowerQuery := strings.ToLower(query)
-
+	known := make(map[string]bool)
 	for _, format := range formats {
-		if strings.Contains(lowerQuery, " "+format) ||
-			strings.Contains(lowerQuery, "."+format) ||
-			strings.HasSuffix(lowerQuery, format) {
-			return format
+		known[format] = true
+	}
+
+	// The target is the last format named on its own ("to mp4", "as .webm"),
+	// or else the extension of an output file ("to clip.mp4"). The input's
+	// own extension is never the target: with no target named there is
+	// nothing to convert to.
+	var target, output string
+	destination := false
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		word = strings.TrimRight(strings.Trim(word, "\"',!?;:"), ".")
+		switch {
+		case word == "to" || word == "into" || word == "as":
+			destination = true
+		case known[strings.TrimPrefix(word, ".")]:
+			target = strings.TrimPrefix(word, ".")
+		case destination:
+			if ext := strings.TrimPrefix(filepath.Ext(word), "."); known[ext] {
+				output = ext
+			}
 		}
 	}
-	return ""
+	if target != "" {
+		return target
+	}
+	return output
 }
 
 func extractSearchTerms(query string) []string {
@@ -639,6 +561,16 @@ func extractSearchTerms(query string) []string {
 	return terms
 }
 
+// dataDir returns Aoiler's per-user data directory for a service
+func dataDir(service string) string {
+	base := os.Getenv("XDG_DATA_HOME")
+	if base == "" {
+		homeDir, _ := os.UserHomeDir()
+		base = filepath.Join(homeDir, ".local", "share")
+	}
+	return filepath.Join(base, "aoiler", service)
+}
+
 // estimateConfidence provides a rough quality estimate of OCR output
 func estimateConfidence(text string) string {
 	if len(text) == 0 {
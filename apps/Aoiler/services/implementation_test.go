@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"kaguyadots/runner"
+)
+
+func TestExtractFormat(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"convert clip.mkv to mp4", "mp4"},
+		{"convert clip.mp4 to webm", "webm"},
+		{"convert song.flac into mp3.", "mp3"},
+		{"convert photo.png as .JPG", "jpg"},
+		{"convert clip.mkv to clip.mp4", "mp4"},
+		{"convert clip.mkv", ""},
+		{"convert clip.mkv to something", ""},
+		{"convert this to something", ""},
+	}
+
+	for _, tt := range tests {
+		if got := extractFormat(tt.query); got != tt.want {
+			t.Errorf("extractFormat(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestExtractSearchTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"where is my hyprland config?", []string{"hyprland", "config"}},
+		{"Find the waybar style.css", []string{"waybar", "style.css"}},
+		{"find kitty, foot", []string{"kitty", "foot"}},
+		{"search for a file", nil},
+		{"find go", nil},
+	}
+
+	for _, tt := range tests {
+		if got := extractSearchTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extractSearchTerms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestLintFormatDispatch(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		file    string
+		linter  string
+		command string
+	}{
+		{"script.py", "black", "black {path}"},
+		{"main.go", "gofmt", "gofmt -w {path}"},
+		{"backup.sh", "shfmt", "shfmt -w {path}"},
+		{"app.js", "prettier", "prettier --write {path}"},
+		{"App.tsx", "prettier", "prettier --write {path}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			fake := runner.NewFake()
+			result, err := NewLinterService(fake, NewPathResolver()).LintFormat(context.Background(), "lint "+path)
+			if err != nil {
+				t.Fatalf("LintFormat: %v", err)
+			}
+			if result.LinterUsed != tt.linter || !result.Fixed || result.FilePath != path {
+				t.Errorf("result = %+v, want linter %s on %s", result, tt.linter, path)
+			}
+
+			want := []string{strings.ReplaceAll(tt.command, "{path}", path)}
+			if got := fake.Commands(); !reflect.DeepEqual(got, want) {
+				t.Errorf("commands = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestLintFormatErrors(t *testing.T) {
+	dir := t.TempDir()
+	unsupported := filepath.Join(dir, "main.rs")
+	broken := filepath.Join(dir, "broken.py")
+	for _, path := range []string{unsupported, broken} {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+		calls   int
+	}{
+		{"no path", "lint my code", "no file path", 0},
+		{"missing file", "lint " + filepath.Join(dir, "gone.py"), "does not exist", 0},
+		{"unsupported", "lint " + unsupported, "unsupported file type: .rs", 0},
+		{"linter fails", "lint " + broken, "exit status 123", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := runner.NewFake().On("black", "error: cannot format broken.py", errors.New("exit status 123"))
+			result, err := NewLinterService(fake, NewPathResolver()).LintFormat(context.Background(), tt.query)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if result.Fixed {
+				t.Error("result.Fixed = true on failure")
+			}
+			if got := len(fake.Calls()); got != tt.calls {
+				t.Errorf("ran %d commands, want %d", got, tt.calls)
+			}
+		})
+	}
+}
+
+func TestOrganizeTarget(t *testing.T) {
+	paths, _, work := testPaths(t)
+	downloads := filepath.Join(work, "Downloads")
+
+	tests := []struct {
+		query    string
+		commands []string
+	}{
+		{"organize ~/work/Downloads by type", []string{"tyr -c -nui " + downloads}},
+		{"sort Downloads", []string{"tyr -c -nui " + downloads}},
+		// No directory named: refused rather than sorting the base directory
+		{"organize by type", nil},
+		{"clean up", nil},
+	}
+
+	for _, tt := range tests {
+		fake := runner.NewFake()
+		sm := &ServiceManager{paths: paths, organizer: NewOrganizerService(fake, paths)}
+		_, err := sm.organizer.Organize(context.Background(), tt.query, "category")
+		if (err != nil) != (tt.commands == nil) {
+			t.Errorf("Organize(%q) err = %v", tt.query, err)
+		}
+		if got := fake.Commands(); !reflect.DeepEqual(got, tt.commands) {
+			t.Errorf("Organize(%q) commands = %q, want %q", tt.query, got, tt.commands)
+		}
+		if plan := sm.PlanAction(Intent{ServiceName: "organizer"}, tt.query); plan.Mutating != (tt.commands != nil) {
+			t.Errorf("PlanAction(%q) = %+v", tt.query, plan)
+		}
+	}
+}
+
+func TestConvertWithFormatNaming(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		existing []string
+		format   string
+		want     string
+	}{
+		{"sibling", "clip.mkv", nil, "mp4", "clip.mp4"},
+		{"output exists", "clip.mkv", []string{"clip.mp4"}, "mp4", "clip_converted.mp4"},
+		{"dotted name", "my.holiday.mov", nil, "webm", "my.holiday.webm"},
+		{"no extension", "recording", nil, "mp3", "recording.mp3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range append([]string{tt.input}, tt.existing...) {
+				if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			input := filepath.Join(dir, tt.input)
+			want := filepath.Join(dir, tt.want)
+			fake := runner.NewFake()
+
+			result, err := NewConverterService(fake, NewPathResolver()).ConvertWithFormat(context.Background(), input, tt.format)
+			if err != nil {
+				t.Fatalf("ConvertWithFormat: %v", err)
+			}
+			if result.OutputPath != want || result.OutputFormat != tt.format {
+				t.Errorf("result = %+v, want output %s", result, want)
+			}
+
+			wantCommands := []string{"ffmpeg -i " + input + " -y " + want}
+			if got := fake.Commands(); !reflect.DeepEqual(got, wantCommands) {
+				t.Errorf("commands = %q, want %q", got, wantCommands)
+			}
+		})
+	}
+}
+
+func TestConvertWithFormatErrors(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "clip.mkv")
+	if err := os.WriteFile(input, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := runner.NewFake().On("ffmpeg", "Invalid data found when processing input", errors.New("exit status 1"))
+	converter := NewConverterService(fake, NewPathResolver())
+
+	if _, err := converter.ConvertWithFormat(context.Background(), filepath.Join(dir, "gone.mkv"), "mp4"); err == nil {
+		t.Error("missing input: want error")
+	}
+
+	result, err := converter.ConvertWithFormat(context.Background(), input, "mp4")
+	if err == nil || !strings.Contains(err.Error(), "Invalid data") {
+		t.Errorf("err = %v, want ffmpeg output", err)
+	}
+	if result.Success {
+		t.Error("result.Success = true on failure")
+	}
+}
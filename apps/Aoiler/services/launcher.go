@@ -0,0 +1,424 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kaguyadots/runner"
+)
+
+// AppEntry is a launchable application from a .desktop file or quickapps.conf
+type AppEntry struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	GenericName string   `json:"genericName,omitempty"`
+	Exec        string   `json:"exec"`
+	Icon        string   `json:"icon,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	Terminal    bool     `json:"terminal"`
+	Source      string   `json:"source"`
+	LaunchCount int      `json:"launchCount"`
+}
+
+type LauncherResult struct {
+	Launched   *AppEntry  `json:"launched,omitempty"`
+	Command    string     `json:"command,omitempty"`
+	PID        int        `json:"pid,omitempty"`
+	Candidates []AppEntry `json:"candidates"`
+}
+
+// Preferences mirrors the [preferences] table of kaguyadots.toml
+type Preferences struct {
+	Term    string `json:"term"`
+	Browser string `json:"browser"`
+	Shell   string `json:"shell"`
+}
+
+// LauncherService indexes desktop entries and quickapps and starts them detached
+type LauncherService struct {
+	runner    runner.Runner
+	mu        sync.Mutex
+	entries   []AppEntry
+	indexedAt time.Time
+	usagePath string
+	usage     map[string]int
+}
+
+func NewLauncherService(r runner.Runner) *LauncherService {
+	ls := &LauncherService{
+		runner:    r,
+		usagePath: filepath.Join(dataDir("launcher"), "usage.json"),
+		usage:     make(map[string]int),
+	}
+	if data, err := os.ReadFile(ls.usagePath); err == nil {
+		json.Unmarshal(data, &ls.usage)
+	}
+	return ls
+}
+
+// Launch finds the best match for the query and starts it. The context only
+// guards the lookup; the launched app is never tied to it.
+func (ls *LauncherService) Launch(ctx context.Context, query string) (LauncherResult, error) {
+	name := extractAppName(query)
+	if name == "" {
+		return LauncherResult{}, fmt.Errorf("no application name in query")
+	}
+
+	candidates := ls.Match(name, 5)
+	if len(candidates) == 0 {
+		return LauncherResult{Candidates: []AppEntry{}}, fmt.Errorf("no application matches %q", name)
+	}
+
+	best := candidates[0]
+	command := ls.commandFor(best)
+
+	if err := ctx.Err(); err != nil {
+		return LauncherResult{Candidates: candidates, Command: command}, err
+	}
+
+	pid, err := ls.runner.Start(runner.Cmd("sh", "-c", command))
+	if err != nil {
+		return LauncherResult{Candidates: candidates, Command: command}, fmt.Errorf("failed to launch %s: %w", command, err)
+	}
+
+	ls.recordLaunch(best.ID)
+	best.LaunchCount = ls.launchCount(best.ID)
+
+	return LauncherResult{
+		Launched:   &best,
+		Command:    command,
+		PID:        pid,
+		Candidates: candidates,
+	}, nil
+}
+
+// Match returns entries ranked by fuzzy score and launch frequency
+func (ls *LauncherService) Match(name string, limit int) []AppEntry {
+	entries := ls.Entries()
+	term := strings.ToLower(strings.TrimSpace(name))
+
+	type scored struct {
+		entry AppEntry
+		score int
+	}
+
+	var results []scored
+	for _, entry := range entries {
+		score := scoreApp(entry, term)
+		if score == 0 {
+			continue
+		}
+		count := ls.launchCount(entry.ID)
+		entry.LaunchCount = count
+		score += min(count*5, 40)
+		results = append(results, scored{entry: entry, score: score})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	matches := []AppEntry{}
+	for _, r := range results {
+		if limit > 0 && len(matches) >= limit {
+			break
+		}
+		matches = append(matches, r.entry)
+	}
+	return matches
+}
+
+// Entries returns the application index, rebuilding it when stale
+func (ls *LauncherService) Entries() []AppEntry {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if ls.entries == nil || time.Since(ls.indexedAt) > 5*time.Minute {
+		ls.entries = buildAppIndex()
+		ls.indexedAt = time.Now()
+	}
+	return ls.entries
+}
+
+// commandFor resolves the shell command for an entry, honouring the
+// browser/term preferences for the generic "browser" and "terminal" aliases
+func (ls *LauncherService) commandFor(entry AppEntry) string {
+	prefs := ReadPreferences()
+	command := entry.Exec
+
+	switch {
+	case entry.ID == "pref:browser" && prefs.Browser != "":
+		command = prefs.Browser
+	case entry.ID == "pref:terminal" && prefs.Term != "":
+		command = prefs.Term
+	}
+
+	if entry.Terminal {
+		term := prefs.Term
+		if term == "" {
+			term = "kitty"
+		}
+		command = term + " -e " + command
+	}
+	return command
+}
+
+func (ls *LauncherService) launchCount(id string) int {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.usage[id]
+}
+
+func (ls *LauncherService) recordLaunch(id string) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	ls.usage[id]++
+	if err := os.MkdirAll(filepath.Dir(ls.usagePath), 0700); err != nil {
+		return
+	}
+	if data, err := json.Marshal(ls.usage); err == nil {
+		os.WriteFile(ls.usagePath, data, 0600)
+		// Earlier versions wrote it world-readable
+		os.Chmod(ls.usagePath, 0600)
+	}
+}
+
+func scoreApp(entry AppEntry, term string) int {
+	name := strings.ToLower(entry.Name)
+	id := strings.ToLower(strings.TrimPrefix(entry.ID, "quickapps:"))
+	execName := strings.ToLower(filepath.Base(strings.Fields(entry.Exec + " ")[0]))
+
+	switch {
+	case name == term || id == term || execName == term:
+		return 100
+	case strings.HasPrefix(name, term) || strings.HasPrefix(execName, term):
+		return 70
+	case strings.Contains(name, term):
+		return 50
+	case strings.Contains(strings.ToLower(entry.GenericName), term):
+		return 40
+	}
+
+	for _, keyword := range entry.Keywords {
+		if strings.HasPrefix(strings.ToLower(keyword), term) {
+			return 30
+		}
+	}
+
+	if isSubsequence(term, name) {
+		return 15
+	}
+	return 0
+}
+
+// isSubsequence reports whether every rune of needle appears in order in haystack
+func isSubsequence(needle, haystack string) bool {
+	if needle == "" {
+		return false
+	}
+	runes := []rune(needle)
+	i := 0
+	for _, r := range haystack {
+		if r == runes[i] {
+			i++
+			if i == len(runes) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func extractAppName(query string) string {
+	words := strings.Fields(strings.ToLower(query))
+	fillers := map[string]bool{
+		"open": true, "launch": true, "start": true, "run": true,
+		"the": true, "my": true, "app": true, "please": true,
+	}
+
+	var kept []string
+	for _, word := range words {
+		if !fillers[word] {
+			kept = append(kept, word)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+func buildAppIndex() []AppEntry {
+	seen := make(map[string]bool)
+	var entries []AppEntry
+
+	// Preference aliases resolve at launch time so edits to kaguyadots.toml apply immediately
+	entries = append(entries,
+		AppEntry{ID: "pref:browser", Name: "Browser", GenericName: "Web Browser", Exec: "zen", Source: "preferences"},
+		AppEntry{ID: "pref:terminal", Name: "Terminal", GenericName: "Terminal Emulator", Exec: "kitty", Source: "preferences", Keywords: []string{"term", "shell", "console"}},
+	)
+
+	for _, entry := range readQuickApps() {
+		seen[entry.ID] = true
+		entries = append(entries, entry)
+	}
+
+	for _, dir := range applicationDirs() {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.desktop"))
+		for _, file := range files {
+			id := filepath.Base(file)
+			// Earlier directories shadow later ones, like the XDG spec
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if entry, ok := parseDesktopFile(file); ok {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
+
+func applicationDirs() []string {
+	homeDir, _ := os.UserHomeDir()
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(homeDir, ".local", "share")
+	}
+	dirs := []string{filepath.Join(dataHome, "applications")}
+
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range strings.Split(dataDirs, ":") {
+		if dir != "" {
+			dirs = append(dirs, filepath.Join(dir, "applications"))
+		}
+	}
+	return dirs
+}
+
+func parseDesktopFile(path string) (AppEntry, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return AppEntry{}, false
+	}
+	defer file.Close()
+
+	entry := AppEntry{ID: filepath.Base(path), Source: "desktop"}
+	inMain := false
+	hidden := false
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inMain = line == "[Desktop Entry]"
+			continue
+		}
+		if !inMain || !strings.Contains(line, "=") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+		switch key {
+		case "Name":
+			entry.Name = value
+		case "GenericName":
+			entry.GenericName = value
+		case "Exec":
+			entry.Exec = stripFieldCodes(value)
+		case "Icon":
+			entry.Icon = value
+		case "Keywords":
+			entry.Keywords = strings.FieldsFunc(value, func(r rune) bool { return r == ';' })
+		case "Terminal":
+			entry.Terminal = value == "true"
+		case "NoDisplay", "Hidden":
+			hidden = hidden || value == "true"
+		case "Type":
+			hidden = hidden || value != "Application"
+		}
+	}
+
+	if hidden || entry.Name == "" || entry.Exec == "" {
+		return AppEntry{}, false
+	}
+	return entry, true
+}
+
+// stripFieldCodes removes %f, %U and friends from a desktop Exec line and
+// turns the %% escape back into a literal %
+func stripFieldCodes(execLine string) string {
+	var kept []string
+	for _, field := range strings.Fields(execLine) {
+		var arg strings.Builder
+		for i := 0; i < len(field); i++ {
+			if field[i] == '%' && i+1 < len(field) {
+				i++
+				if field[i] == '%' {
+					arg.WriteByte('%')
+				}
+				continue
+			}
+			arg.WriteByte(field[i])
+		}
+		if arg.Len() > 0 {
+			kept = append(kept, arg.String())
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+func readQuickApps() []AppEntry {
+	homeDir, _ := os.UserHomeDir()
+	file, err := os.Open(filepath.Join(homeDir, ".config", "kaguyadots", "quickapps.conf"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var entries []AppEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || !strings.Contains(line, "=") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		name, command := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if name == "" || command == "" {
+			continue
+		}
+
+		entries = append(entries, AppEntry{
+			ID:     "quickapps:" + strings.ToLower(name),
+			Name:   name,
+			Exec:   command,
+			Source: "quickapps",
+		})
+	}
+	return entries
+}
+
+// ReadPreferences reads the [preferences] table from kaguyadots.toml
+func ReadPreferences() Preferences {
+	table := readKaguyaTable("preferences")
+	return Preferences{
+		Term:    table["term"],
+		Browser: table["browser"],
+		Shell:   table["shell"],
+	}
+}
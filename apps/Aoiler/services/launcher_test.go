@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"kaguyadots/runner"
+)
+
+// testApps writes desktop entries, quickapps.conf and preferences under a
+// fake home and points the XDG data dirs at it
+func testApps(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+	t.Setenv("XDG_DATA_DIRS", filepath.Join(home, "usr", "share"))
+
+	local := filepath.Join(home, ".local", "share", "applications")
+	system := filepath.Join(home, "usr", "share", "applications")
+	files := map[string]string{
+		filepath.Join(local, "firefox.desktop"):  "[Desktop Entry]\nType=Application\nName=Firefox Private\nExec=firefox --private-window %u\n",
+		filepath.Join(system, "firefox.desktop"): "[Desktop Entry]\nType=Application\nName=Firefox\nExec=firefox %u\n",
+		filepath.Join(system, "org.gnome.Nautilus.desktop"): "[Desktop Entry]\nType=Application\nName=Files\nGenericName=File Manager\n" +
+			"Keywords=folder;explorer;\nIcon=org.gnome.Nautilus\nExec=nautilus --new-window %U\n\n" +
+			"[Desktop Action new-window]\nName=New Window\nExec=nautilus --new-window\n",
+		filepath.Join(system, "htop.desktop"):                           "[Desktop Entry]\nType=Application\nName=Htop\nTerminal=true\nExec=htop\n",
+		filepath.Join(system, "gauge.desktop"):                          "[Desktop Entry]\nType=Application\nName=Gauge\nExec=gauge --label 100%% %f\n",
+		filepath.Join(system, "hidden.desktop"):                         "[Desktop Entry]\nType=Application\nName=Hidden\nExec=hidden\nNoDisplay=true\n",
+		filepath.Join(system, "site.desktop"):                           "[Desktop Entry]\nType=Link\nName=Site\nURL=https://example.com\nExec=xdg-open\n",
+		filepath.Join(home, ".config", "kaguyadots", "quickapps.conf"):  "# name = command\nmusic = spotify --minimized\nbroken =\n",
+		filepath.Join(home, ".config", "kaguyadots", "kaguyadots.toml"): "[preferences]\nterm = \"foot\"\nbrowser = \"librewolf\"\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return home
+}
+
+func TestStripFieldCodes(t *testing.T) {
+	tests := map[string]string{
+		"firefox %u":                    "firefox",
+		"nautilus --new-window %U":      "nautilus --new-window",
+		"gimp-2.10 %F":                  "gimp-2.10",
+		"gauge --label 100%% %f":        "gauge --label 100%",
+		"printf %%s":                    "printf %s",
+		"app %i %c %k --flag":           "app --flag",
+		"code --file-uri=%u --reuse %%": "code --file-uri= --reuse %",
+	}
+	for execLine, want := range tests {
+		if got := stripFieldCodes(execLine); got != want {
+			t.Errorf("stripFieldCodes(%q) = %q, want %q", execLine, got, want)
+		}
+	}
+}
+
+func TestBuildAppIndex(t *testing.T) {
+	testApps(t)
+
+	entries := map[string]AppEntry{}
+	for _, entry := range buildAppIndex() {
+		entries[entry.ID] = entry
+	}
+
+	want := map[string]AppEntry{
+		"firefox.desktop": {ID: "firefox.desktop", Name: "Firefox Private", Exec: "firefox --private-window", Source: "desktop"},
+		"org.gnome.Nautilus.desktop": {ID: "org.gnome.Nautilus.desktop", Name: "Files", GenericName: "File Manager",
+			Exec: "nautilus --new-window", Icon: "org.gnome.Nautilus", Keywords: []string{"folder", "explorer"}, Source: "desktop"},
+		"htop.desktop":    {ID: "htop.desktop", Name: "Htop", Exec: "htop", Terminal: true, Source: "desktop"},
+		"gauge.desktop":   {ID: "gauge.desktop", Name: "Gauge", Exec: "gauge --label 100%", Source: "desktop"},
+		"quickapps:music": {ID: "quickapps:music", Name: "music", Exec: "spotify --minimized", Source: "quickapps"},
+	}
+	for id, entry := range want {
+		if got := entries[id]; !reflect.DeepEqual(got, entry) {
+			t.Errorf("%s = %+v, want %+v", id, got, entry)
+		}
+	}
+	// Hidden entries, links and empty quickapps are skipped; the user's
+	// firefox.desktop shadows the system one
+	for _, id := range []string{"hidden.desktop", "site.desktop", "quickapps:broken"} {
+		if _, ok := entries[id]; ok {
+			t.Errorf("%s indexed", id)
+		}
+	}
+	if len(entries) != len(want)+2 {
+		t.Errorf("%d entries, want %d plus the two preference aliases", len(entries), len(want))
+	}
+}
+
+func TestLauncherMatch(t *testing.T) {
+	testApps(t)
+	ls := NewLauncherService(runner.NewFake())
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"files", "org.gnome.Nautilus.desktop"},
+		{"nautilus", "org.gnome.Nautilus.desktop"},
+		{"file manager", "org.gnome.Nautilus.desktop"},
+		{"explorer", "org.gnome.Nautilus.desktop"},
+		{"fire", "firefox.desktop"},
+		{"music", "quickapps:music"},
+		{"term", "pref:terminal"},
+		{"htp", "htop.desktop"},
+	}
+	for _, tt := range tests {
+		matches := ls.Match(tt.name, 3)
+		if len(matches) == 0 || matches[0].ID != tt.want {
+			t.Errorf("Match(%q) = %+v, want %s first", tt.name, matches, tt.want)
+		}
+	}
+	if matches := ls.Match("zzz", 3); len(matches) != 0 {
+		t.Errorf("Match(zzz) = %+v", matches)
+	}
+}
+
+func TestLauncherLaunch(t *testing.T) {
+	home := testApps(t)
+	fake := runner.NewFake()
+	ls := NewLauncherService(fake)
+
+	tests := []struct {
+		query   string
+		command string
+	}{
+		{"open firefox", "firefox --private-window"},
+		{"launch htop", "foot -e htop"},
+		{"open the browser", "librewolf"},
+		{"start music", "spotify --minimized"},
+	}
+	for _, tt := range tests {
+		fake.Reset()
+		result, err := ls.Launch(context.Background(), tt.query)
+		if err != nil || result.Command != tt.command || result.Launched == nil {
+			t.Errorf("Launch(%q) = %+v, %v; want %q", tt.query, result, err, tt.command)
+			continue
+		}
+		if calls := fake.Commands(); !reflect.DeepEqual(calls, []string{"sh -c " + tt.command}) {
+			t.Errorf("Launch(%q) commands = %q", tt.query, calls)
+		}
+	}
+	if _, err := ls.Launch(context.Background(), "open please"); err == nil {
+		t.Error("no app name: want error")
+	}
+
+	// Launch counts persist privately and lift an app in the ranking
+	usage := filepath.Join(home, ".local", "share", "aoiler", "launcher", "usage.json")
+	info, err := os.Stat(usage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("usage mode = %v, want 0600", info.Mode().Perm())
+	}
+	for i := 0; i < 3; i++ {
+		ls.Launch(context.Background(), "open gauge")
+	}
+	reloaded := NewLauncherService(runner.NewFake())
+	if matches := reloaded.Match("g", 5); len(matches) == 0 || matches[0].ID != "gauge.desktop" || matches[0].LaunchCount != 3 {
+		t.Errorf("Match(g) after launches = %+v", matches)
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,9 +15,15 @@ import (
 // LLMProvider represents different LLM providers
 type LLMProvider string
 type LLMResult struct {
-	Response string `json:"response"`
-	Success  bool   `json:"success"`
-	Provider string `json:"provider,omitempty"`
+	Response  string           `json:"response"`
+	Success   bool             `json:"success"`
+	Provider  string           `json:"provider,omitempty"`
+	ToolCalls []ToolCallRecord `json:"toolCalls,omitempty"`
+	Citations []Citation       `json:"citations,omitempty"`
+	Images    []string         `json:"images,omitempty"`
+	Model     string           `json:"model,omitempty"`
+	Usage     *TokenUsage      `json:"usage,omitempty"`
+	Cached    bool             `json:"cached,omitempty"`
 }
 const (
 	ProviderOpenAI  LLMProvider = "openai"
@@ -25,6 +32,13 @@ const (
 	ProviderDefault LLMProvider = "default"
 )
 
+// maxToolRounds bounds how many times the model may call tools per query
+const maxToolRounds = 6
+
+const assistantSystemPrompt = `You are Aoiler, a desktop assistant for KaguyaDots, a Hyprland dotfiles setup on Arch Linux.
+Use the tools to look at the user's actual files before answering questions about their setup.
+Tools that change files only request confirmation; tell the user what will happen when they confirm.`
+
 // LLMService handles LLM API queries
 type LLMService struct {
 	provider       LLMProvider
@@ -33,6 +47,20 @@ type LLMService struct {
 	geminiKey      string
 	httpClient     *http.Client
 	defaultModel   map[LLMProvider]string
+	tools          *ToolRegistry
+	dotfiles       *DotfilesIndex
+	ledger         *UsageLedger
+	cache          *ResponseCache
+}
+
+// llmCall is one query as sent to a provider, tool rounds included
+type llmCall struct {
+	Model  string
+	System string
+	Query  string
+	Images []ImageInput
+	Tools  *ToolRegistry // nil when the model may not call tools
+	Usage  TokenUsage    // summed over every round trip
 }
 
 // OpenAI API structures
@@ -40,17 +68,68 @@ type OpenAIRequest struct {
 	Model    string          `json:"model"`
 	Messages []OpenAIMessage `json:"messages"`
 	Stream   bool            `json:"stream"`
+	Tools    []OpenAITool    `json:"tools,omitempty"`
 }
 
 type OpenAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string              `json:"role"`
+	Content    string              `json:"content"`
+	Parts      []OpenAIContentPart `json:"-"` // sent as content instead of Content when set
+	ToolCalls  []OpenAIToolCall    `json:"tool_calls,omitempty"`
+	ToolCallID string              `json:"tool_call_id,omitempty"`
+}
+
+// MarshalJSON sends multimodal messages as a content array
+func (m OpenAIMessage) MarshalJSON() ([]byte, error) {
+	type message OpenAIMessage
+	if len(m.Parts) == 0 {
+		return json.Marshal(message(m))
+	}
+	return json.Marshal(struct {
+		message
+		Content []OpenAIContentPart `json:"content"`
+	}{message(m), m.Parts})
+}
+
+type OpenAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *OpenAIImageURL `json:"image_url,omitempty"`
+}
+
+type OpenAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type OpenAITool struct {
+	Type     string             `json:"type"`
+	Function OpenAIToolFunction `json:"function"`
+}
+
+type OpenAIToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+type OpenAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
 }
 
 type OpenAIResponse struct {
 	Choices []struct {
-		Message OpenAIMessage `json:"message"`
+		Message      OpenAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
 	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
 	Error *struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
@@ -62,18 +141,48 @@ type ClaudeRequest struct {
 	Model     string          `json:"model"`
 	Messages  []ClaudeMessage `json:"messages"`
 	MaxTokens int             `json:"max_tokens"`
+	System    string          `json:"system,omitempty"`
+	Tools     []ClaudeTool    `json:"tools,omitempty"`
 }
 
+// ClaudeMessage content is either a plain string or a list of ClaudeContentBlock
 type ClaudeMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+type ClaudeContentBlock struct {
+	Type      string             `json:"type"`
+	Text      string             `json:"text,omitempty"`
+	ID        string             `json:"id,omitempty"`
+	Name      string             `json:"name,omitempty"`
+	Input     json.RawMessage    `json:"input,omitempty"` // tool_use arguments, always sent back as an object
+	ToolUseID string             `json:"tool_use_id,omitempty"`
+	Content   string             `json:"content,omitempty"`
+	IsError   bool               `json:"is_error,omitempty"`
+	Source    *ClaudeImageSource `json:"source,omitempty"`
+}
+
+type ClaudeImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type ClaudeTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"input_schema"`
 }
 
 type ClaudeResponse struct {
-	Content []struct {
-		Text string `json:"text"`
-	} `json:"content"`
-	Error *struct {
+	Content    []ClaudeContentBlock `json:"content"`
+	StopReason string               `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error      *struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
 	} `json:"error,omitempty"`
@@ -81,23 +190,56 @@ type ClaudeResponse struct {
 
 // Gemini API structures
 type GeminiRequest struct {
-	Contents []GeminiContent `json:"contents"`
+	Contents          []GeminiContent `json:"contents"`
+	SystemInstruction *GeminiContent  `json:"systemInstruction,omitempty"`
+	Tools             []GeminiTool    `json:"tools,omitempty"`
 }
 
 type GeminiContent struct {
+	Role  string       `json:"role,omitempty"`
 	Parts []GeminiPart `json:"parts"`
 }
 
 type GeminiPart struct {
-	Text string `json:"text"`
+	Text             string                  `json:"text,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+	InlineData       *GeminiInlineData       `json:"inlineData,omitempty"`
+}
+
+type GeminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type GeminiFunctionCall struct {
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args"`
+}
+
+type GeminiFunctionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type GeminiFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
 }
 
 type GeminiResponse struct {
 	Candidates []struct {
-		Content struct {
-			Parts []GeminiPart `json:"parts"`
-		} `json:"content"`
+		Content GeminiContent `json:"content"`
 	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
 	Error *struct {
 		Message string `json:"message"`
 		Code    int    `json:"code"`
@@ -118,6 +260,8 @@ func NewLLMService() *LLMService {
 			ProviderClaude: "claude-3-5-sonnet-20241022",
 			ProviderGemini: "gemini-1.5-flash",
 		},
+		ledger: NewUsageLedger(),
+		cache:  NewResponseCache(),
 	}
 
 	// Determine which provider to use based on available keys
@@ -126,6 +270,34 @@ func NewLLMService() *LLMService {
 	return service
 }
 
+// SetTools registers the tools the model may call during a query
+func (llm *LLMService) SetTools(tools *ToolRegistry) {
+	llm.tools = tools
+}
+
+// UsageSummary reports this month's token usage and spend
+func (llm *LLMService) UsageSummary() UsageSummary {
+	return llm.ledger.Summary(time.Now().Format("2006-01"), ReadBudgetConfig())
+}
+
+// SetDotfiles enables retrieval of config snippets for each query
+func (llm *LLMService) SetDotfiles(index *DotfilesIndex) {
+	llm.dotfiles = index
+}
+
+// systemPrompt combines the assistant instructions with any config
+// excerpts relevant to the query
+func (llm *LLMService) systemPrompt(query string) (string, []Citation) {
+	if llm.dotfiles == nil {
+		return assistantSystemPrompt, nil
+	}
+	context, citations := llm.dotfiles.BuildContext(query)
+	if context == "" {
+		return assistantSystemPrompt, nil
+	}
+	return assistantSystemPrompt + "\n\n" + context, citations
+}
+
 // detectProvider determines which provider to use based on available API keys
 func (llm *LLMService) detectProvider() LLMProvider {
 	// Priority: OpenAI > Claude > Gemini
@@ -142,7 +314,25 @@ func (llm *LLMService) detectProvider() LLMProvider {
 }
 
 // Query sends a query to the configured LLM provider
-func (llm *LLMService) Query(query string) (LLMResult, error) {
+func (llm *LLMService) Query(ctx context.Context, query string, opts QueryOptions) (LLMResult, error) {
+	return llm.QueryWithImages(ctx, query, nil, opts)
+}
+
+// QueryWithImages sends a query along with images for vision-capable models
+func (llm *LLMService) QueryWithImages(ctx context.Context, query string, images []ImageInput, opts QueryOptions) (LLMResult, error) {
+	system, citations := llm.systemPrompt(query)
+	call := &llmCall{System: system, Query: query, Images: images, Tools: llm.tools}
+	return llm.run(withToolQuery(ctx, query), call, citations, opts)
+}
+
+// QueryTask runs a single-turn task, such as a translation, with its own
+// system prompt and without tools or config excerpts
+func (llm *LLMService) QueryTask(ctx context.Context, system, input string, opts QueryOptions) (LLMResult, error) {
+	return llm.run(ctx, &llmCall{System: system, Query: input}, nil, opts)
+}
+
+// run sends a prepared call through the budget, cache and usage ledger
+func (llm *LLMService) run(ctx context.Context, call *llmCall, citations []Citation, opts QueryOptions) (LLMResult, error) {
 	if llm.provider == ProviderDefault {
 		return LLMResult{
 			Response: "No LLM API key configured. Please set one of:\n- OPENAI_API_KEY\n- CLAUDE_API_KEY\n- GEMINI_API_KEY",
@@ -150,212 +340,418 @@ func (llm *LLMService) Query(query string) (LLMResult, error) {
 		}, nil
 	}
 
+	var result LLMResult
+	var err error
+
+	budget := ReadBudgetConfig()
+	call.Model = llm.defaultModel[llm.provider]
+
+	spent := 0.0
+	if budget.MonthlyBudget > 0 {
+		spent = llm.ledger.MonthCost(time.Now().Format("2006-01"))
+	}
+	var blocked bool
+	call.Model, blocked = budget.Apply(llm.provider, call.Model, spent)
+
+	// Cached answers cost nothing, so they are served even over budget
+	key := cacheKey(llm.provider, call)
+	if !opts.BypassCache {
+		if cached, ok := llm.cache.Get(key); ok {
+			cached.Cached = true
+			cached.Usage = nil
+			return cached, nil
+		}
+	}
+
+	if blocked {
+		return LLMResult{
+			Response: fmt.Sprintf("Monthly LLM budget reached ($%.2f of $%.2f). Raise monthly_budget in kaguyadots.toml to continue.", spent, budget.MonthlyBudget),
+			Success:  false,
+			Provider: string(llm.provider),
+		}, nil
+	}
+
 	switch llm.provider {
 	case ProviderOpenAI:
-		return llm.queryOpenAI(query)
+		result, err = llm.queryOpenAI(ctx, call)
 	case ProviderClaude:
-		return llm.queryClaude(query)
+		result, err = llm.queryClaude(ctx, call)
 	case ProviderGemini:
-		return llm.queryGemini(query)
+		result, err = llm.queryGemini(ctx, call)
 	default:
 		return LLMResult{
 			Response: "Unknown provider",
 			Success:  false,
 		}, fmt.Errorf("unknown provider: %s", llm.provider)
 	}
-}
 
-// queryOpenAI sends a query to OpenAI API
-func (llm *LLMService) queryOpenAI(query string) (LLMResult, error) {
-	url := "https://api.openai.com/v1/chat/completions"
+	result.Provider = string(llm.provider)
+	result.Model = call.Model
+	result.Citations = citations
 
-	reqBody := OpenAIRequest{
-		Model: llm.defaultModel[ProviderOpenAI],
-		Messages: []OpenAIMessage{
-			{
-				Role:    "user",
-				Content: query,
-			},
-		},
-		Stream: false,
+	// Failed requests can still be billed for the rounds that went through
+	if call.Usage.PromptTokens > 0 || call.Usage.CompletionTokens > 0 {
+		usage := call.Usage
+		usage.Cost = budget.Cost(call.Model, usage)
+		result.Usage = &usage
+		llm.ledger.Record(string(llm.provider), call.Model, usage)
+	}
+	for _, image := range call.Images {
+		result.Images = append(result.Images, image.Path)
 	}
 
-	jsonData, err := json.Marshal(reqBody)
+	// Answers built from tool calls depend on file state and may carry
+	// one-time confirmation tokens, so only plain answers are cached
+	if err == nil && result.Success && len(result.ToolCalls) == 0 {
+		llm.cache.Put(key, result)
+	}
+	return result, err
+}
+
+// ClearCache drops every cached answer
+func (llm *LLMService) ClearCache() error {
+	return llm.cache.Clear()
+}
+
+// postJSON sends a JSON request and decodes the JSON response into out
+func (llm *LLMService) postJSON(ctx context.Context, url string, headers map[string]string, payload interface{}, out interface{}) error {
+	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		return LLMResult{Success: false}, fmt.Errorf("failed to marshal request: %w", err)
+		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return LLMResult{Success: false}, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+llm.openAIKey)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
 
 	resp, err := llm.httpClient.Do(req)
 	if err != nil {
-		return LLMResult{Success: false}, fmt.Errorf("request failed: %w", err)
+		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return LLMResult{Success: false}, fmt.Errorf("failed to read response: %w", err)
+		return fmt.Errorf("failed to read response: %w", err)
 	}
 
-	var openAIResp OpenAIResponse
-	if err := json.Unmarshal(body, &openAIResp); err != nil {
-		return LLMResult{Success: false}, fmt.Errorf("failed to parse response: %w", err)
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
 	}
+	return nil
+}
 
-	if openAIResp.Error != nil {
-		return LLMResult{
-			Response: fmt.Sprintf("OpenAI Error: %s", openAIResp.Error.Message),
-			Success:  false,
-		}, nil
+// toolOutputJSON serialises a tool result for the model
+func toolOutputJSON(record ToolCallRecord) string {
+	data, err := json.Marshal(record.toolOutput())
+	if err != nil {
+		return fmt.Sprintf(`{"error": %q}`, err.Error())
 	}
+	return string(data)
+}
 
-	if len(openAIResp.Choices) == 0 {
-		return LLMResult{
-			Response: "No response from OpenAI",
-			Success:  false,
-		}, nil
+// queryOpenAI sends a query to OpenAI API
+func (llm *LLMService) queryOpenAI(ctx context.Context, call *llmCall) (LLMResult, error) {
+	url := "https://api.openai.com/v1/chat/completions"
+	headers := map[string]string{"Authorization": "Bearer " + llm.openAIKey}
+
+	reqBody := OpenAIRequest{
+		Model: call.Model,
+		Messages: []OpenAIMessage{
+			{
+				Role:    "system",
+				Content: call.System,
+			},
+			{
+				Role:    "user",
+				Content: call.Query,
+				Parts:   openAIParts(call.Query, call.Images),
+			},
+		},
+		Stream: false,
 	}
 
-	return LLMResult{
-		Response: strings.TrimSpace(openAIResp.Choices[0].Message.Content),
-		Success:  true,
-	}, nil
+	if call.Tools != nil {
+		for _, tool := range call.Tools.Tools() {
+			reqBody.Tools = append(reqBody.Tools, OpenAITool{
+				Type: "function",
+				Function: OpenAIToolFunction{
+					Name:        tool.Name,
+					Description: tool.Description,
+					Parameters:  tool.Parameters,
+				},
+			})
+		}
+	}
+
+	var transcript []ToolCallRecord
+	for round := 0; ; round++ {
+		var openAIResp OpenAIResponse
+		if err := llm.postJSON(ctx, url, headers, reqBody, &openAIResp); err != nil {
+			return LLMResult{Success: false, ToolCalls: transcript}, err
+		}
+		call.Usage.add(openAIResp.Usage.PromptTokens, openAIResp.Usage.CompletionTokens)
+
+		if openAIResp.Error != nil {
+			return LLMResult{
+				Response:  fmt.Sprintf("OpenAI Error: %s", openAIResp.Error.Message),
+				Success:   false,
+				ToolCalls: transcript,
+			}, nil
+		}
+
+		if len(openAIResp.Choices) == 0 {
+			return LLMResult{
+				Response:  "No response from OpenAI",
+				Success:   false,
+				ToolCalls: transcript,
+			}, nil
+		}
+
+		message := openAIResp.Choices[0].Message
+		if len(message.ToolCalls) == 0 || call.Tools == nil {
+			return LLMResult{
+				Response:  strings.TrimSpace(message.Content),
+				Success:   true,
+				ToolCalls: transcript,
+			}, nil
+		}
+		if round >= maxToolRounds {
+			return toolRoundsExceeded(message.Content, transcript), nil
+		}
+
+		reqBody.Messages = append(reqBody.Messages, message)
+		for _, toolCall := range message.ToolCalls {
+			args := map[string]interface{}{}
+			json.Unmarshal([]byte(toolCall.Function.Arguments), &args)
+
+			record := call.Tools.Call(ctx, toolCall.Function.Name, args)
+			transcript = append(transcript, record)
+			reqBody.Messages = append(reqBody.Messages, OpenAIMessage{
+				Role:       "tool",
+				Content:    toolOutputJSON(record),
+				ToolCallID: toolCall.ID,
+			})
+		}
+	}
+}
+
+// toolRoundsExceeded is the failed result for a model that still wants to
+// call tools after maxToolRounds, keeping any text it wrote so far
+func toolRoundsExceeded(text string, transcript []ToolCallRecord) LLMResult {
+	response := fmt.Sprintf("Stopped after %d tool rounds without an answer", maxToolRounds)
+	if text = strings.TrimSpace(text); text != "" {
+		response = text + "\n\n" + response
+	}
+	return LLMResult{Response: response, Success: false, ToolCalls: transcript}
 }
 
 // queryClaude sends a query to Claude API
-func (llm *LLMService) queryClaude(query string) (LLMResult, error) {
+func (llm *LLMService) queryClaude(ctx context.Context, call *llmCall) (LLMResult, error) {
 	url := "https://api.anthropic.com/v1/messages"
+	headers := map[string]string{
+		"x-api-key":         llm.claudeKey,
+		"anthropic-version": "2023-06-01",
+	}
 
 	reqBody := ClaudeRequest{
-		Model: llm.defaultModel[ProviderClaude],
+		Model: call.Model,
 		Messages: []ClaudeMessage{
 			{
 				Role:    "user",
-				Content: query,
+				Content: claudeUserContent(call.Query, call.Images),
 			},
 		},
 		MaxTokens: 4096,
+		System:    call.System,
 	}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return LLMResult{Success: false}, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return LLMResult{Success: false}, fmt.Errorf("failed to create request: %w", err)
+	if call.Tools != nil {
+		for _, tool := range call.Tools.Tools() {
+			reqBody.Tools = append(reqBody.Tools, ClaudeTool{
+				Name:        tool.Name,
+				Description: tool.Description,
+				InputSchema: tool.Parameters,
+			})
+		}
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", llm.claudeKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
+	var transcript []ToolCallRecord
+	for round := 0; ; round++ {
+		var claudeResp ClaudeResponse
+		if err := llm.postJSON(ctx, url, headers, reqBody, &claudeResp); err != nil {
+			return LLMResult{Success: false, ToolCalls: transcript}, err
+		}
+		call.Usage.add(claudeResp.Usage.InputTokens, claudeResp.Usage.OutputTokens)
+
+		if claudeResp.Error != nil {
+			return LLMResult{
+				Response:  fmt.Sprintf("Claude Error: %s", claudeResp.Error.Message),
+				Success:   false,
+				ToolCalls: transcript,
+			}, nil
+		}
 
-	resp, err := llm.httpClient.Do(req)
-	if err != nil {
-		return LLMResult{Success: false}, fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
+		if len(claudeResp.Content) == 0 {
+			return LLMResult{
+				Response:  "No response from Claude",
+				Success:   false,
+				ToolCalls: transcript,
+			}, nil
+		}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return LLMResult{Success: false}, fmt.Errorf("failed to read response: %w", err)
-	}
+		var text []string
+		wantsTools := false
+		for i, block := range claudeResp.Content {
+			switch block.Type {
+			case "text":
+				text = append(text, block.Text)
+			case "tool_use":
+				wantsTools = call.Tools != nil
+				// The block is sent back as history, where input is required
+				if len(block.Input) == 0 {
+					claudeResp.Content[i].Input = json.RawMessage("{}")
+				}
+			}
+		}
 
-	var claudeResp ClaudeResponse
-	if err := json.Unmarshal(body, &claudeResp); err != nil {
-		return LLMResult{Success: false}, fmt.Errorf("failed to parse response: %w", err)
-	}
+		if claudeResp.StopReason != "tool_use" || !wantsTools {
+			return LLMResult{
+				Response:  strings.TrimSpace(strings.Join(text, "\n")),
+				Success:   true,
+				ToolCalls: transcript,
+			}, nil
+		}
+		if round >= maxToolRounds {
+			return toolRoundsExceeded(strings.Join(text, "\n"), transcript), nil
+		}
 
-	if claudeResp.Error != nil {
-		return LLMResult{
-			Response: fmt.Sprintf("Claude Error: %s", claudeResp.Error.Message),
-			Success:  false,
-		}, nil
-	}
+		var toolResults []ClaudeContentBlock
+		for _, block := range claudeResp.Content {
+			if block.Type != "tool_use" {
+				continue
+			}
+			args := map[string]interface{}{}
+			json.Unmarshal(block.Input, &args)
+
+			record := call.Tools.Call(ctx, block.Name, args)
+			transcript = append(transcript, record)
+			toolResults = append(toolResults, ClaudeContentBlock{
+				Type:      "tool_result",
+				ToolUseID: block.ID,
+				Content:   toolOutputJSON(record),
+				IsError:   record.Error != "",
+			})
+		}
 
-	if len(claudeResp.Content) == 0 {
-		return LLMResult{
-			Response: "No response from Claude",
-			Success:  false,
-		}, nil
+		reqBody.Messages = append(reqBody.Messages,
+			ClaudeMessage{Role: "assistant", Content: claudeResp.Content},
+			ClaudeMessage{Role: "user", Content: toolResults},
+		)
 	}
-
-	return LLMResult{
-		Response: strings.TrimSpace(claudeResp.Content[0].Text),
-		Success:  true,
-	}, nil
 }
 
 // queryGemini sends a query to Gemini API
-func (llm *LLMService) queryGemini(query string) (LLMResult, error) {
-	model := llm.defaultModel[ProviderGemini]
+func (llm *LLMService) queryGemini(ctx context.Context, call *llmCall) (LLMResult, error) {
 	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s",
-		model, llm.geminiKey)
+		call.Model, llm.geminiKey)
 
 	reqBody := GeminiRequest{
 		Contents: []GeminiContent{
 			{
-				Parts: []GeminiPart{
-					{Text: query},
-				},
+				Role:  "user",
+				Parts: geminiParts(call.Query, call.Images),
 			},
 		},
+		SystemInstruction: &GeminiContent{Parts: []GeminiPart{{Text: call.System}}},
 	}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return LLMResult{Success: false}, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return LLMResult{Success: false}, fmt.Errorf("failed to create request: %w", err)
+	if call.Tools != nil {
+		var declarations []GeminiFunctionDeclaration
+		for _, tool := range call.Tools.Tools() {
+			declarations = append(declarations, GeminiFunctionDeclaration{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			})
+		}
+		reqBody.Tools = []GeminiTool{{FunctionDeclarations: declarations}}
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	var transcript []ToolCallRecord
+	for round := 0; ; round++ {
+		var geminiResp GeminiResponse
+		if err := llm.postJSON(ctx, url, nil, reqBody, &geminiResp); err != nil {
+			return LLMResult{Success: false, ToolCalls: transcript}, err
+		}
+		call.Usage.add(geminiResp.UsageMetadata.PromptTokenCount, geminiResp.UsageMetadata.CandidatesTokenCount)
+
+		if geminiResp.Error != nil {
+			return LLMResult{
+				Response:  fmt.Sprintf("Gemini Error: %s", geminiResp.Error.Message),
+				Success:   false,
+				ToolCalls: transcript,
+			}, nil
+		}
 
-	resp, err := llm.httpClient.Do(req)
-	if err != nil {
-		return LLMResult{Success: false}, fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
+		if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
+			return LLMResult{
+				Response:  "No response from Gemini",
+				Success:   false,
+				ToolCalls: transcript,
+			}, nil
+		}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return LLMResult{Success: false}, fmt.Errorf("failed to read response: %w", err)
-	}
+		content := geminiResp.Candidates[0].Content
+		var text []string
+		wantsTools := false
+		for _, part := range content.Parts {
+			if part.FunctionCall != nil {
+				wantsTools = call.Tools != nil
+			} else if part.Text != "" {
+				text = append(text, part.Text)
+			}
+		}
 
-	var geminiResp GeminiResponse
-	if err := json.Unmarshal(body, &geminiResp); err != nil {
-		return LLMResult{Success: false}, fmt.Errorf("failed to parse response: %w", err)
-	}
+		if !wantsTools {
+			return LLMResult{
+				Response:  strings.TrimSpace(strings.Join(text, "\n")),
+				Success:   true,
+				ToolCalls: transcript,
+			}, nil
+		}
+		if round >= maxToolRounds {
+			return toolRoundsExceeded(strings.Join(text, "\n"), transcript), nil
+		}
 
-	if geminiResp.Error != nil {
-		return LLMResult{
-			Response: fmt.Sprintf("Gemini Error: %s", geminiResp.Error.Message),
-			Success:  false,
-		}, nil
-	}
+		var responses []GeminiPart
+		for _, part := range content.Parts {
+			if part.FunctionCall == nil {
+				continue
+			}
+			record := call.Tools.Call(ctx, part.FunctionCall.Name, part.FunctionCall.Args)
+			transcript = append(transcript, record)
+
+			// Round-trip through JSON so the response is a plain object
+			var output map[string]interface{}
+			json.Unmarshal([]byte(toolOutputJSON(record)), &output)
+			responses = append(responses, GeminiPart{
+				FunctionResponse: &GeminiFunctionResponse{Name: part.FunctionCall.Name, Response: output},
+			})
+		}
 
-	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
-		return LLMResult{
-			Response: "No response from Gemini",
-			Success:  false,
-		}, nil
+		content.Role = "model"
+		reqBody.Contents = append(reqBody.Contents,
+			content,
+			GeminiContent{Role: "user", Parts: responses},
+		)
 	}
-
-	return LLMResult{
-		Response: strings.TrimSpace(geminiResp.Candidates[0].Content.Parts[0].Text),
-		Success:  true,
-	}, nil
 }
 
 // GetCurrentProvider returns the currently active provider
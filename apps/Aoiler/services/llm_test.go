@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// testLLM answers every request with reply and keeps the request bodies
+func testLLM(reply string) (*LLMService, *[]string) {
+	var requests []string
+	llm := &LLMService{
+		claudeKey: "test",
+		httpClient: &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			requests = append(requests, string(body))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(reply)),
+				Header:     make(http.Header),
+			}, nil
+		})},
+	}
+	return llm, &requests
+}
+
+func testTools(calls *int) *ToolRegistry {
+	return NewToolRegistry(Tool{
+		Name:       "ping",
+		Parameters: objectSchema(nil, map[string]interface{}{}),
+		Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			*calls++
+			return "pong", nil
+		},
+	})
+}
+
+func TestClaudeToolRoundsExceeded(t *testing.T) {
+	llm, requests := testLLM(`{"stop_reason": "tool_use", "content": [
+		{"type": "text", "text": "Checking."},
+		{"type": "tool_use", "id": "toolu_1", "name": "ping", "input": {"again": true}}
+	]}`)
+	calls := 0
+
+	result, err := llm.queryClaude(context.Background(), &llmCall{Model: "claude", Query: "hi", Tools: testTools(&calls)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Success || !strings.Contains(result.Response, "Stopped after 6 tool rounds") {
+		t.Errorf("result = %+v, want a failure naming the round limit", result)
+	}
+	if calls != maxToolRounds || len(result.ToolCalls) != maxToolRounds || len(*requests) != maxToolRounds+1 {
+		t.Errorf("%d tool calls, %d records, %d requests", calls, len(result.ToolCalls), len(*requests))
+	}
+}
+
+func TestClaudeToolUseKeepsInput(t *testing.T) {
+	// The first reply calls a tool without arguments, the second answers
+	replies := []string{
+		`{"stop_reason": "tool_use", "content": [{"type": "tool_use", "id": "toolu_1", "name": "ping"}]}`,
+		`{"stop_reason": "end_turn", "content": [{"type": "text", "text": "pong"}]}`,
+	}
+	llm, requests := testLLM("")
+	llm.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		*requests = append(*requests, string(body))
+		reply := replies[len(*requests)-1]
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(reply)), Header: make(http.Header)}, nil
+	})
+	calls := 0
+
+	result, err := llm.queryClaude(context.Background(), &llmCall{Model: "claude", Query: "hi", Tools: testTools(&calls)})
+	if err != nil || !result.Success || result.Response != "pong" || calls != 1 {
+		t.Fatalf("result = %+v, %v after %d calls", result, err, calls)
+	}
+
+	var followUp ClaudeRequest
+	json.Unmarshal([]byte((*requests)[1]), &followUp)
+	echoed, _ := json.Marshal(followUp.Messages[1].Content)
+	if !strings.Contains(string(echoed), `"input":{}`) {
+		t.Errorf("assistant turn = %s, want the tool_use with an empty input object", echoed)
+	}
+}
+
+func TestOpenAIToolRoundsExceeded(t *testing.T) {
+	llm, requests := testLLM(`{"choices": [{"message": {"role": "assistant", "content": "",
+		"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "ping", "arguments": "{}"}}]}}]}`)
+	llm.openAIKey = "test"
+	calls := 0
+
+	result, err := llm.queryOpenAI(context.Background(), &llmCall{Model: "gpt", Query: "hi", Tools: testTools(&calls)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Success || result.Response != "Stopped after 6 tool rounds without an answer" {
+		t.Errorf("result = %+v, want a failure naming the round limit", result)
+	}
+	if calls != maxToolRounds || len(*requests) != maxToolRounds+1 {
+		t.Errorf("%d tool calls, %d requests", calls, len(*requests))
+	}
+
+	var last struct {
+		Messages []json.RawMessage `json:"messages"`
+	}
+	json.Unmarshal([]byte((*requests)[len(*requests)-1]), &last)
+	// system, user, then an assistant call and a tool result per round
+	if want := 2 + 2*maxToolRounds; len(last.Messages) != want {
+		t.Errorf("last request has %d messages, want %d", len(last.Messages), want)
+	}
+}
@@ -1,8 +1,15 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
+
+	"kaguyadots/runner"
 )
 
 // Intent represents classified user intent
@@ -12,6 +19,25 @@ type Intent struct {
 	Params      map[string]string
 }
 
+// serviceTimeouts bound how long one query may run per service. Interactive
+// captures include the time the user spends selecting a region.
+var serviceTimeouts = map[string]time.Duration{
+	"filesearch": 30 * time.Second,
+	"organizer":  5 * time.Minute,
+	"linter":     2 * time.Minute,
+	"ocr":        2 * time.Minute,
+	"converter":  60 * time.Minute,
+	"clipboard":  15 * time.Second,
+	"launcher":   15 * time.Second,
+	"llm":        3 * time.Minute,
+	"vision":     4 * time.Minute,
+	"text":       3 * time.Minute,
+	"capture":    maxRecordDuration + 2*time.Minute,
+	"usage":      5 * time.Second,
+}
+
+const defaultServiceTimeout = time.Minute
+
 // ServiceManager manages all services
 type ServiceManager struct {
 	fileSearch *FileSearchService
@@ -20,24 +46,220 @@ type ServiceManager struct {
 	ocr        *OCRService
 	converter  *ConverterService
 	llm        *LLMService
+	clipboard  *ClipboardService
+	launcher   *LauncherService
+	safety     *SafetyGuard
+	dotfiles   *DotfilesIndex
+	vision     *VisionService
+	text       *TextService
+	capture    *CaptureService
+	paths      *PathResolver
 }
 
 // NewServiceManager creates a new service manager
 func NewServiceManager() *ServiceManager {
-	return &ServiceManager{
-		fileSearch: NewFileSearchService(),
-		organizer:  NewOrganizerService(),
-		linter:     NewLinterService(),
-		ocr:        NewOCRService(),
-		converter:  NewConverterService(),
+	return NewServiceManagerWithRunner(runner.Default)
+}
+
+// NewServiceManagerWithRunner creates a service manager whose services run
+// external commands through r
+func NewServiceManagerWithRunner(r runner.Runner) *ServiceManager {
+	paths := NewPathResolver()
+	sm := &ServiceManager{
+		fileSearch: NewFileSearchService(paths),
+		organizer:  NewOrganizerService(r, paths),
+		linter:     NewLinterService(r, paths),
+		ocr:        NewOCRService(r),
+		converter:  NewConverterService(r, paths),
 		llm:        NewLLMService(),
+		clipboard:  NewClipboardService(r),
+		launcher:   NewLauncherService(r),
+		safety:     NewSafetyGuard(),
+		dotfiles:   NewDotfilesIndex(),
+		paths:      paths,
+	}
+	sm.vision = NewVisionService(r, sm.llm, sm.fileSearch)
+	sm.text = NewTextService(sm.llm, sm.clipboard, paths)
+	sm.capture = NewCaptureService(r, sm.clipboard, paths)
+	sm.llm.SetTools(sm.buildTools())
+	sm.llm.SetDotfiles(sm.dotfiles)
+	return sm
+}
+
+// Start launches background listeners used by the services; they stop
+// when ctx is cancelled
+func (sm *ServiceManager) Start(ctx context.Context) error {
+	return sm.clipboard.StartWatcher(ctx)
+}
+
+// Clipboard exposes the clipboard history for direct bindings
+func (sm *ServiceManager) Clipboard() *ClipboardService {
+	return sm.clipboard
+}
+
+// LLM exposes the LLM service for direct bindings
+func (sm *ServiceManager) LLM() *LLMService {
+	return sm.llm
+}
+
+// FileSearch exposes the shared file search service
+func (sm *ServiceManager) FileSearch() *FileSearchService {
+	return sm.fileSearch
+}
+
+// GetPathSuggestions completes the path at the end of a partial query using
+// the filter of the service the query is heading for
+func (sm *ServiceManager) GetPathSuggestions(input string) AutoCompleteResult {
+	intent := sm.ClassifyIntent(input)
+
+	expectsPath := false
+	switch intent.ServiceName {
+	case "organizer", "linter", "converter":
+		expectsPath = true
+	}
+
+	result := sm.fileSearch.Suggest(input, expectsPath, filterForService(intent.ServiceName))
+	result.Service = intent.ServiceName
+	return result
+}
+
+// ResolveCaptureReference rewrites "the screenshot" or "convert it" to
+// name the most recent capture so the query routes like one naming the file
+func (sm *ServiceManager) ResolveCaptureReference(query string) string {
+	return replaceCaptureReference(query, sm.capture.Last())
+}
+
+// RecordQueryPaths remembers existing paths used in a query for ranking
+func (sm *ServiceManager) RecordQueryPaths(query string) {
+	for _, path := range sm.paths.Paths(query) {
+		if _, err := os.Stat(path); err == nil {
+			sm.fileSearch.RecordPath(path)
+		}
+	}
+}
+
+// Stop shuts down background listeners
+func (sm *ServiceManager) Stop() {
+	sm.clipboard.StopWatcher()
+}
+
+var visionKeywords = []string{"on screen", "on my screen", "on the screen", "this screenshot", "this image", "this picture"}
+
+// askingWords mark a query as a question for the LLM rather than a
+// command for one service
+var askingWords = map[string]bool{"what": true, "what's": true, "which": true, "why": true, "how": true}
+
+// compoundClauses join a command to a follow-up only the LLM can do
+var compoundClauses = []string{" and tell me", " and show me", " and explain", " then tell me", " and summarize"}
+
+// isCompoundQuery reports questions and requests with a follow-up clause
+func isCompoundQuery(lowerQuery string) bool {
+	if strings.Contains(lowerQuery, "?") {
+		return true
+	}
+	for _, clause := range compoundClauses {
+		if strings.Contains(lowerQuery, clause) {
+			return true
+		}
 	}
+	for _, word := range strings.Fields(lowerQuery) {
+		if askingWords[strings.Trim(word, ",.;:!")] {
+			return true
+		}
+	}
+	return false
+}
+
+// asksAboutImage reports queries about the screen or an image file
+func asksAboutImage(query, lowerQuery string) bool {
+	for _, keyword := range visionKeywords {
+		if strings.Contains(lowerQuery, keyword) {
+			return true
+		}
+	}
+	return mentionsImage(query)
+}
+
+// IntentForQuery classifies a query, except that a query resubmitted with
+// a confirm token keeps the service the confirmation was issued for
+func (sm *ServiceManager) IntentForQuery(query, confirmToken string) Intent {
+	if service, ok := sm.safety.PendingService(confirmToken); ok {
+		return Intent{ServiceName: service, Confidence: 1, Params: map[string]string{"query": query}}
+	}
+	return sm.ClassifyIntent(query)
+}
+
+// organizeMode is the Tyr mode a query asks for, "" when it names none
+func organizeMode(lowerQuery string) string {
+	if strings.Contains(lowerQuery, "category") || strings.Contains(lowerQuery, "type") {
+		return "category"
+	}
+	if strings.Contains(lowerQuery, "filename") || strings.Contains(lowerQuery, "name") {
+		return "filename"
+	}
+	return ""
 }
 
 // ClassifyIntent uses keyword matching to determine intent
 func (sm *ServiceManager) ClassifyIntent(query string) Intent {
 	lowerQuery := strings.ToLower(query)
 
+	// Text transforms come first: "summarize clipboard" and "convert this
+	// json to yaml" would otherwise go to the clipboard and converter
+	if task, ok := parseTextTask(query); ok {
+		return Intent{
+			ServiceName: "text",
+			Confidence:  0.85,
+			Params:      map[string]string{"task": task.Name},
+		}
+	}
+
+	// Captures start with their verb, so "screenshot window firefox and
+	// copy" isn't taken for a clipboard query
+	if request, ok := parseCaptureRequest(query); ok {
+		return Intent{
+			ServiceName: "capture",
+			Confidence:  0.9,
+			Params:      map[string]string{"kind": request.Kind, "mode": request.Mode},
+		}
+	}
+
+	// Clipboard patterns
+	clipboardKeywords := []string{"clipboard", "what did i copy", "copied"}
+	for _, keyword := range clipboardKeywords {
+		if strings.Contains(lowerQuery, keyword) {
+			return Intent{
+				ServiceName: "clipboard",
+				Confidence:  0.9,
+				Params:      map[string]string{"query": query},
+			}
+		}
+	}
+
+	// LLM usage patterns
+	usageKeywords := []string{"token usage", "llm usage", "api usage", "llm cost", "api cost", "llm budget", "how much have i spent"}
+	for _, keyword := range usageKeywords {
+		if strings.Contains(lowerQuery, keyword) {
+			return Intent{
+				ServiceName: "usage",
+				Confidence:  0.9,
+				Params:      map[string]string{},
+			}
+		}
+	}
+
+	// Questions and multi-step requests ("find my waybar config and tell me
+	// which modules are on the left") need the LLM and its tools; the
+	// keyword checks below would hand them to a single service. Questions
+	// about an image still go to vision.
+	if isCompoundQuery(lowerQuery) && !asksAboutImage(query, lowerQuery) {
+		return Intent{
+			ServiceName: "llm",
+			Confidence:  0.7,
+			Params:      map[string]string{"query": query},
+		}
+	}
+
 	// File search patterns
 	fileSearchKeywords := []string{"find", "where is", "locate", "search for", "look for"}
 	for _, keyword := range fileSearchKeywords {
@@ -55,10 +277,8 @@ func (sm *ServiceManager) ClassifyIntent(query string) Intent {
 	for _, keyword := range organizerKeywords {
 		if strings.Contains(lowerQuery, keyword) {
 			params := make(map[string]string)
-			if strings.Contains(lowerQuery, "category") || strings.Contains(lowerQuery, "type") {
-				params["mode"] = "category"
-			} else if strings.Contains(lowerQuery, "filename") || strings.Contains(lowerQuery, "name") {
-				params["mode"] = "filename"
+			if mode := organizeMode(lowerQuery); mode != "" {
+				params["mode"] = mode
 			}
 			return Intent{
 				ServiceName: "organizer",
@@ -104,6 +324,36 @@ func (sm *ServiceManager) ClassifyIntent(query string) Intent {
 		}
 	}
 
+	// Launcher patterns
+	launcherPrefixes := []string{"open ", "launch ", "start ", "run "}
+	for _, prefix := range launcherPrefixes {
+		if strings.HasPrefix(lowerQuery, prefix) {
+			return Intent{
+				ServiceName: "launcher",
+				Confidence:  0.8,
+				Params:      map[string]string{"query": query},
+			}
+		}
+	}
+
+	// Vision patterns: questions about the screen or an image file
+	for _, keyword := range visionKeywords {
+		if strings.Contains(lowerQuery, keyword) {
+			return Intent{
+				ServiceName: "vision",
+				Confidence:  0.8,
+				Params:      map[string]string{"query": query},
+			}
+		}
+	}
+	if mentionsImage(query) {
+		return Intent{
+			ServiceName: "vision",
+			Confidence:  0.7,
+			Params:      map[string]string{"query": query},
+		}
+	}
+
 	// Default to LLM for everything else
 	return Intent{
 		ServiceName: "llm",
@@ -112,26 +362,122 @@ func (sm *ServiceManager) ClassifyIntent(query string) Intent {
 	}
 }
 
+// Execute runs a query through the safety layer. Mutating actions without a
+// valid confirm token return a *ConfirmationRequest instead of running.
+func (sm *ServiceManager) Execute(ctx context.Context, intent Intent, query string, opts QueryOptions) (interface{}, error) {
+	timeout, ok := serviceTimeouts[intent.ServiceName]
+	if !ok {
+		timeout = defaultServiceTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result, err := sm.execute(ctx, intent, query, opts)
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		err = fmt.Errorf("%s timed out after %s", intent.ServiceName, timeout)
+	case errors.Is(ctx.Err(), context.Canceled):
+		err = fmt.Errorf("%s cancelled: %w", intent.ServiceName, context.Canceled)
+	}
+	return result, err
+}
+
+func (sm *ServiceManager) execute(ctx context.Context, intent Intent, query string, opts QueryOptions) (interface{}, error) {
+	plan := sm.PlanAction(intent, query)
+	if !plan.Mutating {
+		result, err := sm.RouteToService(ctx, intent, query, opts)
+		// Captures only add a new file, so they run unconfirmed; the file
+		// they report is journaled so undo can remove it
+		if capture, ok := result.(CaptureResult); ok && capture.Path != "" {
+			sm.safety.RecordCreated(intent.ServiceName, query, capture.Path)
+		}
+		return result, err
+	}
+
+	if !sm.safety.Confirm(opts.ConfirmToken, plan, query) {
+		request := sm.safety.RequestConfirmation(plan, query)
+		return &request, nil
+	}
+
+	snap, err := sm.safety.Snapshot(plan, query)
+	if err != nil {
+		return nil, fmt.Errorf("refusing to run without a backup: %w", err)
+	}
+
+	result, err := sm.RouteToService(ctx, intent, query, opts)
+	// Commit even on failure: formatters and tyr can change files before erroring
+	if commitErr := sm.safety.Commit(snap); commitErr != nil && err == nil {
+		err = fmt.Errorf("action ran but could not be journaled for undo: %w", commitErr)
+	}
+	return result, err
+}
+
+// UndoLastAction reverts the most recent mutating action
+func (sm *ServiceManager) UndoLastAction() (UndoResult, error) {
+	return sm.safety.UndoLast()
+}
+
 // RouteToService routes the query to appropriate service
-func (sm *ServiceManager) RouteToService(intent Intent, query string) (interface{}, error) {
+func (sm *ServiceManager) RouteToService(ctx context.Context, intent Intent, query string, opts QueryOptions) (interface{}, error) {
 	switch intent.ServiceName {
 	case "filesearch":
-		return sm.fileSearch.Search(query)
+		return sm.fileSearch.Search(ctx, query)
 	case "organizer":
+		// A confirmed query comes back without the classified params
 		mode := intent.Params["mode"]
+		if mode == "" {
+			mode = organizeMode(strings.ToLower(query))
+		}
 		if mode == "" {
 			mode = "category"
 		}
-		return sm.organizer.Organize(query, mode)
+		return sm.organizer.Organize(ctx, query, mode)
 	case "linter":
-		return sm.linter.LintFormat(query)
+		return sm.linter.LintFormat(ctx, query)
 	case "ocr":
-		return sm.ocr.ExtractText()
+		var result OCRResult
+		var err error
+		if image := sm.imagePath(query); image != "" {
+			result, err = sm.ocr.ExtractTextFromFile(ctx, image)
+		} else {
+			result, err = sm.ocr.ExtractText(ctx)
+		}
+		if err == nil && result.Text != "" {
+			// OCR output lands in the clipboard history like any other copy
+			sm.clipboard.AddText(result.Text, "ocr")
+			sm.text.RememberOCR(result.Text)
+		}
+		return result, err
 	case "converter":
-		return sm.converter.Convert(query)
+		return sm.converter.Convert(ctx, query)
+	case "clipboard":
+		return sm.clipboard.HandleQuery(ctx, query)
+	case "launcher":
+		return sm.launcher.Launch(ctx, query)
 	case "llm":
-		return sm.llm.Query(query)
+		return sm.llm.Query(ctx, query, opts)
+	case "vision":
+		return sm.vision.Ask(ctx, query, opts)
+	case "text":
+		return sm.text.Transform(ctx, query, opts)
+	case "capture":
+		return sm.capture.Capture(ctx, query)
+	case "usage":
+		return sm.llm.UsageSummary(), nil
 	default:
 		return nil, fmt.Errorf("unknown service: %s", intent.ServiceName)
 	}
 }
+
+// imagePath returns the first existing image file named in a query
+func (sm *ServiceManager) imagePath(query string) string {
+	for _, path := range sm.paths.Paths(query) {
+		if !imageExtensions[strings.ToLower(filepath.Ext(path))] {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
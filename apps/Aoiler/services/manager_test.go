@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"kaguyadots/runner"
+)
+
+func TestClassifyIntent(t *testing.T) {
+	sm := &ServiceManager{}
+
+	tests := []struct {
+		query   string
+		service string
+		mode    string
+	}{
+		{"what did i copy earlier", "clipboard", ""},
+		{"find what I copied", "clipboard", ""},
+		{"show my token usage", "usage", ""},
+		{"where is my waybar config", "filesearch", ""},
+		{"find hyprland.conf", "filesearch", ""},
+		{"organize ~/Downloads by type", "organizer", "category"},
+		{"sort ~/Pictures by name", "organizer", "filename"},
+		{"clean ~/Downloads", "organizer", ""},
+		{"lint ./main.go", "linter", ""},
+		{"format ~/scripts/backup.sh", "linter", ""},
+		{"extract text from the screen", "ocr", ""},
+		{"ocr", "ocr", ""},
+		{"convert ~/Videos/clip.mkv to mp4", "converter", ""},
+		{"open firefox", "launcher", ""},
+		{"Launch kitty", "launcher", ""},
+		{"what is on my screen", "vision", ""},
+		{"describe ~/Pictures/cat.png", "vision", ""},
+		{"screenshot region and copy to clipboard", "capture", "region"},
+		{"screenshot window firefox", "capture", "window"},
+		{"record screen 30s", "capture", "screen"},
+		{"screenshot text", "ocr", ""},
+		{"summarize clipboard", "text", ""},
+		{"convert this json to yaml", "text", ""},
+		{"translate this to Japanese", "text", ""},
+		{"what is base64", "llm", ""},
+		{"what is a monad", "llm", ""},
+		{"firefox open", "llm", ""},
+		{"Find my waybar config and tell me which modules are on the left", "llm", ""},
+		{"which file sets my hyprland keybinds", "llm", ""},
+		{"where is my kitty config?", "llm", ""},
+		{"what's the flag to follow symlinks in find", "llm", ""},
+		{"how do I sort files by size", "llm", ""},
+		{"show the clipboard", "clipboard", ""},
+		{"what is in ~/Pictures/cat.png", "vision", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			intent := sm.ClassifyIntent(tt.query)
+			if intent.ServiceName != tt.service {
+				t.Errorf("ClassifyIntent(%q) = %q, want %q", tt.query, intent.ServiceName, tt.service)
+			}
+			if got := intent.Params["mode"]; got != tt.mode {
+				t.Errorf("ClassifyIntent(%q) mode = %q, want %q", tt.query, got, tt.mode)
+			}
+		})
+	}
+}
+
+func TestToolConfirmationKeepsService(t *testing.T) {
+	paths, _, work := testPaths(t, "sort/find/tidy.py")
+	sm := &ServiceManager{paths: paths, safety: NewSafetyGuard()}
+
+	// The path's words would classify as organizer or file search
+	path := filepath.Join(work, "sort", "find", "tidy.py")
+	result, err := sm.requestToolConfirmation("linter", "format "+escapePath(path, 0, false))
+	if err != nil {
+		t.Fatalf("requestToolConfirmation: %v", err)
+	}
+	request := result.(map[string]interface{})["confirmation"].(ConfirmationRequest)
+	if request.Plan.Service != "linter" || !reflect.DeepEqual(request.Plan.Modifies, []string{path}) {
+		t.Fatalf("plan = %+v, want the linter on %s", request.Plan, path)
+	}
+
+	// Resubmitting with the token runs the confirmed service
+	intent := sm.IntentForQuery(request.Query, request.Token)
+	if intent.ServiceName != "linter" {
+		t.Errorf("confirmed intent = %q, want linter", intent.ServiceName)
+	}
+	if !sm.safety.Confirm(request.Token, sm.PlanAction(intent, request.Query), request.Query) {
+		t.Error("token does not confirm the resubmitted plan")
+	}
+	if intent := sm.IntentForQuery(request.Query, "bogus"); intent.ServiceName == "linter" {
+		t.Errorf("unknown token kept the linter")
+	}
+}
+
+func TestOrganizeToolOnlyPlans(t *testing.T) {
+	paths, _, work := testPaths(t, "Downloads/photo.png", "Downloads/notes.pdf")
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	fake := runner.NewFake()
+	sm := &ServiceManager{
+		paths:      paths,
+		safety:     NewSafetyGuard(),
+		fileSearch: NewFileSearchService(paths),
+		organizer:  NewOrganizerService(fake, paths),
+	}
+
+	downloads := filepath.Join(work, "Downloads")
+	record := sm.buildTools().Call(context.Background(), "organize_files", map[string]interface{}{"path": "Downloads", "mode": "filename"})
+	if record.Error != "" {
+		t.Fatalf("organize_files: %s", record.Error)
+	}
+	result := record.Result.(map[string]interface{})
+	request := result["confirmation"].(ConfirmationRequest)
+	if result["status"] != "needs_confirmation" || !reflect.DeepEqual(request.Plan.Reorganizes, []string{downloads}) {
+		t.Fatalf("result = %+v, want a confirmation to organize %s", result, downloads)
+	}
+
+	// Nothing ran and nothing moved
+	if calls := fake.Commands(); len(calls) != 0 {
+		t.Errorf("commands = %v, want none", calls)
+	}
+	entries, _ := os.ReadDir(downloads)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"notes.pdf", "photo.png"}) {
+		t.Errorf("Downloads = %v, want it untouched", names)
+	}
+
+	// The confirmed query runs Tyr in the mode the tool asked for
+	intent := sm.IntentForQuery(request.Query, request.Token)
+	if _, err := sm.RouteToService(context.Background(), intent, request.Query, QueryOptions{}); err != nil {
+		t.Fatalf("RouteToService: %v", err)
+	}
+	if calls := fake.Commands(); len(calls) != 1 || calls[0] != "tyr -f -nui "+downloads {
+		t.Errorf("commands = %v, want tyr -f on %s", calls, downloads)
+	}
+}
+
+func TestOCRToolOnlyReadsNamedImages(t *testing.T) {
+	paths, home, work := testPaths(t, "cat.png", "receipt.png")
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	fake := runner.NewFake().On("tesseract", "meow", nil)
+	sm := &ServiceManager{
+		paths:      paths,
+		fileSearch: NewFileSearchService(paths),
+		dotfiles:   NewDotfilesIndexAt(filepath.Join(home, ".config")),
+		ocr:        NewOCRService(fake),
+	}
+	tools := sm.buildTools()
+	ctx := withToolQuery(context.Background(), "what does cat.png say")
+
+	record := tools.Call(ctx, "ocr_image", map[string]interface{}{"path": filepath.Join(work, "cat.png")})
+	if record.Error != "" || record.Result.(OCRResult).Text != "meow" {
+		t.Errorf("named image = %+v", record)
+	}
+
+	fake.Reset()
+	for _, path := range []string{filepath.Join(work, "receipt.png"), "~/.ssh/id_ed25519"} {
+		if record := tools.Call(ctx, "ocr_image", map[string]interface{}{"path": path}); record.Error == "" {
+			t.Errorf("ocr_image(%s) = %+v, want it refused", path, record)
+		}
+	}
+	if calls := fake.Commands(); len(calls) != 0 {
+		t.Errorf("commands = %v, want none", calls)
+	}
+}
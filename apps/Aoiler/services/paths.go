@@ -0,0 +1,128 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// PathResolver finds the paths written in a query and resolves them against
+// a configurable base directory. Services share one resolver so "lint
+// main.go" and the autocomplete popup agree on where main.go lives.
+type PathResolver struct {
+	mu      sync.Mutex
+	baseDir string
+}
+
+func NewPathResolver() *PathResolver {
+	return &PathResolver{}
+}
+
+// BaseDir is the directory relative paths are resolved against, the home
+// directory unless changed
+func (pr *PathResolver) BaseDir() string {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	if pr.baseDir != "" {
+		return pr.baseDir
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		cwd, _ := os.Getwd()
+		return cwd
+	}
+	return homeDir
+}
+
+// SetBaseDir changes the directory relative paths are resolved against
+func (pr *PathResolver) SetBaseDir(dir string) error {
+	resolved := pr.Resolve(dir)
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &os.PathError{Op: "chdir", Path: resolved, Err: os.ErrInvalid}
+	}
+
+	pr.mu.Lock()
+	pr.baseDir = resolved
+	pr.mu.Unlock()
+	return nil
+}
+
+// Resolve expands ~ and $HOME and anchors relative paths at the base directory
+func (pr *PathResolver) Resolve(path string) string {
+	homeDir, _ := os.UserHomeDir()
+
+	switch {
+	case path == "~" || path == "$HOME":
+		return homeDir
+	case strings.HasPrefix(path, "~/"):
+		return filepath.Join(homeDir, path[2:])
+	case strings.HasPrefix(path, "$HOME/"):
+		return filepath.Join(homeDir, path[6:])
+	case path == "":
+		return pr.BaseDir()
+	case filepath.IsAbs(path):
+		return filepath.Clean(path)
+	default:
+		return filepath.Join(pr.BaseDir(), path)
+	}
+}
+
+// Paths returns every path named in a query, resolved, in the order written.
+// Quoted words and words written as paths (~, /, ./) count, URLs do not; a
+// bare word counts when it has a file extension or exists in the base
+// directory.
+func (pr *PathResolver) Paths(query string) []string {
+	var paths []string
+	for _, tok := range tokenizeQuery(query) {
+		value := tok.Value
+		if tok.Quote == 0 {
+			// Sentence punctuation, as in "lint main.go, please"
+			value = strings.TrimRight(value, ".,;:!?")
+			if value == "" {
+				value = tok.Value
+			}
+		}
+		if value == "" || strings.Contains(value, "://") {
+			continue
+		}
+
+		if tok.Quote != 0 || looksLikePath(value) || hasFileExtension(value) {
+			paths = append(paths, pr.Resolve(value))
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(pr.BaseDir(), value)); err == nil {
+			paths = append(paths, pr.Resolve(value))
+		}
+	}
+	return paths
+}
+
+// Path returns the first path named in a query, or "" when there is none
+func (pr *PathResolver) Path(query string) string {
+	if paths := pr.Paths(query); len(paths) > 0 {
+		return paths[0]
+	}
+	return ""
+}
+
+// hasFileExtension reports whether a bare word reads as a file name like
+// main.go rather than a version number like 1.5
+func hasFileExtension(word string) bool {
+	ext := filepath.Ext(word)
+	if len(ext) < 2 || len(ext) == len(word) {
+		return false
+	}
+	for _, r := range ext[1:] {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"kaguyadots/runner"
+)
+
+// testPaths points HOME at a temp dir and returns a resolver based in a
+// "work" directory inside it holding the given files
+func testPaths(t *testing.T, files ...string) (*PathResolver, string, string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	work := filepath.Join(home, "work")
+	for _, name := range append([]string{"."}, files...) {
+		path := filepath.Join(work, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if name != "." {
+			if err := os.WriteFile(path, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(work, "Downloads"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := NewPathResolver()
+	if err := paths.SetBaseDir(work); err != nil {
+		t.Fatal(err)
+	}
+	return paths, home, work
+}
+
+func TestPathResolverPaths(t *testing.T) {
+	paths, home, work := testPaths(t)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"lint ./main.go", []string{filepath.Join(work, "main.go")}},
+		{"lint main.go, please", []string{filepath.Join(work, "main.go")}},
+		{`convert "~/Videos/My Clip.webm" to mp4`, []string{filepath.Join(home, "Videos", "My Clip.webm")}},
+		{`convert ~/Videos/My\ Clip.webm to mp4`, []string{filepath.Join(home, "Videos", "My Clip.webm")}},
+		{"format $HOME/scripts/backup.sh", []string{filepath.Join(home, "scripts", "backup.sh")}},
+		{"organize ~", []string{home}},
+		{"clean Downloads by type", []string{filepath.Join(work, "Downloads")}},
+		{"format .bashrc", []string{filepath.Join(work, ".bashrc")}},
+		{"convert clip.mkv to ~/out/clip.mp4", []string{filepath.Join(work, "clip.mkv"), filepath.Join(home, "out", "clip.mp4")}},
+		{"convert /tmp/a.png to /tmp/b.jpg", []string{"/tmp/a.png", "/tmp/b.jpg"}},
+		{"open https://example.com in version 1.5", nil},
+		{"what is a monad", nil},
+	}
+
+	for _, tt := range tests {
+		if got := paths.Paths(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Paths(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestLintFormatExpandsHome(t *testing.T) {
+	paths, home, _ := testPaths(t)
+	script := filepath.Join(home, "My Scripts", "backup.sh")
+	if err := os.MkdirAll(filepath.Dir(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(script, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := runner.NewFake()
+	result, err := NewLinterService(fake, paths).LintFormat(context.Background(), `format "~/My Scripts/backup.sh"`)
+	if err != nil {
+		t.Fatalf("LintFormat: %v", err)
+	}
+	if result.FilePath != script {
+		t.Errorf("FilePath = %q, want %q", result.FilePath, script)
+	}
+}
+
+func TestConverterPlan(t *testing.T) {
+	paths, home, work := testPaths(t, "clip.mkv", "clip.mp4")
+	converter := NewConverterService(runner.NewFake(), paths)
+
+	tests := []struct {
+		query   string
+		want    Conversion
+		wantErr bool
+	}{
+		{"convert clip.mkv to webm", Conversion{filepath.Join(work, "clip.mkv"), filepath.Join(work, "clip.webm"), "webm"}, false},
+		{"convert clip.mkv to mp4", Conversion{filepath.Join(work, "clip.mkv"), filepath.Join(work, "clip_converted.mp4"), "mp4"}, false},
+		{"convert clip.mkv to ~/Music/clip.mp3", Conversion{filepath.Join(work, "clip.mkv"), filepath.Join(home, "Music", "clip.mp3"), "mp3"}, false},
+		{"convert clip.mkv to something", Conversion{}, true},
+		{"convert it to mp4", Conversion{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := converter.Plan(tt.query)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Plan(%q) = %+v, %v; want %+v", tt.query, got, err, tt.want)
+		}
+	}
+}
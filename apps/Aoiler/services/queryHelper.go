@@ -70,6 +70,9 @@ func (h *HelpService) GetCategories() []string {
 		"Code Tools",
 		"OCR & Text",
 		"Media Conversion",
+		"Clipboard",
+		"Capture",
+		"Apps",
 		"General",
 	}
 }
@@ -135,19 +138,19 @@ func (h *HelpService) getAllSuggestions() []QuerySuggestion {
 
 		// Organization
 		{
-			Query:       "organize [path]",
+			Query:       "organize <path>",
 			Description: "Organize files by category (default)",
 			Category:    "Organization",
 			Examples:    []string{"organize ~/Downloads", "organize .", "tyr ~/Desktop"},
 		},
 		{
-			Query:       "organize [path] by name",
+			Query:       "organize <path> by name",
 			Description: "Organize files alphabetically by filename",
 			Category:    "Organization",
 			Examples:    []string{"organize ~/Pictures by filename", "sort ~/Documents by name"},
 		},
 		{
-			Query:       "clean up [path]",
+			Query:       "clean up <path>",
 			Description: "Tidy up a directory",
 			Category:    "Organization",
 			Examples:    []string{"clean up ~/Downloads", "tidy ~/workspace"},
@@ -180,12 +183,68 @@ func (h *HelpService) getAllSuggestions() []QuerySuggestion {
 			Category:    "OCR & Text",
 			Examples:    []string{"ocr screenshot.png", "extract text from photo.jpg"},
 		},
+		{
+			Query:       "explain this error on screen",
+			Description: "Select a screen region and ask the LLM about it",
+			Category:    "OCR & Text",
+			Examples:    []string{"explain this error on screen", "what is this image on my screen"},
+		},
+		{
+			Query:       "[question] [image]",
+			Description: "Ask the LLM about an image file",
+			Category:    "OCR & Text",
+			Examples:    []string{"what does ~/Pictures/diagram.png show", "describe ./mockup.jpg"},
+		},
 		{
 			Query:       "read text from screen",
 			Description: "Screenshot selection and OCR",
 			Category:    "OCR & Text",
 			Examples:    []string{"capture text", "screenshot text"},
 		},
+		{
+			Query:       "translate this to [language]",
+			Description: "Translate the clipboard, OCR text or a file with the LLM",
+			Category:    "OCR & Text",
+			Examples:    []string{"translate this to Japanese", "translate the ocr text"},
+		},
+		{
+			Query:       "summarize clipboard",
+			Description: "Summarize, proofread or rephrase text with the LLM",
+			Category:    "OCR & Text",
+			Examples:    []string{"summarize clipboard", "fix grammar", "rephrase this"},
+		},
+		{
+			Query:       "convert this json to yaml",
+			Description: "Convert between JSON, YAML and TOML locally",
+			Category:    "OCR & Text",
+			Examples:    []string{"convert this json to yaml", "convert config.toml to json"},
+		},
+		{
+			Query:       "base64 encode clipboard",
+			Description: "Encode, decode, hash or change case locally",
+			Category:    "OCR & Text",
+			Examples:    []string{"base64 decode clipboard", "sha256 of notes.txt", "snake case this and copy"},
+		},
+
+		// Capture
+		{
+			Query:       "screenshot [region|window|screen]",
+			Description: "Save a screenshot, optionally annotated and copied",
+			Category:    "Capture",
+			Examples:    []string{"screenshot region", "screenshot window firefox", "screenshot screen in 3s and annotate"},
+		},
+		{
+			Query:       "record [region|window|screen] [duration]",
+			Description: "Record the screen with wf-recorder",
+			Category:    "Capture",
+			Examples:    []string{"record screen 30s", "record region for 2 minutes with audio"},
+		},
+		{
+			Query:       "ocr the screenshot",
+			Description: "Run OCR, vision or a conversion on the last capture",
+			Category:    "Capture",
+			Examples:    []string{"ocr the screenshot", "describe the last screenshot", "convert the recording to gif"},
+		},
 
 		// Media Conversion
 		{
@@ -207,7 +266,41 @@ func (h *HelpService) getAllSuggestions() []QuerySuggestion {
 			Examples:    []string{"change format image.png to jpg"},
 		},
 
+		// Clipboard
+		{
+			Query:       "clipboard",
+			Description: "Show recent clipboard history",
+			Category:    "Clipboard",
+			Examples:    []string{"clipboard", "clipboard history"},
+		},
+		{
+			Query:       "what did I copy about [term]",
+			Description: "Search clipboard history",
+			Category:    "Clipboard",
+			Examples:    []string{"what did I copy about docker", "search clipboard for ssh"},
+		},
+		{
+			Query:       "copy clipboard [id]",
+			Description: "Pin, delete or re-copy a history entry",
+			Category:    "Clipboard",
+			Examples:    []string{"copy clipboard 12", "pin clipboard 4", "delete clipboard 7"},
+		},
+
+		// Apps
+		{
+			Query:       "open [app]",
+			Description: "Launch an application or quickapp",
+			Category:    "Apps",
+			Examples:    []string{"open zed", "launch browser", "start terminal"},
+		},
+
 		// General
+		{
+			Query:       "llm usage",
+			Description: "Show this month's LLM tokens, cost and budget",
+			Category:    "General",
+			Examples:    []string{"llm usage", "how much have I spent", "llm budget"},
+		},
 		{
 			Query:       "help",
 			Description: "Show available commands and examples",
@@ -226,9 +319,9 @@ func (h *HelpService) GetQuickHelp() string {
   • where is [config] - Locate configuration files
 
 🗂️  Organization
-  • organize [path] - Organize files by category
-  • organize [path] by name - Sort alphabetically
-  • clean up [path] - Tidy up directory
+  • organize <path> - Organize files by category
+  • organize <path> by name - Sort alphabetically
+  • clean up <path> - Tidy up directory
 
 💻 Code Tools
   • format [file] - Auto-format code (Python/Go/JS/Shell)
@@ -237,11 +330,28 @@ func (h *HelpService) GetQuickHelp() string {
 📸 OCR & Text
   • ocr - Screenshot and extract text
   • extract text from [image] - OCR from image file
+  • explain this error on screen - Ask the LLM about a screen region
+  • [question] [image] - Ask the LLM about an image file
+  • translate/summarize/fix grammar - LLM text tasks on clipboard or OCR
+  • convert this json to yaml, base64, sha256, snake case - Local transforms
+
+📷 Capture
+  • screenshot [region|window name|screen] - Save a screenshot
+  • record screen 30s - Record the screen
+  • ocr/describe/convert the screenshot - Chain on the last capture
 
 🎬 Media Conversion
   • convert [file] to [format] - Convert media files
   • Supports: mp4, webm, mp3, wav, png, jpg, etc.
 
+📋 Clipboard
+  • clipboard - Show recent history
+  • what did I copy about [term] - Search history
+  • pin/delete/copy clipboard [id] - Manage entries
+
+🚀 Apps
+  • open [app] - Launch desktop apps and quickapps
+
 💡 Tips:
   • Tab/arrow keys for autocomplete on file paths
   • Most commands support ~ for home directory
@@ -296,6 +406,20 @@ func (h *HelpService) GetExamplesByCategory() []ExampleQueries {
 				"extract text from screenshot.png",
 				"read screen",
 				"capture text",
+				"explain this error on screen",
+				"describe ~/Pictures/mockup.png",
+				"translate this to Japanese",
+				"convert this json to yaml",
+			},
+		},
+		{
+			Category: "Capture",
+			Icon:     "📷",
+			Queries: []string{
+				"screenshot region",
+				"screenshot window firefox",
+				"record screen 30s",
+				"ocr the screenshot",
 			},
 		},
 		{
@@ -308,6 +432,16 @@ func (h *HelpService) GetExamplesByCategory() []ExampleQueries {
 				"change format image.png to jpg",
 			},
 		},
+		{
+			Category: "Clipboard",
+			Icon:     "📋",
+			Queries: []string{
+				"clipboard",
+				"what did I copy about docker",
+				"pin clipboard 4",
+				"copy clipboard 12",
+			},
+		},
 	}
 }
 
@@ -0,0 +1,490 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ActionPlan describes what a routed query is about to do
+type ActionPlan struct {
+	Service     string   `json:"service"`
+	Mutating    bool     `json:"mutating"`
+	Description string   `json:"description"`
+	Modifies    []string `json:"modifies,omitempty"` // files rewritten in place
+	Reorganizes []string `json:"reorganizes,omitempty"`
+	Creates     []string `json:"creates,omitempty"` // files written, possibly over existing ones
+	// Irreversible actions change state outside the filesystem, so no
+	// backup is taken and undo can't revert them
+	Irreversible bool `json:"irreversible,omitempty"`
+}
+
+// ConfirmationRequest is returned instead of a result when a mutating
+// action needs the user's go-ahead. Resubmit the query with Token to run it.
+type ConfirmationRequest struct {
+	Token     string     `json:"token"`
+	Query     string     `json:"query"` // resubmit this with the token to run the action
+	Plan      ActionPlan `json:"plan"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+}
+
+// UndoResult reports what an undo restored
+type UndoResult struct {
+	ActionID string   `json:"actionId"`
+	Service  string   `json:"service"`
+	Query    string   `json:"query"`
+	Restored []string `json:"restored"`
+	Removed  []string `json:"removed"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+type fileMove struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type fileBackup struct {
+	Original string `json:"original"`
+	Backup   string `json:"backup"`
+}
+
+// actionRecord is a journal entry with everything needed to undo an action
+type actionRecord struct {
+	ID          string       `json:"id"`
+	Service     string       `json:"service"`
+	Query       string       `json:"query"`
+	Time        time.Time    `json:"time"`
+	BackupDir   string       `json:"backupDir"`
+	Backups     []fileBackup `json:"backups,omitempty"`
+	Moves       []fileMove   `json:"moves,omitempty"`
+	Created     []string     `json:"created,omitempty"`
+	CreatedDirs []string     `json:"createdDirs,omitempty"`
+}
+
+// snapshot is the pre-action state captured for a single action
+type snapshot struct {
+	record  actionRecord
+	plan    ActionPlan
+	existed map[string]bool
+	trees   map[string]map[uint64]string // root -> inode -> path
+	dirs    map[string]bool
+}
+
+type pendingConfirmation struct {
+	key       string
+	service   string
+	expiresAt time.Time
+}
+
+// SafetyGuard gates mutating actions behind confirmation and keeps an undo journal
+type SafetyGuard struct {
+	mu         sync.Mutex
+	dir        string
+	pending    map[string]pendingConfirmation
+	maxJournal int
+	maxFiles   int
+	tokenTTL   time.Duration
+}
+
+func NewSafetyGuard() *SafetyGuard {
+	return &SafetyGuard{
+		dir:        dataDir("backups"),
+		pending:    make(map[string]pendingConfirmation),
+		maxJournal: 20,
+		maxFiles:   20000,
+		tokenTTL:   2 * time.Minute,
+	}
+}
+
+// RequestConfirmation issues a one-time token bound to this exact plan
+func (g *SafetyGuard) RequestConfirmation(plan ActionPlan, query string) ConfirmationRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	token := hex.EncodeToString(buf)
+	expires := time.Now().Add(g.tokenTTL)
+
+	for t, p := range g.pending {
+		if time.Now().After(p.expiresAt) {
+			delete(g.pending, t)
+		}
+	}
+	g.pending[token] = pendingConfirmation{key: confirmationKey(plan, query), service: plan.Service, expiresAt: expires}
+
+	return ConfirmationRequest{Token: token, Query: query, Plan: plan, ExpiresAt: expires}
+}
+
+// PendingService returns the service a live token was issued for, so a
+// resubmitted query runs through the service that was confirmed rather
+// than whatever its words classify as
+func (g *SafetyGuard) PendingService(token string) (string, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	pending, ok := g.pending[token]
+	if !ok || time.Now().After(pending.expiresAt) {
+		return "", false
+	}
+	return pending.service, true
+}
+
+// Confirm consumes a token, reporting whether it authorises this plan
+func (g *SafetyGuard) Confirm(token string, plan ActionPlan, query string) bool {
+	if token == "" {
+		return false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	pending, ok := g.pending[token]
+	delete(g.pending, token)
+	return ok && time.Now().Before(pending.expiresAt) && pending.key == confirmationKey(plan, query)
+}
+
+func confirmationKey(plan ActionPlan, query string) string {
+	return plan.Service + "\x00" + query
+}
+
+// Snapshot backs up everything the plan may touch before it runs
+func (g *SafetyGuard) Snapshot(plan ActionPlan, query string) (*snapshot, error) {
+	id := time.Now().Format("20060102-150405.000000")
+	snap := &snapshot{
+		record: actionRecord{
+			ID:        id,
+			Service:   plan.Service,
+			Query:     query,
+			Time:      time.Now(),
+			BackupDir: filepath.Join(g.dir, id),
+		},
+		plan:    plan,
+		existed: make(map[string]bool),
+		trees:   make(map[string]map[uint64]string),
+		dirs:    make(map[string]bool),
+	}
+
+	var backup func(path string) error
+	backup = func(path string) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil
+		}
+		// A directory is backed up file by file so undo restores each one
+		if info.IsDir() {
+			tree, _, err := g.scanTree(path)
+			if err != nil {
+				return err
+			}
+			for _, file := range tree {
+				if err := backup(file); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		dest := filepath.Join(snap.record.BackupDir, "files", fmt.Sprintf("%d-%s", len(snap.record.Backups), filepath.Base(path)))
+		if err := copyFilePreserving(path, dest); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+		snap.record.Backups = append(snap.record.Backups, fileBackup{Original: path, Backup: dest})
+		return nil
+	}
+
+	for _, path := range plan.Modifies {
+		if err := backup(path); err != nil {
+			g.Discard(snap)
+			return nil, err
+		}
+	}
+
+	for _, path := range plan.Creates {
+		if _, err := os.Stat(path); err == nil {
+			snap.existed[path] = true
+			if err := backup(path); err != nil {
+				g.Discard(snap)
+				return nil, err
+			}
+		}
+	}
+
+	for _, root := range plan.Reorganizes {
+		tree, dirs, err := g.scanTree(root)
+		if err != nil {
+			g.Discard(snap)
+			return nil, err
+		}
+		snap.trees[root] = tree
+		for dir := range dirs {
+			snap.dirs[dir] = true
+		}
+	}
+
+	return snap, nil
+}
+
+// Commit works out what the action changed and appends it to the journal
+func (g *SafetyGuard) Commit(snap *snapshot) error {
+	for _, path := range snap.plan.Creates {
+		if _, err := os.Stat(path); err == nil && !snap.existed[path] {
+			snap.record.Created = append(snap.record.Created, path)
+		}
+	}
+
+	// Files keep their inode when moved, which lets us pair old and new paths
+	for root, before := range snap.trees {
+		after, dirs, err := g.scanTree(root)
+		if err != nil {
+			continue
+		}
+		for inode, newPath := range after {
+			if oldPath, ok := before[inode]; ok && oldPath != newPath {
+				snap.record.Moves = append(snap.record.Moves, fileMove{From: oldPath, To: newPath})
+			}
+		}
+		for dir := range dirs {
+			if !snap.dirs[dir] {
+				snap.record.CreatedDirs = append(snap.record.CreatedDirs, dir)
+			}
+		}
+	}
+
+	record := snap.record
+	if len(record.Backups) == 0 && len(record.Moves) == 0 && len(record.Created) == 0 {
+		os.RemoveAll(record.BackupDir)
+		return nil
+	}
+	return g.journal(record)
+}
+
+// RecordCreated journals new files an unconfirmed action reports saving,
+// so undo can remove them without touching anything else in their folder
+func (g *SafetyGuard) RecordCreated(service, query string, paths ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	return g.journal(actionRecord{
+		ID:      time.Now().Format("20060102-150405.000000"),
+		Service: service,
+		Query:   query,
+		Time:    time.Now(),
+		Created: paths,
+	})
+}
+
+// journal appends a record, dropping the oldest past maxJournal
+func (g *SafetyGuard) journal(record actionRecord) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	journal := g.loadJournal()
+	journal = append(journal, record)
+	for len(journal) > g.maxJournal {
+		os.RemoveAll(journal[0].BackupDir)
+		journal = journal[1:]
+	}
+	return g.saveJournal(journal)
+}
+
+// Discard drops a snapshot for an action that never ran
+func (g *SafetyGuard) Discard(snap *snapshot) {
+	os.RemoveAll(snap.record.BackupDir)
+}
+
+// UndoLast reverts the most recent journaled action
+func (g *SafetyGuard) UndoLast() (UndoResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	journal := g.loadJournal()
+	if len(journal) == 0 {
+		return UndoResult{}, fmt.Errorf("nothing to undo")
+	}
+
+	record := journal[len(journal)-1]
+	result := UndoResult{
+		ActionID: record.ID,
+		Service:  record.Service,
+		Query:    record.Query,
+		Restored: []string{},
+		Removed:  []string{},
+	}
+
+	for _, move := range record.Moves {
+		if err := os.MkdirAll(filepath.Dir(move.From), 0755); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+		if _, err := os.Stat(move.From); err == nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s already exists, left %s in place", move.From, move.To))
+			continue
+		}
+		if err := os.Rename(move.To, move.From); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+		result.Restored = append(result.Restored, move.From)
+	}
+
+	for _, path := range record.Created {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+		result.Removed = append(result.Removed, path)
+	}
+
+	for _, b := range record.Backups {
+		if err := copyFilePreserving(b.Backup, b.Original); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+		result.Restored = append(result.Restored, b.Original)
+	}
+
+	// Remove folders the action created, deepest first, if they are empty again
+	sort.Slice(record.CreatedDirs, func(i, j int) bool {
+		return len(record.CreatedDirs[i]) > len(record.CreatedDirs[j])
+	})
+	for _, dir := range record.CreatedDirs {
+		if os.Remove(dir) == nil {
+			result.Removed = append(result.Removed, dir+"/")
+		}
+	}
+
+	os.RemoveAll(record.BackupDir)
+	if err := g.saveJournal(journal[:len(journal)-1]); err != nil {
+		return result, err
+	}
+
+	if len(result.Errors) > 0 {
+		return result, fmt.Errorf("undo finished with %d errors", len(result.Errors))
+	}
+	return result, nil
+}
+
+// scanTree maps inodes to paths for every regular file under root
+func (g *SafetyGuard) scanTree(root string) (map[uint64]string, map[string]bool, error) {
+	tree := make(map[uint64]string)
+	dirs := make(map[string]bool)
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			dirs[path] = true
+			return nil
+		}
+		if len(tree) >= g.maxFiles {
+			return fmt.Errorf("%s has more than %d files, too many to back up safely", root, g.maxFiles)
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok && info.Mode().IsRegular() {
+			tree[stat.Ino] = path
+		}
+		return nil
+	})
+	return tree, dirs, err
+}
+
+func (g *SafetyGuard) loadJournal() []actionRecord {
+	var journal []actionRecord
+	if data, err := os.ReadFile(filepath.Join(g.dir, "journal.json")); err == nil {
+		json.Unmarshal(data, &journal)
+	}
+	return journal
+}
+
+func (g *SafetyGuard) saveJournal(journal []actionRecord) error {
+	if err := os.MkdirAll(g.dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(g.dir, "journal.json"), data, 0600)
+}
+
+// copyFilePreserving copies src to dst keeping the permission bits
+func copyFilePreserving(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// PlanAction classifies what a routed query will do to the filesystem
+func (sm *ServiceManager) PlanAction(intent Intent, query string) ActionPlan {
+	plan := ActionPlan{Service: intent.ServiceName}
+
+	switch intent.ServiceName {
+	case "organizer":
+		path := sm.organizer.Target(query)
+		if path == "" {
+			break
+		}
+		plan.Mutating = true
+		plan.Reorganizes = []string{path}
+		plan.Description = fmt.Sprintf("Move the files in %s into sorted folders", path)
+
+	case "linter":
+		path := sm.paths.Path(query)
+		if path == "" {
+			break
+		}
+		plan.Mutating = true
+		plan.Modifies = []string{path}
+		plan.Description = fmt.Sprintf("Rewrite %s in place with its formatter", path)
+
+	case "converter":
+		conversion, err := sm.converter.Plan(query)
+		if err != nil {
+			break
+		}
+		plan.Mutating = true
+		plan.Creates = []string{conversion.Output}
+		plan.Description = fmt.Sprintf("Convert %s and write %s", conversion.Input, conversion.Output)
+
+	case "clipboard":
+		switch action, id := clipboardAction(strings.ToLower(query)); action {
+		case "delete":
+			plan.Mutating, plan.Irreversible = true, true
+			plan.Description = fmt.Sprintf("Delete clipboard entry %d from the history", id)
+		case "clear":
+			plan.Mutating, plan.Irreversible = true, true
+			plan.Description = "Remove every unpinned entry from the clipboard history"
+		}
+	}
+
+	return plan
+}
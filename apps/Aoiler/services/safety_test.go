@@ -0,0 +1,214 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testSafety(t *testing.T) *SafetyGuard {
+	t.Helper()
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	return NewSafetyGuard()
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestConfirmToken(t *testing.T) {
+	g := testSafety(t)
+	plan := ActionPlan{Service: "linter", Mutating: true}
+
+	request := g.RequestConfirmation(plan, "format a.py")
+	if g.Confirm(request.Token, plan, "format b.py") {
+		t.Error("token confirmed a different query")
+	}
+	// A token is single use, even when it was presented for the wrong query
+	if g.Confirm(request.Token, plan, "format a.py") {
+		t.Error("token confirmed twice")
+	}
+
+	request = g.RequestConfirmation(plan, "format a.py")
+	if g.Confirm(request.Token, ActionPlan{Service: "organizer"}, "format a.py") {
+		t.Error("token confirmed a different service")
+	}
+	request = g.RequestConfirmation(plan, "format a.py")
+	if !g.Confirm(request.Token, plan, "format a.py") {
+		t.Error("token did not confirm its own plan")
+	}
+	if g.Confirm("", plan, "format a.py") {
+		t.Error("empty token confirmed")
+	}
+
+	g.tokenTTL = -time.Second
+	request = g.RequestConfirmation(plan, "format a.py")
+	if _, ok := g.PendingService(request.Token); ok {
+		t.Error("expired token still pending")
+	}
+	if g.Confirm(request.Token, plan, "format a.py") {
+		t.Error("expired token confirmed")
+	}
+}
+
+func TestUndoModifies(t *testing.T) {
+	g := testSafety(t)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.py")
+	project := filepath.Join(dir, "project")
+	writeTestFile(t, file, "x=1\n")
+	writeTestFile(t, filepath.Join(project, "a.py"), "a=1\n")
+	writeTestFile(t, filepath.Join(project, "pkg", "b.py"), "b=2\n")
+
+	plan := ActionPlan{Service: "linter", Mutating: true, Modifies: []string{file, project}}
+	snap, err := g.Snapshot(plan, "format")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Directories are backed up file by file
+	if len(snap.record.Backups) != 3 {
+		t.Fatalf("backups = %+v, want 3 files", snap.record.Backups)
+	}
+
+	writeTestFile(t, file, "x = 1\n")
+	writeTestFile(t, filepath.Join(project, "a.py"), "a = 1\n")
+	writeTestFile(t, filepath.Join(project, "pkg", "b.py"), "b = 2\n")
+	if err := g.Commit(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := g.UndoLast()
+	if err != nil || len(result.Restored) != 3 {
+		t.Fatalf("UndoLast = %+v, %v", result, err)
+	}
+	for path, want := range map[string]string{
+		file:                                  "x=1\n",
+		filepath.Join(project, "a.py"):        "a=1\n",
+		filepath.Join(project, "pkg", "b.py"): "b=2\n",
+	} {
+		if got := readTestFile(t, path); got != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+	if _, err := g.UndoLast(); err == nil {
+		t.Error("second UndoLast: want nothing to undo")
+	}
+}
+
+func TestUndoCreatesAndMoves(t *testing.T) {
+	g := testSafety(t)
+	dir := t.TempDir()
+	output := filepath.Join(dir, "clip.mp4")
+	downloads := filepath.Join(dir, "Downloads")
+	writeTestFile(t, output, "old")
+	writeTestFile(t, filepath.Join(downloads, "cat.png"), "png")
+
+	plan := ActionPlan{Service: "organizer", Mutating: true, Creates: []string{output}, Reorganizes: []string{downloads}}
+	snap, err := g.Snapshot(plan, "organize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, output, "new")
+	os.MkdirAll(filepath.Join(downloads, "Images"), 0755)
+	os.Rename(filepath.Join(downloads, "cat.png"), filepath.Join(downloads, "Images", "cat.png"))
+	if err := g.Commit(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.UndoLast(); err != nil {
+		t.Fatal(err)
+	}
+	// An output that overwrote a file brings the old one back
+	if got := readTestFile(t, output); got != "old" {
+		t.Errorf("output = %q, want the old file", got)
+	}
+	if got := readTestFile(t, filepath.Join(downloads, "cat.png")); got != "png" {
+		t.Errorf("cat.png = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(downloads, "Images")); !os.IsNotExist(err) {
+		t.Error("created folder left behind")
+	}
+}
+
+func TestUndoRecordCreated(t *testing.T) {
+	g := testSafety(t)
+	screenshots := filepath.Join(t.TempDir(), "Screenshots")
+	shot := filepath.Join(screenshots, "new.png")
+	// Saved alongside by another tool while the capture ran
+	other := filepath.Join(screenshots, "grimblast.png")
+	writeTestFile(t, shot, "new")
+	writeTestFile(t, other, "other")
+
+	if err := g.RecordCreated("capture", "screenshot", shot); err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.UndoLast()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{shot}; !reflect.DeepEqual(result.Removed, want) {
+		t.Errorf("removed = %v, want %v", result.Removed, want)
+	}
+	if got := readTestFile(t, other); got != "other" {
+		t.Error("another tool's screenshot touched")
+	}
+}
+
+func TestSnapshotTooManyFiles(t *testing.T) {
+	g := testSafety(t)
+	g.maxFiles = 2
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		writeTestFile(t, filepath.Join(dir, name), name)
+	}
+	if _, err := g.Snapshot(ActionPlan{Modifies: []string{dir}}, "format"); err == nil {
+		t.Error("want an error over maxFiles")
+	}
+	// Nothing is journaled for an action that never ran
+	if _, err := g.UndoLast(); err == nil {
+		t.Error("UndoLast after a failed snapshot: want nothing to undo")
+	}
+}
+
+func TestPlanAction(t *testing.T) {
+	paths, _, _ := testPaths(t)
+	sm := &ServiceManager{paths: paths}
+
+	tests := []struct {
+		service      string
+		query        string
+		mutating     bool
+		irreversible bool
+	}{
+		{"clipboard", "delete #3", true, true},
+		{"clipboard", "clear my clipboard history", true, true},
+		{"clipboard", "what did I copy about port 8080", false, false},
+		{"clipboard", "pin 3", false, false},
+		{"capture", "screenshot region", false, false},
+		{"capture", "record the screen for 10s", false, false},
+		{"ocr", "ocr ~/cat.png", false, false},
+	}
+	for _, tt := range tests {
+		plan := sm.PlanAction(Intent{ServiceName: tt.service}, tt.query)
+		if plan.Mutating != tt.mutating || plan.Irreversible != tt.irreversible {
+			t.Errorf("PlanAction(%s, %q) = %+v", tt.service, tt.query, plan)
+		}
+	}
+}
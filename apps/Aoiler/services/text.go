@@ -0,0 +1,608 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+type TextResult struct {
+	Task   string     `json:"task"`
+	Source string     `json:"source"` // clipboard, ocr, inline or a file path
+	Output string     `json:"output"`
+	Local  bool       `json:"local"` // computed without the LLM
+	Copied bool       `json:"copied"`
+	LLM    *LLMResult `json:"llm,omitempty"`
+}
+
+// textTask is a transform recognised in a query
+type textTask struct {
+	Name  string // to-json, base64-encode, case, hash, translate, ...
+	Arg   string // target language, case style or hash algorithm
+	Local bool
+}
+
+// Size limits for text sent through a transform
+const (
+	maxTextInput    = 1024 * 1024
+	maxTextLLMInput = 48 * 1024
+)
+
+// textTaskPrompts are the system prompts of the language tasks
+var textTaskPrompts = map[string]string{
+	"translate": "Translate the user's text into %s. Keep the formatting, code, names and URLs unchanged. Reply with the translation only.",
+	"summarize": "Summarize the user's text in a few short bullet points that keep the key facts. Reply with the summary only.",
+	"grammar":   "Correct the grammar, spelling and punctuation of the user's text without changing its meaning, tone or formatting. Reply with the corrected text only.",
+	"rephrase":  "Rephrase the user's text so it reads clearly and naturally while keeping its meaning and tone. Reply with the new text only.",
+}
+
+// TextService transforms text from the clipboard, the last OCR result or a
+// file. Data conversions, encodings, case changes and hashes run locally;
+// language tasks go to the LLM.
+type TextService struct {
+	llm       *LLMService
+	clipboard *ClipboardService
+	paths     *PathResolver
+
+	mu      sync.Mutex
+	lastOCR string
+}
+
+func NewTextService(llm *LLMService, clipboard *ClipboardService, paths *PathResolver) *TextService {
+	return &TextService{llm: llm, clipboard: clipboard, paths: paths}
+}
+
+// RememberOCR keeps the latest OCR text for "summarize the ocr text"
+func (ts *TextService) RememberOCR(text string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.lastOCR = text
+}
+
+// Transform runs the task named in the query on its input text
+func (ts *TextService) Transform(ctx context.Context, query string, opts QueryOptions) (TextResult, error) {
+	task, ok := parseTextTask(query)
+	if !ok {
+		return TextResult{}, fmt.Errorf("no text task found in query")
+	}
+
+	result := TextResult{Task: task.Name, Local: task.Local}
+
+	// Files are hashed as a stream, so the text size limit doesn't apply
+	if path, _ := ts.inputFile(query); task.Name == "hash" && path != "" {
+		result.Source = path
+		output, err := hashFile(path, task.Arg)
+		if err != nil {
+			return result, err
+		}
+		result.Output = output
+		return ts.finish(ctx, query, result)
+	}
+
+	data, source, err := ts.input(ctx, query)
+	result.Source = source
+	if err != nil {
+		return result, err
+	}
+	if task.Name != "hash" && !utf8.Valid(data) {
+		return result, fmt.Errorf("%s is not text", source)
+	}
+
+	if task.Local {
+		result.Output, err = runLocalTextTask(task, data)
+	} else {
+		var llmResult LLMResult
+		llmResult, err = ts.runLanguageTask(ctx, task, string(data), opts)
+		result.Output = llmResult.Response
+		result.LLM = &llmResult
+		if err == nil && !llmResult.Success {
+			err = fmt.Errorf("%s", llmResult.Response)
+		}
+	}
+	if err != nil {
+		return result, err
+	}
+	return ts.finish(ctx, query, result)
+}
+
+// finish copies the output when the query asks for it
+func (ts *TextService) finish(ctx context.Context, query string, result TextResult) (TextResult, error) {
+	if queryHasWord(query, "copy") {
+		if err := ts.clipboard.WriteText(ctx, result.Output); err != nil {
+			return result, err
+		}
+		result.Copied = true
+	}
+	return result, nil
+}
+
+func (ts *TextService) runLanguageTask(ctx context.Context, task textTask, text string, opts QueryOptions) (LLMResult, error) {
+	if strings.TrimSpace(text) == "" {
+		return LLMResult{}, fmt.Errorf("no text to %s", task.Name)
+	}
+	if len(text) > maxTextLLMInput {
+		return LLMResult{}, fmt.Errorf("text is too long to send (%d KB, limit %d KB)", len(text)/1024, maxTextLLMInput/1024)
+	}
+
+	system := textTaskPrompts[task.Name]
+	if task.Name == "translate" {
+		system = fmt.Sprintf(system, task.Arg)
+	}
+	return ts.llm.QueryTask(ctx, system, text, opts)
+}
+
+// input finds the text a query works on: text after a colon or in quotes,
+// a named file, the last OCR result, or else the clipboard
+func (ts *TextService) input(ctx context.Context, query string) ([]byte, string, error) {
+	if _, inline := splitInlineText(query); inline != "" {
+		return []byte(inline), "inline", nil
+	}
+
+	path, quoted := ts.inputFile(query)
+	if path != "" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, path, err
+		}
+		if info.Size() > maxTextInput {
+			return nil, path, fmt.Errorf("%s is too large (limit %d MB)", path, maxTextInput/(1024*1024))
+		}
+		data, err := os.ReadFile(path)
+		return data, path, err
+	}
+	if quoted != "" {
+		return []byte(quoted), "inline", nil
+	}
+
+	lowerQuery := strings.ToLower(query)
+	if queryHasWord(query, "ocr") || strings.Contains(lowerQuery, "screen text") || strings.Contains(lowerQuery, "screenshot text") {
+		ts.mu.Lock()
+		text := ts.lastOCR
+		ts.mu.Unlock()
+		if text == "" {
+			if entry, ok := ts.clipboard.LatestText("ocr"); ok {
+				text = entry.Text
+			}
+		}
+		if text == "" {
+			return nil, "ocr", fmt.Errorf("no OCR result yet: run ocr first")
+		}
+		return []byte(text), "ocr", nil
+	}
+
+	text, err := ts.clipboard.ReadText(ctx)
+	if err != nil || text == "" {
+		entry, ok := ts.clipboard.LatestText("")
+		if !ok {
+			return nil, "clipboard", fmt.Errorf("the clipboard is empty")
+		}
+		text = entry.Text
+	}
+	return []byte(text), "clipboard", nil
+}
+
+// inputFile returns the file a query names, or the quoted text it names
+// instead. Text after a colon takes precedence over both.
+func (ts *TextService) inputFile(query string) (path, quoted string) {
+	instruction, inline := splitInlineText(query)
+	if inline != "" {
+		return "", ""
+	}
+
+	for _, tok := range tokenizeQuery(instruction) {
+		if tok.Value == "" || (tok.Quote == 0 && !looksLikePath(tok.Value) && !hasFileExtension(tok.Value)) {
+			continue
+		}
+		path := ts.paths.Resolve(tok.Value)
+		info, err := os.Stat(path)
+		switch {
+		case err == nil && !info.IsDir():
+			return path, ""
+		case tok.Quote != 0:
+			return "", tok.Value
+		}
+	}
+	return "", ""
+}
+
+// splitInlineText separates "base64 encode: some text" into the
+// instruction and the text after the first colon
+func splitInlineText(query string) (string, string) {
+	for i := 0; i+1 < len(query); i++ {
+		if query[i] == ':' && unicode.IsSpace(rune(query[i+1])) {
+			return query[:i], strings.TrimSpace(query[i+1:])
+		}
+	}
+	return query, ""
+}
+
+// parseTextTask recognises a text transform in a query. Questions such as
+// "what is base64" are left to the LLM.
+func parseTextTask(query string) (textTask, bool) {
+	instruction, _ := splitInlineText(query)
+	lowerQuery := strings.ToLower(instruction)
+	words := strings.FieldsFunc(lowerQuery, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != ';'
+	})
+	if len(words) == 0 || questionWords[words[0]] {
+		return textTask{}, false
+	}
+	has := func(word string) bool {
+		for _, w := range words {
+			if w == word {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Data conversions need a target ("to yaml") and something to convert:
+	// a named source format, "this"/"it", the clipboard or the OCR text
+	for i, word := range words {
+		if (word == "to" || word == "into" || word == "as") && i+1 < len(words) {
+			format := dataFormat(words[i+1])
+			if format == "" {
+				continue
+			}
+			for _, other := range words[:i] {
+				if dataFormat(other) != "" || other == "this" || other == "it" || other == "clipboard" || other == "ocr" {
+					return textTask{Name: "to-" + format, Local: true}, true
+				}
+			}
+		}
+	}
+
+	switch {
+	case has("base64") || has("b64"):
+		if has("decode") {
+			return textTask{Name: "base64-decode", Local: true}, true
+		}
+		return textTask{Name: "base64-encode", Local: true}, true
+	case has("urlencode") || strings.Contains(lowerQuery, "url encode") || strings.Contains(lowerQuery, "url-encode"):
+		return textTask{Name: "url-encode", Local: true}, true
+	case has("urldecode") || strings.Contains(lowerQuery, "url decode") || strings.Contains(lowerQuery, "url-decode"):
+		return textTask{Name: "url-decode", Local: true}, true
+	}
+
+	for _, algorithm := range []string{"md5", "sha1", "sha256", "sha512"} {
+		if has(algorithm) || has(algorithm+"sum") {
+			return textTask{Name: "hash", Arg: algorithm, Local: true}, true
+		}
+	}
+	if strings.Contains(lowerQuery, "hash of") || strings.Contains(lowerQuery, "hash this") || strings.Contains(lowerQuery, "hash the") {
+		return textTask{Name: "hash", Arg: "sha256", Local: true}, true
+	}
+
+	caseStyles := []struct{ phrase, style string }{
+		{"uppercase", "upper"}, {"upper case", "upper"},
+		{"lowercase", "lower"}, {"lower case", "lower"},
+		{"title case", "title"}, {"titlecase", "title"},
+		{"snake case", "snake"}, {"snake_case", "snake"},
+		{"camel case", "camel"}, {"camelcase", "camel"},
+		{"pascal case", "pascal"}, {"pascalcase", "pascal"},
+		{"kebab case", "kebab"}, {"kebab-case", "kebab"},
+	}
+	for _, c := range caseStyles {
+		if strings.Contains(lowerQuery, c.phrase) {
+			return textTask{Name: "case", Arg: c.style, Local: true}, true
+		}
+	}
+
+	switch {
+	case has("translate"):
+		language := "English"
+		for i, word := range words {
+			if (word == "to" || word == "into") && i+1 < len(words) {
+				runes := []rune(words[i+1])
+				language = string(unicode.ToUpper(runes[0])) + string(runes[1:])
+			}
+		}
+		return textTask{Name: "translate", Arg: language}, true
+	case has("summarize") || has("summarise") || has("tldr") || has("tl;dr") || strings.Contains(lowerQuery, "sum up"):
+		return textTask{Name: "summarize"}, true
+	case has("grammar") || has("proofread") || strings.Contains(lowerQuery, "fix spelling") || strings.Contains(lowerQuery, "fix the spelling"):
+		return textTask{Name: "grammar"}, true
+	case has("rephrase") || has("reword") || has("paraphrase"):
+		return textTask{Name: "rephrase"}, true
+	}
+	return textTask{}, false
+}
+
+var questionWords = map[string]bool{
+	"what": true, "how": true, "why": true, "when": true, "which": true, "who": true,
+	"is": true, "are": true, "does": true, "do": true, "can": true, "explain": true,
+}
+
+func dataFormat(word string) string {
+	switch word {
+	case "json":
+		return "json"
+	case "yaml", "yml":
+		return "yaml"
+	case "toml":
+		return "toml"
+	}
+	return ""
+}
+
+func queryHasWord(query, word string) bool {
+	for _, w := range strings.Fields(strings.ToLower(query)) {
+		if strings.Trim(w, ".,!?;:\"'") == word {
+			return true
+		}
+	}
+	return false
+}
+
+// runLocalTextTask applies a deterministic transform
+func runLocalTextTask(task textTask, data []byte) (string, error) {
+	switch task.Name {
+	case "to-json", "to-yaml", "to-toml":
+		return convertData(data, strings.TrimPrefix(task.Name, "to-"))
+	case "base64-encode":
+		return base64.StdEncoding.EncodeToString(data), nil
+	case "base64-decode":
+		return decodeBase64(string(data))
+	case "url-encode":
+		return url.QueryEscape(string(data)), nil
+	case "url-decode":
+		decoded, err := url.QueryUnescape(strings.TrimSpace(string(data)))
+		if err != nil {
+			return "", fmt.Errorf("not URL encoded: %w", err)
+		}
+		return decoded, nil
+	case "case":
+		return convertCase(string(data), task.Arg), nil
+	case "hash":
+		return hashText(data, task.Arg), nil
+	}
+	return "", fmt.Errorf("unknown text task: %s", task.Name)
+}
+
+// convertData parses JSON, TOML or YAML and writes it out as format
+func convertData(data []byte, format string) (string, error) {
+	value, err := parseData(data)
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case "json":
+		out, err := json.MarshalIndent(value, "", "  ")
+		return string(out), err
+	case "yaml":
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(value); err != nil {
+			return "", err
+		}
+		encoder.Close()
+		return strings.TrimRight(buf.String(), "\n"), nil
+	case "toml":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return "", fmt.Errorf("TOML needs a table at the top level, not a list or value")
+		}
+		var buf bytes.Buffer
+		encoder := toml.NewEncoder(&buf)
+		encoder.Indent = ""
+		if err := encoder.Encode(value); err != nil {
+			return "", err
+		}
+		return strings.TrimRight(buf.String(), "\n"), nil
+	}
+	return "", fmt.Errorf("unknown data format: %s", format)
+}
+
+// parseData detects the input format: JSON first, since it is also valid
+// YAML, then TOML, then YAML
+func parseData(data []byte) (interface{}, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("no data to convert")
+	}
+
+	if json.Valid(trimmed) {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		return normalizeData(value), nil
+	}
+
+	var table map[string]interface{}
+	if _, err := toml.Decode(string(trimmed), &table); err == nil {
+		return normalizeData(table), nil
+	}
+
+	var value interface{}
+	if err := yaml.Unmarshal(trimmed, &value); err != nil {
+		return nil, fmt.Errorf("input is not valid JSON, TOML or YAML: %w", err)
+	}
+	if _, isString := value.(string); isString {
+		return nil, fmt.Errorf("input is not JSON, TOML or YAML data")
+	}
+	return normalizeData(value), nil
+}
+
+// normalizeData turns JSON numbers into ints or floats and YAML's
+// interface-keyed maps into string-keyed ones so every encoder accepts them
+func normalizeData(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeData(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalizeData(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeData(item)
+		}
+		return v
+	case []map[string]interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = normalizeData(item)
+		}
+		return items
+	}
+	return value
+}
+
+func decodeBase64(text string) (string, error) {
+	text = strings.Join(strings.Fields(text), "")
+	encodings := []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding}
+	for _, encoding := range encodings {
+		decoded, err := encoding.DecodeString(text)
+		if err != nil {
+			continue
+		}
+		if !utf8.Valid(decoded) {
+			return "", fmt.Errorf("decoded data is binary (%d bytes), not text", len(decoded))
+		}
+		return string(decoded), nil
+	}
+	return "", fmt.Errorf("input is not valid base64")
+}
+
+func newHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case "md5":
+		return md5.New()
+	case "sha1":
+		return sha1.New()
+	case "sha512":
+		return sha512.New()
+	}
+	return sha256.New()
+}
+
+func hashText(data []byte, algorithm string) string {
+	h := newHash(algorithm)
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashFile hashes a file of any size without reading it into memory
+func hashFile(path, algorithm string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := newHash(algorithm)
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// convertCase changes the case of text; the identifier styles (snake,
+// camel, pascal, kebab) are applied to each line separately
+func convertCase(text, style string) string {
+	switch style {
+	case "upper":
+		return strings.ToUpper(text)
+	case "lower":
+		return strings.ToLower(text)
+	case "title":
+		var sb strings.Builder
+		startOfWord := true
+		for _, r := range text {
+			if startOfWord {
+				sb.WriteRune(unicode.ToUpper(r))
+			} else {
+				sb.WriteRune(unicode.ToLower(r))
+			}
+			startOfWord = unicode.IsSpace(r) || r == '-'
+		}
+		return sb.String()
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		words := splitIdentifierWords(line)
+		switch style {
+		case "snake":
+			lines[i] = strings.Join(words, "_")
+		case "kebab":
+			lines[i] = strings.Join(words, "-")
+		case "camel", "pascal":
+			var sb strings.Builder
+			for j, word := range words {
+				if j == 0 && style == "camel" {
+					sb.WriteString(word)
+					continue
+				}
+				runes := []rune(word)
+				sb.WriteString(string(unicode.ToUpper(runes[0])) + string(runes[1:]))
+			}
+			lines[i] = sb.String()
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitIdentifierWords splits "parseHTTPRequest_v2" into parse, http, request, v2
+func splitIdentifierWords(text string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(text)
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = nil
+		}
+	}
+
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 {
+			prev := current[len(current)-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
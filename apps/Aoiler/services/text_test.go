@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"kaguyadots/runner"
+)
+
+func TestParseTextTask(t *testing.T) {
+	tests := []struct {
+		query string
+		want  textTask
+		ok    bool
+	}{
+		{"convert this JSON to YAML", textTask{Name: "to-yaml", Local: true}, true},
+		{"convert config.json to toml", textTask{Name: "to-toml", Local: true}, true},
+		{"clipboard as json", textTask{Name: "to-json", Local: true}, true},
+		{"base64 encode the clipboard", textTask{Name: "base64-encode", Local: true}, true},
+		{"base64 decode: aGVsbG8=", textTask{Name: "base64-decode", Local: true}, true},
+		{"url encode this", textTask{Name: "url-encode", Local: true}, true},
+		{"sha256 of ~/file.iso", textTask{Name: "hash", Arg: "sha256", Local: true}, true},
+		{"md5sum notes.txt", textTask{Name: "hash", Arg: "md5", Local: true}, true},
+		{"snake case this", textTask{Name: "case", Arg: "snake", Local: true}, true},
+		{"make it uppercase", textTask{Name: "case", Arg: "upper", Local: true}, true},
+		{"translate this to Japanese", textTask{Name: "translate", Arg: "Japanese"}, true},
+		{"translate: hola amigo", textTask{Name: "translate", Arg: "English"}, true},
+		{"summarize clipboard", textTask{Name: "summarize"}, true},
+		{"tl;dr the ocr text", textTask{Name: "summarize"}, true},
+		{"fix grammar", textTask{Name: "grammar"}, true},
+		{"rephrase this", textTask{Name: "rephrase"}, true},
+		{"how do I convert a dict to json in python", textTask{}, false},
+		{"what is base64", textTask{}, false},
+		{"convert video.mkv to mp4", textTask{}, false},
+		{"translate: base64 this", textTask{Name: "translate", Arg: "English"}, true},
+		{"open firefox", textTask{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseTextTask(tt.query)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseTextTask(%q) = %+v, %v; want %+v, %v", tt.query, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRunLocalTextTask(t *testing.T) {
+	tests := []struct {
+		name  string
+		task  textTask
+		input string
+		want  string
+	}{
+		{"json to yaml", textTask{Name: "to-yaml"}, `{"name": "kaguya", "ports": [80, 443], "ratio": 1.5}`,
+			"name: kaguya\nports:\n  - 80\n  - 443\nratio: 1.5"},
+		{"yaml to json", textTask{Name: "to-json"}, "name: kaguya\nenabled: true\n",
+			"{\n  \"enabled\": true,\n  \"name\": \"kaguya\"\n}"},
+		{"toml to json", textTask{Name: "to-json"}, "[preferences]\nterm = \"kitty\"\n",
+			"{\n  \"preferences\": {\n    \"term\": \"kitty\"\n  }\n}"},
+		{"json to toml", textTask{Name: "to-toml"}, `{"aoiler": {"monthly_budget": 5}}`,
+			"[aoiler]\nmonthly_budget = 5"},
+		{"base64 encode", textTask{Name: "base64-encode"}, "hello world", "aGVsbG8gd29ybGQ="},
+		{"base64 decode", textTask{Name: "base64-decode"}, "aGVsbG8gd29ybGQ=\n", "hello world"},
+		{"base64 decode unpadded", textTask{Name: "base64-decode"}, "aGVsbG8", "hello"},
+		{"url encode", textTask{Name: "url-encode"}, "a b&c=d", "a+b%26c%3Dd"},
+		{"url decode", textTask{Name: "url-decode"}, "a+b%26c%3Dd", "a b&c=d"},
+		{"upper", textTask{Name: "case", Arg: "upper"}, "Hello World", "HELLO WORLD"},
+		{"title", textTask{Name: "case", Arg: "title"}, "the quick BROWN fox", "The Quick Brown Fox"},
+		{"snake", textTask{Name: "case", Arg: "snake"}, "parseHTTPRequest v2", "parse_http_request_v2"},
+		{"kebab", textTask{Name: "case", Arg: "kebab"}, "Monthly Budget", "monthly-budget"},
+		{"camel", textTask{Name: "case", Arg: "camel"}, "cache_max_mb\nuser id", "cacheMaxMb\nuserId"},
+		{"pascal", textTask{Name: "case", Arg: "pascal"}, "text service", "TextService"},
+		{"sha256", textTask{Name: "hash", Arg: "sha256"}, "abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"md5", textTask{Name: "hash", Arg: "md5"}, "abc", "900150983cd24fb0d6963f7d28e17f72"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runLocalTextTask(tt.task, []byte(tt.input))
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunLocalTextTaskErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		task  textTask
+		input string
+	}{
+		{"list to toml", textTask{Name: "to-toml"}, `[1, 2]`},
+		{"plain text to json", textTask{Name: "to-json"}, "just some words"},
+		{"invalid base64", textTask{Name: "base64-decode"}, "not base64!"},
+		{"binary base64", textTask{Name: "base64-decode"}, "/wD+"},
+		{"invalid url escape", textTask{Name: "url-decode"}, "100%"},
+	}
+
+	for _, tt := range tests {
+		if _, err := runLocalTextTask(tt.task, []byte(tt.input)); err == nil {
+			t.Errorf("%s: want error", tt.name)
+		}
+	}
+}
+
+func TestTextTransformSources(t *testing.T) {
+	paths, _, work := testPaths(t, "config.json")
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	if err := os.WriteFile(filepath.Join(work, "config.json"), []byte(`{"a": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := runner.NewFake().On("wl-paste", "clip text", nil)
+	clipboard := NewClipboardService(fake)
+	text := NewTextService(nil, clipboard, paths)
+	text.RememberOCR("ocr text")
+
+	tests := []struct {
+		query  string
+		source string
+		output string
+	}{
+		{"uppercase the clipboard", "clipboard", "CLIP TEXT"},
+		{"uppercase the ocr text", "ocr", "OCR TEXT"},
+		{"uppercase: inline text", "inline", "INLINE TEXT"},
+		{`uppercase "quoted text"`, "inline", "QUOTED TEXT"},
+		{"convert config.json to yaml", filepath.Join(work, "config.json"), "a: 1"},
+	}
+
+	for _, tt := range tests {
+		result, err := text.Transform(context.Background(), tt.query, QueryOptions{})
+		if err != nil {
+			t.Errorf("Transform(%q): %v", tt.query, err)
+			continue
+		}
+		if result.Source != tt.source || result.Output != tt.output || !result.Local {
+			t.Errorf("Transform(%q) = %+v, want %q from %s", tt.query, result, tt.output, tt.source)
+		}
+	}
+
+	fake.Reset()
+	result, err := text.Transform(context.Background(), "sha1: abc and copy it", QueryOptions{})
+	if err != nil || !result.Copied {
+		t.Fatalf("copy: %+v, %v", result, err)
+	}
+	calls := fake.Calls()
+	if len(calls) != 1 || !reflect.DeepEqual(calls[0].Args, []string(nil)) || calls[0].Input != result.Output {
+		t.Errorf("calls = %+v, want wl-copy of %q", calls, result.Output)
+	}
+}
+
+func TestTextHashLargeFile(t *testing.T) {
+	paths, _, work := testPaths(t, "disk.iso", "big.txt")
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	// Over the text limit: hashed as a stream, refused as text
+	data := make([]byte, maxTextInput+1)
+	for _, name := range []string{"disk.iso", "big.txt"} {
+		if err := os.WriteFile(filepath.Join(work, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	text := NewTextService(nil, NewClipboardService(runner.NewFake()), paths)
+
+	result, err := text.Transform(context.Background(), "sha256 of disk.iso", QueryOptions{})
+	if err != nil || result.Output != hashText(data, "sha256") || result.Source != filepath.Join(work, "disk.iso") {
+		t.Errorf("sha256 of disk.iso = %+v, %v", result, err)
+	}
+	if _, err := text.Transform(context.Background(), "uppercase big.txt", QueryOptions{}); err == nil {
+		t.Error("uppercase of a file over the limit: want too large")
+	}
+}
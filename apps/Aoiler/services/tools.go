@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tool is an Aoiler capability the LLM may call
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{} // JSON schema of the arguments object
+	Run         func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+}
+
+// ToolCallRecord is one entry of the tool-use transcript returned to the UI
+type ToolCallRecord struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+	Result    interface{}            `json:"result,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+// ToolRegistry holds the tools offered to the LLM
+type ToolRegistry struct {
+	tools []Tool
+	byKey map[string]Tool
+}
+
+func NewToolRegistry(tools ...Tool) *ToolRegistry {
+	registry := &ToolRegistry{byKey: make(map[string]Tool)}
+	for _, tool := range tools {
+		registry.tools = append(registry.tools, tool)
+		registry.byKey[tool.Name] = tool
+	}
+	return registry
+}
+
+// Tools returns the registered tools in declaration order
+func (r *ToolRegistry) Tools() []Tool {
+	return r.tools
+}
+
+// Call runs a tool and records it in the transcript format
+func (r *ToolRegistry) Call(ctx context.Context, name string, args map[string]interface{}) ToolCallRecord {
+	record := ToolCallRecord{Name: name, Arguments: args}
+
+	tool, ok := r.byKey[name]
+	if !ok {
+		record.Error = fmt.Sprintf("unknown tool: %s", name)
+		return record
+	}
+
+	result, err := tool.Run(ctx, args)
+	record.Result = result
+	if err != nil {
+		record.Error = err.Error()
+	}
+	return record
+}
+
+// toolOutput is what the model sees for a call, errors included
+func (record ToolCallRecord) toolOutput() map[string]interface{} {
+	if record.Error != "" {
+		return map[string]interface{}{"error": record.Error, "result": record.Result}
+	}
+	return map[string]interface{}{"result": record.Result}
+}
+
+type toolQueryKey struct{}
+
+// withToolQuery keeps the user's query on the context so tools can check a
+// path against what the user actually asked about
+func withToolQuery(ctx context.Context, query string) context.Context {
+	return context.WithValue(ctx, toolQueryKey{}, query)
+}
+
+func toolQuery(ctx context.Context) string {
+	query, _ := ctx.Value(toolQueryKey{}).(string)
+	return query
+}
+
+func namesPath(named []string, path string) bool {
+	for _, candidate := range named {
+		if filepath.Clean(candidate) == filepath.Clean(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func stringArg(args map[string]interface{}, key string) string {
+	value, _ := args[key].(string)
+	return strings.TrimSpace(value)
+}
+
+func objectSchema(required []string, properties map[string]interface{}) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func stringProperty(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
+
+// maxToolReadBytes caps how much of a file read_file hands to the model
+const maxToolReadBytes = 32 * 1024
+
+// buildTools exposes the services to the LLM. Mutating services never run
+// from a tool call; they hand back a confirmation for the user instead.
+func (sm *ServiceManager) buildTools() *ToolRegistry {
+	return NewToolRegistry(
+		Tool{
+			Name:        "find_file",
+			Description: "Find a file or directory under ~/.config by name, e.g. 'waybar config' or 'hyprland keybinds'. Returns the best matching path.",
+			Parameters: objectSchema([]string{"query"}, map[string]interface{}{
+				"query": stringProperty("Words describing the file to look for"),
+			}),
+			Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				return sm.fileSearch.Search(ctx, stringArg(args, "query"))
+			},
+		},
+		Tool{
+			Name:        "search_configs",
+			Description: "Keyword search over the user's KaguyaDots configs (Hyprland configs, kaguyadots.toml, waybar). Returns matching snippets with file paths and line numbers.",
+			Parameters: objectSchema([]string{"query"}, map[string]interface{}{
+				"query": stringProperty("Setting or topic to look for, e.g. 'border color' or 'screenshot keybind'"),
+			}),
+			Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				return sm.dotfiles.Search(stringArg(args, "query"), 8), nil
+			},
+		},
+		Tool{
+			Name:        "read_file",
+			Description: "Read a KaguyaDots config file or list a config directory under ~/.config/hypr, ~/.config/kaguyadots or ~/.config/waybar. Large files are truncated to the first 32KB.",
+			Parameters: objectSchema([]string{"path"}, map[string]interface{}{
+				"path": stringProperty("Absolute path, or relative to the user's base directory; ~ is expanded"),
+			}),
+			Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				path := sm.fileSearch.ResolvePath(stringArg(args, "path"))
+				// Whatever is read goes to the LLM provider, so only the
+				// configs search_configs already shares are readable
+				if !sm.dotfiles.Contains(path) {
+					return nil, fmt.Errorf("%s is outside the KaguyaDots config directories", path)
+				}
+				return readFileForTool(path)
+			},
+		},
+		Tool{
+			Name:        "organize_files",
+			Description: "Plan sorting a directory's files into folders with Tyr, by type or by filename. Nothing is moved: this returns the plan and the user confirms it in the app.",
+			Parameters: objectSchema([]string{"path"}, map[string]interface{}{
+				"path": stringProperty("Directory to organize"),
+				"mode": map[string]interface{}{"type": "string", "enum": []string{"category", "filename"}},
+			}),
+			Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				path := sm.fileSearch.ResolvePath(stringArg(args, "path"))
+				mode := "category"
+				if stringArg(args, "mode") == "filename" {
+					mode = "filename"
+				}
+				return sm.requestToolConfirmation("organizer", "organize "+escapePath(path, 0, false)+" by "+mode)
+			},
+		},
+		Tool{
+			Name:        "format_code",
+			Description: "Format a source file (Python, Go, shell, JS/TS) in place. Requires the user's confirmation, which this tool requests.",
+			Parameters: objectSchema([]string{"path"}, map[string]interface{}{
+				"path": stringProperty("Source file to format"),
+			}),
+			Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				path := sm.fileSearch.ResolvePath(stringArg(args, "path"))
+				return sm.requestToolConfirmation("linter", "format "+escapePath(path, 0, false))
+			},
+		},
+		Tool{
+			Name:        "convert_media",
+			Description: "Convert a media file to another format with ffmpeg. Requires the user's confirmation, which this tool requests.",
+			Parameters: objectSchema([]string{"path", "format"}, map[string]interface{}{
+				"path":   stringProperty("Media file to convert"),
+				"format": stringProperty("Target format such as mp4, mp3, webm or png"),
+			}),
+			Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				path := sm.fileSearch.ResolvePath(stringArg(args, "path"))
+				format := strings.TrimPrefix(strings.ToLower(stringArg(args, "format")), ".")
+				return sm.requestToolConfirmation("converter", "convert "+escapePath(path, 0, false)+" to "+format)
+			},
+		},
+		Tool{
+			Name:        "ocr_image",
+			Description: "Extract text from an image file the user named, or one in their KaguyaDots configs, with tesseract.",
+			Parameters: objectSchema([]string{"path"}, map[string]interface{}{
+				"path": stringProperty("Image file to read"),
+			}),
+			Run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				path := sm.fileSearch.ResolvePath(stringArg(args, "path"))
+				// The text goes to the LLM provider like read_file's, so
+				// only images the user named or in the configs are read
+				if !sm.dotfiles.Contains(path) && !namesPath(sm.paths.Paths(toolQuery(ctx)), path) {
+					return nil, fmt.Errorf("%s is not named in the query or in the KaguyaDots config directories", path)
+				}
+				return sm.ocr.ExtractTextFromFile(ctx, path)
+			},
+		},
+	)
+}
+
+// requestToolConfirmation turns a mutating tool call into a pending
+// confirmation that the UI can offer to the user. The service comes from
+// the tool, not from classifying the query, so a path containing "sort"
+// or "find" can't turn a format into something else.
+func (sm *ServiceManager) requestToolConfirmation(service, query string) (interface{}, error) {
+	intent := Intent{ServiceName: service, Confidence: 1, Params: map[string]string{"query": query}}
+	plan := sm.PlanAction(intent, query)
+	if !plan.Mutating {
+		return nil, fmt.Errorf("could not plan %q", query)
+	}
+
+	request := sm.safety.RequestConfirmation(plan, query)
+	return map[string]interface{}{
+		"status":       "needs_confirmation",
+		"description":  plan.Description,
+		"confirmation": request,
+		"message":      "Not run yet. Tell the user what will happen; they confirm it in the app.",
+	}, nil
+}
+
+type toolFile struct {
+	Path      string `json:"path"`
+	Content   string `json:"content"`
+	Truncated bool   `json:"truncated"`
+	Size      int64  `json:"size"`
+}
+
+func readFileForTool(path string) (toolFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return toolFile{}, err
+	}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return toolFile{}, err
+		}
+		var names []string
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() {
+				name += "/"
+			}
+			names = append(names, name)
+		}
+		return toolFile{Path: path, Content: strings.Join(names, "\n"), Size: info.Size()}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return toolFile{}, err
+	}
+	defer file.Close()
+
+	buf := make([]byte, maxToolReadBytes)
+	n, _ := file.Read(buf)
+	content := buf[:n]
+	if !utf8.Valid(content) && n < maxToolReadBytes {
+		return toolFile{}, fmt.Errorf("%s is not a text file", path)
+	}
+
+	return toolFile{
+		Path:      path,
+		Content:   strings.ToValidUTF8(string(content), ""),
+		Truncated: info.Size() > int64(n),
+		Size:      info.Size(),
+	}, nil
+}
@@ -0,0 +1,331 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenUsage is the token count and estimated cost of one query
+type TokenUsage struct {
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	Cost             float64 `json:"cost"`
+}
+
+func (u *TokenUsage) add(prompt, completion int) {
+	u.PromptTokens += prompt
+	u.CompletionTokens += completion
+}
+
+// UsageRecord aggregates the usage of one model on one day
+type UsageRecord struct {
+	Date             string  `json:"date"` // YYYY-MM-DD, local time
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model"`
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// UsageSummary reports a month of usage against the budget
+type UsageSummary struct {
+	Month      string             `json:"month"` // YYYY-MM
+	Records    []UsageRecord      `json:"records"`
+	ByProvider map[string]float64 `json:"byProvider"`
+	TotalCost  float64            `json:"totalCost"`
+	Budget     float64            `json:"budget"` // 0 when no budget is set
+	Action     string             `json:"action"`
+	Exceeded   bool               `json:"exceeded"`
+	Message    string             `json:"message"`
+	Unpriced   []string           `json:"unpriced,omitempty"` // models charged at the highest known price
+}
+
+// ModelPrice is the USD price per million tokens
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Budget actions once the monthly budget is used up
+const (
+	BudgetBlock     = "block"
+	BudgetDowngrade = "downgrade"
+)
+
+// defaultPrices are list prices in USD per million tokens; override them
+// in the [aoiler.prices] table of kaguyadots.toml
+var defaultPrices = map[string]ModelPrice{
+	"gpt-4o":                     {Input: 2.50, Output: 10.00},
+	"gpt-4o-mini":                {Input: 0.15, Output: 0.60},
+	"claude-3-5-sonnet-20241022": {Input: 3.00, Output: 15.00},
+	"claude-3-5-haiku-20241022":  {Input: 0.80, Output: 4.00},
+	"gemini-1.5-pro":             {Input: 1.25, Output: 5.00},
+	"gemini-1.5-flash":           {Input: 0.075, Output: 0.30},
+	"gemini-1.5-flash-8b":        {Input: 0.0375, Output: 0.15},
+}
+
+// defaultDowngrades are the cheaper models used once the budget is exceeded
+var defaultDowngrades = map[LLMProvider]string{
+	ProviderOpenAI: "gpt-4o-mini",
+	ProviderClaude: "claude-3-5-haiku-20241022",
+	ProviderGemini: "gemini-1.5-flash-8b",
+}
+
+// usageRetention is how many months of records the ledger keeps
+const usageRetention = 12
+
+// UsageLedger keeps per-day, per-provider token usage on disk
+type UsageLedger struct {
+	mu      sync.Mutex
+	path    string
+	records []UsageRecord
+}
+
+func NewUsageLedger() *UsageLedger {
+	ledger := &UsageLedger{path: filepath.Join(dataDir("usage"), "ledger.json")}
+	if data, err := os.ReadFile(ledger.path); err == nil {
+		json.Unmarshal(data, &ledger.records)
+	}
+	return ledger
+}
+
+// Record adds a query's usage to today's entry for the model
+func (l *UsageLedger) Record(provider, model string, usage TokenUsage) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	today := time.Now().Format("2006-01-02")
+	index := -1
+	for i, record := range l.records {
+		if record.Date == today && record.Provider == provider && record.Model == model {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		l.records = append(l.records, UsageRecord{Date: today, Provider: provider, Model: model})
+		index = len(l.records) - 1
+	}
+
+	record := &l.records[index]
+	record.Requests++
+	record.PromptTokens += usage.PromptTokens
+	record.CompletionTokens += usage.CompletionTokens
+	record.Cost += usage.Cost
+	l.prune(time.Now())
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("failed to create usage directory: %w", err)
+	}
+	data, err := json.MarshalIndent(l.records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(l.path, data, 0600)
+}
+
+// prune drops the records of months older than usageRetention
+func (l *UsageLedger) prune(now time.Time) {
+	y, m, _ := now.Date()
+	cutoff := time.Date(y, m-usageRetention, 1, 0, 0, 0, 0, now.Location()).Format("2006-01-02")
+
+	kept := l.records[:0]
+	for _, record := range l.records {
+		if record.Date >= cutoff {
+			kept = append(kept, record)
+		}
+	}
+	l.records = kept
+}
+
+// Month returns the records of a month (YYYY-MM), oldest first
+func (l *UsageLedger) Month(month string) []UsageRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records := []UsageRecord{}
+	for _, record := range l.records {
+		if strings.HasPrefix(record.Date, month+"-") {
+			records = append(records, record)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Date < records[j].Date
+	})
+	return records
+}
+
+// MonthCost returns the estimated spend of a month (YYYY-MM)
+func (l *UsageLedger) MonthCost(month string) float64 {
+	total := 0.0
+	for _, record := range l.Month(month) {
+		total += record.Cost
+	}
+	return total
+}
+
+// BudgetConfig is read from the [aoiler] table of kaguyadots.toml:
+//
+//	[aoiler]
+//	monthly_budget = 5.00        # USD, 0 or unset disables the budget
+//	budget_action = "downgrade"  # or "block"
+//
+//	[aoiler.prices]
+//	"gpt-4o-mini" = "0.15/0.60"  # USD per million input/output tokens
+//
+//	[aoiler.downgrade]
+//	openai = "gpt-4o-mini"       # model used per provider over budget
+type BudgetConfig struct {
+	MonthlyBudget float64
+	Action        string
+	Prices        map[string]ModelPrice
+	Downgrades    map[LLMProvider]string
+}
+
+// ReadBudgetConfig loads the budget settings, falling back to the defaults
+func ReadBudgetConfig() BudgetConfig {
+	config := BudgetConfig{
+		Action:     BudgetDowngrade,
+		Prices:     make(map[string]ModelPrice),
+		Downgrades: make(map[LLMProvider]string),
+	}
+	for model, price := range defaultPrices {
+		config.Prices[model] = price
+	}
+	for provider, model := range defaultDowngrades {
+		config.Downgrades[provider] = model
+	}
+
+	table := readKaguyaTable("aoiler")
+	if budget, err := strconv.ParseFloat(table["monthly_budget"], 64); err == nil && budget > 0 {
+		config.MonthlyBudget = budget
+	}
+	if table["budget_action"] == BudgetBlock {
+		config.Action = BudgetBlock
+	}
+
+	for model, value := range readKaguyaTable("aoiler.prices") {
+		parts := strings.SplitN(value, "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		input, errIn := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		output, errOut := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if errIn == nil && errOut == nil {
+			config.Prices[model] = ModelPrice{Input: input, Output: output}
+		}
+	}
+
+	for provider, model := range readKaguyaTable("aoiler.downgrade") {
+		if model != "" {
+			config.Downgrades[LLMProvider(provider)] = model
+		}
+	}
+	return config
+}
+
+// Cost estimates the USD cost of a usage with this config's prices. A
+// model without a price is charged at the highest known rates, so it
+// can't slip past the budget.
+func (c BudgetConfig) Cost(model string, usage TokenUsage) float64 {
+	price, ok := c.Prices[model]
+	if !ok {
+		price = c.highestPrice()
+	}
+	cost := (float64(usage.PromptTokens)*price.Input + float64(usage.CompletionTokens)*price.Output) / 1e6
+	return math.Round(cost*1e6) / 1e6
+}
+
+func (c BudgetConfig) highestPrice() ModelPrice {
+	var highest ModelPrice
+	for _, price := range c.Prices {
+		highest.Input = max(highest.Input, price.Input)
+		highest.Output = max(highest.Output, price.Output)
+	}
+	return highest
+}
+
+// Apply picks the model for the next call given the month's spend so far,
+// reporting whether the call is blocked instead
+func (c BudgetConfig) Apply(provider LLMProvider, model string, spent float64) (string, bool) {
+	if c.MonthlyBudget <= 0 || spent < c.MonthlyBudget {
+		return model, false
+	}
+	if c.Action == BudgetBlock {
+		return model, true
+	}
+	if cheaper := c.Downgrades[provider]; cheaper != "" {
+		return cheaper, false
+	}
+	return model, false
+}
+
+// Summary reports a month's usage and budget state
+func (l *UsageLedger) Summary(month string, config BudgetConfig) UsageSummary {
+	summary := UsageSummary{
+		Month:      month,
+		Records:    l.Month(month),
+		ByProvider: make(map[string]float64),
+		Budget:     config.MonthlyBudget,
+		Action:     config.Action,
+	}
+	for _, record := range summary.Records {
+		summary.ByProvider[record.Provider] += record.Cost
+		summary.TotalCost += record.Cost
+		if _, ok := config.Prices[record.Model]; !ok && !slices.Contains(summary.Unpriced, record.Model) {
+			summary.Unpriced = append(summary.Unpriced, record.Model)
+		}
+	}
+	summary.Exceeded = config.MonthlyBudget > 0 && summary.TotalCost >= config.MonthlyBudget
+	summary.Message = FormatUsage(summary)
+	return summary
+}
+
+// FormatUsage renders a summary as a chat reply
+func FormatUsage(summary UsageSummary) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "LLM usage for %s: $%.4f", summary.Month, summary.TotalCost)
+	if summary.Budget > 0 {
+		fmt.Fprintf(&sb, " of $%.2f budget", summary.Budget)
+		if summary.Exceeded {
+			fmt.Fprintf(&sb, " (exceeded, queries are %s)", map[string]string{
+				BudgetBlock:     "blocked",
+				BudgetDowngrade: "using cheaper models",
+			}[summary.Action])
+		}
+	}
+	sb.WriteString("\n")
+
+	providers := make([]string, 0, len(summary.ByProvider))
+	for provider := range summary.ByProvider {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	for _, provider := range providers {
+		prompt, completion := 0, 0
+		for _, record := range summary.Records {
+			if record.Provider == provider {
+				prompt += record.PromptTokens
+				completion += record.CompletionTokens
+			}
+		}
+		fmt.Fprintf(&sb, "\n%s: $%.4f (%d prompt / %d completion tokens)", provider, summary.ByProvider[provider], prompt, completion)
+	}
+	if len(providers) == 0 {
+		sb.WriteString("\nNo queries recorded this month.")
+	}
+	if len(summary.Unpriced) > 0 {
+		fmt.Fprintf(&sb, "\n\nNo price known for %s; counted at the most expensive known rate. Set it in [aoiler.prices] of kaguyadots.toml.", strings.Join(summary.Unpriced, ", "))
+	}
+	return sb.String()
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBudgetCost(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config := ReadBudgetConfig()
+	usage := TokenUsage{PromptTokens: 1000, CompletionTokens: 500}
+
+	if got := config.Cost("gpt-4o-mini", usage); got != 0.00045 {
+		t.Errorf("gpt-4o-mini = %v, want 0.00045", got)
+	}
+	// Unknown models pay the highest input and output rates known
+	if got := config.Cost("gpt-5-preview", usage); got != 0.0105 {
+		t.Errorf("unknown model = %v, want 0.0105", got)
+	}
+
+	path := kaguyaConfigPath()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, []byte("[aoiler]\nmonthly_budget = 2.5\nbudget_action = \"block\"\n\n[aoiler.prices]\n\"gpt-5-preview\" = \"1/2\"\n\"broken\" = \"cheap\"\n"), 0644)
+	config = ReadBudgetConfig()
+	if config.MonthlyBudget != 2.5 || config.Action != BudgetBlock {
+		t.Errorf("config = %+v", config)
+	}
+	if got := config.Cost("gpt-5-preview", usage); got != 0.002 {
+		t.Errorf("configured price = %v, want 0.002", got)
+	}
+	if _, ok := config.Prices["broken"]; ok {
+		t.Error("unparsable price kept")
+	}
+}
+
+func TestBudgetApply(t *testing.T) {
+	config := BudgetConfig{MonthlyBudget: 5, Action: BudgetDowngrade, Downgrades: map[LLMProvider]string{ProviderOpenAI: "gpt-4o-mini"}}
+
+	tests := []struct {
+		action   string
+		provider LLMProvider
+		spent    float64
+		model    string
+		blocked  bool
+	}{
+		{BudgetDowngrade, ProviderOpenAI, 4.99, "gpt-4o", false},
+		{BudgetDowngrade, ProviderOpenAI, 5, "gpt-4o-mini", false},
+		{BudgetDowngrade, ProviderGemini, 6, "gpt-4o", false}, // no downgrade set
+		{BudgetBlock, ProviderOpenAI, 4, "gpt-4o", false},
+		{BudgetBlock, ProviderOpenAI, 5, "gpt-4o", true},
+	}
+	for _, tt := range tests {
+		config.Action = tt.action
+		model, blocked := config.Apply(tt.provider, "gpt-4o", tt.spent)
+		if model != tt.model || blocked != tt.blocked {
+			t.Errorf("%s %s at $%v = %s, %v; want %s, %v", tt.action, tt.provider, tt.spent, model, blocked, tt.model, tt.blocked)
+		}
+	}
+
+	if model, blocked := (BudgetConfig{Action: BudgetBlock}).Apply(ProviderOpenAI, "gpt-4o", 100); model != "gpt-4o" || blocked {
+		t.Error("no budget set still applied")
+	}
+}
+
+func TestUsageLedger(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	ledger := NewUsageLedger()
+	now := time.Now()
+	month := now.Format("2006-01")
+
+	// Records older than the retention are pruned on the next write
+	ledger.records = []UsageRecord{
+		{Date: now.AddDate(-2, 0, 0).Format("2006-01-02"), Provider: "openai", Model: "gpt-4o", Cost: 9},
+		{Date: month + "-01", Provider: "claude", Model: "claude-next", Requests: 1, Cost: 1},
+	}
+	ledger.Record("openai", "gpt-4o", TokenUsage{PromptTokens: 100, CompletionTokens: 10, Cost: 0.5})
+	ledger.Record("openai", "gpt-4o", TokenUsage{PromptTokens: 100, CompletionTokens: 10, Cost: 0.5})
+
+	if len(ledger.records) != 2 {
+		t.Fatalf("records = %+v, want the old one pruned", ledger.records)
+	}
+	info, err := os.Stat(ledger.path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("ledger mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+	if reloaded := NewUsageLedger(); !reflect.DeepEqual(reloaded.records, ledger.records) {
+		t.Errorf("reloaded = %+v", reloaded.records)
+	}
+
+	summary := ledger.Summary(month, BudgetConfig{MonthlyBudget: 2, Action: BudgetBlock, Prices: defaultPrices})
+	if summary.TotalCost != 2 || !summary.Exceeded || summary.ByProvider["openai"] != 1 {
+		t.Errorf("summary = %+v", summary)
+	}
+	if !reflect.DeepEqual(summary.Unpriced, []string{"claude-next"}) || !strings.Contains(summary.Message, "No price known for claude-next") {
+		t.Errorf("unpriced = %v, message %q", summary.Unpriced, summary.Message)
+	}
+	if !strings.Contains(summary.Message, "exceeded, queries are blocked") {
+		t.Errorf("message = %q", summary.Message)
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"kaguyadots/runner"
+)
+
+// ImageInput is an image attached to an LLM query
+type ImageInput struct {
+	Path     string
+	MimeType string
+	Data     []byte
+}
+
+// maxImageBytes is the smallest per-image limit of the supported providers.
+// It applies to the base64 payload, which is a third larger than the file.
+const maxImageBytes = 5 * 1024 * 1024
+
+// LoadImage reads an image file for a vision query
+func LoadImage(path string) (ImageInput, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ImageInput{}, fmt.Errorf("image not found: %s", path)
+	}
+	if encoded := base64.StdEncoding.EncodedLen(int(info.Size())); encoded > maxImageBytes {
+		return ImageInput{}, fmt.Errorf("%s is too large to send (%.1f MB once encoded, limit 5 MB)", filepath.Base(path), float64(encoded)/(1024*1024))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ImageInput{}, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	mimeType := http.DetectContentType(data)
+	switch mimeType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+	default:
+		return ImageInput{}, fmt.Errorf("%s is not a PNG, JPEG, GIF or WebP image", filepath.Base(path))
+	}
+
+	return ImageInput{Path: path, MimeType: mimeType, Data: data}, nil
+}
+
+func (image ImageInput) base64() string {
+	return base64.StdEncoding.EncodeToString(image.Data)
+}
+
+func openAIParts(query string, images []ImageInput) []OpenAIContentPart {
+	if len(images) == 0 {
+		return nil
+	}
+	parts := []OpenAIContentPart{{Type: "text", Text: query}}
+	for _, image := range images {
+		parts = append(parts, OpenAIContentPart{
+			Type:     "image_url",
+			ImageURL: &OpenAIImageURL{URL: "data:" + image.MimeType + ";base64," + image.base64()},
+		})
+	}
+	return parts
+}
+
+func claudeUserContent(query string, images []ImageInput) interface{} {
+	if len(images) == 0 {
+		return query
+	}
+	var blocks []ClaudeContentBlock
+	for _, image := range images {
+		blocks = append(blocks, ClaudeContentBlock{
+			Type:   "image",
+			Source: &ClaudeImageSource{Type: "base64", MediaType: image.MimeType, Data: image.base64()},
+		})
+	}
+	return append(blocks, ClaudeContentBlock{Type: "text", Text: query})
+}
+
+func geminiParts(query string, images []ImageInput) []GeminiPart {
+	parts := []GeminiPart{{Text: query}}
+	for _, image := range images {
+		parts = append(parts, GeminiPart{
+			InlineData: &GeminiInlineData{MimeType: image.MimeType, Data: image.base64()},
+		})
+	}
+	return parts
+}
+
+// VisionService answers questions about a screen region or image files
+type VisionService struct {
+	runner     runner.Runner
+	llm        *LLMService
+	fileSearch *FileSearchService
+}
+
+func NewVisionService(r runner.Runner, llm *LLMService, fileSearch *FileSearchService) *VisionService {
+	return &VisionService{runner: r, llm: llm, fileSearch: fileSearch}
+}
+
+// Ask sends the question with the images named in it, or with a freshly
+// captured screen region when it names none
+func (vs *VisionService) Ask(ctx context.Context, query string, opts QueryOptions) (LLMResult, error) {
+	paths := vs.imagePaths(query)
+
+	if len(paths) == 0 {
+		capture, err := vs.CaptureRegion(ctx)
+		if err != nil {
+			return LLMResult{Success: false}, err
+		}
+		defer os.Remove(capture)
+
+		// The capture is deleted afterwards, so don't report its path
+		result, err := vs.askAbout(ctx, query, []string{capture}, opts)
+		result.Images = nil
+		return result, err
+	}
+
+	return vs.askAbout(ctx, query, paths, opts)
+}
+
+func (vs *VisionService) askAbout(ctx context.Context, query string, paths []string, opts QueryOptions) (LLMResult, error) {
+	var images []ImageInput
+	for _, path := range paths {
+		image, err := LoadImage(path)
+		if err != nil {
+			return LLMResult{Success: false}, err
+		}
+		images = append(images, image)
+	}
+	return vs.llm.QueryWithImages(ctx, query, images, opts)
+}
+
+// imagePaths returns the image files mentioned in a query
+func (vs *VisionService) imagePaths(query string) []string {
+	var paths []string
+	for _, tok := range tokenizeQuery(query) {
+		if !looksLikePath(tok.Value) && tok.Quote == 0 {
+			continue
+		}
+		if !imageExtensions[strings.ToLower(filepath.Ext(tok.Value))] {
+			continue
+		}
+		path := vs.fileSearch.ResolvePath(tok.Value)
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// mentionsImage reports whether a query names an image file
+func mentionsImage(query string) bool {
+	for _, tok := range tokenizeQuery(query) {
+		if (looksLikePath(tok.Value) || tok.Quote != 0) && imageExtensions[strings.ToLower(filepath.Ext(tok.Value))] {
+			return true
+		}
+	}
+	return false
+}
+
+// CaptureRegion lets the user select a screen region with slurp and saves it with grim
+func (vs *VisionService) CaptureRegion(ctx context.Context) (string, error) {
+	geometry, err := vs.runner.Output(ctx, runner.Cmd("slurp"))
+	if err != nil {
+		return "", fmt.Errorf("region selection cancelled or slurp unavailable")
+	}
+
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("aoiler-vision-%d.png", time.Now().UnixNano()))
+	if output, err := vs.runner.CombinedOutput(ctx, runner.Cmd("grim", "-g", strings.TrimSpace(string(geometry)), path)); err != nil {
+		return "", fmt.Errorf("screen capture failed: %s", strings.TrimSpace(string(output)))
+	}
+	return path, nil
+}
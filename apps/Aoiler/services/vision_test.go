@@ -0,0 +1,92 @@
+package services
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pngHeader is enough for http.DetectContentType to call a file a PNG
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+func writeImage(t *testing.T, dir, name string, size int) string {
+	t.Helper()
+	data := make([]byte, size)
+	copy(data, pngHeader)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadImage(t *testing.T) {
+	dir := t.TempDir()
+	// The largest file whose base64 still fits the limit
+	fits := maxImageBytes / 4 * 3
+	small := writeImage(t, dir, "small.png", fits)
+
+	image, err := LoadImage(small)
+	if err != nil || image.MimeType != "image/png" || len(image.Data) != fits {
+		t.Fatalf("LoadImage(small) = %s, %v", image.MimeType, err)
+	}
+	if encoded := len(image.base64()); encoded > maxImageBytes {
+		t.Errorf("accepted an image encoding to %d bytes", encoded)
+	}
+
+	// 4.5 MB is under the raw limit but over it once encoded
+	for _, size := range []int{fits + 1, 4608 * 1024} {
+		path := writeImage(t, dir, "large.png", size)
+		if _, err := LoadImage(path); err == nil || !strings.Contains(err.Error(), "too large") {
+			t.Errorf("LoadImage(%d bytes) = %v, want too large", size, err)
+		}
+	}
+
+	text := filepath.Join(dir, "notes.png")
+	os.WriteFile(text, []byte("not an image"), 0644)
+	if _, err := LoadImage(text); err == nil {
+		t.Error("LoadImage(text file): want not an image")
+	}
+	if _, err := LoadImage(filepath.Join(dir, "missing.png")); err == nil {
+		t.Error("LoadImage(missing): want not found")
+	}
+}
+
+func TestImageParts(t *testing.T) {
+	image := ImageInput{Path: "cat.png", MimeType: "image/png", Data: pngHeader}
+	encoded := base64.StdEncoding.EncodeToString(pngHeader)
+
+	openAI := openAIParts("what is this", []ImageInput{image})
+	if len(openAI) != 2 || openAI[0].Text != "what is this" || openAI[1].ImageURL.URL != "data:image/png;base64,"+encoded {
+		t.Errorf("openAIParts = %+v", openAI)
+	}
+	if openAIParts("hi", nil) != nil {
+		t.Error("openAIParts without images should leave the plain text message")
+	}
+
+	claude, ok := claudeUserContent("what is this", []ImageInput{image}).([]ClaudeContentBlock)
+	if !ok || len(claude) != 2 || claude[0].Source.Data != encoded || claude[1].Text != "what is this" {
+		t.Errorf("claudeUserContent = %+v", claude)
+	}
+
+	gemini := geminiParts("what is this", []ImageInput{image})
+	if len(gemini) != 2 || gemini[1].InlineData.Data != encoded {
+		t.Errorf("geminiParts = %+v", gemini)
+	}
+}
+
+func TestMentionsImage(t *testing.T) {
+	for query, want := range map[string]bool{
+		"describe ~/Pictures/cat.png":    true,
+		`what's in "holiday photo.JPG"`:  true,
+		"what is on my screen":           false,
+		"explain ~/notes.txt":            false,
+		"what does a png file look like": false,
+	} {
+		if got := mentionsImage(query); got != want {
+			t.Errorf("mentionsImage(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
@@ -4,18 +4,22 @@ import (
 	"context"
 	"fmt"
 	"os"
+
+	"kaguyadots/runner"
 )
 
 // App struct
 type App struct {
-	ctx  context.Context
-	args []string
+	ctx    context.Context
+	args   []string
+	runner runner.Runner
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
 	return &App{
-		args: os.Args[1:], // Capture command-line arguments (excluding program name)
+		args:   os.Args[1:], // Capture command-line arguments (excluding program name)
+		runner: runner.Default,
 	}
 }
 
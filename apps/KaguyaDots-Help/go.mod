@@ -5,6 +5,8 @@ go 1.24.0
 require (
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
 	github.com/wailsapp/wails/v2 v2.11.0
+	kaguyadots/filepicker v0.0.0
+	kaguyadots/runner v0.0.0
 )
 
 require (
@@ -37,4 +39,8 @@ require (
 	golang.org/x/text v0.33.0 // indirect
 )
 
+replace kaguyadots/filepicker => ../shared/filepicker
+
+replace kaguyadots/runner => ../shared/runner
+
 // replace github.com/wailsapp/wails/v2 v2.10.2 => /home/dawu/go/pkg/mod
@@ -2,14 +2,15 @@ package main
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"embed"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"kaguyadots/runner"
 )
 
 //go:embed all:frontend/dist
@@ -343,18 +344,13 @@ func (a *App) OpenConfigInNeovim() error {
 
 	// Try to get terminal from script
 	terminal := "kitty" // default fallback
-	cmdGetTerm := exec.Command("bash", scriptPath, "term")
-
-	var out bytes.Buffer
-	cmdGetTerm.Stdout = &out
-
-	if err := cmdGetTerm.Run(); err == nil {
-		if term := strings.TrimSpace(out.String()); term != "" {
+	if out, err := a.runner.Output(context.Background(), runner.Cmd("bash", scriptPath, "term")); err == nil {
+		if term := strings.TrimSpace(string(out)); term != "" {
 			terminal = term
 		}
 	}
 
 	// Launch Neovim in the detected terminal
-	cmd := exec.Command(terminal, "-e", "nvim", configFile)
-	return cmd.Start()
+	_, err = a.runner.Start(runner.Cmd(terminal, "-e", "nvim", configFile))
+	return err
 }
@@ -2,12 +2,14 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"kaguyadots/runner"
 )
 
 type HyprctlMonitor struct {
@@ -42,8 +44,7 @@ type MonitorConfig struct {
 
 // GetMonitors retrieves all connected monitors using hyprctl
 func (a *App) GetMonitors() ([]HyprctlMonitor, error) {
-	cmd := exec.Command("hyprctl", "monitors", "-j")
-	output, err := cmd.Output()
+	output, err := a.runner.Output(context.Background(), runner.Cmd("hyprctl", "monitors", "-j"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute hyprctl: %w", err)
 	}
@@ -98,8 +99,7 @@ func (a *App) SaveMonitorConfig(configs []MonitorConfig) error {
 
 // ReloadHyprland reloads the Hyprland configuration
 func (a *App) ReloadHyprland() error {
-	cmd := exec.Command("hyprctl", "reload")
-	if err := cmd.Run(); err != nil {
+	if err := a.runner.Run(context.Background(), runner.Cmd("hyprctl", "reload")); err != nil {
 		return fmt.Errorf("failed to reload Hyprland: %w", err)
 	}
 	return nil
@@ -208,8 +208,7 @@ func (a *App) TestMonitorConfig(config MonitorConfig) error {
 		config.Scale,
 	)
 
-	cmd := exec.Command("hyprctl", "keyword", "monitor", configStr)
-	if err := cmd.Run(); err != nil {
+	if err := a.runner.Run(context.Background(), runner.Cmd("hyprctl", "keyword", "monitor", configStr)); err != nil {
 		return fmt.Errorf("failed to test monitor config: %w", err)
 	}
 
@@ -9,15 +9,17 @@ import (
 	"image/jpeg"
 	_ "image/png"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/nfnt/resize"
+	"kaguyadots/filepicker"
+	"kaguyadots/runner"
 )
 
 type SystemInfo struct {
-	ctx context.Context
+	ctx    context.Context
+	runner runner.Runner
 }
 
 type SystemInfoData struct {
@@ -33,8 +35,8 @@ type SystemInfoData struct {
 	UserPfpBase64    string  `json:"userPfpBase64"`
 }
 
-func NewSystemInfo() *SystemInfo {
-	return &SystemInfo{}
+func NewSystemInfo(r runner.Runner) *SystemInfo {
+	return &SystemInfo{runner: r}
 }
 
 func (s *SystemInfo) GetSystemInfo() SystemInfoData {
@@ -54,7 +56,7 @@ func (s *SystemInfo) GetSystemInfo() SystemInfoData {
 }
 
 func (a *App) GetSystemInfo() SystemInfoData {
-	sysInfo := NewSystemInfo()
+	sysInfo := NewSystemInfo(a.runner)
 	return sysInfo.GetSystemInfo()
 }
 
@@ -71,7 +73,7 @@ func (s *SystemInfo) getOS() string {
 		}
 	}
 
-	out, err := exec.Command("uname", "-o").Output()
+	out, err := s.runner.Output(context.Background(), runner.Cmd("uname", "-o"))
 	if err != nil {
 		return "Unknown"
 	}
@@ -153,7 +155,7 @@ func (s *SystemInfo) getMemoryValues() (float64, float64) {
 }
 
 func (s *SystemInfo) getUptime() string {
-	out, err := exec.Command("uptime", "-p").Output()
+	out, err := s.runner.Output(context.Background(), runner.Cmd("uptime", "-p"))
 	if err != nil {
 		return "Unknown"
 	}
@@ -310,70 +312,18 @@ func (a *App) OpenFileDialog() (string, error) {
 		picturesDir = homeDir
 	}
 
-	var selectedPath string
-	var lastErr error
-
-	// 1. Try yad (Yet Another Dialog - common in Arch/Hyprland setups)
-	if yadPath, err := exec.LookPath("yad"); err == nil {
-		cmd := exec.Command(yadPath,
-			"--file",
-			"--title=Select Wallpaper",
-			"--filename="+picturesDir+"/",
-			"--file-filter=Images|*.jpg *.jpeg *.png *.webp *.JPG *.JPEG *.PNG *.WEBP",
-			"--file-filter=All files|*",
-		)
-		output, err := cmd.Output()
-		if err != nil {
-			// User likely cancelled
-			return "", fmt.Errorf("file selection cancelled")
-		}
-		selectedPath = strings.TrimSpace(string(output))
-		selectedPath = strings.TrimSuffix(selectedPath, "|")
-		lastErr = nil
-	} else if zenityPath, err := exec.LookPath("zenity"); err == nil {
-		// 2. Try zenity if yad not available
-		cmd := exec.Command(zenityPath,
-			"--file-selection",
-			"--title=Select Wallpaper",
-			"--filename="+picturesDir+"/",
-			"--file-filter=Images | *.jpg *.jpeg *.png *.webp *.JPG *.JPEG *.PNG *.WEBP",
-			"--file-filter=All files | *",
-		)
-		output, err := cmd.Output()
-		if err != nil {
-			// User likely cancelled
-			return "", fmt.Errorf("file selection cancelled")
-		}
-		selectedPath = strings.TrimSpace(string(output))
-		lastErr = nil
-	} else if kdialogPath, err := exec.LookPath("kdialog"); err == nil {
-		// 3. Try kdialog if others not available
-		cmd := exec.Command(kdialogPath,
-			"--getopenfilename",
-			picturesDir,
-			"*.jpg *.jpeg *.png *.webp *.JPG *.JPEG *.PNG *.WEBP|Image files",
-		)
-		output, err := cmd.Output()
-		if err != nil {
-			// User likely cancelled
-			return "", fmt.Errorf("file selection cancelled")
-		}
-		selectedPath = strings.TrimSpace(string(output))
-		lastErr = nil
-	} else {
-		// No dialog tool found
-		if thunarPath, err := exec.LookPath("thunar"); err == nil {
-			exec.Command(thunarPath, picturesDir).Start()
-		}
-		return "", fmt.Errorf("no file picker dialog found. Please install 'yad' or 'zenity': sudo pacman -S yad")
-	}
-
-	// If we got here but selectedPath is empty, user cancelled
-	if selectedPath == "" {
-		if lastErr != nil {
-			return "", lastErr
-		}
-		return "", fmt.Errorf("file selection cancelled")
+	selectedPath, err := filepicker.PickOne(a.ctx, filepicker.Options{
+		Title:    "Select Wallpaper",
+		StartDir: picturesDir,
+		Filters: []filepicker.Filter{
+			{Name: "Images", Patterns: []string{"*.jpg", "*.jpeg", "*.png", "*.webp", "*.JPG", "*.JPEG", "*.PNG", "*.WEBP"}},
+			{Name: "All files", Patterns: []string{"*"}},
+		},
+		Fallback: filepicker.WailsFallback(a.ctx),
+		Runner:   a.runner,
+	})
+	if err != nil {
+		return "", err
 	}
 
 	// Validate it's an image file
@@ -402,10 +352,10 @@ func (a *App) SetWallpaper(wallpaperPath string) error {
 	}
 
 	// Use waypaper with hyprland backend and the wallpaper path
-	cmd := exec.Command("waypaper", "--wallpaper", wallpaperPath)
+	cmd := runner.Cmd("waypaper", "--wallpaper", wallpaperPath)
 
 	// Capture output for debugging
-	output, err := cmd.CombinedOutput()
+	output, err := a.runner.CombinedOutput(context.Background(), cmd)
 	if err != nil {
 		return fmt.Errorf("failed to set wallpaper: %v (output: %s)", err, string(output))
 	}
@@ -494,6 +444,6 @@ func (a *App) SetLockscreenWallpaper(wallpaperPath string) error {
 
 // LaunchWaypaper launches waypaper GUI (kept for backwards compatibility)
 func (a *App) LaunchWaypaper() error {
-	cmd := exec.Command("waypaper")
-	return cmd.Start()
+	_, err := a.runner.Start(runner.Cmd("waypaper"))
+	return err
 }
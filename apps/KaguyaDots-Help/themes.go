@@ -2,13 +2,15 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
+
+	"kaguyadots/runner"
 )
 
 type ThemeConfig struct {
@@ -153,7 +155,7 @@ func (a *App) ApplyTheme(themeName string) error {
 	}
 
 	// Reload SwayNC
-	if err := reloadSwayNC(); err != nil {
+	if err := a.reloadSwayNC(); err != nil {
 		fmt.Printf("Warning: failed to reload swaync: %v\n", err)
 	}
 
@@ -161,15 +163,13 @@ func (a *App) ApplyTheme(themeName string) error {
 }
 
 // reloadSwayNC reloads swaync to apply theme changes
-func reloadSwayNC() error {
-	cmd := exec.Command("swaync-client", "-rs")
-	return cmd.Run()
+func (a *App) reloadSwayNC() error {
+	return a.runner.Run(context.Background(), runner.Cmd("swaync-client", "-rs"))
 }
 
 // ReloadWaybar reloads waybar to apply theme changes
 func (a *App) ReloadWaybar() error {
-	cmd := exec.Command("pkill", "-SIGUSR2", "waybar")
-	return cmd.Run()
+	return a.runner.Run(context.Background(), runner.Cmd("pkill", "-SIGUSR2", "waybar"))
 }
 
 // generateFullColorSet creates all derived colors from base colors
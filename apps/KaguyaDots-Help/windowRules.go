@@ -1,14 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"kaguyadots/runner"
 )
 
 // HyprlandClient represents a window from hyprctl clients -j
@@ -47,8 +48,7 @@ type WindowRule struct {
 
 // GetOpenWindows retrieves all open windows from Hyprland
 func (a *App) GetOpenWindows() ([]HyprlandClient, error) {
-	cmd := exec.Command("hyprctl", "clients", "-j")
-	output, err := cmd.Output()
+	output, err := a.runner.Output(context.Background(), runner.Cmd("hyprctl", "clients", "-j"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute hyprctl: %v", err)
 	}
@@ -138,8 +138,7 @@ func (a *App) SaveWindowRule(ruleStr string) error {
 	}
 
 	// Reload Hyprland config
-	cmd := exec.Command("hyprctl", "reload")
-	if err := cmd.Run(); err != nil {
+	if err := a.runner.Run(context.Background(), runner.Cmd("hyprctl", "reload")); err != nil {
 		return fmt.Errorf("failed to reload hyprland config: %v", err)
 	}
 
@@ -226,8 +225,7 @@ func (a *App) RemoveWindowRule(ruleStr string) error {
 	}
 
 	// Reload Hyprland config
-	cmd := exec.Command("hyprctl", "reload")
-	if err := cmd.Run(); err != nil {
+	if err := a.runner.Run(context.Background(), runner.Cmd("hyprctl", "reload")); err != nil {
 		return fmt.Errorf("failed to reload hyprland config: %v", err)
 	}
 
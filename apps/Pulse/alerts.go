@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAlertCooldown = 10 * time.Minute
+	// minHysteresis is how far a metric must come back past the threshold
+	// before a rule re-arms when the rule sets no clear level; larger
+	// thresholds use 5% of themselves
+	minHysteresis = 2
+)
+
+// AlertRule is one line of the [pulse.alerts] table of kaguyadots.toml:
+//
+//	[pulse.alerts]
+//	"CPU temperature" = "temp > 90 for 30s"
+//	"Battery" = "battery < 10 clear 15 cooldown 30m"
+//
+// The metric is any of the history metrics (see GetHistory).
+type AlertRule struct {
+	Name      string
+	Metric    string
+	Below     bool // fires under the threshold instead of over it
+	Threshold float64
+	Clear     float64       // the level that re-arms the rule
+	For       time.Duration // how long the threshold must be crossed
+	Cooldown  time.Duration // the least time between two alerts
+}
+
+// parseAlertRule reads "<metric> <op> <threshold> [for <duration>]
+// [clear <level>] [cooldown <duration>]" where op is > or <. The
+// threshold may carry a % or °C for readability.
+func parseAlertRule(name, spec string) (AlertRule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) < 3 || len(fields)%2 == 0 {
+		return AlertRule{}, fmt.Errorf("alert %q: want \"<metric> <op> <threshold>\", got %q", name, spec)
+	}
+
+	rule := AlertRule{Name: name, Metric: fields[0], Cooldown: defaultAlertCooldown}
+	switch fields[1] {
+	case ">":
+	case "<":
+		rule.Below = true
+	default:
+		return AlertRule{}, fmt.Errorf("alert %q: unknown comparison %q", name, fields[1])
+	}
+	threshold, err := alertLevel(fields[2])
+	if err != nil {
+		return AlertRule{}, fmt.Errorf("alert %q: %w", name, err)
+	}
+	rule.Threshold = threshold
+
+	hysteresis := max(minHysteresis, math.Abs(threshold)*0.05)
+	rule.Clear = threshold - hysteresis
+	if rule.Below {
+		rule.Clear = threshold + hysteresis
+	}
+
+	for i := 3; i < len(fields); i += 2 {
+		option, value := fields[i], fields[i+1]
+		switch option {
+		case "for", "cooldown":
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return AlertRule{}, fmt.Errorf("alert %q: bad %s duration %q", name, option, value)
+			}
+			if option == "for" {
+				rule.For = d
+			} else {
+				rule.Cooldown = d
+			}
+		case "clear":
+			if rule.Clear, err = alertLevel(value); err != nil {
+				return AlertRule{}, fmt.Errorf("alert %q: %w", name, err)
+			}
+		default:
+			return AlertRule{}, fmt.Errorf("alert %q: unknown option %q", name, option)
+		}
+	}
+
+	if (rule.Below && rule.Clear < rule.Threshold) || (!rule.Below && rule.Clear > rule.Threshold) {
+		return AlertRule{}, fmt.Errorf("alert %q: clear level %g is past the threshold", name, rule.Clear)
+	}
+	return rule, nil
+}
+
+func alertLevel(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSuffix(s, "%"), "°C"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad level %q", s)
+	}
+	return v, nil
+}
+
+// ReadAlertRules loads the [pulse.alerts] table sorted by name. Rules that
+// don't parse are skipped and returned as errors so they can be reported.
+func ReadAlertRules() ([]AlertRule, []error) {
+	var rules []AlertRule
+	var errs []error
+	for name, spec := range readKaguyaTable("pulse.alerts") {
+		rule, err := parseAlertRule(name, spec)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return rules, errs
+}
+
+func (r AlertRule) crossed(value float64) bool {
+	if r.Below {
+		return value < r.Threshold
+	}
+	return value > r.Threshold
+}
+
+func (r AlertRule) cleared(value float64) bool {
+	if r.Below {
+		return value >= r.Clear
+	}
+	return value <= r.Clear
+}
+
+// Alert is a rule starting or stopping to fire
+type Alert struct {
+	Rule      string  `json:"rule"`
+	Metric    string  `json:"metric"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Below     bool    `json:"below"`
+	Firing    bool    `json:"firing"` // false once the metric is back past the clear level
+	Time      int64   `json:"time"`   // Unix milliseconds
+}
+
+// Alerter runs the rules against every sample. A rule fires once its
+// metric has stayed past the threshold for the rule's duration, stays
+// firing until the metric comes back past the clear level, and doesn't
+// alert again within its cooldown.
+type Alerter struct {
+	mu     sync.Mutex
+	rules  []AlertRule
+	states []alertState
+}
+
+type alertState struct {
+	crossedAt time.Time // when the threshold was crossed; zero when it isn't
+	firing    bool
+	alerted   bool // the current firing was delivered, not held back by the cooldown
+	lastAlert time.Time
+	alert     Alert
+}
+
+func NewAlerter(rules []AlertRule) *Alerter {
+	return &Alerter{rules: rules, states: make([]alertState, len(rules))}
+}
+
+// Evaluate takes one sample's metrics and returns the alerts to deliver.
+// Rules whose metric is missing (an unmounted disk) keep their state.
+func (a *Alerter) Evaluate(metrics map[string]float64, now time.Time) []Alert {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var alerts []Alert
+	for i, rule := range a.rules {
+		value, ok := metrics[rule.Metric]
+		if !ok {
+			continue
+		}
+		state := &a.states[i]
+		state.alert.Value = value
+
+		switch {
+		case state.firing:
+			if !rule.cleared(value) {
+				continue
+			}
+			state.firing, state.crossedAt = false, time.Time{}
+			if state.alerted {
+				state.alerted = false
+				alerts = append(alerts, rule.alert(value, false, now))
+			}
+		case rule.crossed(value):
+			if state.crossedAt.IsZero() {
+				state.crossedAt = now
+			}
+			if now.Sub(state.crossedAt) < rule.For {
+				continue
+			}
+			state.firing = true
+			if state.lastAlert.IsZero() || now.Sub(state.lastAlert) >= rule.Cooldown {
+				state.alerted, state.lastAlert = true, now
+				state.alert = rule.alert(value, true, now)
+				alerts = append(alerts, state.alert)
+			}
+		default:
+			state.crossedAt = time.Time{}
+		}
+	}
+	return alerts
+}
+
+// Active returns the alerts firing now, with their latest values
+func (a *Alerter) Active() []Alert {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	alerts := []Alert{}
+	for _, state := range a.states {
+		if state.firing && state.alerted {
+			alerts = append(alerts, state.alert)
+		}
+	}
+	return alerts
+}
+
+func (r AlertRule) alert(value float64, firing bool, now time.Time) Alert {
+	return Alert{
+		Rule:      r.Name,
+		Metric:    r.Metric,
+		Value:     value,
+		Threshold: r.Threshold,
+		Below:     r.Below,
+		Firing:    firing,
+		Time:      now.UnixMilli(),
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAlertRule(t *testing.T) {
+	tests := []struct {
+		spec string
+		want AlertRule
+	}{
+		{"temp > 90 for 30s", AlertRule{Metric: "temp", Threshold: 90, Clear: 85.5, For: 30 * time.Second, Cooldown: 10 * time.Minute}},
+		{"mount./ > 95%", AlertRule{Metric: "mount./", Threshold: 95, Clear: 90.25, Cooldown: 10 * time.Minute}},
+		// Small thresholds still get some hysteresis
+		{"battery < 10 cooldown 30m", AlertRule{Metric: "battery", Below: true, Threshold: 10, Clear: 12, Cooldown: 30 * time.Minute}},
+		{"sensor.nvme.temp1 > 70°C clear 60 cooldown 0s", AlertRule{Metric: "sensor.nvme.temp1", Threshold: 70, Clear: 60}},
+	}
+	for _, tt := range tests {
+		tt.want.Name = "rule"
+		got, err := parseAlertRule("rule", tt.spec)
+		if err != nil || got != tt.want {
+			t.Errorf("parseAlertRule(%q) = %+v, %v, want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+
+	for _, spec := range []string{
+		"temp",
+		"temp >= 90",
+		"temp > hot",
+		"temp > 90 for",
+		"temp > 90 for soon",
+		"temp > 90 until 30s",
+		"temp > 90 clear 95",
+		"battery < 10 clear 5",
+	} {
+		if _, err := parseAlertRule("rule", spec); err == nil {
+			t.Errorf("parseAlertRule(%q): want error", spec)
+		}
+	}
+}
+
+func TestReadAlertRules(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if rules, errs := ReadAlertRules(); len(rules) != 0 || len(errs) != 0 {
+		t.Errorf("no config = %v, %v", rules, errs)
+	}
+
+	dir := filepath.Join(home, ".config", "kaguyadots")
+	os.MkdirAll(dir, 0755)
+	toml := `[pulse]
+interval = "1s"
+
+[pulse.alerts]
+"RAM" = "ram > 90 for 1m"
+"CPU temperature" = "temp > 90 for 30s" # hot
+"Broken" = "ram is full"
+`
+	if err := os.WriteFile(filepath.Join(dir, "kaguyadots.toml"), []byte(toml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, errs := ReadAlertRules()
+	if len(rules) != 2 || rules[0].Name != "CPU temperature" || rules[1].Name != "RAM" || rules[1].For != time.Minute {
+		t.Errorf("rules = %+v", rules)
+	}
+	if len(errs) != 1 {
+		t.Errorf("errs = %v, want the broken rule", errs)
+	}
+}
+
+func TestAlerter(t *testing.T) {
+	rule, _ := parseAlertRule("CPU temperature", "temp > 90 for 30s cooldown 5m")
+	alerter := NewAlerter([]AlertRule{rule})
+	start := time.Unix(1760000000, 0)
+
+	var delivered []string
+	step := func(seconds int, temp float64) {
+		t.Helper()
+		metrics := map[string]float64{"temp": temp}
+		if temp < 0 {
+			delete(metrics, "temp")
+		}
+		for _, alert := range alerter.Evaluate(metrics, start.Add(time.Duration(seconds)*time.Second)) {
+			state := "cleared"
+			if alert.Firing {
+				state = "firing"
+			}
+			delivered = append(delivered, (time.Duration(seconds)*time.Second).String()+" "+state)
+		}
+	}
+
+	step(0, 95)
+	step(20, 95)
+	step(25, 80) // a dip restarts the 30s
+	step(30, 95)
+	step(50, 92)
+	if len(delivered) != 0 {
+		t.Fatalf("fired early: %v", delivered)
+	}
+	step(60, 93)
+	if active := alerter.Active(); len(active) != 1 || active[0].Value != 93 || active[0].Threshold != 90 {
+		t.Errorf("Active = %+v", active)
+	}
+	step(70, 88)  // inside the hysteresis band: still firing
+	step(80, -1)  // metric missing: nothing changes
+	step(90, 97)  // no second alert while firing
+	step(100, 85) // back under 85.5: cleared
+	if active := alerter.Active(); len(active) != 0 {
+		t.Errorf("Active after clearing = %+v", active)
+	}
+
+	// Firing again within the cooldown is held back, and so is its clearing
+	step(110, 95)
+	step(140, 95)
+	step(150, 80)
+	// After the cooldown it alerts again
+	step(400, 95)
+	step(430, 95)
+
+	want := []string{"1m0s firing", "1m40s cleared", "7m10s firing"}
+	if !reflect.DeepEqual(delivered, want) {
+		t.Errorf("delivered = %v, want %v", delivered, want)
+	}
+}
+
+func TestAlertNotification(t *testing.T) {
+	tests := []struct {
+		alert   Alert
+		body    string
+		urgency byte
+	}{
+		{Alert{Rule: "CPU", Metric: "temp", Value: 93.4, Threshold: 90}, "temp is 93°C, above 90°C", urgencyCrit},
+		{Alert{Rule: "Battery", Metric: "battery", Value: 8, Threshold: 10, Below: true}, "battery is 8%, below 10%", urgencyCrit},
+		{Alert{Rule: "RAM", Metric: "ram", Value: 91.2, Threshold: 90}, "ram is 91%, above 90%", urgencyNormal},
+		{Alert{Rule: "Fan", Metric: "sensor.nct6798.fan2", Value: 250, Threshold: 300, Below: true}, "sensor.nct6798.fan2 is 250, below 300", urgencyNormal},
+		{Alert{Rule: "Upload", Metric: "net.up", Value: 2e6, Threshold: 1e6}, "net.up is 2000000 B/s, above 1000000 B/s", urgencyNormal},
+	}
+	for _, tt := range tests {
+		summary, body, urgency := alertNotification(tt.alert)
+		if summary != tt.alert.Rule || body != tt.body || urgency != tt.urgency {
+			t.Errorf("alertNotification(%+v) = %q, %q, %d", tt.alert, summary, body, urgency)
+		}
+	}
+}
@@ -4,16 +4,16 @@ import (
 	"bufio"
 	"context"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"kaguyadots/runner"
 )
 
 type GTKColors struct {
@@ -36,25 +36,52 @@ type GTKColors struct {
 
 // App struct
 type App struct {
-	ctx context.Context
+	ctx     context.Context
+	proc    *ProcFS
+	sys     *SysFS
+	procs   *ProcessTable
+	sampler *Sampler
+	gpus    *GPUMonitor
+	disks   *DiskMonitor
+	alerts  *Alerter
+	notify  *Notifier
+	runner  runner.Runner // runs smartctl, nvidia-smi and iw
+	invalid []error       // [pulse.alerts] lines that didn't parse, logged on startup
+	etc     string        // root for /etc and /run, moved by tests
+
+	mu          sync.Mutex
+	prevStat    StatSample
+	cpuInfo     *CPUInfo
+	prevNet     map[string]NetCounters
+	prevNetTime time.Time
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{}
+	proc := NewProcFS("")
+	sys := NewSysFS("")
+	r := runner.Default
+	a := &App{proc: proc, sys: sys, runner: r, procs: NewProcessTable(proc), gpus: NewGPUMonitor(sys, r), disks: NewDiskMonitor(proc, sys, r)}
+	a.sampler = NewSampler(ReadSamplerConfig(), a.collectStats)
+	rules, errs := ReadAlertRules()
+	a.alerts, a.notify, a.invalid = NewAlerter(rules), NewNotifier(), errs
+	return a
 }
 
 // Enhanced SystemStats with more details
 type EnhancedSystemStats struct {
-	CPU          CPUStats     `json:"cpu"`
-	RAM          MemoryStats  `json:"ram"`
-	Swap         MemoryStats  `json:"swap"`
-	GPU          GPUStats     `json:"gpu"`
-	Temp         TempStats    `json:"temp"`
-	Disks        []DiskStats  `json:"disks"`
-	Network      NetworkStats `json:"network"`
-	Uptime       string       `json:"uptime"`
-	ProcessCount int          `json:"processCount"`
+	CPU          CPUStats      `json:"cpu"`
+	RAM          MemoryStats   `json:"ram"`
+	Swap         MemoryStats   `json:"swap"`
+	GPUs         []GPUStats    `json:"gpus"`
+	Temp         TempStats     `json:"temp"`
+	Sensors      []SensorChip  `json:"sensors"`
+	Disks        []DiskStats   `json:"disks"`
+	DiskIO       []DiskIO      `json:"diskIO"`
+	Battery      *BatteryStats `json:"battery"` // nil without a battery
+	Network      NetworkStats  `json:"network"`
+	Uptime       string        `json:"uptime"`
+	ProcessCount int           `json:"processCount"`
 }
 
 type SystemStats struct {
@@ -67,15 +94,28 @@ type SystemStats struct {
 	Network NetworkStats `json:"network"`
 }
 
+// NetworkStats is throughput in bytes/sec; Down and Up add up the wifi and
+// ethernet interfaces
 type NetworkStats struct {
-	Down     string  `json:"down"`
-	Up       string  `json:"up"`
-	Activity float64 `json:"activity"`
+	Down       float64          `json:"down"`
+	Up         float64          `json:"up"`
+	Interfaces []InterfaceStats `json:"interfaces"`
 }
 type CPUStats struct {
-	Usage float64 `json:"usage"`
-	Cores int     `json:"cores"`
-	Model string  `json:"model"`
+	Usage     float64      `json:"usage"`
+	Cores     int          `json:"cores"`
+	Model     string       `json:"model"`
+	Breakdown CPUBreakdown `json:"breakdown"`
+	PerCore   []CoreStats  `json:"perCore"`
+	Load      LoadAvg      `json:"load"`
+	Governor  string       `json:"governor"`
+}
+
+// CoreStats is one logical CPU's utilisation and clock
+type CoreStats struct {
+	ID int `json:"id"`
+	CPUBreakdown
+	CPUFreq
 }
 
 type MemoryStats struct {
@@ -85,26 +125,18 @@ type MemoryStats struct {
 	Unit    string  `json:"unit"`
 }
 
-type GPUStats struct {
-	Usage  float64 `json:"usage"`
-	Memory float64 `json:"memory"`
-	Temp   float64 `json:"temp"`
-	Name   string  `json:"name"`
-}
-
+// TempStats is the CPU temperature with the limits its sensor reports;
+// High and Crit are zero when the chip has none (k10temp)
 type TempStats struct {
-	CPU  float64 `json:"cpu"`
-	Max  float64 `json:"max"`
-	Unit string  `json:"unit"`
+	CPU   float64 `json:"cpu"`
+	High  float64 `json:"high"`
+	Crit  float64 `json:"crit"`
+	Label string  `json:"label"`
+	Unit  string  `json:"unit"`
 }
 
-type DiskStats struct {
-	Device     string  `json:"device"`
-	MountPoint string  `json:"mountPoint"`
-	Used       float64 `json:"used"`
-	Total      float64 `json:"total"`
-	Percent    float64 `json:"percent"`
-	FileSystem string  `json:"fileSystem"`
+func (a *App) etcPath(elem ...string) string {
+	return filepath.Join(append([]string{a.etc, "/"}, elem...)...)
 }
 
 // startup is called when the app starts. The context is saved
@@ -114,535 +146,178 @@ func (a *App) startup(ctx context.Context) {
 
 	// Position window at top left after startup
 	runtime.WindowSetPosition(ctx, 20, 20)
-}
-
-func getCPU() float64 {
-	cmd := exec.Command("sh", "-c", "top -bn1 | grep 'Cpu(s)' | awk '{print $2 + $4}'")
-	out, _ := cmd.Output()
-	val, _ := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
-	return val
-}
-func getCPUStats() CPUStats {
-	usage := getCPU()
-
-	// Get CPU cores
-	cmd := exec.Command("sh", "-c", "nproc")
-	out, _ := cmd.Output()
-	cores, _ := strconv.Atoi(strings.TrimSpace(string(out)))
 
-	// Get CPU model
-	cmd = exec.Command("sh", "-c", "lscpu | grep 'Model name' | cut -d':' -f2 | xargs")
-	out, _ = cmd.Output()
-	model := strings.TrimSpace(string(out))
-
-	return CPUStats{
-		Usage: usage,
-		Cores: cores,
-		Model: model,
+	for _, err := range a.invalid {
+		runtime.LogWarning(ctx, err.Error())
 	}
+	a.sampler.Start(ctx, func(stats EnhancedSystemStats) {
+		runtime.EventsEmit(ctx, statsEvent, stats)
+		for _, alert := range a.alerts.Evaluate(sampleMetrics(stats), time.Now()) {
+			runtime.EventsEmit(ctx, alertEvent, alert)
+			if err := a.notify.Notify(alert); err != nil {
+				runtime.LogWarning(ctx, "alert "+alert.Rule+": "+err.Error())
+			}
+		}
+	})
 }
 
-func getRAMStats() MemoryStats {
-	cmd := exec.Command("sh", "-c", "free -m | awk 'NR==2{print $3,$2}'")
-	out, _ := cmd.Output()
-	parts := strings.Fields(string(out))
-
-	used, _ := strconv.ParseFloat(parts[0], 64)
-	total, _ := strconv.ParseFloat(parts[1], 64)
-	percent := (used / total) * 100
-
-	return MemoryStats{
-		Used:    used,
-		Total:   total,
-		Percent: percent,
-		Unit:    "MB",
-	}
+// shutdown is called when the app exits
+func (a *App) shutdown(ctx context.Context) {
+	a.sampler.Stop()
+	a.notify.Close()
 }
 
-func getSwapStats() MemoryStats {
-	cmd := exec.Command("sh", "-c", "free -m | awk 'NR==3{print $3,$2}'")
-	out, _ := cmd.Output()
-	parts := strings.Fields(string(out))
-
-	if len(parts) < 2 {
-		return MemoryStats{Used: 0, Total: 0, Percent: 0, Unit: "MB"}
-	}
+// cpuStats reports usage since the previous call; the first call after
+// startup averages since boot
+func (a *App) cpuStats() CPUStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
-	used, _ := strconv.ParseFloat(parts[0], 64)
-	total, _ := strconv.ParseFloat(parts[1], 64)
-	percent := 0.0
-	if total > 0 {
-		percent = (used / total) * 100
-	}
-
-	return MemoryStats{
-		Used:    used,
-		Total:   total,
-		Percent: percent,
-		Unit:    "MB",
-	}
-}
-func getGPUStats() GPUStats {
-	stats := GPUStats{Usage: 0, Memory: 0, Temp: 0, Name: "No GPU Detected"}
-
-	// Method 1: Try NVIDIA
-	cmd := exec.Command("nvidia-smi", "--query-gpu=utilization.gpu,memory.used,temperature.gpu,name", "--format=csv,noheader,nounits")
-	out, err := cmd.Output()
-	if err == nil && len(out) > 0 {
-		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
-		if len(lines) > 0 {
-			parts := strings.Split(lines[0], ",")
-			if len(parts) >= 4 {
-				stats.Usage, _ = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
-				stats.Memory, _ = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
-				stats.Temp, _ = strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
-				stats.Name = strings.TrimSpace(parts[3])
-				return stats
-			}
+	stats := CPUStats{}
+	if a.cpuInfo == nil {
+		if info, err := a.proc.CPUInfo(); err == nil {
+			a.cpuInfo = &info
 		}
 	}
-
-	// Method 2: Try AMD via sysfs
-	amdPaths := []string{
-		"/sys/class/drm/card0/device/gpu_busy_percent",
-		"/sys/class/drm/card1/device/gpu_busy_percent",
+	if a.cpuInfo != nil {
+		stats.Cores = a.cpuInfo.Threads
+		stats.Model = a.cpuInfo.Model
 	}
 
-	for _, path := range amdPaths {
-		data, err := ioutil.ReadFile(path)
-		if err == nil {
-			usage, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
-			if err == nil {
-				stats.Usage = usage
-				stats.Name = "AMD GPU"
-
-				// Try to get AMD temp
-				tempPath := strings.Replace(path, "gpu_busy_percent", "hwmon/hwmon*/temp1_input", 1)
-				cmd = exec.Command("sh", "-c", fmt.Sprintf("cat %s 2>/dev/null", tempPath))
-				out, err = cmd.Output()
-				if err == nil && len(out) > 0 {
-					temp, _ := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
-					stats.Temp = temp / 1000.0 // Convert from millidegrees
-				}
-
-				return stats
-			}
-		}
+	if load, err := a.proc.LoadAvg(); err == nil {
+		stats.Load = load
 	}
 
-	// Method 3: Try Intel integrated graphics
-	intelPath := "/sys/class/drm/card0/gt_cur_freq_mhz"
-	if _, err := os.Stat(intelPath); err == nil {
-		stats.Name = "Intel Integrated GPU"
-		// Intel GPU usage is harder to get, might need radeontop or intel_gpu_top
+	sample, err := a.proc.Stat()
+	if err != nil {
+		return stats
 	}
-
+	stats.Breakdown = Breakdown(a.prevStat.Total, sample.Total)
+	stats.Usage = stats.Breakdown.Usage
+	stats.PerCore = a.coreStats(a.prevStat, sample)
+	stats.Governor = governors(stats.PerCore)
+	a.prevStat = sample
 	return stats
 }
-func getTempStats() TempStats {
-	cpu := 0.0
-	max := 85.0
-
-	// Method 1: Try sensors command
-	cmd := exec.Command("sh", "-c", "sensors 2>/dev/null")
-	out, err := cmd.Output()
-	if err == nil && len(out) > 0 {
-		output := string(out)
-
-		// Try different temperature patterns
-		patterns := []string{
-			`Package id 0:\s+\+(\d+\.\d+)°C`,
-			`Tctl:\s+\+(\d+\.\d+)°C`,
-			`Core 0:\s+\+(\d+\.\d+)°C`,
-			`CPU:\s+\+(\d+\.\d+)°C`,
-			`temp1:\s+\+(\d+\.\d+)°C`,
-		}
-
-		for _, pattern := range patterns {
-			re := regexp.MustCompile(pattern)
-			matches := re.FindStringSubmatch(output)
-			if len(matches) > 1 {
-				cpu, _ = strconv.ParseFloat(matches[1], 64)
-				break
-			}
-		}
-	}
-
-	// Method 2: Try reading from /sys/class/thermal
-	if cpu == 0.0 {
-		// Try thermal zones
-		for i := 0; i < 10; i++ {
-			tempPath := fmt.Sprintf("/sys/class/thermal/thermal_zone%d/temp", i)
-			data, err := ioutil.ReadFile(tempPath)
-			if err == nil {
-				temp, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
-				if err == nil && temp > 1000 {
-					// Temperature is in millidegrees
-					cpu = temp / 1000.0
-					break
-				}
-			}
-		}
-	}
-
-	// Method 3: Try hwmon
-	if cpu == 0.0 {
-		cmd = exec.Command("sh", "-c", "find /sys/class/hwmon -name 'temp*_input' -exec cat {} \\; 2>/dev/null | head -1")
-		out, err = cmd.Output()
-		if err == nil && len(out) > 0 {
-			temp, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
-			if err == nil && temp > 1000 {
-				cpu = temp / 1000.0
-			}
-		}
-	}
-
-	return TempStats{
-		CPU:  cpu,
-		Max:  max,
-		Unit: "°C",
-	}
-}
-func getDiskStats() []DiskStats {
-	var disks []DiskStats
 
-	// Get all mounted filesystems
-	cmd := exec.Command("sh", "-c", "df -h -x tmpfs -x devtmpfs -x squashfs | tail -n +2")
-	out, _ := cmd.Output()
-
-	lines := strings.Split(string(out), "\n")
-	for _, line := range lines {
-		if line == "" {
+// coreStats compares each online core with its previous sample; a core
+// that just came online is compared with boot
+func (a *App) coreStats(prev, cur StatSample) []CoreStats {
+	var cores []CoreStats
+	for id, times := range cur.Cores {
+		// Offline cores are missing from /proc/stat and leave gaps
+		if times.Total() == 0 {
 			continue
 		}
-
-		fields := strings.Fields(line)
-		if len(fields) < 6 {
-			continue
+		var before CPUTimes
+		if id < len(prev.Cores) {
+			before = prev.Cores[id]
 		}
-
-		// Parse used and total (convert from human readable)
-		usedStr := fields[2]
-		totalStr := fields[1]
-		percentStr := strings.TrimSuffix(fields[4], "%")
-
-		percent, _ := strconv.ParseFloat(percentStr, 64)
-
-		disks = append(disks, DiskStats{
-			Device:     fields[0],
-			MountPoint: fields[5],
-			Used:       parseSize(usedStr),
-			Total:      parseSize(totalStr),
-			Percent:    percent,
-			FileSystem: getFileSystem(fields[0]),
+		cores = append(cores, CoreStats{
+			ID:           id,
+			CPUBreakdown: Breakdown(before, times),
+			CPUFreq:      a.sys.CPUFreq(id),
 		})
 	}
-
-	return disks
+	return cores
 }
-func parseSize(sizeStr string) float64 {
-	sizeStr = strings.TrimSpace(sizeStr)
-	if len(sizeStr) < 2 {
-		return 0
-	}
 
-	unit := sizeStr[len(sizeStr)-1:]
-	numStr := sizeStr[:len(sizeStr)-1]
-	num, _ := strconv.ParseFloat(numStr, 64)
-
-	switch unit {
-	case "K":
-		return num / 1024 / 1024 // Convert to GB
-	case "M":
-		return num / 1024 // Convert to GB
-	case "G":
-		return num
-	case "T":
-		return num * 1024
-	default:
-		return num
-	}
-}
-
-func getFileSystem(device string) string {
-	cmd := exec.Command("sh", "-c", fmt.Sprintf("lsblk -no FSTYPE %s 2>/dev/null", device))
-	out, _ := cmd.Output()
-	fs := strings.TrimSpace(string(out))
-	if fs == "" {
-		return "Unknown"
-	}
-	return fs
-}
-
-func getUptime() string {
-	cmd := exec.Command("sh", "-c", "uptime -p | sed 's/up //'")
-	out, _ := cmd.Output()
-	return strings.TrimSpace(string(out))
-}
-
-func getProcessCount() int {
-	cmd := exec.Command("sh", "-c", "ps aux | wc -l")
-	out, _ := cmd.Output()
-	count, _ := strconv.Atoi(strings.TrimSpace(string(out)))
-	return count - 1 // Subtract header line
-}
-func getStorage() float64 {
-	cmd := exec.Command("sh", "-c", "df -h / | awk 'NR==2{print int($5)}'")
-	out, _ := cmd.Output()
-	val, _ := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
-	return val
-}
-
-func getTemp() float64 {
-	cmd := exec.Command("sh", "-c", "sensors 2>/dev/null | grep 'Package id 0:' | awk '{print int($4)}' || sensors 2>/dev/null | grep 'Tctl:' | awk '{print int($2)}' || echo '0'")
-	out, _ := cmd.Output()
-	val, _ := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
-	return val
-}
-
-func getGPU() float64 {
-	// Try nvidia-smi first
-	cmd := exec.Command("sh", "-c", "nvidia-smi --query-gpu=utilization.gpu --format=csv,noheader,nounits 2>/dev/null | head -n1")
-	out, err := cmd.Output()
-	if err == nil && len(out) > 0 {
-		val, _ := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
-		return val
-	}
-	// Fallback for AMD
-	cmd = exec.Command("sh", "-c", "cat /sys/class/drm/card0/device/gpu_busy_percent 2>/dev/null")
-	out, _ = cmd.Output()
-	val, _ := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
-	return val
-}
-
-// Network stats cache to avoid timing issues
-var networkCache = struct {
-	Down       string
-	Up         string
-	Activity   float64
-	LastUpdate time.Time
-}{
-	Down:       "0 B/s",
-	Up:         "0 B/s",
-	Activity:   0,
-	LastUpdate: time.Time{},
-}
-
-func getNetwork() NetworkStats {
-	// Return cached values if updated recently (within 500ms)
-	if time.Since(networkCache.LastUpdate) < 500*time.Millisecond {
-		return NetworkStats{
-			Down:     networkCache.Down,
-			Up:       networkCache.Up,
-			Activity: networkCache.Activity,
+// governors names the scaling governor in use, listing each distinct one
+// when cores differ
+func governors(cores []CoreStats) string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, core := range cores {
+		if core.Governor != "" && !seen[core.Governor] {
+			seen[core.Governor] = true
+			names = append(names, core.Governor)
 		}
 	}
+	return strings.Join(names, ", ")
+}
 
-	stats := NetworkStats{
-		Down:     "0 B/s",
-		Up:       "0 B/s",
-		Activity: 0,
-	}
-
-	// Method 1: Try custom script
-	homeDir, _ := os.UserHomeDir()
-	scriptPath := filepath.Join(homeDir, ".config", "kaguyadots", "scripts", "network.sh")
-
-	if _, err := os.Stat(scriptPath); err == nil {
-		// Run both commands concurrently to save time
-		downChan := make(chan string, 1)
-		upChan := make(chan string, 1)
-
-		go func() {
-			cmd := exec.Command("bash", scriptPath, "down")
-			down, err := cmd.Output()
-			if err == nil {
-				downChan <- strings.TrimSpace(string(down))
-			} else {
-				downChan <- "0 B/s"
-			}
-		}()
-
-		go func() {
-			cmd := exec.Command("bash", scriptPath, "up")
-			up, err := cmd.Output()
-			if err == nil {
-				upChan <- strings.TrimSpace(string(up))
-			} else {
-				upChan <- "0 B/s"
-			}
-		}()
-
-		// Wait for both with timeout
-		timeout := time.After(2 * time.Second)
-		downReceived := false
-		upReceived := false
-
-		for !downReceived || !upReceived {
-			select {
-			case stats.Down = <-downChan:
-				downReceived = true
-			case stats.Up = <-upChan:
-				upReceived = true
-			case <-timeout:
-				if !downReceived {
-					stats.Down = "0 B/s"
-				}
-				if !upReceived {
-					stats.Up = "0 B/s"
-				}
-				downReceived = true
-				upReceived = true
-			}
-		}
-
-		// Calculate activity based on the speeds
-		stats.Activity = calculateActivityFromStrings(stats.Down, stats.Up)
-
-		// Update cache
-		networkCache.Down = stats.Down
-		networkCache.Up = stats.Up
-		networkCache.Activity = stats.Activity
-		networkCache.LastUpdate = time.Now()
-
-		return stats
-	}
+// memoryStats reports RAM and swap in MB as free -m does
+func (a *App) memoryStats() (MemoryStats, MemoryStats) {
+	ram := MemoryStats{Unit: "MB"}
+	swap := MemoryStats{Unit: "MB"}
 
-	// Method 2: Calculate network speed manually using same logic as script
-	cmd := exec.Command("sh", "-c", "ip route | grep '^default' | awk '{print $5}' | head -n1")
-	out, err := cmd.Output()
+	info, err := a.proc.Meminfo()
 	if err != nil {
-		return stats
+		return ram, swap
 	}
+	ram = newMemoryStats(info.MemUsed(), info.MemTotal)
+	swap = newMemoryStats(info.SwapUsed(), info.SwapTotal)
+	return ram, swap
+}
 
-	iface := strings.TrimSpace(string(out))
-	if iface == "" {
-		return stats
-	}
-
-	rxPath := fmt.Sprintf("/sys/class/net/%s/statistics/rx_bytes", iface)
-	txPath := fmt.Sprintf("/sys/class/net/%s/statistics/tx_bytes", iface)
-
-	// Check if interface exists
-	if _, err := os.Stat(rxPath); err != nil {
-		return stats
+func newMemoryStats(used, total uint64) MemoryStats {
+	stats := MemoryStats{
+		Used:  float64(used / (1024 * 1024)),
+		Total: float64(total / (1024 * 1024)),
+		Unit:  "MB",
 	}
-
-	// Get cached file location
-	cacheDir := "/tmp/go_network"
-	cacheFile := filepath.Join(cacheDir, "network_stats")
-	os.MkdirAll(cacheDir, 0755)
-
-	// Read current bytes
-	rx, _ := readNetworkBytes(rxPath)
-	tx, _ := readNetworkBytes(txPath)
-	currentTime := time.Now().Unix()
-
-	// Read previous values
-	var prevRx, prevTx, prevTime uint64
-	if data, err := ioutil.ReadFile(cacheFile); err == nil {
-		fmt.Sscanf(string(data), "%d %d %d", &prevRx, &prevTx, &prevTime)
+	if total > 0 {
+		stats.Percent = float64(used) / float64(total) * 100
 	}
+	return stats
+}
 
-	// Calculate speeds
-	if prevTime > 0 {
-		timeDiff := currentTime - int64(prevTime)
-		if timeDiff > 0 {
-			rxSpeed := float64(rx-prevRx) / float64(timeDiff)
-			txSpeed := float64(tx-prevTx) / float64(timeDiff)
-
-			// Handle negative values (interface reset)
-			if rxSpeed < 0 {
-				rxSpeed = 0
-			}
-			if txSpeed < 0 {
-				txSpeed = 0
-			}
-
-			stats.Down = formatNetworkSpeed(rxSpeed / 1024.0) // Convert to KB/s
-			stats.Up = formatNetworkSpeed(txSpeed / 1024.0)
-			stats.Activity = calculateActivity(rxSpeed/1024.0, txSpeed/1024.0)
-		}
+// tempStats takes the CPU temperature from the sensor tree
+func tempStats(chips []SensorChip) TempStats {
+	stats := TempStats{Unit: "°C"}
+	if sensor, ok := cpuTemp(chips); ok {
+		stats.CPU = sensor.Value
+		stats.High = sensor.Max
+		stats.Crit = sensor.Crit
+		stats.Label = sensor.Label
 	}
-
-	// Save current values
-	cacheData := fmt.Sprintf("%d %d %d", rx, tx, currentTime)
-	ioutil.WriteFile(cacheFile, []byte(cacheData), 0644)
-
-	// Update cache
-	networkCache.Down = stats.Down
-	networkCache.Up = stats.Up
-	networkCache.Activity = stats.Activity
-	networkCache.LastUpdate = time.Now()
-
 	return stats
 }
 
-// Calculate activity from formatted strings like "1.5MB/s"
-func calculateActivityFromStrings(down, up string) float64 {
-	downKB := parseNetworkSpeed(down)
-	upKB := parseNetworkSpeed(up)
-	return calculateActivity(downKB, upKB)
+func (a *App) uptime() string {
+	uptime, err := a.proc.Uptime()
+	if err != nil {
+		return ""
+	}
+	return formatUptime(uptime)
 }
 
-// Parse network speed string to KB/s
-func parseNetworkSpeed(speedStr string) float64 {
-	speedStr = strings.TrimSpace(speedStr)
-
-	// Extract number and unit
-	re := regexp.MustCompile(`([\d.]+)\s*([KMGT]?B)/s`)
-	matches := re.FindStringSubmatch(speedStr)
-
-	if len(matches) < 3 {
+func (a *App) processCount() int {
+	pids, err := a.proc.PIDs()
+	if err != nil {
 		return 0
 	}
-
-	value, _ := strconv.ParseFloat(matches[1], 64)
-	unit := matches[2]
-
-	switch unit {
-	case "B":
-		return value / 1024.0
-	case "KB":
-		return value
-	case "MB":
-		return value * 1024.0
-	case "GB":
-		return value * 1024.0 * 1024.0
-	default:
-		return value
-	}
+	return len(pids)
 }
 
-func readNetworkBytes(path string) (uint64, error) {
-	data, err := ioutil.ReadFile(path)
+// networkStats reports each interface's throughput since the previous
+// call; only the sampler calls it, so that spans one interval
+func (a *App) networkStats() NetworkStats {
+	stats := NetworkStats{Interfaces: []InterfaceStats{}}
+	ifaces, err := a.sys.NetInterfaces()
 	if err != nil {
-		return 0, err
+		return stats
 	}
-	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
-}
 
-func formatNetworkSpeed(kbps float64) string {
-	if kbps < 1 {
-		return fmt.Sprintf("%.0f B/s", kbps*1024)
-	} else if kbps < 1024 {
-		return fmt.Sprintf("%.1f KB/s", kbps)
-	} else {
-		return fmt.Sprintf("%.2f MB/s", kbps/1024)
-	}
-}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	seconds := now.Sub(a.prevNetTime).Seconds()
+	prev := a.prevNet
+	a.prevNet = make(map[string]NetCounters, len(ifaces))
 
-func calculateActivity(down, up float64) float64 {
-	total := down + up
-	if total < 10 {
-		return 10.0
-	} else if total < 100 {
-		return 30.0
-	} else if total < 1024 {
-		return 60.0
-	} else {
-		return 90.0
+	for _, iface := range ifaces {
+		before, seen := prev[iface.Name]
+		rates := interfaceRates(iface, before, seen, seconds)
+		if rates.physical() {
+			stats.Down += rates.RxBytesPerSec
+			stats.Up += rates.TxBytesPerSec
+		}
+		stats.Interfaces = append(stats.Interfaces, rates)
+		a.prevNet[iface.Name] = iface.Counters
 	}
+	a.prevNetTime = now
+	return stats
 }
 
 // GetGTKColors reads the GTK CSS file and extracts color definitions
@@ -762,19 +437,131 @@ func (a *App) GetGTKColors() GTKColors {
 	return colors
 }
 
-// GetEnhancedSystemStats returns comprehensive system information
+// statsEvent carries every sample to the frontend, alertEvent every alert
+// rule that starts or stops firing
+const (
+	statsEvent = "stats"
+	alertEvent = "alert"
+)
+
+// GetEnhancedSystemStats returns the sampler's latest stats; new samples
+// arrive as "stats" events
 func (a *App) GetEnhancedSystemStats() EnhancedSystemStats {
-	return EnhancedSystemStats{
-		CPU:          getCPUStats(),
-		RAM:          getRAMStats(),
-		Swap:         getSwapStats(),
-		GPU:          getGPUStats(),
-		Temp:         getTempStats(),
-		Disks:        getDiskStats(),
-		Network:      getNetwork(),
-		Uptime:       getUptime(),
-		ProcessCount: getProcessCount(),
+	return a.sampler.Latest()
+}
+
+// GetHistory returns a metric's samples over the last window seconds,
+// oldest first; 0 returns all the history kept. Metrics are cpu, cpu.<id>,
+// ram, swap, gpu (the busiest), gpu.<index>, temp, sensor.<chip>.<sensor>
+// (sensor.k10temp.temp1), mount.<mount point> (mount./), disk.<name>.read,
+// .write and .util, battery, net.down, net.up and net.<interface>.down/up.
+func (a *App) GetHistory(metric string, window int) ([]HistoryPoint, error) {
+	return a.sampler.History(metric, time.Duration(window)*time.Second)
+}
+
+// GetAlerts returns the alert rules firing now
+func (a *App) GetAlerts() []Alert {
+	return a.alerts.Active()
+}
+
+// collectStats reads everything once; only the sampler calls it
+func (a *App) collectStats() EnhancedSystemStats {
+	ram, swap := a.memoryStats()
+	chips, _ := a.sys.SensorChips()
+	if chips == nil {
+		chips = []SensorChip{}
+	}
+	stats := EnhancedSystemStats{
+		CPU:          a.cpuStats(),
+		RAM:          ram,
+		Swap:         swap,
+		GPUs:         a.gpus.Read(),
+		Temp:         tempStats(chips),
+		Sensors:      chips,
+		Disks:        a.disks.Usage(),
+		DiskIO:       a.disks.IO(),
+		Network:      a.networkStats(),
+		Uptime:       a.uptime(),
+		ProcessCount: a.processCount(),
+	}
+	if battery, ok := a.sys.Battery(); ok {
+		stats.Battery = &battery
+	}
+	return stats
+}
+
+// GetProcesses returns the process table, sorted and filtered by query
+func (a *App) GetProcesses(query ProcessQuery) (ProcessList, error) {
+	return a.procs.List(query)
+}
+
+// SignalProcess sends a signal such as "TERM" or "KILL" to a process
+func (a *App) SignalProcess(pid int, signal string) error {
+	return a.procs.Signal(pid, signal)
+}
+
+// ReniceProcess changes the nice value of a process
+func (a *App) ReniceProcess(pid int, nice int) error {
+	return a.procs.Renice(pid, nice)
+}
+
+// GetConnectivity returns addresses, Wi-Fi association, routes, DNS and
+// listening sockets. It runs iw, so the frontend calls it on demand rather
+// than every sample.
+func (a *App) GetConnectivity() Connectivity {
+	return a.connectivity(func(name string) (WifiInfo, bool) { return iwLink(a.runner, name) })
+}
+
+// connectivity gathers the details; link asks about a wifi association
+func (a *App) connectivity(link func(name string) (WifiInfo, bool)) Connectivity {
+	info := Connectivity{
+		Interfaces: []InterfaceDetails{},
+		DNS:        Nameservers(a.etcPath("etc", "resolv.conf"), a.etcPath("run", "systemd", "resolve", "resolv.conf")),
+		Listening:  []ListeningSocket{},
 	}
+
+	ifaces, _ := a.sys.NetInterfaces()
+	wireless, _ := a.proc.Wireless()
+	for _, iface := range ifaces {
+		details := InterfaceDetails{Name: iface.Name, Type: iface.Type, State: iface.State}
+		details.MAC, details.MTU, details.SpeedMbps = a.sys.NetLink(iface.Name)
+		details.IPv4, details.IPv6 = interfaceAddrs(iface.Name)
+
+		if iface.Type == InterfaceWifi {
+			wifi := wireless[iface.Name]
+			if assoc, ok := link(iface.Name); ok {
+				assoc.Quality = wifi.Quality
+				if assoc.SignalDBm == 0 {
+					assoc.SignalDBm = wifi.SignalDBm
+				}
+				wifi = assoc
+			}
+			details.Wifi = &wifi
+		}
+		info.Interfaces = append(info.Interfaces, details)
+	}
+
+	if routes, err := a.proc.DefaultRoutes(); err == nil && len(routes) > 0 {
+		info.Gateway = &routes[0]
+	}
+	if routes, err := a.proc.DefaultRoutes6(); err == nil && len(routes) > 0 {
+		info.Gateway6 = &routes[0]
+	}
+
+	if sockets, err := a.proc.ListeningSockets(); err == nil {
+		owners := a.proc.SocketOwners()
+		for _, socket := range sockets {
+			socket.User = a.procs.UserName(socket.UID)
+			if pid, ok := owners[socket.Inode]; ok {
+				socket.PID = pid
+				if stat, err := a.proc.ProcessStat(pid); err == nil {
+					socket.Process = stat.Comm
+				}
+			}
+			info.Listening = append(info.Listening, socket)
+		}
+	}
+	return info
 }
 
 // Add debug logging version
@@ -783,8 +570,10 @@ func (a *App) GetEnhancedSystemStatsDebug() map[string]interface{} {
 
 	// Log to see what we're getting
 	fmt.Printf("Temperature: %.2f°C\n", stats.Temp.CPU)
-	fmt.Printf("GPU: %s - Usage: %.2f%%, Temp: %.2f°C\n", stats.GPU.Name, stats.GPU.Usage, stats.GPU.Temp)
-	fmt.Printf("Network: Down=%s, Up=%s\n", stats.Network.Down, stats.Network.Up)
+	for _, gpu := range stats.GPUs {
+		fmt.Printf("GPU %s: %s - Usage: %.2f%%, Temp: %.2f°C\n", gpu.Card, gpu.Name, gpu.Usage, gpu.Temp)
+	}
+	fmt.Printf("Network: Down=%.0f B/s, Up=%.0f B/s\n", stats.Network.Down, stats.Network.Up)
 
 	return map[string]interface{}{
 		"stats": stats,
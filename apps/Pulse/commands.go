@@ -0,0 +1,19 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"kaguyadots/runner"
+)
+
+// toolTimeout bounds the external tools the sampler runs, so one that
+// hangs (smartctl on a stuck drive) can't stall every other metric
+const toolTimeout = 5 * time.Second
+
+// toolOutput runs a tool under toolTimeout and returns its stdout
+func toolOutput(r runner.Runner, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), toolTimeout)
+	defer cancel()
+	return r.Output(ctx, runner.Cmd(name, args...))
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kaguyadots/runner"
+)
+
+// InterfaceDetails is an interface's addressing and link, read on demand
+// rather than every sample
+type InterfaceDetails struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	State     string    `json:"state"`
+	MAC       string    `json:"mac"`
+	MTU       int       `json:"mtu"`
+	SpeedMbps int       `json:"speedMbps"` // 0 when the driver doesn't report it, as for wifi
+	IPv4      []string  `json:"ipv4"`
+	IPv6      []string  `json:"ipv6"`
+	Wifi      *WifiInfo `json:"wifi,omitempty"`
+}
+
+// WifiInfo is the association of a wireless interface
+type WifiInfo struct {
+	Connected    bool    `json:"connected"`
+	SSID         string  `json:"ssid"`
+	BSSID        string  `json:"bssid"`
+	SignalDBm    int     `json:"signalDbm"`
+	Quality      float64 `json:"quality"` // percent
+	FrequencyMHz float64 `json:"frequencyMHz"`
+	Band         string  `json:"band"`
+	RxBitrate    float64 `json:"rxBitrate"` // Mbit/s
+	TxBitrate    float64 `json:"txBitrate"`
+}
+
+// wifiBand names the band a frequency in MHz belongs to
+func wifiBand(mhz float64) string {
+	switch {
+	case mhz >= 5925:
+		return "6 GHz"
+	case mhz >= 4900:
+		return "5 GHz"
+	case mhz > 0:
+		return "2.4 GHz"
+	}
+	return ""
+}
+
+// Route is a default route from /proc/net/route or /proc/net/ipv6_route
+type Route struct {
+	Interface string `json:"interface"`
+	Gateway   string `json:"gateway"` // empty for point-to-point links
+	Metric    uint64 `json:"metric"`
+}
+
+// ListeningSocket is a TCP socket in the LISTEN state
+type ListeningSocket struct {
+	Protocol string `json:"protocol"` // tcp or tcp6
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+	UID      int    `json:"uid"`
+	User     string `json:"user"`
+	Inode    uint64 `json:"-"`
+	PID      int    `json:"pid"`     // 0 when the owner can't be seen
+	Process  string `json:"process"` // comm of the owning process
+}
+
+// Connectivity is everything the network details panel shows
+type Connectivity struct {
+	Interfaces []InterfaceDetails `json:"interfaces"`
+	Gateway    *Route             `json:"gateway"`
+	Gateway6   *Route             `json:"gateway6"`
+	DNS        []string           `json:"dns"`
+	Listening  []ListeningSocket  `json:"listening"`
+}
+
+// NetLink reads an interface's MAC, MTU and negotiated speed from sysfs.
+// speed is -1 or unreadable for wifi and links that are down.
+func (s *SysFS) NetLink(name string) (mac string, mtu int, speedMbps int) {
+	mac = s.readString("class", "net", name, "address")
+	if n, ok := s.readUint("class", "net", name, "mtu"); ok {
+		mtu = int(n)
+	}
+	if n, err := strconv.Atoi(s.readString("class", "net", name, "speed")); err == nil && n > 0 {
+		speedMbps = n
+	}
+	return mac, mtu, speedMbps
+}
+
+// DefaultRoutes reads the IPv4 routing table and returns its default
+// routes, lowest metric first
+func (p *ProcFS) DefaultRoutes() ([]Route, error) {
+	file, err := os.Open(p.path("net", "route"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var routes []Route
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+		gateway, err := hex.DecodeString(fields[2])
+		if err != nil || len(gateway) != 4 {
+			continue
+		}
+		metric, _ := strconv.ParseUint(fields[6], 10, 64)
+		route := Route{Interface: fields[0], Metric: metric}
+		// The kernel prints the address in host (little endian) order
+		if ip := net.IPv4(gateway[3], gateway[2], gateway[1], gateway[0]); !ip.Equal(net.IPv4zero) {
+			route.Gateway = ip.String()
+		}
+		routes = append(routes, route)
+	}
+	sortRoutes(routes)
+	return routes, scanner.Err()
+}
+
+// DefaultRoutes6 reads the IPv6 routing table's default routes, lowest
+// metric first. The unreachable catch-all on lo is skipped.
+func (p *ProcFS) DefaultRoutes6() ([]Route, error) {
+	file, err := os.Open(p.path("net", "ipv6_route"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	const rtfReject = 0x0200
+
+	var routes []Route
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[1] != "00" || strings.Trim(fields[0], "0") != "" {
+			continue
+		}
+		flags, _ := strconv.ParseUint(fields[8], 16, 32)
+		if flags&rtfReject != 0 {
+			continue
+		}
+		metric, _ := strconv.ParseUint(fields[5], 16, 32)
+		route := Route{Interface: fields[9], Metric: metric}
+		if ip, err := hex.DecodeString(fields[4]); err == nil && len(ip) == 16 && !net.IP(ip).IsUnspecified() {
+			route.Gateway = net.IP(ip).String()
+		}
+		routes = append(routes, route)
+	}
+	sortRoutes(routes)
+	return routes, scanner.Err()
+}
+
+func sortRoutes(routes []Route) {
+	sort.SliceStable(routes, func(i, j int) bool { return routes[i].Metric < routes[j].Metric })
+}
+
+// Wireless reads link quality and signal level per interface from
+// /proc/net/wireless. Quality is scaled from the usual 0-70 to a percent.
+// Every wireless interface is listed, associated or not, so one only
+// counts as connected while it reports a quality or signal level.
+func (p *ProcFS) Wireless() (map[string]WifiInfo, error) {
+	file, err := os.Open(p.path("net", "wireless"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	wifi := make(map[string]WifiInfo)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, rest, ok := strings.Cut(scanner.Text(), ":")
+		fields := strings.Fields(rest)
+		if !ok || len(fields) < 3 || strings.Contains(name, "|") {
+			continue
+		}
+		quality, err1 := strconv.ParseFloat(strings.TrimSuffix(fields[1], "."), 64)
+		level, err2 := strconv.ParseFloat(strings.TrimSuffix(fields[2], "."), 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		// Drivers report 0 or -256 for the level of an idle interface
+		if level >= 0 || level <= -256 {
+			level = 0
+		}
+		wifi[strings.TrimSpace(name)] = WifiInfo{
+			Connected: quality > 0 || level != 0,
+			SignalDBm: int(level),
+			Quality:   clampPercent(quality / 70 * 100),
+		}
+	}
+	return wifi, scanner.Err()
+}
+
+// parseIWLink reads the output of `iw dev <name> link`
+func parseIWLink(output string) WifiInfo {
+	var info WifiInfo
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		key, value, _ := strings.Cut(line, ":")
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(line, "Connected to "):
+			info.Connected = true
+			info.BSSID = strings.Fields(strings.TrimPrefix(line, "Connected to "))[0]
+		case key == "SSID":
+			info.SSID = value
+		case key == "freq":
+			info.FrequencyMHz, _ = strconv.ParseFloat(value, 64)
+			info.Band = wifiBand(info.FrequencyMHz)
+		case key == "signal":
+			if fields := strings.Fields(value); len(fields) > 0 {
+				info.SignalDBm, _ = strconv.Atoi(fields[0])
+			}
+		case key == "rx bitrate", key == "tx bitrate":
+			var rate float64
+			if fields := strings.Fields(value); len(fields) > 0 {
+				rate, _ = strconv.ParseFloat(fields[0], 64)
+			}
+			if key == "rx bitrate" {
+				info.RxBitrate = rate
+			} else {
+				info.TxBitrate = rate
+			}
+		}
+	}
+	return info
+}
+
+// iwLink asks iw for the SSID and bitrates, which /proc/net/wireless
+// doesn't carry. It returns false when iw is missing or fails.
+func iwLink(r runner.Runner, name string) (WifiInfo, bool) {
+	out, err := toolOutput(r, "iw", "dev", name, "link")
+	if err != nil {
+		return WifiInfo{}, false
+	}
+	return parseIWLink(string(out)), true
+}
+
+// Nameservers reads the DNS servers from resolv.conf. When that only
+// points at the systemd-resolved stub, the upstream servers resolved
+// forwards to are read from its own resolv.conf instead.
+func Nameservers(resolvConf, resolvedConf string) []string {
+	servers := readNameservers(resolvConf)
+	if len(servers) == 1 && servers[0] == "127.0.0.53" {
+		if upstream := readNameservers(resolvedConf); len(upstream) > 0 {
+			return upstream
+		}
+	}
+	return servers
+}
+
+func readNameservers(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var servers []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" && !seen[fields[1]] {
+			seen[fields[1]] = true
+			servers = append(servers, fields[1])
+		}
+	}
+	return servers
+}
+
+// ListeningSockets reads the LISTEN sockets of /proc/net/tcp and tcp6,
+// ordered by port
+func (p *ProcFS) ListeningSockets() ([]ListeningSocket, error) {
+	var sockets []ListeningSocket
+	for _, protocol := range []string{"tcp", "tcp6"} {
+		found, err := p.listening(protocol)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		sockets = append(sockets, found...)
+	}
+	sort.SliceStable(sockets, func(i, j int) bool {
+		if sockets[i].Port != sockets[j].Port {
+			return sockets[i].Port < sockets[j].Port
+		}
+		return sockets[i].Protocol < sockets[j].Protocol
+	})
+	return sockets, nil
+}
+
+func (p *ProcFS) listening(protocol string) ([]ListeningSocket, error) {
+	file, err := os.Open(p.path("net", protocol))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	const tcpListen = "0A"
+
+	var sockets []ListeningSocket
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListen {
+			continue
+		}
+		ip, port, err := parseSocketAddress(fields[1])
+		if err != nil {
+			continue
+		}
+		uid, _ := strconv.Atoi(fields[7])
+		inode, _ := strconv.ParseUint(fields[9], 10, 64)
+		sockets = append(sockets, ListeningSocket{
+			Protocol: protocol,
+			Address:  ip.String(),
+			Port:     port,
+			UID:      uid,
+			Inode:    inode,
+		})
+	}
+	return sockets, scanner.Err()
+}
+
+// parseSocketAddress decodes "0100007F:0277". The address is stored as
+// 32-bit words in host (little endian) order; the port is big endian.
+func parseSocketAddress(s string) (net.IP, int, error) {
+	addr, portHex, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, 0, fmt.Errorf("malformed socket address %q", s)
+	}
+	raw, err := hex.DecodeString(addr)
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return nil, 0, fmt.Errorf("malformed socket address %q", s)
+	}
+	for word := 0; word < len(raw); word += 4 {
+		raw[word], raw[word+1], raw[word+2], raw[word+3] = raw[word+3], raw[word+2], raw[word+1], raw[word]
+	}
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("malformed socket port %q", s)
+	}
+	return net.IP(raw), int(port), nil
+}
+
+// SocketOwners maps socket inodes to the pids holding them. Only the
+// processes whose fd directory is readable are seen, which without root
+// means the user's own.
+func (p *ProcFS) SocketOwners() map[uint64]int {
+	owners := make(map[uint64]int)
+	pids, err := p.PIDs()
+	if err != nil {
+		return owners
+	}
+	for _, pid := range pids {
+		dir := p.path(strconv.Itoa(pid), "fd")
+		fds, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(dir, fd.Name()))
+			if err != nil || !strings.HasPrefix(target, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]"), 10, 64)
+			if err == nil {
+				owners[inode] = pid
+			}
+		}
+	}
+	return owners
+}
+
+// interfaceAddrs lists an interface's addresses, IPv4 and IPv6 apart, in
+// CIDR notation
+func interfaceAddrs(name string) (ipv4, ipv6 []string) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, nil
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, nil
+	}
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipnet.IP.To4() != nil {
+			ipv4 = append(ipv4, ipnet.String())
+		} else {
+			ipv6 = append(ipv6, ipnet.String())
+		}
+	}
+	return ipv4, ipv6
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"kaguyadots/runner"
+)
+
+func TestProcDefaultRoutes(t *testing.T) {
+	routes, err := testProcFS().DefaultRoutes()
+	if err != nil {
+		t.Fatalf("DefaultRoutes: %v", err)
+	}
+	want := []Route{
+		{Interface: "wlp2s0", Gateway: "192.168.1.1", Metric: 600},
+		{Interface: "tun0", Gateway: "10.8.0.1", Metric: 1000},
+	}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("routes = %+v, want %+v", routes, want)
+	}
+
+	routes6, err := testProcFS().DefaultRoutes6()
+	if err != nil {
+		t.Fatalf("DefaultRoutes6: %v", err)
+	}
+	want6 := []Route{{Interface: "wlp2s0", Gateway: "fe80::234:56ff:fe78:9abc", Metric: 1024}}
+	if !reflect.DeepEqual(routes6, want6) {
+		t.Errorf("routes6 = %+v, want %+v", routes6, want6)
+	}
+}
+
+func TestProcWireless(t *testing.T) {
+	wifi, err := testProcFS().Wireless()
+	if err != nil {
+		t.Fatalf("Wireless: %v", err)
+	}
+	got, ok := wifi["wlp2s0"]
+	if len(wifi) != 2 || !ok || !got.Connected || got.SignalDBm != -52 || got.Quality < 82.85 || got.Quality > 82.86 {
+		t.Errorf("Wireless = %+v", wifi)
+	}
+	// Listed but not associated
+	if idle := wifi["wlan1"]; idle.Connected || idle.SignalDBm != 0 || idle.Quality != 0 {
+		t.Errorf("wlan1 = %+v, want not connected", idle)
+	}
+}
+
+func TestParseIWLink(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "iw", "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := WifiInfo{
+		Connected: true, SSID: "Kaguya 5G", BSSID: "12:34:56:78:9a:bc", SignalDBm: -52,
+		FrequencyMHz: 5180, Band: "5 GHz", RxBitrate: 866.7, TxBitrate: 780,
+	}
+	if got := parseIWLink(string(data)); got != want {
+		t.Errorf("parseIWLink = %+v, want %+v", got, want)
+	}
+
+	data, _ = os.ReadFile(filepath.Join("testdata", "iw", "link-disconnected"))
+	if got := parseIWLink(string(data)); got != (WifiInfo{}) {
+		t.Errorf("disconnected = %+v", got)
+	}
+}
+
+func TestNameservers(t *testing.T) {
+	stub := filepath.Join("testdata", "etc", "resolv.conf")
+	resolved := filepath.Join("testdata", "run", "systemd", "resolve", "resolv.conf")
+
+	// The stub hides the real servers, and duplicates are dropped
+	if got, want := Nameservers(stub, resolved), []string{"192.168.1.1", "2606:4700:4700::1111"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Nameservers = %v, want %v", got, want)
+	}
+	if got := Nameservers(stub, filepath.Join("testdata", "missing")); !reflect.DeepEqual(got, []string{"127.0.0.53"}) {
+		t.Errorf("without resolved's file = %v, want the stub", got)
+	}
+	if got := Nameservers(resolved, ""); len(got) != 2 {
+		t.Errorf("plain resolv.conf = %v", got)
+	}
+}
+
+func TestProcListeningSockets(t *testing.T) {
+	sockets, err := testProcFS().ListeningSockets()
+	if err != nil {
+		t.Fatalf("ListeningSockets: %v", err)
+	}
+	want := []ListeningSocket{
+		{Protocol: "tcp", Address: "0.0.0.0", Port: 22, Inode: 23457},
+		{Protocol: "tcp6", Address: "::", Port: 22, Inode: 23458},
+		{Protocol: "tcp", Address: "127.0.0.1", Port: 631, Inode: 23456},
+		{Protocol: "tcp6", Address: "::1", Port: 631, Inode: 23459},
+		{Protocol: "tcp", Address: "127.0.0.1", Port: 8080, UID: 4242000, Inode: 34567},
+	}
+	if !reflect.DeepEqual(sockets, want) {
+		t.Errorf("sockets = %+v, want %+v", sockets, want)
+	}
+
+	if owners := testProcFS().SocketOwners(); !reflect.DeepEqual(owners, map[uint64]int{34567: 4242}) {
+		t.Errorf("SocketOwners = %v", owners)
+	}
+	if _, _, err := parseSocketAddress("0100007F"); err == nil {
+		t.Error("address without port: want error")
+	}
+}
+
+func TestAppConnectivity(t *testing.T) {
+	app := &App{proc: testProcFS(), sys: testSysFS(), procs: NewProcessTable(testProcFS()), etc: "testdata"}
+	link := func(name string) (WifiInfo, bool) {
+		return WifiInfo{Connected: true, SSID: "Kaguya 5G", FrequencyMHz: 2412, Band: "2.4 GHz"}, name == "wlp2s0"
+	}
+
+	info := app.connectivity(link)
+	if info.Gateway == nil || info.Gateway.Gateway != "192.168.1.1" || info.Gateway6 == nil {
+		t.Errorf("gateways = %+v, %+v", info.Gateway, info.Gateway6)
+	}
+	if len(info.DNS) != 2 || len(info.Listening) != 5 {
+		t.Errorf("dns = %v, %d listening", info.DNS, len(info.Listening))
+	}
+	if web := info.Listening[4]; web.PID != 4242 || web.Process != "Web Content (x)" || web.User != "4242000" {
+		t.Errorf("socket owner = %+v", web)
+	}
+
+	byName := make(map[string]InterfaceDetails)
+	for _, iface := range info.Interfaces {
+		byName[iface.Name] = iface
+	}
+	wifi := byName["wlp2s0"]
+	// iw gives the SSID, /proc/net/wireless the signal and quality
+	if wifi.MAC != "3c:9c:0f:12:34:56" || wifi.SpeedMbps != 0 || wifi.Wifi == nil ||
+		wifi.Wifi.SSID != "Kaguya 5G" || wifi.Wifi.SignalDBm != -52 || wifi.Wifi.Quality == 0 {
+		t.Errorf("wlp2s0 = %+v (wifi %+v)", wifi, wifi.Wifi)
+	}
+	if eth := byName["enp3s0"]; eth.SpeedMbps != 1000 || eth.MTU != 1500 || eth.Wifi != nil {
+		t.Errorf("enp3s0 = %+v", eth)
+	}
+}
+
+func TestIWLinkCommand(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "iw", "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := runner.NewFake().On("iw dev wlan0", string(data), nil)
+	if info, ok := iwLink(fake, "wlan0"); !ok || info.SSID != "Kaguya 5G" {
+		t.Errorf("iwLink = %+v, %v", info, ok)
+	}
+	if want := []string{"iw dev wlan0 link"}; !reflect.DeepEqual(fake.Commands(), want) {
+		t.Errorf("commands = %q, want %q", fake.Commands(), want)
+	}
+
+	missing := runner.NewFake().Missing("iw").On("iw", "", errors.New("exec: \"iw\": executable file not found in $PATH"))
+	if _, ok := iwLink(missing, "wlan0"); ok {
+		t.Error("iwLink without iw: want false")
+	}
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"kaguyadots/runner"
+)
+
+// DiskStats is one mounted filesystem. Sizes are bytes as statfs reports
+// them, so they match df -B1.
+type DiskStats struct {
+	Device     string  `json:"device"`
+	MountPoint string  `json:"mountPoint"`
+	FileSystem string  `json:"fileSystem"`
+	Total      uint64  `json:"total"`
+	Used       uint64  `json:"used"`
+	Available  uint64  `json:"available"` // free to unprivileged users
+	Percent    float64 `json:"percent"`   // used of what users can fill, like df
+	ReadOnly   bool    `json:"readOnly"`
+}
+
+// DiskIO is one block device's throughput since the previous sample
+type DiskIO struct {
+	Name             string       `json:"name"` // nvme0n1, sda
+	Model            string       `json:"model"`
+	Size             uint64       `json:"size"` // bytes
+	ReadBytesPerSec  float64      `json:"readBytesPerSec"`
+	WriteBytesPerSec float64      `json:"writeBytesPerSec"`
+	ReadIOPS         float64      `json:"readIOPS"`
+	WriteIOPS        float64      `json:"writeIOPS"`
+	Utilization      float64      `json:"utilization"` // percent of time busy
+	Health           *SmartHealth `json:"health"`      // nil without smartctl or permission
+}
+
+// SmartHealth is the part of smartctl's report worth a glance
+type SmartHealth struct {
+	Passed       bool    `json:"passed"`
+	Temp         float64 `json:"temp"` // °C
+	PowerOnHours uint64  `json:"powerOnHours"`
+	PercentUsed  float64 `json:"percentUsed"` // NVMe wear estimate
+	BadSectors   uint64  `json:"badSectors"`  // reallocated and pending (ATA), media errors (NVMe)
+}
+
+// Mount is one line of /proc/self/mountinfo
+type Mount struct {
+	ID         int
+	Device     string // major:minor
+	Root       string // subvolume or bind source within the filesystem
+	MountPoint string
+	Options    string
+	FSType     string
+	Source     string
+}
+
+// Mounts reads /proc/self/mountinfo in mount order
+func (p *ProcFS) Mounts() ([]Mount, error) {
+	f, err := os.Open(p.path("self", "mountinfo"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []Mount
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 28 22 0:30 /@home /home rw,relatime shared:9 - btrfs /dev/sda1 rw,subvol=/@home
+		before, after, ok := strings.Cut(scanner.Text(), " - ")
+		fields, super := strings.Fields(before), strings.Fields(after)
+		if !ok || len(fields) < 6 || len(super) < 2 {
+			continue
+		}
+		id, _ := strconv.Atoi(fields[0])
+		mounts = append(mounts, Mount{
+			ID:         id,
+			Device:     fields[2],
+			Root:       unescapeMount(fields[3]),
+			MountPoint: unescapeMount(fields[4]),
+			Options:    fields[5],
+			FSType:     super[0],
+			Source:     unescapeMount(super[1]),
+		})
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMount undoes the octal escapes mountinfo uses for spaces, tabs,
+// newlines and backslashes
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// DiskCounters are a block device's totals since boot from /proc/diskstats
+type DiskCounters struct {
+	Reads        uint64
+	Writes       uint64
+	ReadSectors  uint64 // always 512 bytes, whatever the device's block size
+	WriteSectors uint64
+	IOTicks      uint64 // ms spent with I/O in flight
+}
+
+const sectorSize = 512
+
+// DiskCounters reads /proc/diskstats, keyed by device name
+func (p *ProcFS) DiskCounters() (map[string]DiskCounters, error) {
+	f, err := os.Open(p.path("diskstats"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	counters := make(map[string]DiskCounters)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 13 {
+			continue
+		}
+		n := func(i int) uint64 {
+			v, _ := strconv.ParseUint(fields[i], 10, 64)
+			return v
+		}
+		counters[fields[2]] = DiskCounters{
+			Reads:        n(3),
+			ReadSectors:  n(5),
+			Writes:       n(7),
+			WriteSectors: n(9),
+			IOTicks:      n(12),
+		}
+	}
+	return counters, scanner.Err()
+}
+
+// BlockDevice is a whole disk under /sys/block
+type BlockDevice struct {
+	Name  string
+	Model string
+	Size  uint64 // bytes
+}
+
+// BlockDevices lists the disks, leaving out loop and RAM devices and
+// empty drives
+func (s *SysFS) BlockDevices() ([]BlockDevice, error) {
+	entries, err := os.ReadDir(s.path("block"))
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []BlockDevice
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") || strings.HasPrefix(name, "zram") {
+			continue
+		}
+		sectors, _ := s.readUint("block", name, "size")
+		if sectors == 0 {
+			continue
+		}
+		devices = append(devices, BlockDevice{
+			Name:  name,
+			Model: s.readString("block", name, "device", "model"),
+			Size:  sectors * sectorSize,
+		})
+	}
+	return devices, nil
+}
+
+// fsUsage is what statfs says about a filesystem, in bytes
+type fsUsage struct {
+	Total     uint64
+	Free      uint64
+	Available uint64
+}
+
+func statfs(path string) (fsUsage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return fsUsage{}, err
+	}
+	size := uint64(st.Frsize)
+	if size == 0 {
+		size = uint64(st.Bsize)
+	}
+	return fsUsage{Total: st.Blocks * size, Free: st.Bfree * size, Available: st.Bavail * size}, nil
+}
+
+// smartEvery is how long a SMART report is reused. Health moves slowly and
+// smartctl is too heavy to run every sample.
+const smartEvery = 10 * time.Minute
+
+// smartctl asks for health and attributes without spinning up a drive
+// that is in standby
+func smartctl(r runner.Runner, device string) ([]byte, error) {
+	return toolOutput(r, "smartctl", "--json", "-H", "-A", "-n", "standby", "/dev/"+device)
+}
+
+// DiskMonitor reads filesystem usage, per-disk I/O rates and SMART health
+type DiskMonitor struct {
+	proc   *ProcFS
+	sys    *SysFS
+	statfs func(path string) (fsUsage, error)
+	smart  func(device string) ([]byte, error)
+	now    func() time.Time
+
+	mu       sync.Mutex
+	prev     map[string]DiskCounters
+	prevTime time.Time
+	health   map[string]smartReport
+	noSmart  bool // smartctl isn't installed
+}
+
+type smartReport struct {
+	health  *SmartHealth
+	checked time.Time
+}
+
+func NewDiskMonitor(proc *ProcFS, sys *SysFS, r runner.Runner) *DiskMonitor {
+	return &DiskMonitor{
+		proc:   proc,
+		sys:    sys,
+		statfs: statfs,
+		smart:  func(device string) ([]byte, error) { return smartctl(r, device) },
+		now:    time.Now,
+		health: make(map[string]smartReport),
+	}
+}
+
+// Usage returns the mounted block-device filesystems in mount order. A
+// filesystem mounted twice (btrfs subvolumes, bind mounts) is listed once,
+// at its first mount point.
+func (m *DiskMonitor) Usage() []DiskStats {
+	disks := []DiskStats{}
+	mounts, err := m.proc.Mounts()
+	if err != nil {
+		return disks
+	}
+
+	seen := make(map[string]bool)
+	for _, mount := range mounts {
+		if !strings.HasPrefix(mount.Source, "/dev/") || mount.FSType == "squashfs" || seen[mount.Device] {
+			continue
+		}
+		usage, err := m.statfs(mount.MountPoint)
+		if err != nil || usage.Total == 0 {
+			continue
+		}
+		seen[mount.Device] = true
+
+		used := usage.Total - usage.Free
+		disk := DiskStats{
+			Device:     mount.Source,
+			MountPoint: mount.MountPoint,
+			FileSystem: mount.FSType,
+			Total:      usage.Total,
+			Used:       used,
+			Available:  usage.Available,
+			ReadOnly:   slices.Contains(strings.Split(mount.Options, ","), "ro"),
+		}
+		if fillable := used + usage.Available; fillable > 0 {
+			disk.Percent = float64(used) / float64(fillable) * 100
+		}
+		disks = append(disks, disk)
+	}
+	return disks
+}
+
+// IO returns every disk's rates since the previous call, zero on the first
+func (m *DiskMonitor) IO() []DiskIO {
+	disks := []DiskIO{}
+	devices, err := m.sys.BlockDevices()
+	if err != nil {
+		return disks
+	}
+	counters, _ := m.proc.DiskCounters()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	seconds := now.Sub(m.prevTime).Seconds()
+	prev := m.prev
+	m.prev = counters
+	m.prevTime = now
+
+	for _, device := range devices {
+		disk := DiskIO{Name: device.Name, Model: device.Model, Size: device.Size, Health: m.smartHealth(device.Name, now)}
+		cur, ok := counters[device.Name]
+		before, seen := prev[device.Name]
+		if ok && seen && seconds > 0 {
+			disk.ReadBytesPerSec = counterRate(before.ReadSectors, cur.ReadSectors, seconds) * sectorSize
+			disk.WriteBytesPerSec = counterRate(before.WriteSectors, cur.WriteSectors, seconds) * sectorSize
+			disk.ReadIOPS = counterRate(before.Reads, cur.Reads, seconds)
+			disk.WriteIOPS = counterRate(before.Writes, cur.Writes, seconds)
+			disk.Utilization = clampPercent(counterRate(before.IOTicks, cur.IOTicks, seconds) / 10)
+		}
+		disks = append(disks, disk)
+	}
+	return disks
+}
+
+// smartHealth returns the cached report for a disk, asking smartctl again
+// once it is stale. A drive in standby or one smartctl can't open keeps
+// its last report.
+func (m *DiskMonitor) smartHealth(device string, now time.Time) *SmartHealth {
+	report, ok := m.health[device]
+	if m.noSmart || (ok && now.Sub(report.checked) < smartEvery) {
+		return report.health
+	}
+
+	// smartctl's exit status is a bit mask that is set for failing drives
+	// too, so the output is looked at whatever the error
+	out, err := m.smart(device)
+	if errors.Is(err, exec.ErrNotFound) {
+		m.noSmart = true
+		return nil
+	}
+	if health, ok := parseSmartctl(out); ok {
+		report.health = health
+	}
+	report.checked = now
+	m.health[device] = report
+	return report.health
+}
+
+// parseSmartctl reads smartctl --json output; ok is false when it has no
+// health verdict (no permission, drive asleep, unsupported device)
+func parseSmartctl(out []byte) (*SmartHealth, bool) {
+	var report struct {
+		SmartStatus *struct {
+			Passed bool `json:"passed"`
+		} `json:"smart_status"`
+		Temperature struct {
+			Current float64 `json:"current"`
+		} `json:"temperature"`
+		PowerOnTime struct {
+			Hours uint64 `json:"hours"`
+		} `json:"power_on_time"`
+		NVMe *struct {
+			PercentageUsed float64 `json:"percentage_used"`
+			MediaErrors    uint64  `json:"media_errors"`
+		} `json:"nvme_smart_health_information_log"`
+		ATA struct {
+			Table []struct {
+				ID  int `json:"id"`
+				Raw struct {
+					Value uint64 `json:"value"`
+				} `json:"raw"`
+			} `json:"table"`
+		} `json:"ata_smart_attributes"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil || report.SmartStatus == nil {
+		return nil, false
+	}
+
+	health := &SmartHealth{
+		Passed:       report.SmartStatus.Passed,
+		Temp:         report.Temperature.Current,
+		PowerOnHours: report.PowerOnTime.Hours,
+	}
+	if report.NVMe != nil {
+		health.PercentUsed = report.NVMe.PercentageUsed
+		health.BadSectors = report.NVMe.MediaErrors
+	}
+	for _, attr := range report.ATA.Table {
+		// 5 Reallocated_Sector_Ct, 197 Current_Pending_Sector
+		if attr.ID == 5 || attr.ID == 197 {
+			health.BadSectors += attr.Raw.Value
+		}
+	}
+	return health, true
+}
+
+// counterRate is the per-second increase of a counter; one that went
+// backwards (device re-added) has no rate
+func counterRate(prev, cur uint64, seconds float64) float64 {
+	if cur < prev {
+		return 0
+	}
+	return float64(cur-prev) / seconds
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"kaguyadots/runner"
+)
+
+func testDiskMonitor(proc *ProcFS) (*DiskMonitor, *int) {
+	calls := 0
+	m := NewDiskMonitor(proc, testSysFS(), runner.NewFake())
+	m.statfs = func(path string) (fsUsage, error) {
+		switch path {
+		case "/":
+			return fsUsage{Total: 100 << 30, Free: 30 << 30, Available: 25 << 30}, nil
+		case "/boot":
+			return fsUsage{Total: 512 << 20, Free: 384 << 20, Available: 384 << 20}, nil
+		case "/home":
+			return fsUsage{Total: 2000 << 30, Free: 100 << 30, Available: 100 << 30}, nil
+		case "/run/media/kaguya/USB Stick":
+			return fsUsage{Total: 16 << 30, Free: 4 << 30, Available: 4 << 30}, nil
+		}
+		return fsUsage{}, fmt.Errorf("statfs %s: permission denied", path)
+	}
+	m.smart = func(device string) ([]byte, error) {
+		calls++
+		return os.ReadFile(filepath.Join("testdata", "smartctl", device+".json"))
+	}
+	return m, &calls
+}
+
+func TestProcMounts(t *testing.T) {
+	mounts, err := testProcFS().Mounts()
+	if err != nil {
+		t.Fatalf("Mounts: %v", err)
+	}
+	if len(mounts) != 10 {
+		t.Fatalf("mounts = %d, want 10", len(mounts))
+	}
+	want := Mount{ID: 28, Device: "0:30", Root: "/@home", MountPoint: "/home", Options: "rw,relatime", FSType: "btrfs", Source: "/dev/sda1"}
+	if mounts[6] != want {
+		t.Errorf("mounts[6] = %+v, want %+v", mounts[6], want)
+	}
+	if got := mounts[9].MountPoint; got != "/run/media/kaguya/USB Stick" {
+		t.Errorf("escaped mount point = %q", got)
+	}
+}
+
+func TestUnescapeMount(t *testing.T) {
+	for in, want := range map[string]string{
+		`/mnt/a\040b`:   "/mnt/a b",
+		`/mnt/tab\011x`: "/mnt/tab\tx",
+		`/mnt/back\134`: `/mnt/back\`,
+		`/mnt/plain`:    "/mnt/plain",
+		`/mnt/odd\04`:   `/mnt/odd\04`,
+	} {
+		if got := unescapeMount(in); got != want {
+			t.Errorf("unescapeMount(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	m, _ := testDiskMonitor(testProcFS())
+	disks := m.Usage()
+
+	var points []string
+	for _, disk := range disks {
+		points = append(points, disk.MountPoint)
+	}
+	// Pseudo filesystems, snaps and the second btrfs subvolume are left out
+	if want := []string{"/", "/boot", "/home", "/run/media/kaguya/USB Stick"}; !reflect.DeepEqual(points, want) {
+		t.Fatalf("mount points = %v, want %v", points, want)
+	}
+
+	root := DiskStats{
+		Device: "/dev/nvme0n1p2", MountPoint: "/", FileSystem: "ext4",
+		Total: 100 << 30, Used: 70 << 30, Available: 25 << 30, Percent: disks[0].Percent,
+	}
+	// Reserved blocks don't count, like df: 70 of the 95 users can fill
+	if percent := disks[0].Percent; percent < 73.68 || percent > 73.69 {
+		t.Errorf("root percent = %v, want 73.68", percent)
+	}
+	if disks[0] != root {
+		t.Errorf("root = %+v, want %+v", disks[0], root)
+	}
+	if home := disks[2]; home.FileSystem != "btrfs" || home.Percent != 95 || home.ReadOnly {
+		t.Errorf("home = %+v", home)
+	}
+	if usb := disks[3]; !usb.ReadOnly || usb.Device != "/dev/sdb1" {
+		t.Errorf("usb = %+v", usb)
+	}
+}
+
+func TestDiskIO(t *testing.T) {
+	root := copyTree(t, filepath.Join("testdata", "proc"))
+	m, calls := testDiskMonitor(NewProcFS(root))
+	now := time.Unix(1760000000, 0)
+	m.now = func() time.Time { return now }
+
+	first := m.IO()
+	if len(first) != 2 || first[0].Name != "nvme0n1" || first[1].Name != "sda" {
+		t.Fatalf("disks = %+v, want nvme0n1 and sda", first)
+	}
+	if nvme := first[0]; nvme.Model != "Samsung SSD 980 PRO 1TB" || nvme.Size != 1953525168*512 || nvme.ReadBytesPerSec != 0 {
+		t.Errorf("first nvme0n1 = %+v", nvme)
+	}
+
+	// 2s later: 4000 more sectors and 200 reads, 1000 sectors and 50
+	// writes, and 500ms busy
+	stats := " 259       0 nvme0n1 120200 500 9004000 40100 80050 3000 6001000 90100 0 60500 130200 0 0 0 0 1000 2000\n" +
+		"   8       0 sda 5000 10 400000 3000 2000 5 160000 4000 0 5000 7000\n"
+	if err := os.WriteFile(filepath.Join(root, "diskstats"), []byte(stats), 0644); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Second)
+
+	disks := m.IO()
+	nvme := disks[0]
+	if nvme.ReadBytesPerSec != 1024000 || nvme.WriteBytesPerSec != 256000 || nvme.ReadIOPS != 100 || nvme.WriteIOPS != 25 || nvme.Utilization != 25 {
+		t.Errorf("nvme0n1 = %+v", nvme)
+	}
+	if sda := disks[1]; sda.ReadBytesPerSec != 0 || sda.Utilization != 0 {
+		t.Errorf("idle sda = %+v", sda)
+	}
+
+	// SMART is asked once per disk and reused until it is stale
+	if *calls != 2 {
+		t.Errorf("smartctl calls = %d, want 2", *calls)
+	}
+	want := SmartHealth{Passed: true, Temp: 39, PowerOnHours: 4321, PercentUsed: 3}
+	if nvme.Health == nil || *nvme.Health != want {
+		t.Errorf("nvme0n1 health = %+v, want %+v", nvme.Health, want)
+	}
+	if sda := disks[1].Health; sda == nil || sda.Passed || sda.BadSectors != 160 {
+		t.Errorf("sda health = %+v, want failing with 160 bad sectors", sda)
+	}
+}
+
+func TestSmartHealthCache(t *testing.T) {
+	m, calls := testDiskMonitor(testProcFS())
+	now := time.Unix(1760000000, 0)
+
+	healthy := m.smartHealth("nvme0n1", now)
+	// A drive that went to sleep keeps its last report
+	m.smart = func(string) ([]byte, error) {
+		*calls++
+		return os.ReadFile(filepath.Join("testdata", "smartctl", "standby.json"))
+	}
+	if got := m.smartHealth("nvme0n1", now.Add(smartEvery)); got != healthy || *calls != 2 {
+		t.Errorf("standby = %+v after %d calls, want the last report", got, *calls)
+	}
+
+	m.smart = func(string) ([]byte, error) {
+		*calls++
+		return nil, &exec.Error{Name: "smartctl", Err: exec.ErrNotFound}
+	}
+	if got := m.smartHealth("sda", now); got != nil {
+		t.Errorf("without smartctl = %+v", got)
+	}
+	m.smartHealth("sdb", now)
+	if !m.noSmart || *calls != 3 {
+		t.Errorf("noSmart = %v after %d calls, want smartctl given up on", m.noSmart, *calls)
+	}
+}
+
+func TestParseSmartctl(t *testing.T) {
+	if _, ok := parseSmartctl([]byte("not json")); ok {
+		t.Error("garbage: want no report")
+	}
+	out, err := os.ReadFile(filepath.Join("testdata", "smartctl", "standby.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := parseSmartctl(out); ok {
+		t.Error("standby: want no report")
+	}
+}
+
+func TestSmartctlCommand(t *testing.T) {
+	fake := runner.NewFake().On("smartctl", `{"smart_status": {"passed": true}}`, nil)
+	out, err := NewDiskMonitor(testProcFS(), testSysFS(), fake).smart("nvme0n1")
+	if health, ok := parseSmartctl(out); err != nil || !ok || !health.Passed {
+		t.Errorf("smartctl = %s, %v", out, err)
+	}
+	if want := []string{"smartctl --json -H -A -n standby /dev/nvme0n1"}; !reflect.DeepEqual(fake.Commands(), want) {
+		t.Errorf("commands = %q, want %q", fake.Commands(), want)
+	}
+}
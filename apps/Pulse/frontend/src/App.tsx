@@ -1,12 +1,16 @@
 import React, { useState, useEffect } from "react";
 import {
   Activity,
+  AlertTriangle,
+  BatteryMedium,
   Cpu,
   HardDrive,
   Thermometer,
   Zap,
   Clock,
+  Globe,
   List,
+  ListTree,
   LucideIcon,
 } from "lucide-react";
 import KaguyaDotsLoader from "./components/loader";
@@ -18,10 +22,30 @@ interface GTKColors {
   cardBgColor: string;
 }
 
+interface CPUBreakdown {
+  usage: number;
+  user: number;
+  system: number;
+  iowait: number;
+  steal: number;
+}
+
+interface CoreStats extends CPUBreakdown {
+  id: number;
+  curMHz: number;
+  minMHz: number;
+  maxMHz: number;
+  governor: string;
+}
+
 interface CPUStats {
   usage: number;
   model: string;
   cores: number;
+  breakdown: CPUBreakdown;
+  perCore: CoreStats[] | null;
+  load: { one: number; five: number; fifteen: number };
+  governor: string;
 }
 
 interface RAMStats {
@@ -37,29 +61,110 @@ interface SwapStats {
 }
 
 interface GPUStats {
-  usage: number;
+  card: string;
+  vendor: string;
   name: string;
+  driver: string;
+  pciSlot: string;
+  usage: number;
+  memoryUsed: number;
+  memoryTotal: number;
   temp: number;
-  memory: number;
+  powerWatts: number;
+  coreMHz: number;
+  maxCoreMHz: number;
+  memoryMHz: number;
+  rc6: number;
 }
 
+// High and crit are the CPU sensor's own limits, zero when it has none
 interface TempStats {
   cpu: number;
+  high: number;
+  crit: number;
+  label: string;
+  unit: string;
+}
+
+interface Sensor {
+  id: string;
+  kind: string;
+  label: string;
+  value: number;
+  min: number;
   max: number;
+  crit: number;
+  unit: string;
+  alarm: boolean;
+}
+
+interface SensorChip {
+  id: string; // what alert rules use: sensor.<id>.<sensor id>
+  hwmon: string;
+  name: string;
+  device: string;
+  sensors: Sensor[];
 }
 
+interface InterfaceStats {
+  name: string;
+  type: string;
+  state: string;
+  rxBytesPerSec: number;
+  txBytesPerSec: number;
+  rxPacketsPerSec: number;
+  txPacketsPerSec: number;
+  rxBytes: number;
+  txBytes: number;
+  rxErrors: number;
+  txErrors: number;
+  rxDropped: number;
+  txDropped: number;
+}
+
+// Bytes per second; down and up add up the wifi and ethernet interfaces
 interface NetworkStats {
-  down: string;
-  up: string;
+  down: number;
+  up: number;
+  interfaces: InterfaceStats[];
 }
 
+// Sizes are bytes; percent is of what users can fill, like df
 interface DiskStats {
   mountPoint: string;
   device: string;
+  fileSystem: string;
   total: number;
   used: number;
+  available: number;
   percent: number;
-  fileSystem: string;
+  readOnly: boolean;
+}
+
+interface SmartHealth {
+  passed: boolean;
+  temp: number;
+  powerOnHours: number;
+  percentUsed: number;
+  badSectors: number;
+}
+
+interface DiskIO {
+  name: string;
+  model: string;
+  size: number;
+  readBytesPerSec: number;
+  writeBytesPerSec: number;
+  readIOPS: number;
+  writeIOPS: number;
+  utilization: number;
+  health: SmartHealth | null;
+}
+
+interface BatteryStats {
+  percent: number;
+  status: string;
+  charging: boolean;
 }
 
 interface SystemStats {
@@ -68,12 +173,115 @@ interface SystemStats {
   cpu: CPUStats;
   ram: RAMStats;
   swap: SwapStats;
-  gpu: GPUStats;
+  gpus: GPUStats[];
   temp: TempStats;
+  sensors: SensorChip[];
   network: NetworkStats;
   disks: DiskStats[];
+  diskIO: DiskIO[];
+  battery: BatteryStats | null;
+}
+
+interface ProcessInfo {
+  pid: number;
+  ppid: number;
+  name: string;
+  user: string;
+  state: string;
+  cpu: number;
+  rss: number;
+  threads: number;
+  nice: number;
+  startTime: string;
+  cmdline: string;
+  cgroup: string;
+  depth: number;
+}
+
+interface ProcessQuery {
+  sortBy: string;
+  desc: boolean;
+  filter: string;
+  user: string;
+  tree: boolean;
+  limit: number;
+}
+
+interface ProcessList {
+  processes: ProcessInfo[];
+  total: number;
+}
+
+interface WifiInfo {
+  connected: boolean;
+  ssid: string;
+  bssid: string;
+  signalDbm: number;
+  quality: number;
+  frequencyMHz: number;
+  band: string;
+  rxBitrate: number;
+  txBitrate: number;
+}
+
+interface InterfaceDetails {
+  name: string;
+  type: string;
+  state: string;
+  mac: string;
+  mtu: number;
+  speedMbps: number;
+  ipv4: string[] | null;
+  ipv6: string[] | null;
+  wifi?: WifiInfo;
+}
+
+interface Route {
+  interface: string;
+  gateway: string;
+  metric: number;
+}
+
+interface ListeningSocket {
+  protocol: string;
+  address: string;
+  port: number;
+  uid: number;
+  user: string;
+  pid: number;
+  process: string;
+}
+
+interface Connectivity {
+  interfaces: InterfaceDetails[];
+  gateway: Route | null;
+  gateway6: Route | null;
+  dns: string[] | null;
+  listening: ListeningSocket[];
 }
 
+// An alert rule from [pulse.alerts] starting or stopping to fire
+interface Alert {
+  rule: string;
+  metric: string;
+  value: number;
+  threshold: number;
+  below: boolean;
+  firing: boolean;
+  time: number;
+}
+
+interface HistoryPoint {
+  t: number;
+  v: number;
+}
+
+type History = Record<string, HistoryPoint[]>;
+
+// Metrics shown as sparklines and how many seconds of them to keep
+const historyMetrics = ["cpu", "ram", "net.down", "net.up"];
+const historyWindow = 300;
+
 // Extend Window interface
 declare global {
   interface Window {
@@ -82,9 +290,18 @@ declare global {
         App: {
           GetEnhancedSystemStats: () => Promise<SystemStats>;
           GetGTKColors: () => Promise<GTKColors>;
+          GetProcesses: (query: ProcessQuery) => Promise<ProcessList>;
+          SignalProcess: (pid: number, signal: string) => Promise<void>;
+          ReniceProcess: (pid: number, nice: number) => Promise<void>;
+          GetHistory: (metric: string, window: number) => Promise<HistoryPoint[]>;
+          GetConnectivity: () => Promise<Connectivity>;
+          GetAlerts: () => Promise<Alert[]>;
         };
       };
     };
+    runtime: {
+      EventsOn: (event: string, callback: (data: any) => void) => () => void;
+    };
   }
 }
 
@@ -104,15 +321,78 @@ interface StatCardProps {
   color?: string;
 }
 
+interface SparklineProps {
+  points: HistoryPoint[];
+  color?: string;
+  max?: number;
+  height?: number;
+}
+
 interface ProgressBarProps {
   value: number;
   color?: string;
   label: string;
 }
 
+const processColumns: { key: string; label: string }[] = [
+  { key: "pid", label: "PID" },
+  { key: "name", label: "Name" },
+  { key: "user", label: "User" },
+  { key: "cpu", label: "CPU %" },
+  { key: "memory", label: "Memory" },
+  { key: "threads", label: "Threads" },
+  { key: "start", label: "Started" },
+];
+
+const formatBytes = (bytes: number) => {
+  const units = ["B", "KB", "MB", "GB", "TB"];
+  let i = 0;
+  while (bytes >= 1024 && i < units.length - 1) {
+    bytes /= 1024;
+    i++;
+  }
+  return `${bytes.toFixed(i === 0 ? 0 : 1)} ${units[i]}`;
+};
+
+// The scale a reading is drawn against: its critical limit, else its
+// high limit, else 100°C for temperatures without either
+const sensorLimit = (crit: number, high: number) => crit || high || 100;
+
+// Warning colours follow the sensor's own limits rather than a fixed 85°C
+const limitColor = (value: number, high: number, crit: number, base: string) => {
+  if (crit > 0 && value >= crit) return "#ef4444";
+  if (high > 0 && value >= high) return "#f97316";
+  if (crit > 0 && value >= crit * 0.9) return "#f59e0b";
+  return base;
+};
+
+const formatSensor = (sensor: Sensor) => {
+  switch (sensor.kind) {
+    case "fan":
+      return `${Math.round(sensor.value)} RPM`;
+    case "temp":
+      return `${sensor.value.toFixed(1)}°C`;
+    default:
+      return `${sensor.value.toFixed(2)} ${sensor.unit}`;
+  }
+};
+
 const SystemMonitor = () => {
   const [stats, setStats] = useState<SystemStats | null>(null);
   const [gtkColors, setGtkColors] = useState<GTKColors | null>(null);
+  const [processes, setProcesses] = useState<ProcessList | null>(null);
+  const [processQuery, setProcessQuery] = useState<ProcessQuery>({
+    sortBy: "cpu",
+    desc: true,
+    filter: "",
+    user: "",
+    tree: false,
+    limit: 50,
+  });
+  const [processError, setProcessError] = useState<string | null>(null);
+  const [history, setHistory] = useState<History>({});
+  const [connectivity, setConnectivity] = useState<Connectivity | null>(null);
+  const [alerts, setAlerts] = useState<Alert[]>([]);
 
   useEffect(() => {
     const fetchGTKColors = async () => {
@@ -129,19 +409,115 @@ const SystemMonitor = () => {
   }, []);
 
   useEffect(() => {
-    const fetchStats = async () => {
+    const loadStats = async () => {
       try {
-        const data = await window.go.main.App.GetEnhancedSystemStats();
+        const [data, ...series] = await Promise.all([
+          window.go.main.App.GetEnhancedSystemStats(),
+          ...historyMetrics.map((metric) =>
+            window.go.main.App.GetHistory(metric, historyWindow).catch(
+              () => [] as HistoryPoint[],
+            ),
+          ),
+        ]);
         setStats(data);
+        setHistory(
+          Object.fromEntries(
+            historyMetrics.map((metric, i) => [metric, series[i] || []]),
+          ),
+        );
       } catch (err) {
         console.error("Failed to fetch stats:", err);
       }
     };
-    fetchStats();
-    const statsInterval = setInterval(fetchStats, 1000);
-    return () => clearInterval(statsInterval);
+    loadStats();
+
+    // The sampler pushes every new sample; extend the sparklines locally
+    // rather than refetching the history
+    return window.runtime.EventsOn("stats", (data: SystemStats) => {
+      setStats(data);
+      const now = Date.now();
+      const values: Record<string, number> = {
+        cpu: data.cpu.usage,
+        ram: data.ram.percent,
+        "net.down": data.network.down,
+        "net.up": data.network.up,
+      };
+      setHistory((prev) => {
+        const next: History = {};
+        for (const metric of historyMetrics) {
+          next[metric] = [
+            ...(prev[metric] || []).filter(
+              (p) => p.t >= now - historyWindow * 1000,
+            ),
+            { t: now, v: values[metric] },
+          ];
+        }
+        return next;
+      });
+    });
+  }, []);
+
+  // Alerts firing before the window opened, then every change as it
+  // happens; a rule that fires again replaces its earlier alert
+  useEffect(() => {
+    window.go.main.App.GetAlerts()
+      .then(setAlerts)
+      .catch((err) => console.error("Failed to fetch alerts:", err));
+    return window.runtime.EventsOn("alert", (alert: Alert) => {
+      setAlerts((prev) => [
+        ...prev.filter((a) => a.rule !== alert.rule),
+        ...(alert.firing ? [alert] : []),
+      ]);
+    });
+  }, []);
+
+  // Addresses and associations change rarely, and reading them runs iw
+  useEffect(() => {
+    const fetchConnectivity = async () => {
+      try {
+        setConnectivity(await window.go.main.App.GetConnectivity());
+      } catch (err) {
+        console.error("Failed to fetch connectivity:", err);
+      }
+    };
+    fetchConnectivity();
+    const connectivityInterval = setInterval(fetchConnectivity, 10000);
+    return () => clearInterval(connectivityInterval);
   }, []);
 
+  const fetchProcesses = async () => {
+    try {
+      const list = await window.go.main.App.GetProcesses(processQuery);
+      setProcesses(list);
+    } catch (err) {
+      console.error("Failed to fetch processes:", err);
+    }
+  };
+
+  useEffect(() => {
+    fetchProcesses();
+    const processInterval = setInterval(fetchProcesses, 2000);
+    return () => clearInterval(processInterval);
+  }, [processQuery]);
+
+  const sortProcesses = (key: string) => {
+    setProcessQuery((q) => ({
+      ...q,
+      sortBy: key,
+      desc: q.sortBy === key ? !q.desc : key !== "name" && key !== "user",
+    }));
+  };
+
+  const runProcessAction = async (action: () => Promise<void>) => {
+    try {
+      await action();
+      setProcessError(null);
+      fetchProcesses();
+    } catch (err) {
+      setProcessError(String(err));
+    }
+  };
+
   if (!stats || !gtkColors) {
     return (
       <div
@@ -223,6 +599,46 @@ const SystemMonitor = () => {
     </div>
   );
 
+  const Sparkline: React.FC<SparklineProps> = ({
+    points,
+    color = accent,
+    max,
+    height = 32,
+  }) => {
+    if (points.length < 2) {
+      return <div style={{ height }} />;
+    }
+    const top = max ?? Math.max(...points.map((p) => p.v), 1);
+    const start = points[0].t;
+    const span = Math.max(points[points.length - 1].t - start, 1);
+    const line = points
+      .map(
+        (p) =>
+          `${(((p.t - start) / span) * 100).toFixed(2)},${(height - (p.v / top) * height).toFixed(2)}`,
+      )
+      .join(" ");
+    return (
+      <svg
+        width="100%"
+        height={height}
+        viewBox={`0 0 100 ${height}`}
+        preserveAspectRatio="none"
+      >
+        <polygon
+          points={`0,${height} ${line} 100,${height}`}
+          fill={`${color}20`}
+        />
+        <polyline
+          points={line}
+          fill="none"
+          stroke={color}
+          strokeWidth={1.5}
+          vectorEffect="non-scaling-stroke"
+        />
+      </svg>
+    );
+  };
+
   const ProgressBar: React.FC<ProgressBarProps> = ({
     value,
     color = accent,
@@ -271,9 +687,52 @@ const SystemMonitor = () => {
             <List size={14} />
             <span>Processes: {stats.processCount}</span>
           </div>
+          {stats.battery && (
+            <div className="flex items-center gap-2">
+              <BatteryMedium size={14} />
+              <span>
+                Battery: {Math.round(stats.battery.percent)}%
+                {stats.battery.status && ` (${stats.battery.status})`}
+              </span>
+            </div>
+          )}
         </div>
       </div>
 
+      {/* Alerts */}
+      {alerts.length > 0 && (
+        <div className="mb-6 space-y-2">
+          {alerts.map((alert) => (
+            <div
+              key={alert.rule}
+              className="flex items-center gap-3 rounded-lg px-4 py-2 text-sm"
+              style={{
+                backgroundColor: "#ef444420",
+                border: "1px solid #ef444460",
+                color: fg,
+              }}
+            >
+              <AlertTriangle size={16} style={{ color: "#ef4444" }} />
+              <span className="font-semibold">{alert.rule}</span>
+              <span className="flex-1" style={{ color: `${fg}cc` }}>
+                {alert.metric} at {Math.round(alert.value * 10) / 10},{" "}
+                {alert.below ? "below" : "above"} {alert.threshold} since{" "}
+                {new Date(alert.time).toLocaleTimeString()}
+              </span>
+              <button
+                onClick={() =>
+                  setAlerts((prev) => prev.filter((a) => a.rule !== alert.rule))
+                }
+                className="text-xs px-2 py-0.5 rounded"
+                style={{ border: `1px solid ${fg}30`, color: `${fg}cc` }}
+              >
+                Dismiss
+              </button>
+            </div>
+          ))}
+        </div>
+      )}
+
       <div className="grid grid-cols-1 lg:grid-cols-3 gap-6">
         {/* CPU Section */}
         <StatCard title="Processor" icon={Cpu} color={accent}>
@@ -293,10 +752,45 @@ const SystemMonitor = () => {
                 <div className="font-medium">{stats.cpu.model}</div>
                 <div className="mt-1" style={{ color: `${fg}80` }}>
                   {stats.cpu.cores} Cores
+                  {stats.cpu.governor && ` · ${stats.cpu.governor}`}
+                </div>
+                <div className="mt-1" style={{ color: `${fg}80` }}>
+                  Load {stats.cpu.load.one.toFixed(2)}{" "}
+                  {stats.cpu.load.five.toFixed(2)}{" "}
+                  {stats.cpu.load.fifteen.toFixed(2)}
+                </div>
+                <div className="mt-1" style={{ color: `${fg}80` }}>
+                  usr {Math.round(stats.cpu.breakdown.user)}% · sys{" "}
+                  {Math.round(stats.cpu.breakdown.system)}% · io{" "}
+                  {Math.round(stats.cpu.breakdown.iowait)}%
+                  {stats.cpu.breakdown.steal >= 1 &&
+                    ` · steal ${Math.round(stats.cpu.breakdown.steal)}%`}
                 </div>
               </div>
             </div>
           </div>
+          <div className="mt-3">
+            <Sparkline points={history.cpu || []} color={accent} max={100} />
+          </div>
+          {stats.cpu.perCore && stats.cpu.perCore.length > 0 && (
+            <div
+              className="mt-4 pt-3 grid grid-cols-2 gap-x-4 gap-y-2"
+              style={{ borderTop: `1px solid ${fg}20` }}
+            >
+              {stats.cpu.perCore.map((core) => (
+                <ProgressBar
+                  key={core.id}
+                  value={core.usage}
+                  color={accent}
+                  label={
+                    core.curMHz > 0
+                      ? `CPU ${core.id} · ${(core.curMHz / 1000).toFixed(2)} GHz`
+                      : `CPU ${core.id}`
+                  }
+                />
+              ))}
+            </div>
+          )}
         </StatCard>
 
         {/* Memory Section */}
@@ -337,74 +831,144 @@ const SystemMonitor = () => {
                 </div>
               </div>
             </div>
+            <Sparkline points={history.ram || []} color="#8b5cf6" max={100} />
           </div>
         </StatCard>
 
         {/* GPU Section */}
         <StatCard title="Graphics" icon={Zap} color="#ec4899">
-          <div className="flex items-center justify-between">
-            <CircularProgress
-              value={stats.gpu.usage}
-              color="#ec4899"
-              size={100}
-            >
-              <div className="text-center">
-                <div className="text-2xl font-bold" style={{ color: fg }}>
-                  {Math.round(stats.gpu.usage)}%
-                </div>
-                <div className="text-xs" style={{ color: `${fg}80` }}>
-                  GPU
-                </div>
-              </div>
-            </CircularProgress>
-            <div className="flex-1 ml-4 space-y-2">
-              <div className="text-xs" style={{ color: `${fg}cc` }}>
-                <div className="font-medium">{stats.gpu.name}</div>
-                {stats.gpu.temp > 0 && (
-                  <div className="mt-1" style={{ color: `${fg}80` }}>
-                    Temp: {Math.round(stats.gpu.temp)}°C
+          {stats.gpus.length === 0 && (
+            <div className="text-xs" style={{ color: `${fg}80` }}>
+              No GPU detected
+            </div>
+          )}
+          <div className="space-y-4">
+            {stats.gpus.map((gpu, idx) => (
+              <div
+                key={gpu.card || gpu.pciSlot}
+                className="flex items-center justify-between"
+                style={
+                  idx > 0
+                    ? { borderTop: `1px solid ${fg}20`, paddingTop: "1rem" }
+                    : undefined
+                }
+              >
+                <CircularProgress
+                  value={gpu.usage}
+                  color="#ec4899"
+                  size={idx === 0 ? 100 : 72}
+                >
+                  <div className="text-center">
+                    <div className="text-xl font-bold" style={{ color: fg }}>
+                      {Math.round(gpu.usage)}%
+                    </div>
+                    <div className="text-xs" style={{ color: `${fg}80` }}>
+                      {gpu.card || "GPU"}
+                    </div>
                   </div>
-                )}
-                {stats.gpu.memory > 0 && (
-                  <div style={{ color: `${fg}80` }}>
-                    VRAM: {Math.round(stats.gpu.memory)} MB
+                </CircularProgress>
+                <div className="flex-1 ml-4 space-y-1 text-xs" style={{ color: `${fg}80` }}>
+                  <div className="font-medium" style={{ color: `${fg}cc` }}>
+                    {gpu.name}
                   </div>
-                )}
+                  {gpu.temp > 0 && <div>Temp: {Math.round(gpu.temp)}°C</div>}
+                  {gpu.powerWatts > 0 && <div>Power: {gpu.powerWatts.toFixed(1)} W</div>}
+                  {gpu.coreMHz > 0 && (
+                    <div>
+                      Core: {Math.round(gpu.coreMHz)}
+                      {gpu.maxCoreMHz > 0 && ` / ${Math.round(gpu.maxCoreMHz)}`} MHz
+                      {gpu.memoryMHz > 0 && ` · Mem: ${Math.round(gpu.memoryMHz)} MHz`}
+                    </div>
+                  )}
+                  {gpu.vendor === "intel" && gpu.rc6 > 0 && (
+                    <div>RC6: {Math.round(gpu.rc6)}%</div>
+                  )}
+                  {gpu.memoryTotal > 0 && (
+                    <ProgressBar
+                      value={(gpu.memoryUsed / gpu.memoryTotal) * 100}
+                      color="#ec4899"
+                      label={`VRAM ${formatBytes(gpu.memoryUsed)} / ${formatBytes(gpu.memoryTotal)}`}
+                    />
+                  )}
+                </div>
               </div>
-            </div>
+            ))}
           </div>
         </StatCard>
 
         {/* Temperature */}
         <StatCard title="Temperature" icon={Thermometer} color="#f43f5e">
-          <div className="flex items-center justify-between">
-            <CircularProgress
-              value={(stats.temp.cpu / stats.temp.max) * 100}
-              color="#f43f5e"
-              size={100}
-            >
-              <div className="text-center">
-                <div className="text-2xl font-bold" style={{ color: fg }}>
-                  {Math.round(stats.temp.cpu)}°
-                </div>
-                <div className="text-xs" style={{ color: `${fg}80` }}>
-                  CPU
+          {(() => {
+            const { cpu, high, crit, label } = stats.temp;
+            const percent = (cpu / sensorLimit(crit, high)) * 100;
+            const color = limitColor(cpu, high, crit, "#f43f5e");
+            return (
+              <div className="flex items-center justify-between">
+                <CircularProgress value={percent} color={color} size={100}>
+                  <div className="text-center">
+                    <div className="text-2xl font-bold" style={{ color: fg }}>
+                      {Math.round(cpu)}°
+                    </div>
+                    <div className="text-xs" style={{ color: `${fg}80` }}>
+                      CPU
+                    </div>
+                  </div>
+                </CircularProgress>
+                <div className="flex-1 ml-4">
+                  <div className="text-xs space-y-2" style={{ color: `${fg}cc` }}>
+                    <div>Current: {Math.round(cpu)}°C</div>
+                    {label && <div>Sensor: {label}</div>}
+                    {high > 0 && <div>High: {Math.round(high)}°C</div>}
+                    {crit > 0 && <div>Critical: {Math.round(crit)}°C</div>}
+                    <div className="mt-2">
+                      <ProgressBar value={percent} color={color} label="Temperature" />
+                    </div>
+                  </div>
                 </div>
               </div>
-            </CircularProgress>
-            <div className="flex-1 ml-4">
-              <div className="text-xs space-y-2" style={{ color: `${fg}cc` }}>
-                <div>Current: {Math.round(stats.temp.cpu)}°C</div>
-                <div>Max Safe: {Math.round(stats.temp.max)}°C</div>
-                <div className="mt-2">
-                  <ProgressBar
-                    value={(stats.temp.cpu / stats.temp.max) * 100}
-                    color="#f43f5e"
-                    label="Temperature"
-                  />
+            );
+          })()}
+        </StatCard>
+
+        {/* Sensors */}
+        <StatCard title="Sensors" icon={Thermometer} color="#f97316">
+          {stats.sensors.length === 0 && (
+            <div className="text-xs" style={{ color: `${fg}80` }}>
+              No hwmon sensors found
+            </div>
+          )}
+          <div className="space-y-3 max-h-64 overflow-y-auto text-xs">
+            {stats.sensors.map((chip) => (
+              <div key={chip.id}>
+                <div className="font-medium mb-1" style={{ color: `${fg}cc` }}>
+                  {chip.id}
+                  {chip.device && (
+                    <span style={{ color: `${fg}80` }}> · {chip.device}</span>
+                  )}
                 </div>
+                {chip.sensors.map((sensor) => (
+                  <div
+                    key={sensor.id}
+                    className="flex justify-between font-mono"
+                    style={{
+                      color: sensor.alarm
+                        ? "#ef4444"
+                        : limitColor(sensor.value, sensor.max, sensor.crit, `${fg}99`),
+                    }}
+                    title={[
+                      sensor.min ? `min ${sensor.min}` : "",
+                      sensor.max ? `max ${sensor.max}` : "",
+                      sensor.crit ? `crit ${sensor.crit}` : "",
+                    ]
+                      .filter(Boolean)
+                      .join(" · ")}
+                  >
+                    <span>{sensor.label}</span>
+                    <span>{formatSensor(sensor)}</span>
+                  </div>
+                ))}
               </div>
-            </div>
+            ))}
           </div>
         </StatCard>
 
@@ -414,30 +978,139 @@ const SystemMonitor = () => {
             <div className="flex justify-between items-center text-sm">
               <span style={{ color: `${fg}cc` }}>↓ Download</span>
               <span className="font-mono font-bold" style={{ color: accent }}>
-                {stats.network.down}
+                {formatBytes(stats.network.down)}/s
               </span>
             </div>
             <div className="flex justify-between items-center text-sm">
               <span style={{ color: `${fg}cc` }}>↑ Upload</span>
               <span className="font-mono font-bold" style={{ color: accent }}>
-                {stats.network.up}
+                {formatBytes(stats.network.up)}/s
               </span>
             </div>
+            <Sparkline points={history["net.down"] || []} color="#06b6d4" />
+            <Sparkline points={history["net.up"] || []} color={accent} />
+            <div
+              className="pt-2 space-y-1 text-xs"
+              style={{ borderTop: `1px solid ${fg}20` }}
+            >
+              {stats.network.interfaces
+                .filter((iface) => iface.type !== "loopback")
+                .map((iface) => (
+                  <div
+                    key={iface.name}
+                    className="flex justify-between"
+                    title={`${iface.rxErrors + iface.txErrors} errors · ${iface.rxDropped + iface.txDropped} dropped`}
+                    style={{ color: iface.state === "down" ? `${fg}60` : `${fg}cc` }}
+                  >
+                    <span>
+                      {iface.name}
+                      <span style={{ color: `${fg}60` }}> {iface.type}</span>
+                    </span>
+                    <span className="font-mono">
+                      ↓ {formatBytes(iface.rxBytesPerSec)}/s ↑{" "}
+                      {formatBytes(iface.txBytesPerSec)}/s
+                    </span>
+                  </div>
+                ))}
+            </div>
           </div>
         </StatCard>
 
+        {/* Connectivity */}
+        {connectivity && (
+          <StatCard title="Connectivity" icon={Globe} color="#06b6d4">
+            <div className="space-y-3 text-xs" style={{ color: `${fg}cc` }}>
+              {connectivity.interfaces
+                .filter((iface) => iface.type !== "loopback" && iface.state !== "down")
+                .map((iface) => (
+                  <div key={iface.name} className="space-y-0.5">
+                    <div className="flex justify-between font-medium">
+                      <span>
+                        {iface.name}
+                        <span style={{ color: `${fg}60` }}> {iface.type}</span>
+                      </span>
+                      {iface.speedMbps > 0 && <span>{iface.speedMbps} Mb/s</span>}
+                    </div>
+                    {iface.wifi?.connected && (
+                      <div>
+                        {iface.wifi.ssid} · {iface.wifi.signalDbm} dBm (
+                        {Math.round(iface.wifi.quality)}%) · {iface.wifi.band} ·{" "}
+                        {iface.wifi.rxBitrate} / {iface.wifi.txBitrate} Mb/s
+                      </div>
+                    )}
+                    {[...(iface.ipv4 || []), ...(iface.ipv6 || [])].map((addr) => (
+                      <div key={addr} className="font-mono" style={{ color: `${fg}99` }}>
+                        {addr}
+                      </div>
+                    ))}
+                    {iface.mac && (
+                      <div className="font-mono" style={{ color: `${fg}60` }}>
+                        {iface.mac} · MTU {iface.mtu}
+                      </div>
+                    )}
+                  </div>
+                ))}
+              <div className="pt-2 space-y-0.5" style={{ borderTop: `1px solid ${fg}20` }}>
+                {connectivity.gateway && (
+                  <div className="flex justify-between">
+                    <span>Gateway</span>
+                    <span className="font-mono">
+                      {connectivity.gateway.gateway || "point-to-point"} via{" "}
+                      {connectivity.gateway.interface}
+                    </span>
+                  </div>
+                )}
+                {connectivity.gateway6?.gateway && (
+                  <div className="flex justify-between">
+                    <span>Gateway v6</span>
+                    <span className="font-mono">{connectivity.gateway6.gateway}</span>
+                  </div>
+                )}
+                {connectivity.dns && connectivity.dns.length > 0 && (
+                  <div className="flex justify-between">
+                    <span>DNS</span>
+                    <span className="font-mono text-right">
+                      {connectivity.dns.join(", ")}
+                    </span>
+                  </div>
+                )}
+              </div>
+              {connectivity.listening.length > 0 && (
+                <div className="pt-2" style={{ borderTop: `1px solid ${fg}20` }}>
+                  <div className="font-medium mb-1">Listening</div>
+                  {connectivity.listening.map((socket) => (
+                    <div
+                      key={`${socket.protocol}-${socket.address}-${socket.port}`}
+                      className="flex justify-between font-mono"
+                    >
+                      <span>
+                        {socket.address.includes(":") ? `[${socket.address}]` : socket.address}:
+                        {socket.port}
+                      </span>
+                      <span style={{ color: `${fg}80` }}>
+                        {socket.process || socket.user}
+                        {socket.pid > 0 && ` (${socket.pid})`}
+                      </span>
+                    </div>
+                  ))}
+                </div>
+              )}
+            </div>
+          </StatCard>
+        )}
+
         {/* Storage Devices */}
         <StatCard title="Storage Devices" icon={HardDrive} color="#f59e0b">
           <div className="space-y-3">
-            {stats.disks.map((disk: DiskStats, idx: number) => (
-              <div key={idx} className="space-y-1">
+            {stats.disks.map((disk: DiskStats) => (
+              <div key={disk.mountPoint} className="space-y-1">
                 <div
                   className="flex justify-between text-xs"
                   style={{ color: `${fg}cc` }}
                 >
                   <span className="font-medium">{disk.mountPoint}</span>
                   <span>
-                    {Math.round(disk.used)} / {Math.round(disk.total)} GB
+                    {formatBytes(disk.used)} / {formatBytes(disk.total)}
                   </span>
                 </div>
                 <ProgressBar
@@ -446,12 +1119,200 @@ const SystemMonitor = () => {
                   label={disk.device}
                 />
                 <div className="text-xs" style={{ color: `${fg}60` }}>
-                  {disk.fileSystem}
+                  {disk.fileSystem} · {formatBytes(disk.available)} free
+                  {disk.readOnly && " · read-only"}
                 </div>
               </div>
             ))}
+            {stats.diskIO.map((disk: DiskIO) => (
+              <div
+                key={disk.name}
+                className="pt-2 space-y-1 text-xs"
+                style={{ borderTop: `1px solid ${fg}20`, color: `${fg}80` }}
+              >
+                <div className="flex justify-between" style={{ color: `${fg}cc` }}>
+                  <span className="font-medium">
+                    {disk.name}
+                    {disk.model && ` · ${disk.model}`}
+                  </span>
+                  <span>{formatBytes(disk.size)}</span>
+                </div>
+                <div className="flex justify-between font-mono">
+                  <span>
+                    R {formatBytes(disk.readBytesPerSec)}/s · W{" "}
+                    {formatBytes(disk.writeBytesPerSec)}/s
+                  </span>
+                  <span>
+                    {Math.round(disk.readIOPS + disk.writeIOPS)} IOPS ·{" "}
+                    {Math.round(disk.utilization)}%
+                  </span>
+                </div>
+                {disk.health && (
+                  <div
+                    style={{
+                      color: !disk.health.passed
+                        ? "#ef4444"
+                        : disk.health.badSectors > 0
+                          ? "#f59e0b"
+                          : `${fg}80`,
+                    }}
+                  >
+                    SMART {disk.health.passed ? "passed" : "FAILING"}
+                    {disk.health.temp > 0 && ` · ${Math.round(disk.health.temp)}°C`}
+                    {disk.health.powerOnHours > 0 && ` · ${disk.health.powerOnHours} h`}
+                    {disk.health.percentUsed > 0 && ` · ${disk.health.percentUsed}% worn`}
+                    {disk.health.badSectors > 0 && ` · ${disk.health.badSectors} bad sectors`}
+                  </div>
+                )}
+              </div>
+            ))}
           </div>
         </StatCard>
+
+        {/* Processes */}
+        <div className="lg:col-span-3">
+          <StatCard title="Processes" icon={List} color={accent}>
+            <div className="flex items-center gap-2 mb-3 text-xs">
+              <input
+                type="text"
+                value={processQuery.filter}
+                placeholder="Filter by name, command, user or PID"
+                onChange={(e) =>
+                  setProcessQuery((q) => ({ ...q, filter: e.target.value }))
+                }
+                className="flex-1 rounded px-2 py-1 outline-none"
+                style={{
+                  backgroundColor: "rgba(255,255,255,0.05)",
+                  border: `1px solid ${fg}20`,
+                  color: fg,
+                }}
+              />
+              <button
+                onClick={() =>
+                  setProcessQuery((q) => ({ ...q, tree: !q.tree }))
+                }
+                className="flex items-center gap-1 rounded px-2 py-1"
+                style={{
+                  border: `1px solid ${processQuery.tree ? accent : `${fg}20`}`,
+                  color: processQuery.tree ? accent : `${fg}cc`,
+                }}
+              >
+                <ListTree size={14} />
+                Tree
+              </button>
+              {processes && (
+                <span style={{ color: `${fg}80` }}>
+                  {processes.processes.length} of {processes.total}
+                </span>
+              )}
+            </div>
+            {processError && (
+              <div
+                className="mb-3 rounded px-2 py-1 text-xs"
+                style={{ backgroundColor: "#f43f5e20", color: "#f43f5e" }}
+              >
+                {processError}
+              </div>
+            )}
+            <div className="overflow-x-auto">
+              <table className="w-full text-xs font-mono">
+                <thead>
+                  <tr style={{ color: `${fg}99` }}>
+                    {processColumns.map((col) => (
+                      <th
+                        key={col.key}
+                        onClick={() => sortProcesses(col.key)}
+                        className="text-left font-medium px-2 py-1 cursor-pointer select-none"
+                        style={{
+                          color: processQuery.sortBy === col.key ? accent : undefined,
+                        }}
+                      >
+                        {col.label}
+                        {processQuery.sortBy === col.key &&
+                          (processQuery.desc ? " ↓" : " ↑")}
+                      </th>
+                    ))}
+                    <th className="text-left font-medium px-2 py-1">Nice</th>
+                    <th />
+                  </tr>
+                </thead>
+                <tbody>
+                  {processes?.processes.map((p) => (
+                    <tr
+                      key={p.pid}
+                      title={[p.cmdline, p.cgroup].filter(Boolean).join("\n")}
+                      style={{ borderTop: `1px solid ${fg}10`, color: `${fg}cc` }}
+                    >
+                      <td className="px-2 py-1">{p.pid}</td>
+                      <td
+                        className="px-2 py-1 truncate max-w-xs"
+                        style={{ paddingLeft: `${0.5 + p.depth}rem` }}
+                      >
+                        {p.depth > 0 && "└ "}
+                        {p.name}
+                        <span style={{ color: `${fg}60` }}> {p.state}</span>
+                      </td>
+                      <td className="px-2 py-1">{p.user}</td>
+                      <td
+                        className="px-2 py-1"
+                        style={{ color: p.cpu >= 50 ? "#f43f5e" : undefined }}
+                      >
+                        {p.cpu.toFixed(1)}
+                      </td>
+                      <td className="px-2 py-1">{formatBytes(p.rss)}</td>
+                      <td className="px-2 py-1">{p.threads}</td>
+                      <td className="px-2 py-1">
+                        {new Date(p.startTime).toLocaleTimeString()}
+                      </td>
+                      <td className="px-2 py-1 whitespace-nowrap">
+                        <button
+                          onClick={() =>
+                            runProcessAction(() =>
+                              window.go.main.App.ReniceProcess(p.pid, Math.max(p.nice - 1, -20)),
+                            )
+                          }
+                          className="px-1"
+                        >
+                          −
+                        </button>
+                        {p.nice}
+                        <button
+                          onClick={() =>
+                            runProcessAction(() =>
+                              window.go.main.App.ReniceProcess(p.pid, Math.min(p.nice + 1, 19)),
+                            )
+                          }
+                          className="px-1"
+                        >
+                          +
+                        </button>
+                      </td>
+                      <td className="px-2 py-1 whitespace-nowrap text-right">
+                        {["TERM", "KILL"].map((signal) => (
+                          <button
+                            key={signal}
+                            onClick={() =>
+                              runProcessAction(() =>
+                                window.go.main.App.SignalProcess(p.pid, signal),
+                              )
+                            }
+                            className="ml-1 rounded px-1"
+                            style={{
+                              border: `1px solid ${signal === "KILL" ? "#f43f5e" : fg}40`,
+                              color: signal === "KILL" ? "#f43f5e" : `${fg}cc`,
+                            }}
+                          >
+                            {signal}
+                          </button>
+                        ))}
+                      </td>
+                    </tr>
+                  ))}
+                </tbody>
+              </table>
+            </div>
+          </StatCard>
+        </div>
       </div>
     </div>
   );
@@ -2,12 +2,15 @@ module system-visualizer
 
 go 1.24.0
 
-require github.com/wailsapp/wails/v2 v2.11.0
+require (
+	github.com/godbus/dbus/v5 v5.2.2
+	github.com/wailsapp/wails/v2 v2.11.0
+	kaguyadots/runner v0.0.0
+)
 
 require (
 	github.com/bep/debounce v1.2.1 // indirect
 	github.com/go-ole/go-ole v1.3.0 // indirect
-	github.com/godbus/dbus/v5 v5.2.2 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/gorilla/websocket v1.5.3 // indirect
 	github.com/jchv/go-winloader v0.0.0-20250406163304-c1995be93bd1 // indirect
@@ -34,4 +37,6 @@ require (
 	golang.org/x/text v0.33.0 // indirect
 )
 
+replace kaguyadots/runner => ../shared/runner
+
 // replace github.com/wailsapp/wails/v2 v2.10.2 => /home/dawu/go/pkg/mod
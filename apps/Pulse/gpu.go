@@ -0,0 +1,442 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kaguyadots/runner"
+)
+
+// PCI vendor IDs as found in /sys/class/drm/card*/device/vendor
+const (
+	pciVendorAMD    = "0x1002"
+	pciVendorIntel  = "0x8086"
+	pciVendorNVIDIA = "0x10de"
+)
+
+// GPUStats is one graphics card. Fields a driver doesn't expose stay zero.
+type GPUStats struct {
+	Card        string  `json:"card"` // card0; empty for NVIDIA GPUs without a DRM device
+	Vendor      string  `json:"vendor"`
+	Name        string  `json:"name"`
+	Driver      string  `json:"driver"`
+	PCISlot     string  `json:"pciSlot"`
+	Usage       float64 `json:"usage"`       // percent busy
+	MemoryUsed  uint64  `json:"memoryUsed"`  // bytes of VRAM
+	MemoryTotal uint64  `json:"memoryTotal"` // 0 for integrated GPUs sharing RAM
+	Temp        float64 `json:"temp"`        // °C
+	PowerWatts  float64 `json:"powerWatts"`
+	CoreMHz     float64 `json:"coreMHz"`
+	MaxCoreMHz  float64 `json:"maxCoreMHz"`
+	MemoryMHz   float64 `json:"memoryMHz"`
+	RC6         float64 `json:"rc6"` // Intel: percent of time power gated
+}
+
+// DRMCard is a /sys/class/drm/cardN device
+type DRMCard struct {
+	Name    string
+	Vendor  string // PCI vendor ID
+	Device  string // PCI device ID
+	Driver  string
+	PCISlot string // 0000:03:00.0
+}
+
+var drmCardName = regexp.MustCompile(`^card\d+$`)
+
+// DRMCards lists the graphics cards, skipping connectors (card1-DP-1) and
+// render nodes
+func (s *SysFS) DRMCards() ([]DRMCard, error) {
+	entries, err := os.ReadDir(s.path("class", "drm"))
+	if err != nil {
+		return nil, err
+	}
+
+	var cards []DRMCard
+	for _, entry := range entries {
+		name := entry.Name()
+		if !drmCardName.MatchString(name) {
+			continue
+		}
+		card := DRMCard{
+			Name:   name,
+			Vendor: strings.ToLower(s.readString("class", "drm", name, "device", "vendor")),
+			Device: strings.ToLower(s.readString("class", "drm", name, "device", "device")),
+		}
+		for _, line := range strings.Split(s.readString("class", "drm", name, "device", "uevent"), "\n") {
+			key, value, _ := strings.Cut(line, "=")
+			switch key {
+			case "DRIVER":
+				card.Driver = value
+			case "PCI_SLOT_NAME":
+				card.PCISlot = strings.ToLower(value)
+			}
+		}
+		cards = append(cards, card)
+	}
+	sort.Slice(cards, func(i, j int) bool { return cardIndex(cards[i].Name) < cardIndex(cards[j].Name) })
+	return cards, nil
+}
+
+// cardIndex orders card2 before card10; GPUs without a card sort last
+func cardIndex(name string) int {
+	if n, err := strconv.Atoi(strings.TrimPrefix(name, "card")); err == nil {
+		return n
+	}
+	return int(^uint(0) >> 1)
+}
+
+// GPUBackend reads the cards of one vendor. Read gets all of them at once
+// so a backend that queries a tool can do it once per sample.
+type GPUBackend interface {
+	Vendor() string // PCI vendor ID
+	Read(cards []DRMCard) []GPUStats
+}
+
+// GPUMonitor reads every card through its vendor's backend
+type GPUMonitor struct {
+	sys      *SysFS
+	backends []GPUBackend
+	names    *pciNames
+}
+
+func NewGPUMonitor(sys *SysFS, r runner.Runner) *GPUMonitor {
+	names := newPCINames("/usr/share/hwdata/pci.ids", "/usr/share/misc/pci.ids")
+	return &GPUMonitor{
+		sys:   sys,
+		names: names,
+		backends: []GPUBackend{
+			&amdGPU{sys: sys, names: names},
+			newIntelGPU(sys, names),
+			&nvidiaGPU{query: func() ([]byte, error) { return nvidiaSMI(r) }},
+		},
+	}
+}
+
+// Read returns every GPU, ordered by card. Cards without a backend are
+// listed by name only.
+func (m *GPUMonitor) Read() []GPUStats {
+	cards, _ := m.sys.DRMCards()
+	byVendor := make(map[string][]DRMCard)
+	for _, card := range cards {
+		byVendor[card.Vendor] = append(byVendor[card.Vendor], card)
+	}
+
+	gpus := []GPUStats{}
+	for _, backend := range m.backends {
+		gpus = append(gpus, backend.Read(byVendor[backend.Vendor()])...)
+		delete(byVendor, backend.Vendor())
+	}
+	for _, cards := range byVendor {
+		for _, card := range cards {
+			gpus = append(gpus, newGPUStats(card, "", m.names))
+		}
+	}
+
+	sort.SliceStable(gpus, func(i, j int) bool { return cardIndex(gpus[i].Card) < cardIndex(gpus[j].Card) })
+	return gpus
+}
+
+func newGPUStats(card DRMCard, vendor string, names *pciNames) GPUStats {
+	return GPUStats{
+		Card:    card.Name,
+		Vendor:  vendor,
+		Name:    names.lookup(card.Vendor, card.Device),
+		Driver:  card.Driver,
+		PCISlot: card.PCISlot,
+	}
+}
+
+// amdGPU reads amdgpu's sysfs attributes and hwmon sensors
+type amdGPU struct {
+	sys   *SysFS
+	names *pciNames
+}
+
+func (b *amdGPU) Vendor() string { return pciVendorAMD }
+
+func (b *amdGPU) Read(cards []DRMCard) []GPUStats {
+	var gpus []GPUStats
+	for _, card := range cards {
+		dev := []string{"class", "drm", card.Name, "device"}
+		attr := func(name string) []string { return append(append([]string{}, dev...), name) }
+
+		gpu := newGPUStats(card, "amd", b.names)
+		if name := b.sys.readString(attr("product_name")...); name != "" {
+			gpu.Name = name
+		}
+		if busy, ok := b.sys.readUint(attr("gpu_busy_percent")...); ok {
+			gpu.Usage = float64(busy)
+		}
+		gpu.MemoryUsed, _ = b.sys.readUint(attr("mem_info_vram_used")...)
+		gpu.MemoryTotal, _ = b.sys.readUint(attr("mem_info_vram_total")...)
+		gpu.CoreMHz, gpu.MaxCoreMHz = dpmClock(b.sys.readString(attr("pp_dpm_sclk")...))
+		gpu.MemoryMHz, _ = dpmClock(b.sys.readString(attr("pp_dpm_mclk")...))
+
+		if hwmon := b.sys.hwmonDir(attr("hwmon")...); hwmon != nil {
+			// temp1 is the edge sensor; junction and memory follow
+			if temp, ok := b.sys.readUint(append(hwmon, "temp1_input")...); ok {
+				gpu.Temp = float64(temp) / 1000
+			}
+			// RDNA3 and later only report power1_input
+			for _, name := range []string{"power1_average", "power1_input"} {
+				if uw, ok := b.sys.readUint(append(hwmon, name)...); ok {
+					gpu.PowerWatts = float64(uw) / 1e6
+					break
+				}
+			}
+		}
+		gpus = append(gpus, gpu)
+	}
+	return gpus
+}
+
+// dpmClock reads a pp_dpm_* table, returning the level marked active with
+// "*" and the highest level
+func dpmClock(table string) (current, highest float64) {
+	for _, line := range strings.Split(table, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		mhz, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(fields[1]), "mhz"), 64)
+		if err != nil {
+			continue
+		}
+		highest = max(highest, mhz)
+		if len(fields) > 2 && fields[2] == "*" {
+			current = mhz
+		}
+	}
+	return current, highest
+}
+
+// hwmonDir returns the first hwmon directory under a device, as the path
+// elements readUint takes
+func (s *SysFS) hwmonDir(elem ...string) []string {
+	entries, err := os.ReadDir(s.path(elem...))
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "hwmon") {
+			return append(append([]string{}, elem...), entry.Name())
+		}
+	}
+	return nil
+}
+
+// intelGPU reads i915's frequency attributes. The driver has no busy
+// counter, so usage is the time the GPU spent out of RC6 (power gated)
+// since the previous read.
+type intelGPU struct {
+	sys   *SysFS
+	names *pciNames
+	now   func() time.Time
+
+	mu   sync.Mutex
+	prev map[string]rc6Sample
+}
+
+type rc6Sample struct {
+	residency uint64 // ms
+	taken     time.Time
+}
+
+func newIntelGPU(sys *SysFS, names *pciNames) *intelGPU {
+	return &intelGPU{sys: sys, names: names, now: time.Now, prev: make(map[string]rc6Sample)}
+}
+
+func (b *intelGPU) Vendor() string { return pciVendorIntel }
+
+func (b *intelGPU) Read(cards []DRMCard) []GPUStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var gpus []GPUStats
+	for _, card := range cards {
+		attr := func(elem ...string) []string { return append([]string{"class", "drm", card.Name}, elem...) }
+		mhz := func(name string) float64 {
+			n, _ := b.sys.readUint(attr(name)...)
+			return float64(n)
+		}
+
+		gpu := newGPUStats(card, "intel", b.names)
+		gpu.CoreMHz = mhz("gt_act_freq_mhz")
+		if gpu.CoreMHz == 0 {
+			gpu.CoreMHz = mhz("gt_cur_freq_mhz")
+		}
+		gpu.MaxCoreMHz = mhz("gt_max_freq_mhz")
+
+		if residency, ok := b.sys.readUint(attr("power", "rc6_residency_ms")...); ok {
+			now := b.now()
+			if prev, seen := b.prev[card.Name]; seen && residency >= prev.residency {
+				if elapsed := now.Sub(prev.taken).Milliseconds(); elapsed > 0 {
+					gpu.RC6 = clampPercent(float64(residency-prev.residency) / float64(elapsed) * 100)
+					gpu.Usage = 100 - gpu.RC6
+				}
+			}
+			b.prev[card.Name] = rc6Sample{residency: residency, taken: now}
+		}
+		gpus = append(gpus, gpu)
+	}
+	return gpus
+}
+
+// nvidiaGPU queries nvidia-smi once for every NVIDIA GPU and matches them
+// to DRM cards by PCI slot. GPUs the driver shows no card for (headless
+// compute cards, nvidia-drm without modeset) are still listed.
+type nvidiaGPU struct {
+	query func() ([]byte, error)
+}
+
+const nvidiaQuery = "index,pci.bus_id,name,utilization.gpu,memory.used,memory.total,temperature.gpu,power.draw,clocks.gr,clocks.mem"
+
+func nvidiaSMI(r runner.Runner) ([]byte, error) {
+	return toolOutput(r, "nvidia-smi", "--query-gpu="+nvidiaQuery, "--format=csv,noheader,nounits")
+}
+
+func (b *nvidiaGPU) Vendor() string { return pciVendorNVIDIA }
+
+func (b *nvidiaGPU) Read(cards []DRMCard) []GPUStats {
+	bySlot := make(map[string]DRMCard)
+	var gpus []GPUStats
+	for _, card := range cards {
+		bySlot[pciBus(card.PCISlot)] = card
+	}
+
+	if out, err := b.query(); err == nil {
+		for _, gpu := range parseNvidiaSMI(string(out)) {
+			if card, ok := bySlot[pciBus(gpu.PCISlot)]; ok {
+				gpu.Card, gpu.Driver = card.Name, card.Driver
+				delete(bySlot, pciBus(gpu.PCISlot))
+			}
+			gpus = append(gpus, gpu)
+		}
+	}
+	// Without nvidia-smi (nouveau, or the tool missing) only the card is known
+	for _, card := range cards {
+		if _, unmatched := bySlot[pciBus(card.PCISlot)]; unmatched {
+			gpus = append(gpus, GPUStats{Card: card.Name, Vendor: "nvidia", Name: "NVIDIA GPU", Driver: card.Driver, PCISlot: card.PCISlot})
+		}
+	}
+	return gpus
+}
+
+// parseNvidiaSMI reads nvidiaQuery's CSV. Values nvidia-smi can't report
+// are "[N/A]" and stay zero.
+func parseNvidiaSMI(output string) []GPUStats {
+	var gpus []GPUStats
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) < 10 {
+			continue
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		num := func(i int) float64 {
+			n, _ := strconv.ParseFloat(fields[i], 64)
+			return n
+		}
+		mib := func(i int) uint64 { return uint64(num(i)) * 1024 * 1024 }
+
+		gpus = append(gpus, GPUStats{
+			Vendor:      "nvidia",
+			Name:        fields[2],
+			PCISlot:     strings.ToLower(fields[1]),
+			Usage:       num(3),
+			MemoryUsed:  mib(4),
+			MemoryTotal: mib(5),
+			Temp:        num(6),
+			PowerWatts:  num(7),
+			CoreMHz:     num(8),
+			MemoryMHz:   num(9),
+		})
+	}
+	return gpus
+}
+
+// pciBus drops the PCI domain, which nvidia-smi prints with eight digits
+// and sysfs with four
+func pciBus(slot string) string {
+	if _, bus, ok := strings.Cut(strings.ToLower(slot), ":"); ok {
+		return bus
+	}
+	return slot
+}
+
+// pciNames looks up device names in the pci.ids database, loading it the
+// first time a name is needed
+type pciNames struct {
+	paths []string
+	once  sync.Once
+	names map[string]string // "1002:73bf"
+}
+
+func newPCINames(paths ...string) *pciNames {
+	return &pciNames{paths: paths}
+}
+
+// lookup returns the marketing name in brackets when pci.ids has one
+// ("Radeon RX 6800/6800 XT / 6900 XT"), the chip name otherwise, or the
+// vendor's name when the device is unknown
+func (n *pciNames) lookup(vendor, device string) string {
+	n.once.Do(n.load)
+	vendor = strings.TrimPrefix(vendor, "0x")
+	device = strings.TrimPrefix(device, "0x")
+
+	if name, ok := n.names[vendor+":"+device]; ok {
+		if start, end := strings.LastIndex(name, "["), strings.LastIndex(name, "]"); start >= 0 && end > start {
+			return name[start+1 : end]
+		}
+		return name
+	}
+	switch "0x" + vendor {
+	case pciVendorAMD:
+		return "AMD GPU"
+	case pciVendorIntel:
+		return "Intel GPU"
+	case pciVendorNVIDIA:
+		return "NVIDIA GPU"
+	}
+	return "GPU"
+}
+
+func (n *pciNames) load() {
+	n.names = make(map[string]string)
+	for _, path := range n.paths {
+		file, err := os.Open(filepath.Clean(path))
+		if err != nil {
+			continue
+		}
+		defer file.Close()
+
+		vendor := ""
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "" || strings.HasPrefix(line, "#"):
+			case strings.HasPrefix(line, "C "):
+				// Device classes close the vendor list
+				return
+			case strings.HasPrefix(line, "\t\t"):
+			case strings.HasPrefix(line, "\t"):
+				if id, name, ok := strings.Cut(strings.TrimPrefix(line, "\t"), "  "); ok && vendor != "" {
+					n.names[vendor+":"+id] = name
+				}
+			default:
+				id, _, _ := strings.Cut(line, "  ")
+				vendor = id
+			}
+		}
+		return
+	}
+}
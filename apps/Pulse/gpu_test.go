@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"kaguyadots/runner"
+)
+
+func testGPUMonitor(t *testing.T, smi string, smiErr error) (*GPUMonitor, *intelGPU) {
+	t.Helper()
+	sys := testSysFS()
+	names := newPCINames(filepath.Join("testdata", "missing.ids"), filepath.Join("testdata", "pci.ids"))
+	intel := newIntelGPU(sys, names)
+	return &GPUMonitor{
+		sys:   sys,
+		names: names,
+		backends: []GPUBackend{
+			&amdGPU{sys: sys, names: names},
+			intel,
+			&nvidiaGPU{query: func() ([]byte, error) { return []byte(smi), smiErr }},
+		},
+	}, intel
+}
+
+func TestSysDRMCards(t *testing.T) {
+	cards, err := testSysFS().DRMCards()
+	if err != nil {
+		t.Fatalf("DRMCards: %v", err)
+	}
+	want := []DRMCard{
+		{Name: "card0", Vendor: "0x8086", Device: "0x46a6", Driver: "i915", PCISlot: "0000:00:02.0"},
+		{Name: "card1", Vendor: "0x1002", Device: "0x73bf", Driver: "amdgpu", PCISlot: "0000:03:00.0"},
+		{Name: "card2", Vendor: "0x10de", Device: "0x2684", Driver: "nvidia", PCISlot: "0000:01:00.0"},
+	}
+	if !reflect.DeepEqual(cards, want) {
+		t.Errorf("cards = %+v, want %+v", cards, want)
+	}
+}
+
+func TestGPUMonitor(t *testing.T) {
+	smi, err := os.ReadFile(filepath.Join("testdata", "nvidia-smi", "query.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	monitor, intel := testGPUMonitor(t, string(smi), nil)
+	now := time.Unix(1760000000, 0)
+	intel.now = func() time.Time { return now }
+
+	gpus := monitor.Read()
+	if len(gpus) != 4 {
+		t.Fatalf("gpus = %+v, want four", gpus)
+	}
+
+	want := []GPUStats{
+		{Card: "card0", Vendor: "intel", Name: "Iris Xe Graphics", Driver: "i915", PCISlot: "0000:00:02.0", CoreMHz: 750, MaxCoreMHz: 1400},
+		{
+			Card: "card1", Vendor: "amd", Name: "Radeon RX 6800/6800 XT / 6900 XT", Driver: "amdgpu", PCISlot: "0000:03:00.0",
+			Usage: 37, MemoryUsed: 2147483648, MemoryTotal: 17163091968, Temp: 52, PowerWatts: 123,
+			CoreMHz: 1800, MaxCoreMHz: 2250, MemoryMHz: 1000,
+		},
+		{
+			Card: "card2", Vendor: "nvidia", Name: "NVIDIA GeForce RTX 4090", Driver: "nvidia", PCISlot: "00000000:01:00.0",
+			Usage: 64, MemoryUsed: 8192 << 20, MemoryTotal: 24564 << 20, Temp: 58, PowerWatts: 285.4, CoreMHz: 2520, MemoryMHz: 10501,
+		},
+		// A compute card with no DRM device, and power it can't report
+		{
+			Vendor: "nvidia", Name: "NVIDIA Tesla T4", PCISlot: "00000000:02:00.0",
+			MemoryTotal: 15360 << 20, Temp: 34, CoreMHz: 300, MemoryMHz: 405,
+		},
+	}
+	if !reflect.DeepEqual(gpus, want) {
+		t.Errorf("gpus =\n%+v\nwant\n%+v", gpus, want)
+	}
+}
+
+func TestIntelRC6Usage(t *testing.T) {
+	root := copyTree(t, filepath.Join("testdata", "sys"))
+	sys := NewSysFS(root)
+	intel := newIntelGPU(sys, newPCINames())
+	now := time.Unix(1760000000, 0)
+	intel.now = func() time.Time { return now }
+	cards, _ := sys.DRMCards()
+
+	if gpu := intel.Read(cards[:1])[0]; gpu.Usage != 0 || gpu.Name != "Intel GPU" {
+		t.Errorf("first read = %+v, want no usage yet", gpu)
+	}
+
+	// 1.5s of a 2s interval gated is 25% busy
+	residency := filepath.Join(root, "class", "drm", "card0", "power", "rc6_residency_ms")
+	if err := os.WriteFile(residency, []byte("601500\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Second)
+	if gpu := intel.Read(cards[:1])[0]; gpu.RC6 != 75 || gpu.Usage != 25 {
+		t.Errorf("second read = %+v, want 75%% RC6", gpu)
+	}
+}
+
+func TestNvidiaWithoutSMI(t *testing.T) {
+	monitor, _ := testGPUMonitor(t, "", errors.New("exec: \"nvidia-smi\": executable file not found in $PATH"))
+	gpus := monitor.Read()
+	if len(gpus) != 3 {
+		t.Fatalf("gpus = %+v", gpus)
+	}
+	if gpu := gpus[2]; gpu.Card != "card2" || gpu.Vendor != "nvidia" || gpu.Name != "NVIDIA GPU" || gpu.Usage != 0 {
+		t.Errorf("card2 = %+v, want the card alone", gpu)
+	}
+}
+
+func TestDPMClock(t *testing.T) {
+	current, highest := dpmClock("0: 500Mhz \n1: 1800Mhz *\n2: 2250Mhz \n")
+	if current != 1800 || highest != 2250 {
+		t.Errorf("dpmClock = %v, %v", current, highest)
+	}
+	if current, highest := dpmClock(""); current != 0 || highest != 0 {
+		t.Errorf("empty table = %v, %v", current, highest)
+	}
+}
+
+func TestNvidiaSMICommand(t *testing.T) {
+	fake := runner.NewFake().On("nvidia-smi", "0, 00000000:01:00.0, NVIDIA GeForce RTX 4090, 5, 100, 24564, 40, 30.5, 210, 405\n", nil)
+	out, err := nvidiaSMI(fake)
+	if gpus := parseNvidiaSMI(string(out)); err != nil || len(gpus) != 1 {
+		t.Errorf("nvidiaSMI = %q, %v", out, err)
+	}
+	if want := []string{"nvidia-smi --query-gpu=" + nvidiaQuery + " --format=csv,noheader,nounits"}; !reflect.DeepEqual(fake.Commands(), want) {
+		t.Errorf("commands = %q, want %q", fake.Commands(), want)
+	}
+}
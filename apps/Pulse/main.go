@@ -27,6 +27,7 @@ func main() {
 		BackgroundColour: &options.RGBA{R: 15, G: 20, B: 22, A: 255},
 		Frameless:        false,
 		OnStartup:        app.startup,
+		OnShutdown:       app.shutdown,
 		Bind: []interface{}{
 			app,
 		},
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// Interface types as reported to the frontend
+const (
+	InterfaceWifi     = "wifi"
+	InterfaceEthernet = "ethernet"
+	InterfaceVPN      = "vpn"
+	InterfaceVirtual  = "virtual"
+	InterfaceLoopback = "loopback"
+)
+
+// ARPHRD values from /sys/class/net/*/type
+const (
+	arphrdEther    = 1
+	arphrdPPP      = 512
+	arphrdLoopback = 772
+	arphrdNone     = 65534 // WireGuard and tun devices
+)
+
+// NetCounters are an interface's cumulative counters from
+// /sys/class/net/*/statistics
+type NetCounters struct {
+	RxBytes   uint64 `json:"rxBytes"`
+	TxBytes   uint64 `json:"txBytes"`
+	RxPackets uint64 `json:"rxPackets"`
+	TxPackets uint64 `json:"txPackets"`
+	RxErrors  uint64 `json:"rxErrors"`
+	TxErrors  uint64 `json:"txErrors"`
+	RxDropped uint64 `json:"rxDropped"`
+	TxDropped uint64 `json:"txDropped"`
+}
+
+// NetInterface is one entry of /sys/class/net
+type NetInterface struct {
+	Name     string
+	Type     string
+	State    string // operstate: up, down, dormant, unknown...
+	Counters NetCounters
+}
+
+// NetInterfaces lists every network interface sorted by name. Interfaces
+// that vanish while being read are skipped.
+func (s *SysFS) NetInterfaces() ([]NetInterface, error) {
+	entries, err := os.ReadDir(s.path("class", "net"))
+	if err != nil {
+		return nil, err
+	}
+
+	var ifaces []NetInterface
+	for _, entry := range entries {
+		name := entry.Name()
+		counters, ok := s.netCounters(name)
+		if !ok {
+			continue
+		}
+		ifaces = append(ifaces, NetInterface{
+			Name:     name,
+			Type:     s.interfaceType(name),
+			State:    s.readString("class", "net", name, "operstate"),
+			Counters: counters,
+		})
+	}
+	sort.Slice(ifaces, func(i, j int) bool { return ifaces[i].Name < ifaces[j].Name })
+	return ifaces, nil
+}
+
+func (s *SysFS) netCounters(name string) (NetCounters, bool) {
+	read := func(counter string) uint64 {
+		n, _ := s.readUint("class", "net", name, "statistics", counter)
+		return n
+	}
+	if _, ok := s.readUint("class", "net", name, "statistics", "rx_bytes"); !ok {
+		return NetCounters{}, false
+	}
+	return NetCounters{
+		RxBytes:   read("rx_bytes"),
+		TxBytes:   read("tx_bytes"),
+		RxPackets: read("rx_packets"),
+		TxPackets: read("tx_packets"),
+		RxErrors:  read("rx_errors"),
+		TxErrors:  read("tx_errors"),
+		RxDropped: read("rx_dropped"),
+		TxDropped: read("tx_dropped"),
+	}, true
+}
+
+// interfaceType tells hardware from software interfaces: only wifi and
+// ethernet have a backing device, tunnels have tun_flags or no link layer,
+// and everything else (bridges, veth, docker) is virtual
+func (s *SysFS) interfaceType(name string) string {
+	exists := func(elem ...string) bool {
+		_, err := os.Stat(s.path(append([]string{"class", "net", name}, elem...)...))
+		return err == nil
+	}
+	arphrd, _ := s.readUint("class", "net", name, "type")
+
+	switch {
+	case arphrd == arphrdLoopback:
+		return InterfaceLoopback
+	case exists("wireless") || exists("phy80211"):
+		return InterfaceWifi
+	case exists("tun_flags") || arphrd == arphrdNone || arphrd == arphrdPPP ||
+		hasAnyPrefix(name, "wg", "tun", "tap", "tailscale", "ppp"):
+		return InterfaceVPN
+	case arphrd == arphrdEther && exists("device") && !exists("bridge"):
+		return InterfaceEthernet
+	}
+	return InterfaceVirtual
+}
+
+func hasAnyPrefix(s string, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// InterfaceStats is an interface's throughput since the previous sample
+// alongside its cumulative counters
+type InterfaceStats struct {
+	Name            string  `json:"name"`
+	Type            string  `json:"type"`
+	State           string  `json:"state"`
+	RxBytesPerSec   float64 `json:"rxBytesPerSec"`
+	TxBytesPerSec   float64 `json:"txBytesPerSec"`
+	RxPacketsPerSec float64 `json:"rxPacketsPerSec"`
+	TxPacketsPerSec float64 `json:"txPacketsPerSec"`
+	NetCounters
+}
+
+// physical reports whether an interface's traffic counts toward the
+// totals. Tunnels, bridges and veths carry traffic that also crosses a
+// physical interface, so adding them would count it twice.
+func (i InterfaceStats) physical() bool {
+	return i.Type == InterfaceWifi || i.Type == InterfaceEthernet
+}
+
+// interfaceRates compares two readings taken seconds apart. A counter that
+// went backwards (driver reload, interface recreated) reads as zero.
+func interfaceRates(iface NetInterface, prev NetCounters, seen bool, seconds float64) InterfaceStats {
+	stats := InterfaceStats{
+		Name:        iface.Name,
+		Type:        iface.Type,
+		State:       iface.State,
+		NetCounters: iface.Counters,
+	}
+	if !seen || seconds <= 0 {
+		return stats
+	}
+	rate := func(cur, before uint64) float64 {
+		if cur < before {
+			return 0
+		}
+		return float64(cur-before) / seconds
+	}
+	cur := iface.Counters
+	stats.RxBytesPerSec = rate(cur.RxBytes, prev.RxBytes)
+	stats.TxBytesPerSec = rate(cur.TxBytes, prev.TxBytes)
+	stats.RxPacketsPerSec = rate(cur.RxPackets, prev.RxPackets)
+	stats.TxPacketsPerSec = rate(cur.TxPackets, prev.TxPackets)
+	return stats
+}
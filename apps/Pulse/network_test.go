@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSysNetInterfaces(t *testing.T) {
+	ifaces, err := testSysFS().NetInterfaces()
+	if err != nil {
+		t.Fatalf("NetInterfaces: %v", err)
+	}
+
+	types := make(map[string]string)
+	for _, iface := range ifaces {
+		types[iface.Name] = iface.Type
+	}
+	want := map[string]string{
+		"docker0": InterfaceVirtual,
+		"enp3s0":  InterfaceEthernet,
+		"lo":      InterfaceLoopback,
+		"tun0":    InterfaceVPN,
+		"wg0":     InterfaceVPN,
+		"wlp2s0":  InterfaceWifi,
+	}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("types = %v, want %v", types, want)
+	}
+
+	wifi := ifaces[len(ifaces)-1]
+	wantCounters := NetCounters{
+		RxBytes: 7340032000, TxBytes: 524288000, RxPackets: 5200000, TxPackets: 1900000,
+		RxErrors: 3, RxDropped: 120,
+	}
+	if wifi.Name != "wlp2s0" || wifi.State != "up" || wifi.Counters != wantCounters {
+		t.Errorf("wlp2s0 = %+v", wifi)
+	}
+}
+
+func TestInterfaceRates(t *testing.T) {
+	iface := NetInterface{Name: "wlp2s0", Type: InterfaceWifi, State: "up", Counters: NetCounters{RxBytes: 3000, TxBytes: 500, RxPackets: 30, TxPackets: 10}}
+
+	got := interfaceRates(iface, NetCounters{RxBytes: 1000, TxBytes: 400, RxPackets: 10, TxPackets: 6}, true, 2)
+	if got.RxBytesPerSec != 1000 || got.TxBytesPerSec != 50 || got.RxPacketsPerSec != 10 || got.TxPacketsPerSec != 2 {
+		t.Errorf("rates = %+v", got)
+	}
+	if got.RxBytes != 3000 || !got.physical() {
+		t.Errorf("counters = %+v", got)
+	}
+
+	// First sight and reset counters have no rate
+	if got := interfaceRates(iface, NetCounters{}, false, 2); got.RxBytesPerSec != 0 {
+		t.Errorf("unseen interface rate = %v", got.RxBytesPerSec)
+	}
+	if got := interfaceRates(iface, NetCounters{RxBytes: 9000}, true, 2); got.RxBytesPerSec != 0 || got.TxBytesPerSec != 250 {
+		t.Errorf("reset counter rates = %+v", got)
+	}
+}
+
+func TestAppNetworkStats(t *testing.T) {
+	app := &App{sys: testSysFS()}
+
+	first := app.networkStats()
+	if len(first.Interfaces) != 6 || first.Down != 0 {
+		t.Fatalf("first sample = %+v, want six interfaces and no rate", first)
+	}
+
+	// Pretend the last sample was a second ago with less traffic since
+	app.prevNetTime = time.Now().Add(-time.Second)
+	for name, counters := range app.prevNet {
+		counters.RxBytes /= 2
+		counters.TxBytes /= 2
+		app.prevNet[name] = counters
+	}
+
+	stats := app.networkStats()
+	// Only wlp2s0 counts: enp3s0 is idle and the rest aren't physical
+	if stats.Down < 3670016000*0.99 || stats.Down > 3670016000*1.01 {
+		t.Errorf("Down = %.0f, want about half the wifi counter", stats.Down)
+	}
+	for _, iface := range stats.Interfaces {
+		if iface.Name == "wg0" && iface.RxBytesPerSec == 0 {
+			t.Error("wg0 has no rate of its own")
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	notifyBus     = "org.freedesktop.Notifications"
+	notifyPath    = "/org/freedesktop/Notifications"
+	notifyIcon    = "utilities-system-monitor"
+	urgencyNormal = byte(1)
+	urgencyCrit   = byte(2)
+)
+
+// Notifier shows alerts through the freedesktop notification daemon
+// (swaync in KaguyaDots). A rule that fires again replaces its earlier
+// notification, and one that clears closes it.
+type Notifier struct {
+	mu   sync.Mutex
+	conn *dbus.Conn
+	ids  map[string]uint32 // notification ID by rule
+}
+
+func NewNotifier() *Notifier {
+	return &Notifier{ids: make(map[string]uint32)}
+}
+
+// Notify shows or withdraws an alert's notification. The session bus is
+// connected on first use, so Pulse starts fine without one.
+func (n *Notifier) Notify(alert Alert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn == nil {
+		conn, err := dbus.ConnectSessionBus()
+		if err != nil {
+			return fmt.Errorf("connecting to the session bus: %w", err)
+		}
+		n.conn = conn
+	}
+	obj := n.conn.Object(notifyBus, notifyPath)
+
+	if !alert.Firing {
+		id, ok := n.ids[alert.Rule]
+		if !ok {
+			return nil
+		}
+		delete(n.ids, alert.Rule)
+		return obj.Call(notifyBus+".CloseNotification", 0, id).Err
+	}
+
+	summary, body, urgency := alertNotification(alert)
+	hints := map[string]dbus.Variant{
+		"urgency": dbus.MakeVariant(urgency),
+	}
+	var id uint32
+	err := obj.Call(notifyBus+".Notify", 0,
+		"Pulse", n.ids[alert.Rule], notifyIcon, summary, body, []string{}, hints, int32(-1),
+	).Store(&id)
+	if err != nil {
+		return fmt.Errorf("sending notification: %w", err)
+	}
+	n.ids[alert.Rule] = id
+	return nil
+}
+
+// Close drops the bus connection
+func (n *Notifier) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+}
+
+// alertNotification words an alert. Temperatures and running out of
+// something (battery, disk space) are critical.
+func alertNotification(alert Alert) (summary, body string, urgency byte) {
+	unit := metricUnit(alert.Metric)
+	side := "above"
+	if alert.Below {
+		side = "below"
+	}
+	body = fmt.Sprintf("%s is %s, %s %s", alert.Metric, formatMetric(alert.Value, unit), side, formatMetric(alert.Threshold, unit))
+
+	urgency = urgencyNormal
+	if unit == "°C" || alert.Metric == "battery" || strings.HasPrefix(alert.Metric, "mount.") {
+		urgency = urgencyCrit
+	}
+	return alert.Rule, body, urgency
+}
+
+// metricUnit is the unit of a history metric's values
+func metricUnit(metric string) string {
+	switch {
+	case metric == "temp", strings.HasPrefix(metric, "sensor.") && strings.Contains(metric, ".temp"):
+		return "°C"
+	case strings.HasPrefix(metric, "net."), strings.HasSuffix(metric, ".read"), strings.HasSuffix(metric, ".write"):
+		return "B/s"
+	case strings.HasPrefix(metric, "sensor."):
+		return ""
+	}
+	return "%"
+}
+
+func formatMetric(value float64, unit string) string {
+	switch unit {
+	case "%":
+		return fmt.Sprintf("%.0f%%", value)
+	case "°C":
+		return fmt.Sprintf("%.0f°C", value)
+	case "":
+		return fmt.Sprintf("%g", value)
+	}
+	return fmt.Sprintf("%.0f %s", value, unit)
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of the times in /proc/[pid]/stat. It is
+// 100 on every Linux architecture Pulse runs on.
+const clockTicks = 100
+
+// ProcessInfo is one row of the process table
+type ProcessInfo struct {
+	PID       int       `json:"pid"`
+	PPID      int       `json:"ppid"`
+	Name      string    `json:"name"`
+	User      string    `json:"user"`
+	State     string    `json:"state"`
+	CPU       float64   `json:"cpu"` // percent of one core, as top shows it
+	RSS       uint64    `json:"rss"` // bytes
+	Threads   int       `json:"threads"`
+	Nice      int       `json:"nice"`
+	StartTime time.Time `json:"startTime"`
+	Cmdline   string    `json:"cmdline"`
+	Cgroup    string    `json:"cgroup"`
+	Depth     int       `json:"depth"` // nesting in tree view
+}
+
+// ProcessQuery selects and orders the process table
+type ProcessQuery struct {
+	SortBy string `json:"sortBy"` // cpu, memory, pid, name, user, threads or start
+	Desc   bool   `json:"desc"`
+	Filter string `json:"filter"` // matches name, cmdline, user or pid
+	User   string `json:"user"`
+	Tree   bool   `json:"tree"`
+	Limit  int    `json:"limit"`
+}
+
+// ProcessList is a page of the process table
+type ProcessList struct {
+	Processes []ProcessInfo `json:"processes"`
+	Total     int           `json:"total"` // processes matching before the limit
+}
+
+// processSample remembers a process's CPU time to compute usage on the next
+// refresh; startTime tells a reused pid apart
+type processSample struct {
+	startTime uint64
+	jiffies   uint64
+}
+
+// ProcessTable lists processes from /proc with CPU usage measured between
+// refreshes
+type ProcessTable struct {
+	proc *ProcFS
+
+	mu       sync.Mutex
+	prev     map[int]processSample
+	prevTime time.Time
+	users    map[int]string
+	now      func() time.Time
+}
+
+func NewProcessTable(proc *ProcFS) *ProcessTable {
+	return &ProcessTable{
+		proc:  proc,
+		prev:  make(map[int]processSample),
+		users: make(map[int]string),
+		now:   time.Now,
+	}
+}
+
+// List reads every process and applies the query. Processes that exit
+// while being read are skipped.
+func (pt *ProcessTable) List(query ProcessQuery) (ProcessList, error) {
+	pids, err := pt.proc.PIDs()
+	if err != nil {
+		return ProcessList{}, fmt.Errorf("failed to list processes: %w", err)
+	}
+	stat, err := pt.proc.Stat()
+	if err != nil {
+		return ProcessList{}, err
+	}
+	uptime, err := pt.proc.Uptime()
+	if err != nil {
+		return ProcessList{}, err
+	}
+
+	pt.mu.Lock()
+	now := pt.now()
+	elapsed := now.Sub(pt.prevTime).Seconds()
+	samples := make(map[int]processSample, len(pids))
+	pageSize := uint64(os.Getpagesize())
+
+	processes := make([]ProcessInfo, 0, len(pids))
+	for _, pid := range pids {
+		ps, err := pt.proc.ProcessStat(pid)
+		if err != nil {
+			continue
+		}
+		jiffies := ps.UTime + ps.STime
+		samples[pid] = processSample{startTime: ps.StartTime, jiffies: jiffies}
+
+		info := ProcessInfo{
+			PID:       pid,
+			PPID:      ps.PPID,
+			Name:      ps.Comm,
+			State:     ps.State,
+			Threads:   ps.Threads,
+			Nice:      ps.Nice,
+			StartTime: stat.BootTime.Add(time.Duration(ps.StartTime) * time.Second / clockTicks),
+			Cmdline:   pt.proc.Cmdline(pid),
+			Cgroup:    pt.proc.Cgroup(pid),
+		}
+		if ps.RSSPages > 0 {
+			info.RSS = uint64(ps.RSSPages) * pageSize
+		}
+		if uid, err := pt.proc.ProcessUID(pid); err == nil {
+			info.User = pt.userName(uid)
+		}
+
+		if prev, ok := pt.prev[pid]; ok && prev.startTime == ps.StartTime && elapsed > 0 {
+			info.CPU = float64(jiffies-min(prev.jiffies, jiffies)) / clockTicks / elapsed * 100
+		} else {
+			// New since the last refresh: average over its lifetime like ps
+			lifetime := uptime.Seconds() - float64(ps.StartTime)/clockTicks
+			if lifetime > 0 {
+				info.CPU = float64(jiffies) / clockTicks / lifetime * 100
+			}
+		}
+		processes = append(processes, info)
+	}
+	pt.prev = samples
+	pt.prevTime = now
+	pt.mu.Unlock()
+
+	return applyProcessQuery(processes, query), nil
+}
+
+// UserName resolves a uid through the table's cache
+func (pt *ProcessTable) UserName(uid int) string {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	return pt.userName(uid)
+}
+
+// userName resolves a uid, falling back to the number for users that
+// aren't in the passwd database (containers, removed accounts)
+func (pt *ProcessTable) userName(uid int) string {
+	if name, ok := pt.users[uid]; ok {
+		return name
+	}
+	name := strconv.Itoa(uid)
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+	pt.users[uid] = name
+	return name
+}
+
+func applyProcessQuery(processes []ProcessInfo, query ProcessQuery) ProcessList {
+	matches := func(p ProcessInfo) bool {
+		if query.User != "" && p.User != query.User {
+			return false
+		}
+		if query.Filter == "" {
+			return true
+		}
+		filter := strings.ToLower(query.Filter)
+		return strings.Contains(strings.ToLower(p.Name), filter) ||
+			strings.Contains(strings.ToLower(p.Cmdline), filter) ||
+			strings.Contains(strings.ToLower(p.User), filter) ||
+			strconv.Itoa(p.PID) == filter
+	}
+
+	less := processLess(query.SortBy, query.Desc)
+	var list []ProcessInfo
+	total := 0
+	if query.Tree {
+		list, total = processTree(processes, matches, less)
+	} else {
+		for _, p := range processes {
+			if matches(p) {
+				list = append(list, p)
+			}
+		}
+		sort.SliceStable(list, func(i, j int) bool { return less(list[i], list[j]) })
+		total = len(list)
+	}
+
+	if query.Limit > 0 && len(list) > query.Limit {
+		list = list[:query.Limit]
+	}
+	if list == nil {
+		list = []ProcessInfo{}
+	}
+	return ProcessList{Processes: list, Total: total}
+}
+
+// processLess orders by the named column, breaking ties by ascending pid
+func processLess(sortBy string, desc bool) func(a, b ProcessInfo) bool {
+	var compare func(a, b ProcessInfo) int
+	switch sortBy {
+	case "memory", "rss":
+		compare = func(a, b ProcessInfo) int { return cmpOrdered(a.RSS, b.RSS) }
+	case "pid":
+		compare = func(a, b ProcessInfo) int { return cmpOrdered(a.PID, b.PID) }
+	case "name":
+		compare = func(a, b ProcessInfo) int { return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)) }
+	case "user":
+		compare = func(a, b ProcessInfo) int { return strings.Compare(a.User, b.User) }
+	case "threads":
+		compare = func(a, b ProcessInfo) int { return cmpOrdered(a.Threads, b.Threads) }
+	case "start":
+		compare = func(a, b ProcessInfo) int { return a.StartTime.Compare(b.StartTime) }
+	default:
+		compare = func(a, b ProcessInfo) int { return cmpOrdered(a.CPU, b.CPU) }
+	}
+
+	return func(a, b ProcessInfo) bool {
+		c := compare(a, b)
+		if desc {
+			c = -c
+		}
+		if c == 0 {
+			c = cmpOrdered(a.PID, b.PID)
+		}
+		return c < 0
+	}
+}
+
+func cmpOrdered[T int | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// processTree flattens the parent/child forest depth first, siblings in
+// sort order. A filter keeps the matches and their ancestors so every row
+// still hangs off its parent; total counts the matches alone.
+func processTree(processes []ProcessInfo, matches func(ProcessInfo) bool, less func(a, b ProcessInfo) bool) ([]ProcessInfo, int) {
+	byPID := make(map[int]ProcessInfo, len(processes))
+	for _, p := range processes {
+		byPID[p.PID] = p
+	}
+
+	keep := make(map[int]bool)
+	total := 0
+	for _, p := range processes {
+		if !matches(p) {
+			continue
+		}
+		total++
+		for pid := p.PID; !keep[pid]; {
+			keep[pid] = true
+			parent, ok := byPID[byPID[pid].PPID]
+			if !ok || parent.PID == pid {
+				break
+			}
+			pid = parent.PID
+		}
+	}
+
+	children := make(map[int][]ProcessInfo)
+	var roots []ProcessInfo
+	for _, p := range processes {
+		if !keep[p.PID] {
+			continue
+		}
+		if _, ok := byPID[p.PPID]; ok && p.PPID != p.PID {
+			children[p.PPID] = append(children[p.PPID], p)
+		} else {
+			roots = append(roots, p)
+		}
+	}
+
+	var list []ProcessInfo
+	var walk func(nodes []ProcessInfo, depth int)
+	walk = func(nodes []ProcessInfo, depth int) {
+		sort.SliceStable(nodes, func(i, j int) bool { return less(nodes[i], nodes[j]) })
+		for _, node := range nodes {
+			node.Depth = depth
+			list = append(list, node)
+			walk(children[node.PID], depth+1)
+		}
+	}
+	walk(roots, 0)
+	return list, total
+}
+
+var processSignals = map[string]syscall.Signal{
+	"TERM": syscall.SIGTERM,
+	"KILL": syscall.SIGKILL,
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"STOP": syscall.SIGSTOP,
+	"CONT": syscall.SIGCONT,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+}
+
+// ErrProcessPermission is returned when the process belongs to another
+// user, or a renice needs privileges Pulse doesn't have
+var ErrProcessPermission = errors.New("permission denied")
+
+// Signal sends a signal by name ("TERM", "SIGKILL", "kill")
+func (pt *ProcessTable) Signal(pid int, name string) error {
+	if err := checkTargetPID(pid); err != nil {
+		return err
+	}
+	key := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "SIG")
+	sig, ok := processSignals[key]
+	if !ok {
+		return fmt.Errorf("unsupported signal %q", name)
+	}
+	return processError(pid, "send SIG"+key+" to", syscall.Kill(pid, sig))
+}
+
+// Renice sets a process's nice value; going below the current value needs
+// CAP_SYS_NICE
+func (pt *ProcessTable) Renice(pid, nice int) error {
+	if err := checkTargetPID(pid); err != nil {
+		return err
+	}
+	if nice < -20 || nice > 19 {
+		return fmt.Errorf("nice value %d is outside -20..19", nice)
+	}
+	return processError(pid, "renice", syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice))
+}
+
+// checkTargetPID rejects pids that kill(2) treats as process groups and init
+func checkTargetPID(pid int) error {
+	if pid <= 1 {
+		return fmt.Errorf("refusing to act on pid %d", pid)
+	}
+	return nil
+}
+
+func processError(pid int, action string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, syscall.EPERM), errors.Is(err, syscall.EACCES):
+		return fmt.Errorf("cannot %s process %d: %w", action, pid, ErrProcessPermission)
+	case errors.Is(err, syscall.ESRCH):
+		return fmt.Errorf("process %d no longer exists", pid)
+	default:
+		return fmt.Errorf("cannot %s process %d: %w", action, pid, err)
+	}
+}
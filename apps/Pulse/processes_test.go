@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// copyTree copies a fixture tree so a test can change it
+func copyTree(t *testing.T, src string) string {
+	t.Helper()
+	dst := t.TempDir()
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(target, filepath.Join(dst, rel))
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, rel), data, 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dst
+}
+
+func pids(list ProcessList) []int {
+	var out []int
+	for _, p := range list.Processes {
+		out = append(out, p.PID)
+	}
+	return out
+}
+
+func TestProcessTableList(t *testing.T) {
+	root := copyTree(t, filepath.Join("testdata", "proc"))
+	table := NewProcessTable(NewProcFS(root))
+	now := time.Unix(1760788523, 0)
+	table.now = func() time.Time { return now }
+
+	list, err := table.List(ProcessQuery{SortBy: "pid"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if !reflect.DeepEqual(pids(list), []int{1, 4242, 4300}) || list.Total != 3 {
+		t.Fatalf("pids = %v, total %d", pids(list), list.Total)
+	}
+
+	web := list.Processes[1]
+	if web.Name != "Web Content (x)" || web.User != "4242000" || web.State != "R" || web.Threads != 27 || web.Nice != 5 {
+		t.Errorf("process = %+v", web)
+	}
+	if web.Cmdline != "/usr/lib/firefox/firefox -contentproc -childID 3" {
+		t.Errorf("Cmdline = %q", web.Cmdline)
+	}
+	if web.Cgroup != "/user.slice/user-1000.slice/app-firefox.scope" {
+		t.Errorf("Cgroup = %q", web.Cgroup)
+	}
+	if want := time.Unix(1760000000, 0).Add(6543210 * time.Millisecond); !web.StartTime.Equal(want) {
+		t.Errorf("StartTime = %v, want %v", web.StartTime, want)
+	}
+	if web.RSS != 120000*uint64(os.Getpagesize()) {
+		t.Errorf("RSS = %d", web.RSS)
+	}
+	if list.Processes[0].User != "root" || list.Processes[0].Cmdline != "/sbin/init splash" {
+		t.Errorf("init = %+v", list.Processes[0])
+	}
+	// First sight of a process averages over its lifetime: 62s of CPU
+	// over 781980.2s alive
+	if math.Abs(web.CPU-62/781980.2*100) > 0.0001 {
+		t.Errorf("lifetime CPU = %f", web.CPU)
+	}
+
+	// 200 more jiffies over two seconds is one core fully busy
+	stat, _ := os.ReadFile(filepath.Join(root, "4242", "stat"))
+	busier := strings.Replace(string(stat), " 5000 1200 ", " 5100 1300 ", 1)
+	if err := os.WriteFile(filepath.Join(root, "4242", "stat"), []byte(busier), 0644); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Second)
+
+	list, err = table.List(ProcessQuery{Filter: "firefox"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list.Processes) != 1 || math.Abs(list.Processes[0].CPU-100) > 0.0001 {
+		t.Errorf("filtered = %+v, want firefox at 100%%", list.Processes)
+	}
+}
+
+func TestApplyProcessQuery(t *testing.T) {
+	processes := []ProcessInfo{
+		{PID: 1, PPID: 0, Name: "systemd", User: "root", CPU: 0.1, RSS: 12, Threads: 1},
+		{PID: 900, PPID: 1, Name: "sshd", User: "root", CPU: 0, RSS: 8, Threads: 1},
+		{PID: 1200, PPID: 1, Name: "Hyprland", User: "kaguya", CPU: 7, RSS: 300, Threads: 20},
+		{PID: 1300, PPID: 1200, Name: "kitty", User: "kaguya", CPU: 2, RSS: 90, Threads: 8},
+		{PID: 1310, PPID: 1300, Name: "fish", User: "kaguya", CPU: 0, RSS: 10, Threads: 1},
+		{PID: 1400, PPID: 1200, Name: "firefox", User: "kaguya", CPU: 40, RSS: 900, Threads: 90},
+	}
+
+	tests := []struct {
+		name   string
+		query  ProcessQuery
+		want   []int
+		depths []int
+		total  int
+	}{
+		{"cpu desc", ProcessQuery{SortBy: "cpu", Desc: true}, []int{1400, 1200, 1300, 1, 900, 1310}, nil, 6},
+		{"memory limit", ProcessQuery{SortBy: "memory", Desc: true, Limit: 2}, []int{1400, 1200}, nil, 6},
+		{"name", ProcessQuery{SortBy: "name"}, []int{1400, 1310, 1200, 1300, 900, 1}, nil, 6},
+		{"user", ProcessQuery{User: "root", SortBy: "pid", Desc: true}, []int{900, 1}, nil, 2},
+		{"filter pid", ProcessQuery{Filter: "1300"}, []int{1300}, nil, 1},
+		{"tree", ProcessQuery{Tree: true, SortBy: "pid"}, []int{1, 900, 1200, 1300, 1310, 1400}, []int{0, 1, 1, 2, 3, 2}, 6},
+		{"tree by cpu", ProcessQuery{Tree: true, SortBy: "cpu", Desc: true}, []int{1, 1200, 1400, 1300, 1310, 900}, []int{0, 1, 2, 2, 3, 1}, 6},
+		{"tree filter keeps ancestors", ProcessQuery{Tree: true, Filter: "fish"}, []int{1, 1200, 1300, 1310}, []int{0, 1, 2, 3}, 1},
+		{"no match", ProcessQuery{Filter: "btop"}, nil, nil, 0},
+	}
+
+	for _, tt := range tests {
+		list := applyProcessQuery(processes, tt.query)
+		if !reflect.DeepEqual(pids(list), tt.want) || list.Total != tt.total {
+			t.Errorf("%s: pids = %v (total %d), want %v (total %d)", tt.name, pids(list), list.Total, tt.want, tt.total)
+			continue
+		}
+		if list.Processes == nil {
+			t.Errorf("%s: Processes is nil, want an empty list for JSON", tt.name)
+		}
+		for i, depth := range tt.depths {
+			if list.Processes[i].Depth != depth {
+				t.Errorf("%s: depth of %d = %d, want %d", tt.name, list.Processes[i].PID, list.Processes[i].Depth, depth)
+			}
+		}
+	}
+}
+
+func TestProcessSignalAndRenice(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sleep: %v", err)
+	}
+	pid := cmd.Process.Pid
+	table := NewProcessTable(NewProcFS(""))
+
+	if err := table.Renice(pid, 10); err != nil {
+		t.Fatalf("Renice: %v", err)
+	}
+	stat, err := NewProcFS("").ProcessStat(pid)
+	if err != nil || stat.Nice != 10 {
+		t.Errorf("nice = %d, %v; want 10", stat.Nice, err)
+	}
+
+	if err := table.Signal(pid, "sigterm"); err != nil {
+		t.Fatalf("Signal: %v", err)
+	}
+	cmd.Wait()
+	if err := table.Signal(pid, "TERM"); err == nil || !strings.Contains(err.Error(), "no longer exists") {
+		t.Errorf("signal to exited process: err = %v", err)
+	}
+
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{table.Signal(pid, "BOGUS"), `unsupported signal "BOGUS"`},
+		{table.Signal(1, "KILL"), "refusing to act on pid 1"},
+		{table.Signal(-1, "KILL"), "refusing to act on pid -1"},
+		{table.Renice(pid, 40), "nice value 40 is outside -20..19"},
+	} {
+		if tt.err == nil || tt.err.Error() != tt.want {
+			t.Errorf("err = %v, want %q", tt.err, tt.want)
+		}
+	}
+
+	err = processError(4242, "renice", syscall.EACCES)
+	if !errors.Is(err, ErrProcessPermission) || err.Error() != "cannot renice process 4242: permission denied" {
+		t.Errorf("permission error = %v", err)
+	}
+}
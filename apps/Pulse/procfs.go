@@ -0,0 +1,534 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProcFS reads kernel statistics from a proc filesystem. The root is
+// configurable so the parsers can run against fixture trees in tests.
+type ProcFS struct {
+	root string
+}
+
+// NewProcFS returns a reader rooted at root, or at /proc when root is ""
+func NewProcFS(root string) *ProcFS {
+	if root == "" {
+		root = "/proc"
+	}
+	return &ProcFS{root: root}
+}
+
+func (p *ProcFS) path(elem ...string) string {
+	return filepath.Join(append([]string{p.root}, elem...)...)
+}
+
+// CPUTimes are the jiffies a CPU has spent in each state since boot
+type CPUTimes struct {
+	User      uint64
+	Nice      uint64
+	System    uint64
+	Idle      uint64
+	IOWait    uint64
+	IRQ       uint64
+	SoftIRQ   uint64
+	Steal     uint64
+	Guest     uint64
+	GuestNice uint64
+}
+
+// Total is the jiffies across all states. Guest time is already counted in
+// user and nice, so it is left out.
+func (t CPUTimes) Total() uint64 {
+	return t.User + t.Nice + t.System + t.Idle + t.IOWait + t.IRQ + t.SoftIRQ + t.Steal
+}
+
+// IdleTotal is the time the CPU had nothing to run, including waiting on I/O
+func (t CPUTimes) IdleTotal() uint64 {
+	return t.Idle + t.IOWait
+}
+
+// Usage returns the busy percentage between two samples; a counter that
+// went backwards (CPU hotplug) reads as idle
+func Usage(prev, cur CPUTimes) float64 {
+	total := float64(cur.Total()) - float64(prev.Total())
+	idle := float64(cur.IdleTotal()) - float64(prev.IdleTotal())
+	if total <= 0 || idle < 0 {
+		return 0
+	}
+	return clampPercent((total - idle) / total * 100)
+}
+
+// CPUBreakdown splits the time between two samples into percentages. User
+// includes nice and System includes interrupt handling.
+type CPUBreakdown struct {
+	Usage  float64 `json:"usage"`
+	User   float64 `json:"user"`
+	System float64 `json:"system"`
+	IOWait float64 `json:"iowait"`
+	Steal  float64 `json:"steal"`
+}
+
+// Breakdown returns where the CPU spent its time between two samples
+func Breakdown(prev, cur CPUTimes) CPUBreakdown {
+	total := float64(cur.Total()) - float64(prev.Total())
+	if total <= 0 {
+		return CPUBreakdown{}
+	}
+	share := func(before, after uint64) float64 {
+		return clampPercent((float64(after) - float64(before)) / total * 100)
+	}
+	return CPUBreakdown{
+		Usage:  Usage(prev, cur),
+		User:   share(prev.User+prev.Nice, cur.User+cur.Nice),
+		System: share(prev.System+prev.IRQ+prev.SoftIRQ, cur.System+cur.IRQ+cur.SoftIRQ),
+		IOWait: share(prev.IOWait, cur.IOWait),
+		Steal:  share(prev.Steal, cur.Steal),
+	}
+}
+
+func clampPercent(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 100 {
+		return 100
+	}
+	return v
+}
+
+// StatSample is one read of /proc/stat
+type StatSample struct {
+	Total        CPUTimes
+	Cores        []CPUTimes // indexed by the N in cpuN
+	ProcsRunning int
+	ProcsBlocked int
+	BootTime     time.Time
+	Taken        time.Time
+}
+
+// Stat parses /proc/stat
+func (p *ProcFS) Stat() (StatSample, error) {
+	file, err := os.Open(p.path("stat"))
+	if err != nil {
+		return StatSample{}, err
+	}
+	defer file.Close()
+
+	sample := StatSample{Taken: time.Now()}
+	scanner := bufio.NewScanner(file)
+	// The intr line lists every interrupt and can outgrow the default buffer
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch key := fields[0]; {
+		case key == "cpu":
+			sample.Total = parseCPUTimes(fields[1:])
+		case strings.HasPrefix(key, "cpu"):
+			n, err := strconv.Atoi(key[3:])
+			if err != nil || n < 0 {
+				continue
+			}
+			for len(sample.Cores) <= n {
+				sample.Cores = append(sample.Cores, CPUTimes{})
+			}
+			sample.Cores[n] = parseCPUTimes(fields[1:])
+		case key == "procs_running":
+			sample.ProcsRunning, _ = strconv.Atoi(fields[1])
+		case key == "procs_blocked":
+			sample.ProcsBlocked, _ = strconv.Atoi(fields[1])
+		case key == "btime":
+			if secs, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				sample.BootTime = time.Unix(secs, 0)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return StatSample{}, err
+	}
+	if sample.Total.Total() == 0 {
+		return StatSample{}, fmt.Errorf("%s has no cpu line", p.path("stat"))
+	}
+	return sample, nil
+}
+
+// parseCPUTimes reads the columns of a cpu line; older kernels have fewer
+func parseCPUTimes(fields []string) CPUTimes {
+	var v [10]uint64
+	for i := 0; i < len(fields) && i < len(v); i++ {
+		v[i], _ = strconv.ParseUint(fields[i], 10, 64)
+	}
+	return CPUTimes{
+		User: v[0], Nice: v[1], System: v[2], Idle: v[3], IOWait: v[4],
+		IRQ: v[5], SoftIRQ: v[6], Steal: v[7], Guest: v[8], GuestNice: v[9],
+	}
+}
+
+// Meminfo holds the /proc/meminfo fields Pulse reports, in bytes
+type Meminfo struct {
+	MemTotal     uint64
+	MemFree      uint64
+	MemAvailable uint64
+	Buffers      uint64
+	Cached       uint64
+	SwapTotal    uint64
+	SwapFree     uint64
+}
+
+// Meminfo parses /proc/meminfo
+func (p *ProcFS) Meminfo() (Meminfo, error) {
+	file, err := os.Open(p.path("meminfo"))
+	if err != nil {
+		return Meminfo{}, err
+	}
+	defer file.Close()
+
+	fields := map[string]*uint64{}
+	var info Meminfo
+	fields["MemTotal"] = &info.MemTotal
+	fields["MemFree"] = &info.MemFree
+	fields["MemAvailable"] = &info.MemAvailable
+	fields["Buffers"] = &info.Buffers
+	fields["Cached"] = &info.Cached
+	fields["SwapTotal"] = &info.SwapTotal
+	fields["SwapFree"] = &info.SwapFree
+
+	hasAvailable := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		target, wanted := fields[key]
+		if !wanted {
+			continue
+		}
+		parts := strings.Fields(value)
+		if len(parts) == 0 {
+			continue
+		}
+		n, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(parts) > 1 && parts[1] == "kB" {
+			n *= 1024
+		}
+		*target = n
+		if key == "MemAvailable" {
+			hasAvailable = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Meminfo{}, err
+	}
+	if info.MemTotal == 0 {
+		return Meminfo{}, fmt.Errorf("%s has no MemTotal", p.path("meminfo"))
+	}
+	// Kernels before 3.14 don't report MemAvailable
+	if !hasAvailable {
+		info.MemAvailable = info.MemFree + info.Buffers + info.Cached
+	}
+	return info, nil
+}
+
+// MemUsed is memory in use the way free(1) counts it
+func (m Meminfo) MemUsed() uint64 {
+	if m.MemAvailable > m.MemTotal {
+		return 0
+	}
+	return m.MemTotal - m.MemAvailable
+}
+
+// SwapUsed is swap in use
+func (m Meminfo) SwapUsed() uint64 {
+	if m.SwapFree > m.SwapTotal {
+		return 0
+	}
+	return m.SwapTotal - m.SwapFree
+}
+
+// Uptime parses /proc/uptime
+func (p *ProcFS) Uptime() (time.Duration, error) {
+	data, err := os.ReadFile(p.path("uptime"))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("%s is empty", p.path("uptime"))
+	}
+	secs, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse uptime: %w", err)
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
+
+// LoadAvg is the run queue length averaged over 1, 5 and 15 minutes
+type LoadAvg struct {
+	One     float64 `json:"one"`
+	Five    float64 `json:"five"`
+	Fifteen float64 `json:"fifteen"`
+}
+
+// LoadAvg parses /proc/loadavg
+func (p *ProcFS) LoadAvg() (LoadAvg, error) {
+	data, err := os.ReadFile(p.path("loadavg"))
+	if err != nil {
+		return LoadAvg{}, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return LoadAvg{}, fmt.Errorf("%s has %d fields", p.path("loadavg"), len(fields))
+	}
+
+	var values [3]float64
+	for i := range values {
+		if values[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return LoadAvg{}, fmt.Errorf("failed to parse load average: %w", err)
+		}
+	}
+	return LoadAvg{One: values[0], Five: values[1], Fifteen: values[2]}, nil
+}
+
+// CPUInfo is the static description of the processor
+type CPUInfo struct {
+	Model   string
+	Threads int // logical CPUs
+	Cores   int // physical cores, or Threads when the kernel doesn't say
+}
+
+// CPUInfo parses /proc/cpuinfo
+func (p *ProcFS) CPUInfo() (CPUInfo, error) {
+	file, err := os.Open(p.path("cpuinfo"))
+	if err != nil {
+		return CPUInfo{}, err
+	}
+	defer file.Close()
+
+	var info CPUInfo
+	physicalID := ""
+	cores := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "processor":
+			info.Threads++
+		case "model name", "Model", "Hardware", "cpu model":
+			// x86 says "model name"; ARM boards say Model or Hardware
+			if info.Model == "" {
+				info.Model = value
+			}
+		case "physical id":
+			physicalID = value
+		case "core id":
+			cores[physicalID+"/"+value] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return CPUInfo{}, err
+	}
+
+	info.Cores = len(cores)
+	if info.Cores == 0 {
+		info.Cores = info.Threads
+	}
+	return info, nil
+}
+
+// ProcessStat is the part of /proc/[pid]/stat Pulse uses
+type ProcessStat struct {
+	PID       int
+	Comm      string
+	State     string
+	PPID      int
+	UTime     uint64 // jiffies
+	STime     uint64 // jiffies
+	Nice      int
+	Threads   int
+	StartTime uint64 // jiffies after boot
+	VSize     uint64 // bytes
+	RSSPages  int64
+	Processor int
+}
+
+// PIDs lists the processes in the proc root
+func (p *ProcFS) PIDs() ([]int, error) {
+	entries, err := os.ReadDir(p.root)
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if pid, err := strconv.Atoi(entry.Name()); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// ProcessStat parses /proc/[pid]/stat
+func (p *ProcFS) ProcessStat(pid int) (ProcessStat, error) {
+	data, err := os.ReadFile(p.path(strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return ProcessStat{}, err
+	}
+	return parseProcessStat(string(data))
+}
+
+// parseProcessStat splits a stat line around the command name, which is in
+// parentheses and may itself contain spaces and parentheses
+func parseProcessStat(line string) (ProcessStat, error) {
+	start := strings.IndexByte(line, '(')
+	end := strings.LastIndexByte(line, ')')
+	if start < 0 || end < start {
+		return ProcessStat{}, fmt.Errorf("malformed stat line")
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(line[:start]))
+	if err != nil {
+		return ProcessStat{}, fmt.Errorf("malformed pid: %w", err)
+	}
+	// fields[0] is field 3 (state) in proc(5) numbering
+	fields := strings.Fields(line[end+1:])
+	if len(fields) < 22 {
+		return ProcessStat{}, fmt.Errorf("stat line for %d has %d fields", pid, len(fields)+2)
+	}
+
+	stat := ProcessStat{PID: pid, Comm: line[start+1 : end], State: fields[0]}
+	stat.PPID, _ = strconv.Atoi(fields[1])
+	stat.UTime, _ = strconv.ParseUint(fields[11], 10, 64)
+	stat.STime, _ = strconv.ParseUint(fields[12], 10, 64)
+	stat.Nice, _ = strconv.Atoi(fields[16])
+	stat.Threads, _ = strconv.Atoi(fields[17])
+	stat.StartTime, _ = strconv.ParseUint(fields[19], 10, 64)
+	stat.VSize, _ = strconv.ParseUint(fields[20], 10, 64)
+	stat.RSSPages, _ = strconv.ParseInt(fields[21], 10, 64)
+	if len(fields) > 36 {
+		stat.Processor, _ = strconv.Atoi(fields[36])
+	}
+	return stat, nil
+}
+
+// ProcessUID returns the real user id from /proc/[pid]/status
+func (p *ProcFS) ProcessUID(pid int) (int, error) {
+	file, err := os.Open(p.path(strconv.Itoa(pid), "status"))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "Uid:")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			break
+		}
+		return strconv.Atoi(fields[0])
+	}
+	return 0, fmt.Errorf("no Uid line for %d", pid)
+}
+
+// Cmdline returns the command line of a process with its arguments joined
+// by spaces; kernel threads have none
+func (p *ProcFS) Cmdline(pid int) string {
+	data, err := os.ReadFile(p.path(strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return ""
+	}
+	args := strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")
+	return strings.TrimSpace(strings.Join(args, " "))
+}
+
+// Cgroup returns the cgroup a process belongs to. The unified (v2)
+// hierarchy wins; on hybrid setups it is often "/" and the systemd v1
+// hierarchy names the unit instead.
+func (p *ProcFS) Cgroup(pid int) string {
+	data, err := os.ReadFile(p.path(strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return ""
+	}
+
+	var unified, systemd, other string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		switch {
+		case parts[0] == "0" && parts[1] == "":
+			unified = parts[2]
+		case parts[1] == "name=systemd":
+			systemd = parts[2]
+		case other == "" && parts[2] != "/":
+			other = parts[2]
+		}
+	}
+
+	for _, path := range []string{unified, systemd, other} {
+		if path != "" && path != "/" {
+			return path
+		}
+	}
+	if unified != "" {
+		return unified
+	}
+	return systemd
+}
+
+// formatUptime renders a duration like `uptime -p` without the "up"
+func formatUptime(d time.Duration) string {
+	minutes := int(d / time.Minute)
+	units := []struct {
+		name string
+		size int
+	}{
+		{"week", 7 * 24 * 60},
+		{"day", 24 * 60},
+		{"hour", 60},
+		{"minute", 1},
+	}
+
+	var parts []string
+	for _, unit := range units {
+		n := minutes / unit.size
+		minutes %= unit.size
+		if n == 0 {
+			continue
+		}
+		part := fmt.Sprintf("%d %s", n, unit.name)
+		if n != 1 {
+			part += "s"
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "0 minutes"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func testProcFS() *ProcFS {
+	return NewProcFS(filepath.Join("testdata", "proc"))
+}
+
+func TestProcStat(t *testing.T) {
+	sample, err := testProcFS().Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+
+	want := CPUTimes{User: 10000, Nice: 500, System: 3000, Idle: 80000, IOWait: 1000, IRQ: 100, SoftIRQ: 200, Steal: 300}
+	if sample.Total != want {
+		t.Errorf("Total = %+v, want %+v", sample.Total, want)
+	}
+	if len(sample.Cores) != 2 || sample.Cores[1].User != 4000 {
+		t.Errorf("Cores = %+v, want 2 cores", sample.Cores)
+	}
+	if sample.ProcsRunning != 3 || sample.ProcsBlocked != 1 {
+		t.Errorf("procs = %d running, %d blocked", sample.ProcsRunning, sample.ProcsBlocked)
+	}
+	if !sample.BootTime.Equal(time.Unix(1760000000, 0)) {
+		t.Errorf("BootTime = %v", sample.BootTime)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	prev := CPUTimes{User: 100, System: 50, Idle: 800, IOWait: 50}
+	tests := []struct {
+		name string
+		cur  CPUTimes
+		want float64
+	}{
+		{"half busy", CPUTimes{User: 150, System: 75, Idle: 865, IOWait: 60}, 50},
+		{"all idle", CPUTimes{User: 100, System: 50, Idle: 900, IOWait: 50}, 0},
+		{"iowait is idle", CPUTimes{User: 100, System: 50, Idle: 800, IOWait: 150}, 0},
+		{"fully busy", CPUTimes{User: 200, System: 150, Idle: 800, IOWait: 50}, 100},
+		{"no time passed", prev, 0},
+		{"counters reset", CPUTimes{User: 10, Idle: 10}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := Usage(prev, tt.cur); math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("%s: Usage = %.3f, want %.3f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProcMeminfo(t *testing.T) {
+	info, err := testProcFS().Meminfo()
+	if err != nil {
+		t.Fatalf("Meminfo: %v", err)
+	}
+	if info.MemTotal != 16303428*1024 || info.MemAvailable != 9876544*1024 {
+		t.Errorf("info = %+v", info)
+	}
+	if got, want := info.MemUsed(), uint64(16303428-9876544)*1024; got != want {
+		t.Errorf("MemUsed = %d, want %d", got, want)
+	}
+	if got, want := info.SwapUsed(), uint64(8388604-6291452)*1024; got != want {
+		t.Errorf("SwapUsed = %d, want %d", got, want)
+	}
+
+	stats := newMemoryStats(info.MemUsed(), info.MemTotal)
+	if stats.Total != 15921 || stats.Used != 6276 || math.Abs(stats.Percent-39.42) > 0.01 {
+		t.Errorf("memory stats = %+v", stats)
+	}
+}
+
+func TestMeminfoWithoutAvailable(t *testing.T) {
+	root := t.TempDir()
+	data := "MemTotal: 1000 kB\nMemFree: 100 kB\nBuffers: 50 kB\nCached: 250 kB\n"
+	if err := os.WriteFile(filepath.Join(root, "meminfo"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := NewProcFS(root).Meminfo()
+	if err != nil {
+		t.Fatalf("Meminfo: %v", err)
+	}
+	if info.MemAvailable != 400*1024 {
+		t.Errorf("MemAvailable = %d, want free+buffers+cached", info.MemAvailable)
+	}
+}
+
+func TestProcUptime(t *testing.T) {
+	uptime, err := testProcFS().Uptime()
+	if err != nil {
+		t.Fatalf("Uptime: %v", err)
+	}
+	if got := formatUptime(uptime); got != "1 week, 2 days, 3 hours, 2 minutes" {
+		t.Errorf("formatUptime = %q", got)
+	}
+	if got := formatUptime(30 * time.Second); got != "0 minutes" {
+		t.Errorf("formatUptime(30s) = %q", got)
+	}
+	if got := formatUptime(61 * time.Minute); got != "1 hour, 1 minute" {
+		t.Errorf("formatUptime(61m) = %q", got)
+	}
+}
+
+func TestProcCPUInfo(t *testing.T) {
+	info, err := testProcFS().CPUInfo()
+	if err != nil {
+		t.Fatalf("CPUInfo: %v", err)
+	}
+	want := CPUInfo{Model: "AMD Ryzen 7 5800X 8-Core Processor", Threads: 4, Cores: 2}
+	if info != want {
+		t.Errorf("CPUInfo = %+v, want %+v", info, want)
+	}
+}
+
+func TestProcProcesses(t *testing.T) {
+	proc := testProcFS()
+	pids, err := proc.PIDs()
+	if err != nil {
+		t.Fatalf("PIDs: %v", err)
+	}
+	sort.Ints(pids)
+	if !reflect.DeepEqual(pids, []int{1, 4242, 4300}) {
+		t.Errorf("PIDs = %v", pids)
+	}
+
+	stat, err := proc.ProcessStat(4242)
+	if err != nil {
+		t.Fatalf("ProcessStat: %v", err)
+	}
+	want := ProcessStat{
+		PID: 4242, Comm: "Web Content (x)", State: "R", PPID: 4100,
+		UTime: 5000, STime: 1200, Nice: 5, Threads: 27, StartTime: 654321,
+		VSize: 3200000000, RSSPages: 120000, Processor: 1,
+	}
+	if stat != want {
+		t.Errorf("ProcessStat = %+v, want %+v", stat, want)
+	}
+
+	if _, err := parseProcessStat("12 (short) S 1 2"); err == nil {
+		t.Error("truncated stat line: want error")
+	}
+}
+
+func TestBreakdown(t *testing.T) {
+	prev := CPUTimes{User: 100, Nice: 10, System: 40, Idle: 800, IOWait: 20, IRQ: 5, SoftIRQ: 5, Steal: 20}
+	cur := CPUTimes{User: 140, Nice: 20, System: 60, Idle: 880, IOWait: 40, IRQ: 10, SoftIRQ: 10, Steal: 40}
+
+	got := Breakdown(prev, cur)
+	want := CPUBreakdown{Usage: 50, User: 25, System: 15, IOWait: 10, Steal: 10}
+	if got != want {
+		t.Errorf("Breakdown = %+v, want %+v", got, want)
+	}
+	if got := Breakdown(cur, cur); got != (CPUBreakdown{}) {
+		t.Errorf("Breakdown with no elapsed time = %+v", got)
+	}
+}
+
+func TestProcLoadAvg(t *testing.T) {
+	load, err := testProcFS().LoadAvg()
+	if err != nil {
+		t.Fatalf("LoadAvg: %v", err)
+	}
+	if want := (LoadAvg{One: 2.15, Five: 1.73, Fifteen: 1.20}); load != want {
+		t.Errorf("LoadAvg = %+v, want %+v", load, want)
+	}
+}
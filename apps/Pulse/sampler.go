@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSampleInterval = time.Second
+	minSampleInterval     = 250 * time.Millisecond
+	defaultHistory        = 15 * time.Minute
+	maxHistory            = 24 * time.Hour
+	// maxHistoryPoints caps each ring: 24h at one sample a second, or 6h
+	// at the fastest interval
+	maxHistoryPoints = 86400
+)
+
+// SamplerConfig is the [pulse] table of kaguyadots.toml
+type SamplerConfig struct {
+	Interval time.Duration
+	History  time.Duration
+}
+
+// ReadSamplerConfig reads the sampling interval and how much history to
+// keep, falling back to one sample a second for fifteen minutes
+func ReadSamplerConfig() SamplerConfig {
+	config := SamplerConfig{Interval: defaultSampleInterval, History: defaultHistory}
+
+	table := readKaguyaTable("pulse")
+	if interval, err := time.ParseDuration(table["interval"]); err == nil && interval > 0 {
+		config.Interval = max(interval, minSampleInterval)
+	}
+	if history, err := time.ParseDuration(table["history"]); err == nil && history > 0 {
+		config.History = min(history, maxHistory)
+	}
+	config.History = min(config.History, config.Interval*maxHistoryPoints)
+	return config
+}
+
+// size is the number of samples each ring buffer holds
+func (c SamplerConfig) size() int {
+	return min(max(int(c.History/c.Interval), 1), maxHistoryPoints)
+}
+
+// HistoryPoint is one sample of a metric; Time is Unix milliseconds so the
+// frontend can use it as is
+type HistoryPoint struct {
+	Time  int64   `json:"t"`
+	Value float64 `json:"v"`
+}
+
+// ring keeps the newest points, overwriting the oldest once full. It
+// grows as samples arrive so a long history costs nothing up front.
+type ring struct {
+	points []HistoryPoint
+	size   int
+	next   int
+	last   int64 // time of the newest point
+}
+
+func newRing(size int) *ring {
+	return &ring{size: size}
+}
+
+func (r *ring) push(p HistoryPoint) {
+	r.last = p.Time
+	if len(r.points) < r.size {
+		r.points = append(r.points, p)
+		return
+	}
+	r.points[r.next] = p
+	r.next = (r.next + 1) % r.size
+}
+
+// since returns the points at or after the given Unix millisecond, oldest
+// first
+func (r *ring) since(from int64) []HistoryPoint {
+	ordered := append(append([]HistoryPoint{}, r.points[r.next:]...), r.points[:r.next]...)
+	start := sort.Search(len(ordered), func(i int) bool { return ordered[i].Time >= from })
+	return ordered[start:]
+}
+
+// Sampler collects system stats on a fixed interval in one goroutine, keeps
+// a ring buffer per metric and hands every sample to a listener. The
+// frontend reads the latest sample instead of triggering collection.
+type Sampler struct {
+	config  SamplerConfig
+	collect func() EnhancedSystemStats
+	now     func() time.Time
+
+	mu      sync.RWMutex
+	latest  EnhancedSystemStats
+	sampled bool
+	history map[string]*ring
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewSampler(config SamplerConfig, collect func() EnhancedSystemStats) *Sampler {
+	return &Sampler{
+		config:  config,
+		collect: collect,
+		now:     time.Now,
+		history: make(map[string]*ring),
+	}
+}
+
+// Start samples immediately and then on every interval until Stop is
+// called or ctx ends, passing each sample to emit
+func (s *Sampler) Start(ctx context.Context, emit func(EnhancedSystemStats)) {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	done := s.done
+	s.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+		for {
+			stats := s.Sample()
+			if emit != nil {
+				emit(stats)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends the sampling goroutine and waits for it to exit
+func (s *Sampler) Stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// Sample collects one set of stats and records it in the history
+func (s *Sampler) Sample() EnhancedSystemStats {
+	stats := s.collect()
+	point := s.now().UnixMilli()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latest = stats
+	s.sampled = true
+	for metric, value := range sampleMetrics(stats) {
+		r, ok := s.history[metric]
+		if !ok {
+			r = newRing(s.config.size())
+			s.history[metric] = r
+		}
+		r.push(HistoryPoint{Time: point, Value: value})
+	}
+
+	// Interfaces, disks and sensor chips come and go; a metric missing for
+	// a whole history window has nothing left to show, so its ring is freed
+	cutoff := point - s.config.History.Milliseconds()
+	for metric, r := range s.history {
+		if r.last < cutoff {
+			delete(s.history, metric)
+		}
+	}
+	return stats
+}
+
+// Latest returns the newest sample, collecting one if the sampler hasn't
+// run yet
+func (s *Sampler) Latest() EnhancedSystemStats {
+	s.mu.RLock()
+	latest, sampled := s.latest, s.sampled
+	s.mu.RUnlock()
+
+	if !sampled {
+		return s.Sample()
+	}
+	return latest
+}
+
+// History returns a metric's samples from the last window, oldest first;
+// a zero window returns everything kept
+func (s *Sampler) History(metric string, window time.Duration) ([]HistoryPoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.history[metric]
+	if !ok {
+		return nil, fmt.Errorf("no history for metric %q", metric)
+	}
+	var from int64
+	if window > 0 {
+		from = s.now().Add(-window).UnixMilli()
+	}
+	return r.since(from), nil
+}
+
+// Metrics lists the metrics that have history, sorted by name
+func (s *Sampler) Metrics() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	metrics := make([]string, 0, len(s.history))
+	for metric := range s.history {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+	return metrics
+}
+
+// sampleMetrics flattens a sample into the values kept as history.
+// Per-core usage is "cpu.<id>", per-GPU usage "gpu.<index>" in the order
+// of the GPU list, hwmon readings "sensor.<chip>.<sensor>" by the chip's
+// stable ID (sensor.k10temp.temp1), filesystem
+// usage "mount.<mount point>" (mount./home), the battery charge as
+// "battery" on machines that have one, and network and disk rates
+// are bytes/sec, per interface as "net.<name>.down" and "net.<name>.up"
+// and per disk as "disk.<name>.read" and "disk.<name>.write", with the
+// disk's busy percentage as "disk.<name>.util".
+func sampleMetrics(stats EnhancedSystemStats) map[string]float64 {
+	metrics := map[string]float64{
+		"cpu":      stats.CPU.Usage,
+		"ram":      stats.RAM.Percent,
+		"swap":     stats.Swap.Percent,
+		"gpu":      0,
+		"temp":     stats.Temp.CPU,
+		"net.down": stats.Network.Down,
+		"net.up":   stats.Network.Up,
+	}
+	for _, core := range stats.CPU.PerCore {
+		metrics["cpu."+strconv.Itoa(core.ID)] = core.Usage
+	}
+	for i, gpu := range stats.GPUs {
+		metrics["gpu"] = max(metrics["gpu"], gpu.Usage)
+		metrics["gpu."+strconv.Itoa(i)] = gpu.Usage
+	}
+	for _, chip := range stats.Sensors {
+		for _, sensor := range chip.Sensors {
+			metrics["sensor."+chip.ID+"."+sensor.ID] = sensor.Value
+		}
+	}
+	if stats.Battery != nil {
+		metrics["battery"] = stats.Battery.Percent
+	}
+	for _, disk := range stats.Disks {
+		metrics["mount."+disk.MountPoint] = disk.Percent
+	}
+	for _, disk := range stats.DiskIO {
+		metrics["disk."+disk.Name+".read"] = disk.ReadBytesPerSec
+		metrics["disk."+disk.Name+".write"] = disk.WriteBytesPerSec
+		metrics["disk."+disk.Name+".util"] = disk.Utilization
+	}
+	for _, iface := range stats.Network.Interfaces {
+		metrics["net."+iface.Name+".down"] = iface.RxBytesPerSec
+		metrics["net."+iface.Name+".up"] = iface.TxBytesPerSec
+	}
+	return metrics
+}
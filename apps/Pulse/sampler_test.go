@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRing(t *testing.T) {
+	r := newRing(3)
+	if got := r.since(0); len(got) != 0 {
+		t.Errorf("empty ring = %v", got)
+	}
+	for i := int64(1); i <= 5; i++ {
+		r.push(HistoryPoint{Time: i * 1000, Value: float64(i)})
+	}
+
+	want := []HistoryPoint{{3000, 3}, {4000, 4}, {5000, 5}}
+	if got := r.since(0); !reflect.DeepEqual(got, want) {
+		t.Errorf("since(0) = %v, want %v", got, want)
+	}
+	if got := r.since(4000); !reflect.DeepEqual(got, want[1:]) {
+		t.Errorf("since(4000) = %v, want %v", got, want[1:])
+	}
+
+	// The result is a copy the caller may keep
+	got := r.since(0)
+	r.push(HistoryPoint{Time: 6000, Value: 6})
+	if got[0].Value != 3 {
+		t.Errorf("since shares the ring's storage: %v", got)
+	}
+}
+
+func TestSamplerHistory(t *testing.T) {
+	tick := 0
+	s := NewSampler(SamplerConfig{Interval: time.Second, History: 3 * time.Second}, func() EnhancedSystemStats {
+		tick++
+		return EnhancedSystemStats{
+			CPU:     CPUStats{Usage: float64(tick * 10), PerCore: []CoreStats{{ID: 2, CPUBreakdown: CPUBreakdown{Usage: float64(tick)}}}},
+			RAM:     MemoryStats{Percent: 50},
+			Network: NetworkStats{Down: 1536, Up: 512, Interfaces: []InterfaceStats{{Name: "wlan0", RxBytesPerSec: 1536, TxBytesPerSec: 512}}},
+		}
+	})
+	now := time.Unix(1760000000, 0)
+	s.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		s.Sample()
+		now = now.Add(time.Second)
+	}
+
+	cpu, err := s.History("cpu", 0)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	var values []float64
+	for _, p := range cpu {
+		values = append(values, p.Value)
+	}
+	if !reflect.DeepEqual(values, []float64{20, 30, 40}) {
+		t.Errorf("cpu history = %v, want the last three samples", values)
+	}
+
+	recent, _ := s.History("cpu.2", 2*time.Second)
+	if len(recent) != 2 || recent[1].Value != 4 || recent[1].Time != time.Unix(1760000003, 0).UnixMilli() {
+		t.Errorf("cpu.2 over 2s = %v", recent)
+	}
+	if up, _ := s.History("net.wlan0.up", 0); up[0].Value != 512 {
+		t.Errorf("net.wlan0.up = %v", up)
+	}
+	if _, err := s.History("fan", 0); err == nil {
+		t.Error("unknown metric: want error")
+	}
+	if got := s.Latest().CPU.Usage; got != 40 {
+		t.Errorf("Latest cpu = %v, want the newest sample", got)
+	}
+	if got := s.Metrics(); !reflect.DeepEqual(got, []string{"cpu", "cpu.2", "gpu", "net.down", "net.up", "net.wlan0.down", "net.wlan0.up", "ram", "swap", "temp"}) {
+		t.Errorf("Metrics = %v", got)
+	}
+}
+
+func TestSamplerEvictsGoneMetrics(t *testing.T) {
+	tick := 0
+	s := NewSampler(SamplerConfig{Interval: time.Second, History: 3 * time.Second}, func() EnhancedSystemStats {
+		tick++
+		stats := EnhancedSystemStats{}
+		// A USB Ethernet adapter unplugged after the second sample
+		if tick <= 2 {
+			stats.Network.Interfaces = []InterfaceStats{{Name: "enx0", RxBytesPerSec: 100}}
+		}
+		return stats
+	})
+	now := time.Unix(1760000000, 0)
+	s.now = func() time.Time { return now }
+
+	has := func(metric string) bool {
+		_, err := s.History(metric, 0)
+		return err == nil
+	}
+	for i := 1; i <= 6; i++ {
+		s.Sample()
+		// Last seen at the second sample, kept for one history window
+		if want := i <= 5; has("net.enx0.down") != want {
+			t.Errorf("sample %d: net.enx0.down kept = %v, want %v", i, !want, want)
+		}
+		now = now.Add(time.Second)
+	}
+	if !has("cpu") {
+		t.Error("cpu evicted")
+	}
+}
+
+func TestSamplerStartStop(t *testing.T) {
+	s := NewSampler(SamplerConfig{Interval: 10 * time.Millisecond, History: time.Second}, func() EnhancedSystemStats {
+		return EnhancedSystemStats{Uptime: "up"}
+	})
+
+	samples := make(chan EnhancedSystemStats, 100)
+	s.Start(context.Background(), func(stats EnhancedSystemStats) { samples <- stats })
+	s.Start(context.Background(), nil) // already running
+	for i := 0; i < 3; i++ {
+		select {
+		case stats := <-samples:
+			if stats.Uptime != "up" {
+				t.Fatalf("emitted %+v", stats)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no sample emitted")
+		}
+	}
+	s.Stop()
+	s.Stop()
+
+	n := len(samples)
+	time.Sleep(50 * time.Millisecond)
+	if len(samples) != n {
+		t.Error("sampler kept running after Stop")
+	}
+}
+
+func TestReadSamplerConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if got := ReadSamplerConfig(); got != (SamplerConfig{Interval: time.Second, History: 15 * time.Minute}) {
+		t.Errorf("defaults = %+v", got)
+	}
+
+	dir := filepath.Join(home, ".config", "kaguyadots")
+	os.MkdirAll(dir, 0755)
+	toml := "[aoiler]\ninterval = \"5s\"\n\n[pulse]\ninterval = \"100ms\" # too fast\nhistory = \"48h\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "kaguyadots.toml"), []byte(toml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := ReadSamplerConfig()
+	// 24h at 250ms is over the per-ring cap, so history is shortened
+	if config.Interval != 250*time.Millisecond || config.History != 6*time.Hour {
+		t.Errorf("config = %+v, want clamped values", config)
+	}
+	if config.size() != maxHistoryPoints {
+		t.Errorf("size = %d", config.size())
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sensor is one hwmon channel, scaled to °C, RPM, V, W or A. Thresholds
+// the chip doesn't report are zero.
+type Sensor struct {
+	ID    string  `json:"id"`   // temp1, fan2, in0...
+	Kind  string  `json:"kind"` // temp, fan, voltage, power or current
+	Label string  `json:"label"`
+	Value float64 `json:"value"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"` // the "high" warning threshold
+	Crit  float64 `json:"crit"`
+	Unit  string  `json:"unit"`
+	Alarm bool    `json:"alarm"` // the chip flags the reading out of range
+}
+
+// SensorChip is one /sys/class/hwmon device. The kernel numbers hwmon
+// devices in probe order, which can change between boots, so chips are
+// known by their name instead, with the device they belong to added when
+// several share a name.
+type SensorChip struct {
+	ID      string   `json:"id"`     // k10temp, or nvme-nvme0 next to nvme-nvme1
+	Hwmon   string   `json:"hwmon"`  // hwmon2, for this boot only
+	Name    string   `json:"name"`   // k10temp, nvme, drivetemp...
+	Device  string   `json:"device"` // drive model for nvme and drivetemp
+	Sensors []Sensor `json:"sensors"`
+}
+
+// sensorKinds maps hwmon attribute prefixes to their kind, unit and the
+// divisor from sysfs units (millidegrees, millivolts, microwatts...)
+var sensorKinds = map[string]struct {
+	kind  string
+	unit  string
+	scale float64
+	order int
+}{
+	"temp":  {"temp", "°C", 1000, 0},
+	"fan":   {"fan", "RPM", 1, 1},
+	"in":    {"voltage", "V", 1000, 2},
+	"power": {"power", "W", 1e6, 3},
+	"curr":  {"current", "A", 1000, 4},
+}
+
+var sensorInput = regexp.MustCompile(`^(temp|fan|in|power|curr)(\d+)_(input|average)$`)
+
+// SensorChips reads every hwmon chip in index order. Channels whose input
+// can't be read (disconnected probes report ENODATA) are skipped.
+func (s *SysFS) SensorChips() ([]SensorChip, error) {
+	entries, err := os.ReadDir(s.path("class", "hwmon"))
+	if err != nil {
+		return nil, err
+	}
+
+	var chips []SensorChip
+	named := make(map[string]int)
+	for _, entry := range entries {
+		hwmon := entry.Name()
+		if !strings.HasPrefix(hwmon, "hwmon") {
+			continue
+		}
+		chip := SensorChip{
+			ID:      s.readString("class", "hwmon", hwmon, "name"),
+			Hwmon:   hwmon,
+			Name:    s.readString("class", "hwmon", hwmon, "name"),
+			Device:  strings.TrimSpace(s.readString("class", "hwmon", hwmon, "device", "model")),
+			Sensors: s.sensors(hwmon),
+		}
+		if chip.ID == "" {
+			chip.ID = hwmon
+		}
+		if len(chip.Sensors) > 0 {
+			chips = append(chips, chip)
+			named[chip.ID]++
+		}
+	}
+
+	for i, chip := range chips {
+		if named[chip.ID] > 1 {
+			chips[i].ID = chip.ID + "-" + s.hwmonDevice(chip.Hwmon)
+		}
+	}
+	sort.Slice(chips, func(i, j int) bool { return hwmonIndex(chips[i].Hwmon) < hwmonIndex(chips[j].Hwmon) })
+	return chips, nil
+}
+
+// hwmonDevice names the device a chip belongs to (nvme0, 0000:03:00.0,
+// coretemp.0) from its device link, falling back to the hwmon name for
+// chips without one
+func (s *SysFS) hwmonDevice(hwmon string) string {
+	if target, err := os.Readlink(s.path("class", "hwmon", hwmon, "device")); err == nil {
+		return filepath.Base(target)
+	}
+	return hwmon
+}
+
+func hwmonIndex(id string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(id, "hwmon"))
+	return n
+}
+
+func (s *SysFS) sensors(chip string) []Sensor {
+	entries, err := os.ReadDir(s.path("class", "hwmon", chip))
+	if err != nil {
+		return nil
+	}
+
+	type channel struct {
+		prefix string
+		index  int
+	}
+	var channels []channel
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		m := sensorInput.FindStringSubmatch(entry.Name())
+		if m == nil || seen[m[1]+m[2]] {
+			continue
+		}
+		seen[m[1]+m[2]] = true
+		index, _ := strconv.Atoi(m[2])
+		channels = append(channels, channel{m[1], index})
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		a, b := sensorKinds[channels[i].prefix].order, sensorKinds[channels[j].prefix].order
+		if a != b {
+			return a < b
+		}
+		return channels[i].index < channels[j].index
+	})
+
+	var sensors []Sensor
+	for _, ch := range channels {
+		kind := sensorKinds[ch.prefix]
+		id := ch.prefix + strconv.Itoa(ch.index)
+		read := func(attrs ...string) (float64, bool) {
+			for _, attr := range attrs {
+				if n, err := strconv.ParseInt(s.readString("class", "hwmon", chip, id+"_"+attr), 10, 64); err == nil {
+					return float64(n) / kind.scale, true
+				}
+			}
+			return 0, false
+		}
+
+		value, ok := read("input", "average")
+		if !ok {
+			continue
+		}
+		sensor := Sensor{ID: id, Kind: kind.kind, Label: s.readString("class", "hwmon", chip, id+"_label"), Value: value, Unit: kind.unit}
+		if sensor.Label == "" {
+			sensor.Label = id
+		}
+		sensor.Min, _ = read("min")
+		sensor.Max, _ = read("max", "cap")
+		sensor.Crit, _ = read("crit")
+		// An unset minimum reads as absolute zero on some chips
+		if sensor.Kind == "temp" && sensor.Min <= -273 {
+			sensor.Min = 0
+		}
+		for _, alarm := range []string{"_alarm", "_crit_alarm", "_max_alarm"} {
+			if s.readString("class", "hwmon", chip, id+alarm) == "1" {
+				sensor.Alarm = true
+			}
+		}
+		sensors = append(sensors, sensor)
+	}
+	return sensors
+}
+
+// cpuSensors lists, in order of preference, the chips and labels that
+// carry the CPU package temperature. An empty label takes the chip's
+// first temperature.
+var cpuSensors = []struct{ chip, label string }{
+	{"k10temp", "Tdie"},
+	{"k10temp", "Tctl"},
+	{"zenpower", "Tdie"},
+	{"zenpower", "Tctl"},
+	{"coretemp", "Package id 0"},
+	{"cpu_thermal", ""},
+	{"soc_thermal", ""},
+	{"acpitz", ""},
+}
+
+// cpuTemp picks the sensor that best stands for the CPU
+func cpuTemp(chips []SensorChip) (Sensor, bool) {
+	for _, want := range cpuSensors {
+		for _, chip := range chips {
+			if chip.Name != want.chip {
+				continue
+			}
+			for _, sensor := range chip.Sensors {
+				if sensor.Kind == "temp" && (want.label == "" || sensor.Label == want.label) {
+					return sensor, true
+				}
+			}
+		}
+	}
+	return Sensor{}, false
+}
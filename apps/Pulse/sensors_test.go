@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSysSensorChips(t *testing.T) {
+	chips, err := testSysFS().SensorChips()
+	if err != nil {
+		t.Fatalf("SensorChips: %v", err)
+	}
+
+	var names []string
+	for _, chip := range chips {
+		names = append(names, chip.Hwmon+" "+chip.ID)
+	}
+	want := []string{"hwmon0 acpitz", "hwmon1 k10temp", "hwmon2 nvme", "hwmon3 amdgpu", "hwmon4 nct6798", "hwmon5 drivetemp"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("chips = %v, want %v", names, want)
+	}
+
+	nvme := chips[2]
+	if nvme.Device != "Samsung SSD 980 PRO 1TB" {
+		t.Errorf("nvme device = %q", nvme.Device)
+	}
+	// The unset -273.15 minimum is dropped
+	composite := Sensor{ID: "temp1", Kind: "temp", Label: "Composite", Value: 38.85, Max: 81.85, Crit: 84.85, Unit: "°C"}
+	if nvme.Sensors[0] != composite {
+		t.Errorf("nvme temp1 = %+v, want %+v", nvme.Sensors[0], composite)
+	}
+	if drive := chips[5]; drive.Device != "ST2000DM008-2FR1" || drive.Sensors[0].Crit != 70 {
+		t.Errorf("drivetemp = %+v", drive)
+	}
+
+	var ids []string
+	for _, sensor := range chips[3].Sensors {
+		ids = append(ids, sensor.ID)
+	}
+	if want := []string{"temp1", "temp2", "fan1", "in0", "power1"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("amdgpu sensors = %v, want %v", ids, want)
+	}
+	power := chips[3].Sensors[4]
+	if power.Label != "PPT" || power.Value != 123 || power.Max != 255 || power.Unit != "W" {
+		t.Errorf("amdgpu power1 = %+v", power)
+	}
+
+	board := make(map[string]Sensor)
+	for _, sensor := range chips[4].Sensors {
+		board[sensor.ID] = sensor
+	}
+	if _, ok := board["temp7"]; ok {
+		t.Error("temp7 has no input and should be skipped")
+	}
+	if in1 := board["in1"]; in1.Value != 1.84 || in1.Min != 1.7 || in1.Max != 2 || in1.Alarm {
+		t.Errorf("in1 = %+v", in1)
+	}
+	if !board["in3"].Alarm {
+		t.Error("in3 alarm not reported")
+	}
+	if fan := board["fan2"]; fan.Kind != "fan" || fan.Value != 860 || fan.Min != 300 || fan.Label != "fan2" {
+		t.Errorf("fan2 = %+v", fan)
+	}
+	if curr := board["curr1"]; curr.Kind != "current" || curr.Value != 1.5 || curr.Unit != "A" {
+		t.Errorf("curr1 = %+v", curr)
+	}
+}
+
+func TestSensorChipIDs(t *testing.T) {
+	root := t.TempDir()
+	devices := filepath.Join(root, "devices")
+	// Two NVMe drives probed in the opposite order to their controllers,
+	// as can happen on any boot
+	for hwmon, chip := range map[string][2]string{
+		"hwmon0": {"nvme", "nvme1"},
+		"hwmon1": {"k10temp", "0000:00:18.3"},
+		"hwmon2": {"nvme", "nvme0"},
+	} {
+		dir := filepath.Join(root, "class", "hwmon", hwmon)
+		os.MkdirAll(filepath.Join(devices, chip[1]), 0755)
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "name"), []byte(chip[0]+"\n"), 0644)
+		os.WriteFile(filepath.Join(dir, "temp1_input"), []byte("40000\n"), 0644)
+		if err := os.Symlink(filepath.Join("..", "..", "..", "devices", chip[1]), filepath.Join(dir, "device")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chips, err := NewSysFS(root).SensorChips()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, chip := range chips {
+		ids = append(ids, chip.ID)
+	}
+	if want := []string{"nvme-nvme1", "k10temp", "nvme-nvme0"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+	if metrics := sampleMetrics(EnhancedSystemStats{Sensors: chips}); metrics["sensor.nvme-nvme0.temp1"] != 40 || metrics["sensor.k10temp.temp1"] != 40 {
+		t.Errorf("metrics = %v", metrics)
+	}
+}
+
+func TestCPUTemp(t *testing.T) {
+	chips, _ := testSysFS().SensorChips()
+	if sensor, ok := cpuTemp(chips); !ok || sensor.Label != "Tctl" || sensor.Value != 61.25 {
+		t.Errorf("cpuTemp = %+v, %v, want k10temp Tctl", sensor, ok)
+	}
+
+	intel := []SensorChip{
+		{Name: "acpitz", Sensors: []Sensor{{ID: "temp1", Kind: "temp", Label: "temp1", Value: 30}}},
+		{Name: "coretemp", Sensors: []Sensor{
+			{ID: "temp2", Kind: "temp", Label: "Core 0", Value: 50},
+			{ID: "temp1", Kind: "temp", Label: "Package id 0", Value: 55, Max: 80, Crit: 100},
+		}},
+	}
+	if sensor, _ := cpuTemp(intel); sensor.Label != "Package id 0" {
+		t.Errorf("coretemp = %+v, want the package sensor", sensor)
+	}
+	if sensor, _ := cpuTemp(intel[:1]); sensor.Value != 30 {
+		t.Errorf("acpitz fallback = %+v", sensor)
+	}
+	if _, ok := cpuTemp(nil); ok {
+		t.Error("no chips: want no sensor")
+	}
+
+	if got := tempStats(intel); got != (TempStats{CPU: 55, High: 80, Crit: 100, Label: "Package id 0", Unit: "°C"}) {
+		t.Errorf("tempStats = %+v", got)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SysFS reads device attributes from a sysfs tree, rooted like ProcFS so
+// tests can point it at fixtures
+type SysFS struct {
+	root string
+}
+
+// NewSysFS returns a reader rooted at root, or at /sys when root is ""
+func NewSysFS(root string) *SysFS {
+	if root == "" {
+		root = "/sys"
+	}
+	return &SysFS{root: root}
+}
+
+func (s *SysFS) path(elem ...string) string {
+	return filepath.Join(append([]string{s.root}, elem...)...)
+}
+
+// readString returns a trimmed attribute, or "" when it can't be read
+func (s *SysFS) readString(elem ...string) string {
+	data, err := os.ReadFile(s.path(elem...))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readUint returns a numeric attribute and whether it could be read
+func (s *SysFS) readUint(elem ...string) (uint64, bool) {
+	n, err := strconv.ParseUint(s.readString(elem...), 10, 64)
+	return n, err == nil
+}
+
+// CPUFreq is a core's clock and frequency policy. Frequencies are in MHz
+// and zero when the driver doesn't expose them (VMs, some ARM boards).
+type CPUFreq struct {
+	CurMHz   float64 `json:"curMHz"`
+	MinMHz   float64 `json:"minMHz"`
+	MaxMHz   float64 `json:"maxMHz"`
+	Governor string  `json:"governor"`
+}
+
+// CPUFreq reads /sys/devices/system/cpu/cpuN/cpufreq. The hardware limits
+// are used for min and max since the scaling limits follow power profiles.
+func (s *SysFS) CPUFreq(cpu int) CPUFreq {
+	dir := []string{"devices", "system", "cpu", "cpu" + strconv.Itoa(cpu), "cpufreq"}
+	khz := func(name string) float64 {
+		if n, ok := s.readUint(append(dir, name)...); ok {
+			return float64(n) / 1000
+		}
+		return 0
+	}
+
+	freq := CPUFreq{
+		CurMHz:   khz("scaling_cur_freq"),
+		MinMHz:   khz("cpuinfo_min_freq"),
+		MaxMHz:   khz("cpuinfo_max_freq"),
+		Governor: s.readString(append(dir, "scaling_governor")...),
+	}
+	if freq.CurMHz == 0 {
+		freq.CurMHz = khz("cpuinfo_cur_freq")
+	}
+	return freq
+}
+
+// BatteryStats combines the system's batteries
+type BatteryStats struct {
+	Percent  float64 `json:"percent"`
+	Status   string  `json:"status"` // Charging, Discharging, Full, Not charging
+	Charging bool    `json:"charging"`
+}
+
+// Battery reads /sys/class/power_supply; ok is false on machines without
+// a battery. With two batteries the charge is their average and the
+// status that of the first.
+func (s *SysFS) Battery() (BatteryStats, bool) {
+	entries, err := os.ReadDir(s.path("class", "power_supply"))
+	if err != nil {
+		return BatteryStats{}, false
+	}
+
+	var stats BatteryStats
+	count := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if s.readString("class", "power_supply", name, "type") != "Battery" ||
+			s.readString("class", "power_supply", name, "scope") == "Device" {
+			continue
+		}
+		capacity, ok := s.readUint("class", "power_supply", name, "capacity")
+		if !ok {
+			continue
+		}
+		if count == 0 {
+			stats.Status = s.readString("class", "power_supply", name, "status")
+		}
+		stats.Percent += float64(capacity)
+		count++
+	}
+	if count == 0 {
+		return BatteryStats{}, false
+	}
+	stats.Percent /= float64(count)
+	stats.Charging = stats.Status == "Charging"
+	return stats, true
+}
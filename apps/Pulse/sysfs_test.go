@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func testSysFS() *SysFS {
+	return NewSysFS(filepath.Join("testdata", "sys"))
+}
+
+func TestSysCPUFreq(t *testing.T) {
+	tests := []struct {
+		cpu  int
+		want CPUFreq
+	}{
+		{0, CPUFreq{CurMHz: 3412, MinMHz: 2200, MaxMHz: 4850, Governor: "schedutil"}},
+		// Falls back to cpuinfo_cur_freq without scaling_cur_freq
+		{1, CPUFreq{CurMHz: 2200, MinMHz: 2200, MaxMHz: 4850, Governor: "performance"}},
+		// No cpufreq directory at all, as in most VMs
+		{7, CPUFreq{}},
+	}
+
+	for _, tt := range tests {
+		if got := testSysFS().CPUFreq(tt.cpu); got != tt.want {
+			t.Errorf("CPUFreq(%d) = %+v, want %+v", tt.cpu, got, tt.want)
+		}
+	}
+}
+
+func TestAppCPUStats(t *testing.T) {
+	app := &App{proc: testProcFS(), sys: testSysFS()}
+
+	stats := app.cpuStats()
+	if stats.Model != "AMD Ryzen 7 5800X 8-Core Processor" || stats.Cores != 4 {
+		t.Errorf("model = %q, cores = %d", stats.Model, stats.Cores)
+	}
+	if stats.Load.One != 2.15 || stats.Governor != "schedutil, performance" {
+		t.Errorf("load = %+v, governor = %q", stats.Load, stats.Governor)
+	}
+	if len(stats.PerCore) != 2 {
+		t.Fatalf("PerCore = %+v, want 2 cores", stats.PerCore)
+	}
+
+	// The first sample compares with boot
+	core := stats.PerCore[0]
+	near := func(got, want float64) bool { return math.Abs(got-want) < 0.01 }
+	if core.ID != 0 || !near(core.Usage, 17.29) || !near(core.User, 13.43) || !near(core.System, 3.54) ||
+		!near(core.IOWait, 1.07) || !near(core.Steal, 0.32) || core.CurMHz != 3412 {
+		t.Errorf("core 0 = %+v", core)
+	}
+
+	// Nothing changed in the fixture, so the next sample is idle
+	if stats := app.cpuStats(); stats.Usage != 0 || stats.PerCore[1].Usage != 0 {
+		t.Errorf("second sample = %+v, want no usage", stats)
+	}
+}
+
+func TestSysBattery(t *testing.T) {
+	// The mouse's battery is left out
+	battery, ok := testSysFS().Battery()
+	if want := (BatteryStats{Percent: 50, Status: "Discharging"}); !ok || battery != want {
+		t.Errorf("Battery = %+v, %v, want %+v", battery, ok, want)
+	}
+	if _, ok := NewSysFS(t.TempDir()).Battery(); ok {
+		t.Error("desktop without power_supply: want no battery")
+	}
+}
@@ -0,0 +1,209 @@
+// Package filepicker opens a native file chooser for the KaguyaDots apps.
+//
+// It tries the org.freedesktop.portal.FileChooser D-Bus portal first, then
+// the yad, zenity and kdialog CLI dialogs, and finally an app supplied
+// fallback (normally Wails' runtime.OpenFileDialog).
+package filepicker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"kaguyadots/runner"
+)
+
+// ErrCancelled is returned when the user closes the dialog without choosing
+var ErrCancelled = errors.New("file selection cancelled")
+
+// errUnavailable marks a backend that is not installed or not reachable,
+// so Pick moves on to the next one instead of giving up
+var errUnavailable = errors.New("file picker unavailable")
+
+// Filter restricts the files shown, e.g. {"Images", []string{"*.png", "*.jpg"}}
+type Filter struct {
+	Name     string
+	Patterns []string
+}
+
+// Options describes a single file chooser request
+type Options struct {
+	Title           string
+	StartDir        string
+	SelectDirectory bool
+	Multiple        bool
+	Filters         []Filter
+
+	// Fallback is used when neither the portal nor a CLI dialog is available
+	Fallback func(opts Options) ([]string, error)
+
+	// Runner runs the CLI dialogs; nil means runner.Default
+	Runner runner.Runner
+}
+
+type backend struct {
+	name string
+	pick func(ctx context.Context, opts Options) ([]string, error)
+}
+
+// Pick shows a file chooser and returns the selected absolute paths. The
+// dialog is closed and ctx.Err() returned if ctx ends first.
+func Pick(ctx context.Context, opts Options) ([]string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.Title == "" {
+		opts.Title = "Select File"
+		if opts.SelectDirectory {
+			opts.Title = "Select Directory"
+		}
+	}
+	if opts.Runner == nil {
+		opts.Runner = runner.Default
+	}
+
+	backends := []backend{
+		{"portal", pickPortal},
+		{"yad", pickYad},
+		{"zenity", pickZenity},
+		{"kdialog", pickKdialog},
+	}
+	if opts.Fallback != nil {
+		backends = append(backends, backend{"native", func(ctx context.Context, opts Options) ([]string, error) {
+			return opts.Fallback(opts)
+		}})
+	}
+
+	for _, b := range backends {
+		paths, err := b.pick(ctx, opts)
+		if errors.Is(err, errUnavailable) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) == 0 {
+			return nil, ErrCancelled
+		}
+		return paths, nil
+	}
+
+	return nil, fmt.Errorf("no file picker found. Please install 'xdg-desktop-portal' or 'yad': sudo pacman -S yad")
+}
+
+// PickOne is Pick for a single path
+func PickOne(ctx context.Context, opts Options) (string, error) {
+	opts.Multiple = false
+	paths, err := Pick(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	return paths[0], nil
+}
+
+func pickYad(ctx context.Context, opts Options) ([]string, error) {
+	path, err := opts.Runner.LookPath("yad")
+	if err != nil {
+		return nil, errUnavailable
+	}
+	return runDialog(ctx, opts.Runner, path, yadArgs(opts))
+}
+
+func yadArgs(opts Options) []string {
+	args := []string{"--file", "--title=" + opts.Title, "--separator=\n"}
+	if opts.SelectDirectory {
+		args = append(args, "--directory")
+	}
+	if opts.Multiple {
+		args = append(args, "--multiple")
+	}
+	if opts.StartDir != "" {
+		args = append(args, "--filename="+strings.TrimSuffix(opts.StartDir, "/")+"/")
+	}
+	for _, f := range opts.Filters {
+		args = append(args, "--file-filter="+f.Name+"|"+strings.Join(f.Patterns, " "))
+	}
+	return args
+}
+
+func pickZenity(ctx context.Context, opts Options) ([]string, error) {
+	path, err := opts.Runner.LookPath("zenity")
+	if err != nil {
+		return nil, errUnavailable
+	}
+	return runDialog(ctx, opts.Runner, path, zenityArgs(opts))
+}
+
+func zenityArgs(opts Options) []string {
+	args := []string{"--file-selection", "--title=" + opts.Title, "--separator=\n"}
+	if opts.SelectDirectory {
+		args = append(args, "--directory")
+	}
+	if opts.Multiple {
+		args = append(args, "--multiple")
+	}
+	if opts.StartDir != "" {
+		args = append(args, "--filename="+strings.TrimSuffix(opts.StartDir, "/")+"/")
+	}
+	for _, f := range opts.Filters {
+		args = append(args, "--file-filter="+f.Name+" | "+strings.Join(f.Patterns, " "))
+	}
+	return args
+}
+
+func pickKdialog(ctx context.Context, opts Options) ([]string, error) {
+	path, err := opts.Runner.LookPath("kdialog")
+	if err != nil {
+		return nil, errUnavailable
+	}
+	return runDialog(ctx, opts.Runner, path, kdialogArgs(opts))
+}
+
+func kdialogArgs(opts Options) []string {
+	startDir := opts.StartDir
+	if startDir == "" {
+		startDir = "."
+	}
+
+	var args []string
+	switch {
+	case opts.SelectDirectory:
+		args = []string{"--getexistingdirectory", startDir}
+	case opts.Multiple:
+		args = []string{"--getopenfilename", startDir, kdialogFilter(opts.Filters), "--multiple", "--separate-output"}
+	default:
+		args = []string{"--getopenfilename", startDir, kdialogFilter(opts.Filters)}
+	}
+	return append(args, "--title", opts.Title)
+}
+
+// kdialogFilter builds "*.png *.jpg|Images\n*|All files"
+func kdialogFilter(filters []Filter) string {
+	var parts []string
+	for _, f := range filters {
+		parts = append(parts, strings.Join(f.Patterns, " ")+"|"+f.Name)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// runDialog runs a CLI dialog and splits its newline separated output.
+// Every dialog exits non-zero when the user cancels.
+func runDialog(ctx context.Context, r runner.Runner, path string, args []string) ([]string, error) {
+	output, err := r.Output(ctx, runner.Cmd(path, args...))
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, ErrCancelled
+	}
+
+	var paths []string
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSuffix(strings.TrimSpace(line), "|")
+		if line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths, nil
+}
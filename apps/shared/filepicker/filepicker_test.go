@@ -0,0 +1,134 @@
+package filepicker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+
+	"kaguyadots/runner"
+)
+
+var images = []Filter{
+	{Name: "Images", Patterns: []string{"*.png", "*.jpg"}},
+	{Name: "All files", Patterns: []string{"*"}},
+}
+
+func TestDialogArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(Options) []string
+		opts  Options
+		want  []string
+	}{
+		{"yad file", yadArgs, Options{Title: "Pick", StartDir: "/home/k/Pictures/", Filters: images},
+			[]string{"--file", "--title=Pick", "--separator=\n", "--filename=/home/k/Pictures/", "--file-filter=Images|*.png *.jpg", "--file-filter=All files|*"}},
+		{"yad directories", yadArgs, Options{Title: "Pick", SelectDirectory: true, Multiple: true},
+			[]string{"--file", "--title=Pick", "--separator=\n", "--directory", "--multiple"}},
+		{"zenity file", zenityArgs, Options{Title: "Pick", StartDir: "/tmp", Filters: images[:1]},
+			[]string{"--file-selection", "--title=Pick", "--separator=\n", "--filename=/tmp/", "--file-filter=Images | *.png *.jpg"}},
+		{"zenity directory", zenityArgs, Options{Title: "Pick", SelectDirectory: true},
+			[]string{"--file-selection", "--title=Pick", "--separator=\n", "--directory"}},
+		{"kdialog file", kdialogArgs, Options{Title: "Pick", Filters: images},
+			[]string{"--getopenfilename", ".", "*.png *.jpg|Images\n*|All files", "--title", "Pick"}},
+		{"kdialog multiple", kdialogArgs, Options{Title: "Pick", StartDir: "/tmp", Multiple: true},
+			[]string{"--getopenfilename", "/tmp", "", "--multiple", "--separate-output", "--title", "Pick"}},
+		{"kdialog directory", kdialogArgs, Options{Title: "Pick", StartDir: "/tmp", SelectDirectory: true, Filters: images},
+			[]string{"--getexistingdirectory", "/tmp", "--title", "Pick"}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.build(tt.opts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: args = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestKdialogFilter(t *testing.T) {
+	tests := []struct {
+		filters []Filter
+		want    string
+	}{
+		{nil, ""},
+		{images[:1], "*.png *.jpg|Images"},
+		{images, "*.png *.jpg|Images\n*|All files"},
+	}
+	for _, tt := range tests {
+		if got := kdialogFilter(tt.filters); got != tt.want {
+			t.Errorf("kdialogFilter(%v) = %q, want %q", tt.filters, got, tt.want)
+		}
+	}
+}
+
+func TestParsePortalResponse(t *testing.T) {
+	uris := func(uris ...string) map[string]dbus.Variant {
+		return map[string]dbus.Variant{"uris": dbus.MakeVariant(uris)}
+	}
+
+	tests := []struct {
+		name string
+		body []interface{}
+		want []string
+		err  error
+	}{
+		{"one file", []interface{}{uint32(0), uris("file:///home/k/My%20Clip.webm")}, []string{"/home/k/My Clip.webm"}, nil},
+		{"several", []interface{}{uint32(0), uris("file:///a.png", "file:///b.png")}, []string{"/a.png", "/b.png"}, nil},
+		{"remote uri skipped", []interface{}{uint32(0), uris("sftp://host/a.png", "file:///b.png")}, []string{"/b.png"}, nil},
+		{"no uris", []interface{}{uint32(0), map[string]dbus.Variant{}}, nil, nil},
+		{"cancelled", []interface{}{uint32(1), uris()}, nil, ErrCancelled},
+	}
+	for _, tt := range tests {
+		got, err := parsePortalResponse(tt.body)
+		if !reflect.DeepEqual(got, tt.want) || !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %q, %v; want %q, %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+
+	if _, err := parsePortalResponse([]interface{}{uint32(2), uris()}); err == nil || errors.Is(err, ErrCancelled) {
+		t.Errorf("portal failure: err = %v, want a failure other than cancel", err)
+	}
+}
+
+func TestPortalOptions(t *testing.T) {
+	options := portalOptions("tok", Options{StartDir: "/tmp", Multiple: true, Filters: images[:1]})
+
+	if options["handle_token"].Value() != "tok" || options["multiple"].Value() != true || options["directory"].Value() != false {
+		t.Errorf("options = %v", options)
+	}
+	if folder := options["current_folder"].Value().([]byte); string(folder) != "/tmp\x00" {
+		t.Errorf("current_folder = %q, want NUL terminated", folder)
+	}
+	want := []portalFilter{{Name: "Images", Patterns: []portalPattern{{0, "*.png"}, {0, "*.jpg"}}}}
+	if got := options["filters"].Value(); !reflect.DeepEqual(got, want) {
+		t.Errorf("filters = %v, want %v", got, want)
+	}
+	if _, ok := portalOptions("tok", Options{})["current_folder"]; ok {
+		t.Error("current_folder set without a StartDir")
+	}
+}
+
+func TestRunDialog(t *testing.T) {
+	fake := runner.NewFake().
+		On("/usr/bin/yad --file --title=Pick", "/a.png|\n/b c.png\n\n", nil).
+		On("/usr/bin/zenity", "", errors.New("exit status 1"))
+	opts := Options{Title: "Pick", Runner: fake}
+
+	paths, err := pickYad(context.Background(), opts)
+	if err != nil || !reflect.DeepEqual(paths, []string{"/a.png", "/b c.png"}) {
+		t.Errorf("yad = %q, %v", paths, err)
+	}
+	if _, err := pickZenity(context.Background(), opts); !errors.Is(err, ErrCancelled) {
+		t.Errorf("zenity exiting non-zero: err = %v, want ErrCancelled", err)
+	}
+	if _, err := pickKdialog(context.Background(), Options{Runner: runner.NewFake().Missing("kdialog")}); !errors.Is(err, errUnavailable) {
+		t.Errorf("missing kdialog: err = %v, want errUnavailable", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pickYad(ctx, opts); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled ctx: err = %v, want context.Canceled", err)
+	}
+}
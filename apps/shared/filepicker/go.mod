@@ -0,0 +1,17 @@
+module kaguyadots/filepicker
+
+go 1.24.0
+
+require (
+	github.com/godbus/dbus/v5 v5.2.2
+	github.com/wailsapp/wails/v2 v2.11.0
+	kaguyadots/runner v0.0.0
+)
+
+require (
+	github.com/leaanthony/slicer v1.6.0 // indirect
+	github.com/leaanthony/u v1.1.1 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+)
+
+replace kaguyadots/runner => ../runner
@@ -0,0 +1,147 @@
+package filepicker
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	portalBus       = "org.freedesktop.portal.Desktop"
+	portalPath      = "/org/freedesktop/portal/desktop"
+	portalChooser   = "org.freedesktop.portal.FileChooser"
+	portalRequest   = "org.freedesktop.portal.Request"
+	portalTimeout   = 10 * time.Minute
+	portalCancelled = 1
+)
+
+// portalFilter matches the a(sa(us)) filter signature of the portal
+type portalFilter struct {
+	Name     string
+	Patterns []portalPattern
+}
+
+type portalPattern struct {
+	Kind    uint32 // 0 = glob, 1 = mime type
+	Pattern string
+}
+
+// pickPortal asks xdg-desktop-portal to show the compositor's file chooser
+func pickPortal(ctx context.Context, opts Options) ([]string, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, errUnavailable
+	}
+	defer conn.Close()
+
+	// Subscribe to the response before calling so a fast reply isn't missed
+	token := fmt.Sprintf("kaguyadots%d", rand.Int63())
+	sender := strings.ReplaceAll(strings.TrimPrefix(conn.Names()[0], ":"), ".", "_")
+	handle := dbus.ObjectPath(fmt.Sprintf("%s/request/%s/%s", portalPath, sender, token))
+
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(handle),
+		dbus.WithMatchInterface(portalRequest),
+		dbus.WithMatchMember("Response"),
+	); err != nil {
+		return nil, errUnavailable
+	}
+
+	signals := make(chan *dbus.Signal, 1)
+	conn.Signal(signals)
+
+	var requestPath dbus.ObjectPath
+	obj := conn.Object(portalBus, portalPath)
+	call := obj.CallWithContext(ctx, portalChooser+".OpenFile", 0, "", opts.Title, portalOptions(token, opts))
+	if err := call.Store(&requestPath); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, errUnavailable
+	}
+
+	// Portals older than 0.9 ignore handle_token and pick their own path
+	if requestPath != handle {
+		conn.AddMatchSignal(
+			dbus.WithMatchObjectPath(requestPath),
+			dbus.WithMatchInterface(portalRequest),
+			dbus.WithMatchMember("Response"),
+		)
+	}
+
+	// A dialog left open is closed rather than abandoned on screen
+	closeRequest := func() {
+		conn.Object(portalBus, requestPath).Call(portalRequest+".Close", 0)
+	}
+
+	timeout := time.NewTimer(portalTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Path != requestPath || len(signal.Body) < 2 {
+				continue
+			}
+			return parsePortalResponse(signal.Body)
+		case <-timeout.C:
+			closeRequest()
+			return nil, ErrCancelled
+		case <-ctx.Done():
+			closeRequest()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// portalOptions builds the OpenFile options vardict
+func portalOptions(token string, opts Options) map[string]dbus.Variant {
+	options := map[string]dbus.Variant{
+		"handle_token": dbus.MakeVariant(token),
+		"multiple":     dbus.MakeVariant(opts.Multiple),
+		"directory":    dbus.MakeVariant(opts.SelectDirectory),
+	}
+	if opts.StartDir != "" {
+		// current_folder is a NUL terminated byte string
+		options["current_folder"] = dbus.MakeVariant(append([]byte(opts.StartDir), 0))
+	}
+	if len(opts.Filters) > 0 {
+		var filters []portalFilter
+		for _, f := range opts.Filters {
+			pf := portalFilter{Name: f.Name}
+			for _, pattern := range f.Patterns {
+				pf.Patterns = append(pf.Patterns, portalPattern{Kind: 0, Pattern: pattern})
+			}
+			filters = append(filters, pf)
+		}
+		options["filters"] = dbus.MakeVariant(filters)
+	}
+	return options
+}
+
+func parsePortalResponse(body []interface{}) ([]string, error) {
+	code, _ := body[0].(uint32)
+	if code == portalCancelled {
+		return nil, ErrCancelled
+	}
+	if code != 0 {
+		return nil, fmt.Errorf("file chooser portal failed (response %d)", code)
+	}
+
+	results, _ := body[1].(map[string]dbus.Variant)
+	uris, _ := results["uris"].Value().([]string)
+
+	var paths []string
+	for _, uri := range uris {
+		parsed, err := url.Parse(uri)
+		if err != nil || parsed.Scheme != "file" {
+			continue
+		}
+		paths = append(paths, parsed.Path)
+	}
+	return paths, nil
+}
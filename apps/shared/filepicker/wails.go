@@ -0,0 +1,45 @@
+package filepicker
+
+import (
+	"context"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// WailsFallback returns a Fallback that uses the Wails runtime dialogs
+func WailsFallback(ctx context.Context) func(opts Options) ([]string, error) {
+	return func(opts Options) ([]string, error) {
+		if ctx == nil {
+			return nil, errUnavailable
+		}
+
+		dialog := runtime.OpenDialogOptions{
+			Title:            opts.Title,
+			DefaultDirectory: opts.StartDir,
+		}
+		for _, f := range opts.Filters {
+			dialog.Filters = append(dialog.Filters, runtime.FileFilter{
+				DisplayName: f.Name,
+				Pattern:     strings.Join(f.Patterns, ";"),
+			})
+		}
+
+		switch {
+		case opts.SelectDirectory:
+			path, err := runtime.OpenDirectoryDialog(ctx, dialog)
+			if err != nil || path == "" {
+				return nil, err
+			}
+			return []string{path}, nil
+		case opts.Multiple:
+			return runtime.OpenMultipleFilesDialog(ctx, dialog)
+		default:
+			path, err := runtime.OpenFileDialog(ctx, dialog)
+			if err != nil || path == "" {
+				return nil, err
+			}
+			return []string{path}, nil
+		}
+	}
+}
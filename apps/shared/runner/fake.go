@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Call is one command received by a Fake
+type Call struct {
+	Command
+	Input    string // what was read from Stdin
+	Detached bool   // started with Start
+}
+
+type fakeResponse struct {
+	prefix string
+	output string
+	err    error
+}
+
+// Fake records commands instead of running them. Responses are matched by
+// the longest registered prefix of the command line; unmatched commands
+// succeed with no output.
+type Fake struct {
+	mu        sync.Mutex
+	calls     []Call
+	responses []fakeResponse
+	missing   map[string]bool
+	nextPID   int
+}
+
+// NewFake returns a Fake where every command succeeds silently
+func NewFake() *Fake {
+	return &Fake{missing: make(map[string]bool), nextPID: 1000}
+}
+
+// On sets the output and error for commands starting with prefix
+func (f *Fake) On(prefix, output string, err error) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses = append(f.responses, fakeResponse{prefix: prefix, output: output, err: err})
+	return f
+}
+
+// Missing makes LookPath fail for the named executables
+func (f *Fake) Missing(names ...string) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, name := range names {
+		f.missing[name] = true
+	}
+	return f
+}
+
+// Calls returns every command received so far
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call{}, f.calls...)
+}
+
+// Commands returns the received command lines
+func (f *Fake) Commands() []string {
+	var lines []string
+	for _, call := range f.Calls() {
+		lines = append(lines, call.String())
+	}
+	return lines
+}
+
+// Reset forgets the recorded calls but keeps the responses
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+func (f *Fake) record(c Command, detached bool) fakeResponse {
+	call := Call{Command: c, Detached: detached}
+	if c.Stdin != nil {
+		data, _ := io.ReadAll(c.Stdin)
+		call.Input = string(data)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+
+	line := c.String()
+	best := fakeResponse{}
+	for _, response := range f.responses {
+		if strings.HasPrefix(line, response.prefix) && len(response.prefix) >= len(best.prefix) {
+			best = response
+		}
+	}
+	return best
+}
+
+func (f *Fake) Output(ctx context.Context, c Command) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	response := f.record(c, false)
+	return []byte(response.output), response.err
+}
+
+func (f *Fake) CombinedOutput(ctx context.Context, c Command) ([]byte, error) {
+	return f.Output(ctx, c)
+}
+
+func (f *Fake) Run(ctx context.Context, c Command) error {
+	_, err := f.Output(ctx, c)
+	return err
+}
+
+func (f *Fake) Start(c Command) (int, error) {
+	response := f.record(c, true)
+	if response.err != nil {
+		return 0, response.err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextPID++
+	return f.nextPID, nil
+}
+
+func (f *Fake) LookPath(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.missing[name] {
+		return "", fmt.Errorf("exec: %q: executable file not found in $PATH", name)
+	}
+	return "/usr/bin/" + name, nil
+}
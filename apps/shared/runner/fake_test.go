@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFakeMatchesLongestPrefix(t *testing.T) {
+	fake := NewFake().
+		On("hyprctl", "ok", nil).
+		On("hyprctl monitors", "[]", nil).
+		On("hyprctl reload", "", errors.New("exit status 1"))
+
+	tests := []struct {
+		cmd     Command
+		output  string
+		wantErr bool
+	}{
+		{Cmd("hyprctl", "monitors", "-j"), "[]", false},
+		{Cmd("hyprctl", "clients", "-j"), "ok", false},
+		{Cmd("hyprctl", "reload"), "", true},
+		{Cmd("waypaper"), "", false},
+	}
+
+	for _, tt := range tests {
+		output, err := fake.Output(context.Background(), tt.cmd)
+		if string(output) != tt.output || (err != nil) != tt.wantErr {
+			t.Errorf("Output(%s) = %q, %v; want %q, error %v", tt.cmd, output, err, tt.output, tt.wantErr)
+		}
+	}
+
+	if got := len(fake.Calls()); got != len(tests) {
+		t.Errorf("recorded %d calls, want %d", got, len(tests))
+	}
+}
+
+func TestFakeRecordsInputAndDetached(t *testing.T) {
+	fake := NewFake().Missing("yad")
+
+	if err := fake.Run(context.Background(), Cmd("wl-copy").WithStdin(strings.NewReader("hello"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fake.Start(Cmd("sh", "-c", "kitty")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fake.LookPath("yad"); err == nil {
+		t.Error("LookPath(yad) succeeded for a missing binary")
+	}
+
+	calls := fake.Calls()
+	if calls[0].Input != "hello" || calls[0].Detached {
+		t.Errorf("wl-copy call = %+v", calls[0])
+	}
+	if !calls[1].Detached || calls[1].String() != "sh -c kitty" {
+		t.Errorf("start call = %+v", calls[1])
+	}
+}
+
+func TestFakeHonoursCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	fake := NewFake()
+	if err := fake.Run(ctx, Cmd("ffmpeg")); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if len(fake.Calls()) != 0 {
+		t.Error("cancelled command was recorded")
+	}
+}
@@ -0,0 +1,3 @@
+module kaguyadots/runner
+
+go 1.24.0
@@ -0,0 +1,135 @@
+// Package runner runs external commands for the KaguyaDots apps.
+//
+// Services take a Runner instead of calling os/exec directly so they can be
+// tested with Fake, which records every command and replays canned output.
+package runner
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Command describes one process to run
+type Command struct {
+	Name  string
+	Args  []string
+	Env   []string  // appended to the current environment
+	Stdin io.Reader // nil for no input
+	// Interrupt makes cancelling send SIGINT first, for programs such as
+	// recorders that finalise their output when interrupted
+	Interrupt bool
+}
+
+// Cmd builds a Command from a name and arguments
+func Cmd(name string, args ...string) Command {
+	return Command{Name: name, Args: args}
+}
+
+// WithEnv returns a copy of the command with extra environment variables
+func (c Command) WithEnv(env ...string) Command {
+	c.Env = append(append([]string{}, c.Env...), env...)
+	return c
+}
+
+// WithStdin returns a copy of the command reading input from r
+func (c Command) WithStdin(r io.Reader) Command {
+	c.Stdin = r
+	return c
+}
+
+// WithInterrupt returns a copy of the command that is interrupted rather
+// than killed when its context is cancelled
+func (c Command) WithInterrupt() Command {
+	c.Interrupt = true
+	return c
+}
+
+// String renders the command line, space separated
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
+
+// Runner executes commands
+type Runner interface {
+	// Output runs the command and returns its stdout
+	Output(ctx context.Context, cmd Command) ([]byte, error)
+	// CombinedOutput runs the command and returns stdout and stderr together
+	CombinedOutput(ctx context.Context, cmd Command) ([]byte, error)
+	// Run runs the command, discarding its output
+	Run(ctx context.Context, cmd Command) error
+	// Start launches the command in its own session so it outlives the
+	// app, and returns its pid
+	Start(cmd Command) (int, error)
+	// LookPath finds an executable in PATH
+	LookPath(name string) (string, error)
+}
+
+// System runs commands with os/exec. Cancelling the context kills the
+// command's whole process group, so scripts don't leave children behind.
+// Commands marked Interrupt get SIGINT and interruptGrace to exit first.
+type System struct{}
+
+const interruptGrace = 5 * time.Second
+
+// Default is the Runner services use unless given another
+var Default Runner = System{}
+
+func (System) command(ctx context.Context, c Command) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	cmd.Stdin = c.Stdin
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = 2 * time.Second
+	if c.Interrupt {
+		cmd.Cancel = func() error {
+			pgid := -cmd.Process.Pid
+			time.AfterFunc(interruptGrace, func() { syscall.Kill(pgid, syscall.SIGKILL) })
+			return syscall.Kill(pgid, syscall.SIGINT)
+		}
+		cmd.WaitDelay = interruptGrace
+	}
+	return cmd
+}
+
+func (s System) Output(ctx context.Context, c Command) ([]byte, error) {
+	return s.command(ctx, c).Output()
+}
+
+func (s System) CombinedOutput(ctx context.Context, c Command) ([]byte, error) {
+	return s.command(ctx, c).CombinedOutput()
+}
+
+func (s System) Run(ctx context.Context, c Command) error {
+	return s.command(ctx, c).Run()
+}
+
+func (System) Start(c Command) (int, error) {
+	cmd := exec.Command(c.Name, c.Args...)
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	cmd.Stdin = c.Stdin
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	// Setsid detaches the session but the process is still our child, so
+	// reap it when it exits instead of leaving a zombie
+	go cmd.Wait()
+	return cmd.Process.Pid, nil
+}
+
+func (System) LookPath(name string) (string, error) {
+	return exec.LookPath(name)
+}
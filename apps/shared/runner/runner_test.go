@@ -0,0 +1,35 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSystemInterrupt(t *testing.T) {
+	if _, err := (System{}).LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	script := `trap 'echo finalised; exit 0' INT; sleep 10 >/dev/null 2>&1 & wait`
+
+	// An interrupted command gets to clean up before it exits
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	output, _ := System{}.Output(ctx, Cmd("sh", "-c", script).WithInterrupt())
+	if !strings.Contains(string(output), "finalised") {
+		t.Errorf("output = %q, want the INT trap to run", output)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("took %v to stop", elapsed)
+	}
+
+	// Other commands are killed outright
+	ctx, cancel = context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	output, _ = System{}.Output(ctx, Cmd("sh", "-c", script))
+	if strings.Contains(string(output), "finalised") {
+		t.Errorf("killed command ran its INT trap: %q", output)
+	}
+}